@@ -0,0 +1,193 @@
+package main
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"reflect"
+	"sync"
+)
+
+// defaultCacheSize is how many query results are cached per workspace.
+const defaultCacheSize = 1000
+
+// cacheKey identifies a read-only query about a document at a given content.
+type cacheKey struct {
+	method    string
+	path      string
+	line      int
+	character int
+	extra     string
+	hash      [sha256.Size]byte
+}
+
+// cacheEntry is a cached query result.
+type cacheEntry struct {
+	key   cacheKey
+	value any
+}
+
+// resultCache is a least recently used cache of read-only LSP query results.
+// Results depend on the whole workspace, not only on the queried document, so
+// the cache is emptied whenever gopls may see the workspace differently.
+type resultCache struct {
+	mu            sync.Mutex
+	maxEntries    int
+	entries       map[cacheKey]*list.Element
+	order         *list.List
+	generation    uint64
+	hits          int64
+	misses        int64
+	evictions     int64
+	invalidations int64
+}
+
+// newResultCache creates a cache holding up to maxEntries results. Zero selects
+// the default size and a negative size disables caching.
+func newResultCache(maxEntries int) *resultCache {
+	if maxEntries == 0 {
+		maxEntries = defaultCacheSize
+	}
+	if maxEntries < 0 {
+		maxEntries = 0
+	}
+	return &resultCache{
+		maxEntries: maxEntries,
+		entries:    make(map[cacheKey]*list.Element),
+		order:      list.New(),
+	}
+}
+
+// get returns the cached result for key. The returned generation must be
+// passed to put so results computed across an invalidation are not stored.
+func (rc *resultCache) get(key cacheKey) (any, uint64, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if rc.maxEntries == 0 {
+		return nil, rc.generation, false
+	}
+
+	element, exists := rc.entries[key]
+	if !exists {
+		rc.misses++
+		return nil, rc.generation, false
+	}
+	rc.hits++
+	rc.order.MoveToFront(element)
+	entry, _ := element.Value.(*cacheEntry)
+	return entry.value, rc.generation, true
+}
+
+// put stores a result unless the cache was invalidated since generation.
+func (rc *resultCache) put(key cacheKey, value any, generation uint64) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if rc.maxEntries == 0 || generation != rc.generation {
+		return
+	}
+
+	if element, exists := rc.entries[key]; exists {
+		element.Value = &cacheEntry{key: key, value: value}
+		rc.order.MoveToFront(element)
+		return
+	}
+	rc.entries[key] = rc.order.PushFront(&cacheEntry{key: key, value: value})
+
+	for rc.order.Len() > rc.maxEntries {
+		oldest := rc.order.Back()
+		entry, _ := oldest.Value.(*cacheEntry)
+		rc.order.Remove(oldest)
+		delete(rc.entries, entry.key)
+		rc.evictions++
+	}
+}
+
+// invalidate drops every cached result.
+func (rc *resultCache) invalidate() {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.generation++
+	if len(rc.entries) == 0 {
+		return
+	}
+	rc.entries = make(map[cacheKey]*list.Element)
+	rc.order.Init()
+	rc.invalidations++
+}
+
+// stats returns the size and hit-rate statistics of the cache.
+func (rc *resultCache) stats() CacheStats {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	stats := CacheStats{
+		Entries:       len(rc.entries),
+		MaxEntries:    rc.maxEntries,
+		Hits:          rc.hits,
+		Misses:        rc.misses,
+		Evictions:     rc.evictions,
+		Invalidations: rc.invalidations,
+	}
+	if lookups := rc.hits + rc.misses; lookups > 0 {
+		stats.HitRate = float64(rc.hits) / float64(lookups)
+	}
+	return stats
+}
+
+// documentCacheKey builds the cache key of a query about an open document.
+// It reports false when the document is not open.
+func (c *goplsClient) documentCacheKey(
+	method, relativePath string, line, character int, extra string,
+) (cacheKey, bool) {
+	c.openFilesMux.Lock()
+	defer c.openFilesMux.Unlock()
+
+	document, isOpen := c.openFiles[relativePath]
+	if !isOpen {
+		return cacheKey{}, false
+	}
+	return cacheKey{
+		method:    method,
+		path:      relativePath,
+		line:      line,
+		character: character,
+		extra:     extra,
+		hash:      document.hash,
+	}, true
+}
+
+// cachedQuery returns the cached result of a query about an open document or
+// runs fetch and caches its result. Cached results are shared between callers
+// and must not be modified.
+func cachedQuery[T any](
+	c *goplsClient, method, relativePath string, line, character int, extra string, fetch func() (T, error),
+) (T, error) {
+	key, cacheable := c.documentCacheKey(method, relativePath, line, character, extra)
+	if !cacheable {
+		return fetch()
+	}
+
+	cached, generation, hit := c.cache.get(key)
+	if value, ok := cached.(T); hit && ok {
+		c.logger.Debug("cache hit", "method", method, "relativePath", relativePath)
+		return value, nil
+	}
+
+	value, err := fetch()
+	if err != nil {
+		return value, err
+	}
+	c.cache.put(key, value, generation)
+	return value, nil
+}
+
+// invalidateCacheOnDiagnostics empties the cache when gopls republishes
+// different diagnostics for a file, which means its view of the workspace changed.
+func (c *goplsClient) invalidateCacheOnDiagnostics(previous *fileDiagnostics, diagnostics []Diagnostic) {
+	if previous == nil || reflect.DeepEqual(previous.items, diagnostics) {
+		return
+	}
+	c.cache.invalidate()
+}
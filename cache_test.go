@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestResultCacheEviction(t *testing.T) {
+	cache := newResultCache(2)
+	keys := []cacheKey{{method: "a"}, {method: "b"}, {method: "c"}}
+
+	for _, key := range keys[:2] {
+		_, generation, _ := cache.get(key)
+		cache.put(key, key.method, generation)
+	}
+
+	// Using a makes b the least recently used entry
+	if value, _, hit := cache.get(keys[0]); !hit || value != "a" {
+		t.Fatalf("Expected hit for a, got %v", value)
+	}
+	_, generation, _ := cache.get(keys[2])
+	cache.put(keys[2], "c", generation)
+
+	if _, _, hit := cache.get(keys[1]); hit {
+		t.Error("Expected b to be evicted")
+	}
+	stats := cache.stats()
+	if stats.Entries != 2 || stats.Evictions != 1 || stats.Hits != 1 || stats.Misses != 4 || stats.HitRate != 0.2 {
+		t.Errorf("Unexpected cache stats: %+v", stats)
+	}
+}
+
+func TestResultCacheInvalidation(t *testing.T) {
+	cache := newResultCache(10)
+	key := cacheKey{method: "textDocument/hover"}
+
+	// A result computed across an invalidation is not stored
+	_, generation, _ := cache.get(key)
+	cache.invalidate()
+	cache.put(key, "stale", generation)
+	if _, _, hit := cache.get(key); hit {
+		t.Error("Expected result from before the invalidation to be dropped")
+	}
+
+	_, generation, _ = cache.get(key)
+	cache.put(key, "fresh", generation)
+	cache.invalidate()
+	if _, _, hit := cache.get(key); hit {
+		t.Error("Expected invalidation to drop cached results")
+	}
+	if stats := cache.stats(); stats.Invalidations != 1 || stats.Entries != 0 {
+		t.Errorf("Unexpected cache stats: %+v", stats)
+	}
+
+	disabled := newResultCache(-1)
+	_, generation, _ = disabled.get(key)
+	disabled.put(key, "value", generation)
+	if _, _, hit := disabled.get(key); hit {
+		t.Error("Expected disabled cache to never hit")
+	}
+}
+
+// answerHover answers the next hover request with the given markdown.
+func answerHover(t *testing.T, client *goplsClient, messages <-chan *incomingMessage, contents string) {
+	t.Helper()
+
+	request := expectNotification(t, messages, "textDocument/hover")
+	client.handleLSPMessage(&incomingMessage{
+		JSONRPC: jsonrpcVersion,
+		ID:      request.ID,
+		Result:  json.RawMessage(`{"contents":{"kind":"markdown","value":"` + contents + `"}}`),
+	})
+}
+
+// hoverAsync requests hover information for main.go in the background.
+func hoverAsync(client *goplsClient) <-chan *Hover {
+	resultCh := make(chan *Hover, 1)
+	go func() {
+		hover, _ := client.getHover(context.Background(), "main.go", 0, 8)
+		resultCh <- hover
+	}()
+	return resultCh
+}
+
+func TestCachedHover(t *testing.T) {
+	client, messages := newLoadedTestClient(t)
+
+	resultCh := hoverAsync(client)
+	expectNotification(t, messages, "textDocument/didOpen")
+	answerHover(t, client, messages, "package main")
+	if hover := <-resultCh; len(hover.Contents) != 1 || hover.Contents[0] != "package main" {
+		t.Fatalf("Unexpected hover: %+v", hover)
+	}
+
+	// The same query on unchanged contents is answered from the cache
+	if hover := <-hoverAsync(client); len(hover.Contents) != 1 || hover.Contents[0] != "package main" {
+		t.Fatalf("Unexpected cached hover: %+v", hover)
+	}
+	select {
+	case message := <-messages:
+		t.Fatalf("Expected cached hover without LSP traffic, got %s", message.Method)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Changing the file invalidates the cache
+	mainPath := filepath.Join(client.workspacePath, "main.go")
+	if err := os.WriteFile(mainPath, []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	resultCh = hoverAsync(client)
+	expectNotification(t, messages, "textDocument/didChange")
+	expectNotification(t, messages, "textDocument/didSave")
+	answerHover(t, client, messages, "package main // changed")
+	if hover := <-resultCh; hover.Contents[0] != "package main // changed" {
+		t.Fatalf("Expected fresh hover after change, got %+v", hover)
+	}
+
+	stats := client.cache.stats()
+	if stats.Hits != 1 || stats.Misses != 2 || stats.Invalidations != 1 {
+		t.Errorf("Unexpected cache stats: %+v", stats)
+	}
+
+	// Republished diagnostics that differ also invalidate the cache
+	publishDiagnostics(client, 2, `[]`)
+	publishDiagnostics(client, 2, `[{"range":{},"severity":1,"message":"broken"}]`)
+	if stats := client.cache.stats(); stats.Entries != 0 || stats.Invalidations != 2 {
+		t.Errorf("Expected changed diagnostics to invalidate the cache, got %+v", stats)
+	}
+}
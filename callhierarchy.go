@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// Directions of a call hierarchy request.
+const (
+	callDirectionIncoming = "incoming"
+	callDirectionOutgoing = "outgoing"
+	callDirectionBoth     = "both"
+)
+
+const (
+	// defaultCallHierarchyDepth is how many levels of calls are expanded by default.
+	defaultCallHierarchyDepth = 3
+	// maxCallHierarchyDepth caps the requested depth.
+	maxCallHierarchyDepth = 10
+	// maxCallHierarchyNodes bounds the size of each call tree, since functions
+	// reached along several paths are expanded once per path.
+	maxCallHierarchyNodes = 500
+)
+
+// callHierarchyRoot is a function or method at the requested position with the
+// trees of its incoming and outgoing calls. The truncated flags mark trees whose
+// first level was cut short at maxCallHierarchyNodes.
+type callHierarchyRoot struct {
+	item              CallHierarchyItem
+	path              string
+	incoming          []*callHierarchyNode
+	outgoing          []*callHierarchyNode
+	incomingTruncated bool
+	outgoingTruncated bool
+}
+
+// callHierarchyNode is a function or method in a call tree.
+type callHierarchyNode struct {
+	item CallHierarchyItem
+	path string
+	// callPath is the file holding callRanges, the call sites linking the node
+	// to its parent: in the node for incoming calls, in the parent for outgoing calls.
+	callPath   string
+	callRanges []Range
+	// cycle marks a function already on the path from the root; its calls are not expanded again.
+	cycle bool
+	// truncated marks a node some or all of whose calls were left out because
+	// the tree reached maxCallHierarchyNodes.
+	truncated bool
+	calls     []*callHierarchyNode
+}
+
+// hierarchyCall is an incoming or outgoing call of a call hierarchy item.
+type hierarchyCall struct {
+	item      CallHierarchyItem
+	rangesURI string
+	ranges    []Range
+}
+
+// callHierarchyWalker expands the calls of an item in one direction.
+type callHierarchyWalker struct {
+	client   *goplsClient
+	incoming bool
+	onPath   map[string]bool
+	fetched  map[string][]hierarchyCall
+	budget   int
+}
+
+// callHierarchy prepares the call hierarchy at a position with
+// textDocument/prepareCallHierarchy and expands the incoming and/or outgoing
+// calls of each item up to depth levels.
+func (c *goplsClient) callHierarchy(
+	ctx context.Context, relativePath string, line, character int, direction string, depth int,
+) ([]callHierarchyRoot, error) {
+	c.logger.Debug("callHierarchy called", "relativePath", relativePath,
+		"line", line, "character", character, "direction", direction, "depth", depth)
+
+	// Callers are searched across the workspace and would miss packages that are still loading
+	if err := c.waitForInitialLoad(ctx); err != nil {
+		return nil, err
+	}
+
+	// Ensure file is open in gopls
+	if err := c.ensureFileOpen(relativePath); err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	// Send textDocument/prepareCallHierarchy request and wait for response
+	var items []CallHierarchyItem
+	params := c.positionParams(relativePath, line, character)
+	if err := c.call(ctx, "textDocument/prepareCallHierarchy", params, &items); err != nil {
+		return nil, fmt.Errorf("failed to prepare call hierarchy: %w", err)
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("no function or method at %s:%d:%d",
+			relativePath, convertLineFromLSP(line), character)
+	}
+
+	roots := make([]callHierarchyRoot, len(items))
+	for i, item := range items {
+		roots[i] = callHierarchyRoot{item: item, path: c.uriToRelativePath(item.URI)}
+
+		if direction != callDirectionOutgoing {
+			calls, truncated, err := c.newCallHierarchyWalker(true).expand(ctx, item, depth)
+			if err != nil {
+				return nil, err
+			}
+			roots[i].incoming, roots[i].incomingTruncated = calls, truncated
+		}
+		if direction != callDirectionIncoming {
+			calls, truncated, err := c.newCallHierarchyWalker(false).expand(ctx, item, depth)
+			if err != nil {
+				return nil, err
+			}
+			roots[i].outgoing, roots[i].outgoingTruncated = calls, truncated
+		}
+	}
+	return roots, nil
+}
+
+// newCallHierarchyWalker creates a walker for incoming or outgoing calls.
+func (c *goplsClient) newCallHierarchyWalker(incoming bool) *callHierarchyWalker {
+	return &callHierarchyWalker{
+		client:   c,
+		incoming: incoming,
+		onPath:   make(map[string]bool),
+		fetched:  make(map[string][]hierarchyCall),
+		budget:   maxCallHierarchyNodes,
+	}
+}
+
+// callHierarchyKey identifies a call hierarchy item by its file and name position.
+func callHierarchyKey(item CallHierarchyItem) string {
+	return fmt.Sprintf("%s:%d:%d", item.URI, item.SelectionRange.Start.Line, item.SelectionRange.Start.Character)
+}
+
+// expand returns the calls of an item, expanding each call depth-1 more levels.
+// Calls back into an item on the path from the root are reported as cycles.
+// Once the tree holds maxCallHierarchyNodes calls, the remaining ones are left
+// out and expand reports that the calls of item were truncated.
+func (w *callHierarchyWalker) expand(
+	ctx context.Context, item CallHierarchyItem, depth int,
+) ([]*callHierarchyNode, bool, error) {
+	calls, err := w.calls(ctx, item)
+	if err != nil {
+		return nil, false, err
+	}
+
+	key := callHierarchyKey(item)
+	w.onPath[key] = true
+	defer delete(w.onPath, key)
+
+	nodes := make([]*callHierarchyNode, 0, len(calls))
+	for _, call := range calls {
+		if w.budget <= 0 {
+			return nodes, true, nil
+		}
+		w.budget--
+
+		node := &callHierarchyNode{
+			item:       call.item,
+			path:       w.client.uriToRelativePath(call.item.URI),
+			callPath:   w.client.uriToRelativePath(call.rangesURI),
+			callRanges: call.ranges,
+		}
+		switch {
+		case w.onPath[callHierarchyKey(call.item)]:
+			node.cycle = true
+		case depth <= 1:
+		default:
+			if node.calls, node.truncated, err = w.expand(ctx, call.item, depth-1); err != nil {
+				return nil, false, err
+			}
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, false, nil
+}
+
+// calls sends a callHierarchy/incomingCalls or callHierarchy/outgoingCalls
+// request, asking gopls only once per item.
+func (w *callHierarchyWalker) calls(ctx context.Context, item CallHierarchyItem) ([]hierarchyCall, error) {
+	key := callHierarchyKey(item)
+	if calls, ok := w.fetched[key]; ok {
+		return calls, nil
+	}
+
+	var calls []hierarchyCall
+	params := CallHierarchyCallsParams{Item: item}
+	if w.incoming {
+		// Send callHierarchy/incomingCalls request and wait for response
+		var result []CallHierarchyIncomingCall
+		if err := w.client.call(ctx, "callHierarchy/incomingCalls", params, &result); err != nil {
+			return nil, fmt.Errorf("failed to get incoming calls: %w", err)
+		}
+		for _, call := range result {
+			calls = append(calls, hierarchyCall{item: call.From, rangesURI: call.From.URI, ranges: call.FromRanges})
+		}
+	} else {
+		// Send callHierarchy/outgoingCalls request and wait for response
+		var result []CallHierarchyOutgoingCall
+		if err := w.client.call(ctx, "callHierarchy/outgoingCalls", params, &result); err != nil {
+			return nil, fmt.Errorf("failed to get outgoing calls: %w", err)
+		}
+		for _, call := range result {
+			calls = append(calls, hierarchyCall{item: call.To, rangesURI: item.URI, ranges: call.FromRanges})
+		}
+	}
+
+	w.fetched[key] = calls
+	return calls, nil
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MegaGrindStone/gopls-mcp/internal/fakegopls"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// pingPongGo is a file with two mutually recursive functions.
+const pingPongGo = "package main\n\nfunc ping(n int) {\n\tif n > 0 {\n\t\tpong(n - 1)\n\t}\n}\n\n" +
+	"func pong(n int) {\n\tping(n)\n}\n"
+
+// callHierarchyItem returns the JSON of a call hierarchy item for a function
+// declared at a 0-based line of a file.
+func callHierarchyItem(file, name string, line int) string {
+	return `{"name":"` + name + `","kind":12,"detail":"main","uri":"${rootUri}/` + file + `",` +
+		`"range":{"start":{"line":` + strconv.Itoa(line) + `,"character":0},"end":{"line":` + strconv.Itoa(line+2) +
+		`,"character":1}},"selectionRange":{"start":{"line":` + strconv.Itoa(line) + `,"character":5},` +
+		`"end":{"line":` + strconv.Itoa(line) + `,"character":9}}}`
+}
+
+// callHierarchyScript scripts a gopls that reports the calls between ping and
+// pong in pingpong.go and finds no function on the first line.
+func callHierarchyScript() *fakegopls.Script {
+	ping, pong := callHierarchyItem("pingpong.go", "ping", 2), callHierarchyItem("pingpong.go", "pong", 8)
+	pingCallsPong := `[{"start":{"line":4,"character":2},"end":{"line":4,"character":6}}]`
+	pongCallsPing := `[{"start":{"line":9,"character":1},"end":{"line":9,"character":5}}]`
+
+	script := fakeGoplsScript()
+	script.Responses = append(script.Responses,
+		fakegopls.Response{
+			Method: "textDocument/prepareCallHierarchy",
+			Params: json.RawMessage(`{"position":{"line":0}}`),
+			Result: json.RawMessage(`null`),
+		},
+		fakegopls.Response{
+			Method: "textDocument/prepareCallHierarchy",
+			Result: json.RawMessage(`[` + ping + `]`),
+		},
+		fakegopls.Response{
+			Method: "callHierarchy/incomingCalls",
+			Params: json.RawMessage(`{"item":{"name":"ping"}}`),
+			Result: json.RawMessage(`[{"from":` + pong + `,"fromRanges":` + pongCallsPing + `}]`),
+		},
+		fakegopls.Response{
+			Method: "callHierarchy/incomingCalls",
+			Params: json.RawMessage(`{"item":{"name":"pong"}}`),
+			Result: json.RawMessage(`[{"from":` + ping + `,"fromRanges":` + pingCallsPong + `}]`),
+		},
+		fakegopls.Response{
+			Method: "callHierarchy/outgoingCalls",
+			Params: json.RawMessage(`{"item":{"name":"ping"}}`),
+			Result: json.RawMessage(`[{"to":` + pong + `,"fromRanges":` + pingCallsPong + `}]`),
+		},
+		fakegopls.Response{
+			Method: "callHierarchy/outgoingCalls",
+			Params: json.RawMessage(`{"item":{"name":"pong"}}`),
+			Result: json.RawMessage(`[{"to":` + ping + `,"fromRanges":` + pongCallsPing + `}]`),
+		},
+	)
+	return script
+}
+
+// wideCallHierarchyScript scripts a gopls where main calls 30 functions that
+// each call the same 30 other functions, a tree of 930 calls.
+func wideCallHierarchyScript() *fakegopls.Script {
+	outgoing := func(prefix string, firstLine int) string {
+		calls := make([]string, 30)
+		for i := range calls {
+			line := firstLine + i
+			calls[i] = `{"to":` + callHierarchyItem("main.go", prefix+strconv.Itoa(i), line) + `,"fromRanges":[]}`
+		}
+		return `[` + strings.Join(calls, ",") + `]`
+	}
+
+	script := fakeGoplsScript()
+	script.Responses = append(script.Responses,
+		fakegopls.Response{
+			Method: "textDocument/prepareCallHierarchy",
+			Result: json.RawMessage(`[` + callHierarchyItem("main.go", "main", 2) + `]`),
+		},
+		fakegopls.Response{
+			Method: "callHierarchy/outgoingCalls",
+			Params: json.RawMessage(`{"item":{"name":"main"}}`),
+			Result: json.RawMessage(outgoing("middle", 100)),
+		},
+		fakegopls.Response{
+			Method: "callHierarchy/outgoingCalls",
+			Result: json.RawMessage(outgoing("leaf", 200)),
+		},
+	)
+	return script
+}
+
+// callTreeNode decodes a call of a call hierarchy result, whose nested calls
+// are untyped in CallHierarchyCallResult.
+type callTreeNode struct {
+	Name      string           `json:"name"`
+	Location  LocationResult   `json:"location"`
+	CallSites []LocationResult `json:"callSites"`
+	Cycle     bool             `json:"cycle"`
+	Truncated bool             `json:"truncated"`
+	Calls     []callTreeNode   `json:"calls"`
+}
+
+// countCalls returns the number of calls in call trees.
+func countCalls(nodes []callTreeNode) int {
+	count := len(nodes)
+	for _, node := range nodes {
+		count += countCalls(node.Calls)
+	}
+	return count
+}
+
+// callTree decodes a call hierarchy result.
+type callTree struct {
+	Items []struct {
+		Name              string         `json:"name"`
+		Location          LocationResult `json:"location"`
+		Incoming          []callTreeNode `json:"incoming"`
+		Outgoing          []callTreeNode `json:"outgoing"`
+		OutgoingTruncated bool           `json:"outgoingTruncated"`
+	} `json:"items"`
+}
+
+func TestCallHierarchy(t *testing.T) {
+	workspacePath := newFakeGoplsWorkspace(t)
+	if err := os.WriteFile(filepath.Join(workspacePath, "pingpong.go"), []byte(pingPongGo), 0644); err != nil {
+		t.Fatalf("failed to write pingpong.go: %v", err)
+	}
+	config := fakeGoplsConfig(t, writeFakeGoplsScript(t, callHierarchyScript()))
+	client := startFakeGoplsClient(t, workspacePath, config)
+	session := connectMCP(t, map[string]*goplsClient{workspacePath: client})
+
+	arguments := map[string]any{"workspace": workspacePath, "path": "pingpong.go", "line": 3, "character": 5}
+	tree := callTool[callTree](t, session, toolCallHierarchy, arguments)
+	if len(tree.Items) != 1 || tree.Items[0].Name != "ping" ||
+		tree.Items[0].Location != (LocationResult{URI: "pingpong.go", Line: 3, Character: 5, EndLine: 3, EndCharacter: 9}) {
+		t.Fatalf("Unexpected call hierarchy items: %+v", tree.Items)
+	}
+
+	// ping calls pong on line 5, which calls ping again on line 10
+	outgoing := tree.Items[0].Outgoing
+	if len(outgoing) != 1 || outgoing[0].Name != "pong" || outgoing[0].Cycle ||
+		outgoing[0].CallSites[0] != (LocationResult{URI: "pingpong.go", Line: 5, Character: 2, EndLine: 5, EndCharacter: 6}) {
+		t.Fatalf("Unexpected outgoing calls: %+v", outgoing)
+	}
+	if calls := outgoing[0].Calls; len(calls) != 1 || calls[0].Name != "ping" || !calls[0].Cycle ||
+		calls[0].CallSites[0].Line != 10 || len(calls[0].Calls) != 0 {
+		t.Errorf("Expected the call back into ping to be reported as a cycle, got %+v", calls)
+	}
+
+	// ping is called by pong on line 10, which is called by ping on line 5
+	incoming := tree.Items[0].Incoming
+	if len(incoming) != 1 || incoming[0].Name != "pong" || incoming[0].CallSites[0].Line != 10 {
+		t.Fatalf("Unexpected incoming calls: %+v", incoming)
+	}
+	if calls := incoming[0].Calls; len(calls) != 1 || !calls[0].Cycle || calls[0].CallSites[0].Line != 5 {
+		t.Errorf("Expected the call from ping to be reported as a cycle, got %+v", calls)
+	}
+
+	arguments["direction"] = callDirectionIncoming
+	arguments["depth"] = 1
+	shallow := callTool[callTree](t, session, toolCallHierarchy, arguments)
+	if len(shallow.Items) != 1 || len(shallow.Items[0].Outgoing) != 0 || len(shallow.Items[0].Incoming) != 1 ||
+		len(shallow.Items[0].Incoming[0].Calls) != 0 || shallow.Items[0].Incoming[0].Cycle {
+		t.Errorf("Expected only the direct callers, got %+v", shallow.Items)
+	}
+
+	// A position without a function is rejected
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	result, err := session.CallTool(ctx, &mcp.CallToolParams{
+		Name:      toolCallHierarchy,
+		Arguments: map[string]any{"workspace": workspacePath, "path": "pingpong.go", "line": 1, "character": 0},
+	})
+	if err == nil && !result.IsError {
+		t.Error("Expected a call hierarchy without a function to fail")
+	}
+}
+
+func TestCallHierarchyCapsWideTrees(t *testing.T) {
+	workspacePath := newFakeGoplsWorkspace(t)
+	config := fakeGoplsConfig(t, writeFakeGoplsScript(t, wideCallHierarchyScript()))
+	client := startFakeGoplsClient(t, workspacePath, config)
+	session := connectMCP(t, map[string]*goplsClient{workspacePath: client})
+
+	tree := callTool[callTree](t, session, toolCallHierarchy, map[string]any{
+		"workspace": workspacePath, "path": "main.go", "line": 3, "character": 5,
+		"direction": callDirectionOutgoing, "depth": 2,
+	})
+	if len(tree.Items) != 1 {
+		t.Fatalf("Unexpected call hierarchy items: %+v", tree.Items)
+	}
+	outgoing := tree.Items[0].Outgoing
+	if count := countCalls(outgoing); count != maxCallHierarchyNodes {
+		t.Errorf("Expected the tree to be capped at %d calls, got %d", maxCallHierarchyNodes, count)
+	}
+
+	// Each middle function takes 31 calls, so the 17th keeps only 3 of its calls
+	if !tree.Items[0].OutgoingTruncated || len(outgoing) != 17 {
+		t.Fatalf("Expected the calls of main to be truncated after 17 calls, got %d", len(outgoing))
+	}
+	for i, node := range outgoing {
+		if truncated := i == len(outgoing)-1; node.Truncated != truncated {
+			t.Errorf("Expected call %s to have truncated=%t, got %+v", node.Name, truncated, node)
+		}
+	}
+	if last := outgoing[len(outgoing)-1]; len(last.Calls) != 3 {
+		t.Errorf("Expected the last expanded call to keep 3 calls, got %d", len(last.Calls))
+	}
+}
+
+func TestCallHierarchyRejectsInvalidArguments(t *testing.T) {
+	tools, mockClient := createTestMCPTools()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, arguments := range []CallHierarchyParams{
+		{Workspace: "/test/workspace", Path: "main.go", Line: 1, Direction: "sideways"},
+		{Workspace: "/test/workspace", Path: "main.go", Line: 1, Depth: -1},
+		{Workspace: "/test/workspace", Path: "main.go", Line: 1, Depth: maxCallHierarchyDepth + 1},
+	} {
+		if _, err := tools.HandleCallHierarchy(ctx, nil,
+			&mcp.CallToolParamsFor[CallHierarchyParams]{Arguments: arguments}); err == nil {
+			t.Errorf("Expected %+v to be rejected", arguments)
+		}
+	}
+	if mockClient.callHierarchyCalled {
+		t.Error("Expected invalid arguments to be rejected before asking gopls")
+	}
+}
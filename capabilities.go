@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// toolCapabilities maps tools to the server capability they depend on.
+// Tools that are not listed are always available.
+var toolCapabilities = map[string]func(ServerCapabilities) bool{
+	toolGoToDefinition:      func(s ServerCapabilities) bool { return s.DefinitionProvider.Enabled },
+	toolFindReferences:      func(s ServerCapabilities) bool { return s.ReferencesProvider.Enabled },
+	toolGetHoverInfo:        func(s ServerCapabilities) bool { return s.HoverProvider.Enabled },
+	toolGetDocumentSymbols:  func(s ServerCapabilities) bool { return s.DocumentSymbolProvider.Enabled },
+	toolGetWorkspaceSymbols: func(s ServerCapabilities) bool { return s.WorkspaceSymbolProvider.Enabled },
+	toolGetSignatureHelp:    func(s ServerCapabilities) bool { return s.SignatureHelpProvider.Enabled },
+	toolGetCompletions:      func(s ServerCapabilities) bool { return s.CompletionProvider.Enabled },
+	toolGetTypeDefinition:   func(s ServerCapabilities) bool { return s.TypeDefinitionProvider.Enabled },
+	toolFindImplementations: func(s ServerCapabilities) bool { return s.ImplementationProvider.Enabled },
+	toolCallHierarchy:       func(s ServerCapabilities) bool { return s.CallHierarchyProvider.Enabled },
+	toolFormatDocument:      func(s ServerCapabilities) bool { return s.DocumentFormattingProvider.Enabled },
+	toolOrganizeImports:     func(s ServerCapabilities) bool { return s.CodeActionProvider.Enabled },
+	toolGetInlayHints:       func(s ServerCapabilities) bool { return s.InlayHintProvider.Enabled },
+	toolRenameSymbol:        func(s ServerCapabilities) bool { return s.RenameProvider.Enabled },
+	toolCodeActions:         func(s ServerCapabilities) bool { return s.CodeActionProvider.Enabled },
+}
+
+// capabilities returns the capabilities gopls reported during initialization and
+// whether initialization has completed at least once.
+func (c *goplsClient) capabilities() (ServerCapabilities, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.serverCapabilities, c.initialized
+}
+
+// supportsTool reports whether the client's gopls supports the named tool. Tools
+// are assumed to be supported until gopls has reported its capabilities.
+func (c *goplsClient) supportsTool(tool string) bool {
+	supported, ok := toolCapabilities[tool]
+	if !ok {
+		return true
+	}
+
+	capabilities, known := c.capabilities()
+	return !known || supported(capabilities)
+}
+
+// describeServer reports the gopls server behind the client.
+func (c *goplsClient) describeServer() WorkspaceServerInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	info := WorkspaceServerInfo{
+		Workspace:        c.workspacePath,
+		Running:          c.running,
+		Initialized:      c.initialized,
+		ServerName:       c.serverInfo.Name,
+		Version:          goplsVersion(c.serverInfo.Version),
+		PositionEncoding: c.serverCapabilities.PositionEncoding,
+		Features:         supportedFeatures(c.serverCapabilities),
+		Capabilities:     c.serverCapabilities,
+		Settings:         c.currentSettings(),
+	}
+	if info.Initialized && info.PositionEncoding == "" {
+		// utf-16 is the LSP default when the server does not choose an encoding
+		info.PositionEncoding = "utf-16"
+	}
+	return info
+}
+
+// toolAvailable reports whether at least one workspace supports the named tool.
+func (m mcpTools) toolAvailable(tool string) bool {
+	if len(m.clients) == 0 {
+		return true
+	}
+	for _, client := range m.clients {
+		if client.supportsTool(tool) {
+			return true
+		}
+	}
+	return false
+}
+
+// requireTool returns an error if the client's gopls does not support the named tool.
+func (m mcpTools) requireTool(client goplsClientInterface, tool string) error {
+	if !client.supportsTool(tool) {
+		return fmt.Errorf("%s is not supported by gopls for workspace: %s", tool, client.workspaceRoot())
+	}
+	return nil
+}
+
+// addTool registers a tool unless no workspace's gopls supports it.
+func addTool[In, Out any](server *mcp.Server, tools mcpTools, tool *mcp.Tool, handler mcp.ToolHandlerFor[In, Out]) {
+	if !tools.toolAvailable(tool.Name) {
+		slog.Info("hiding tool not supported by gopls", "tool", tool.Name)
+		return
+	}
+	mcp.AddTool(server, tool, handler)
+}
+
+// supportedFeatures lists the capability names gopls advertised as enabled.
+func supportedFeatures(capabilities ServerCapabilities) []string {
+	providers := []struct {
+		name       string
+		capability ProviderCapability
+	}{
+		{"hover", capabilities.HoverProvider},
+		{"completion", capabilities.CompletionProvider},
+		{"signatureHelp", capabilities.SignatureHelpProvider},
+		{"definition", capabilities.DefinitionProvider},
+		{"typeDefinition", capabilities.TypeDefinitionProvider},
+		{"implementation", capabilities.ImplementationProvider},
+		{"references", capabilities.ReferencesProvider},
+		{"documentSymbol", capabilities.DocumentSymbolProvider},
+		{"workspaceSymbol", capabilities.WorkspaceSymbolProvider},
+		{"codeAction", capabilities.CodeActionProvider},
+		{"documentFormatting", capabilities.DocumentFormattingProvider},
+		{"documentRangeFormatting", capabilities.DocumentRangeFormattingProvider},
+		{"rename", capabilities.RenameProvider},
+		{"callHierarchy", capabilities.CallHierarchyProvider},
+		{"inlayHint", capabilities.InlayHintProvider},
+		{"diagnostic", capabilities.DiagnosticProvider},
+	}
+
+	features := make([]string, 0, len(providers))
+	for _, provider := range providers {
+		if provider.capability.Enabled {
+			features = append(features, provider.name)
+		}
+	}
+	return features
+}
+
+// goplsVersion extracts the module version from the serverInfo version gopls
+// reports, which is a JSON-encoded build info document in recent releases.
+func goplsVersion(version string) string {
+	var buildInfo struct {
+		Main struct {
+			Version string `json:"Version"`
+		} `json:"Main"`
+	}
+	if err := json.Unmarshal([]byte(version), &buildInfo); err == nil && buildInfo.Main.Version != "" {
+		return buildInfo.Main.Version
+	}
+	return version
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+)
+
+func TestProviderCapabilityDecoding(t *testing.T) {
+	var capabilities ServerCapabilities
+	raw := `{"hoverProvider":true,"renameProvider":{"prepareProvider":true},` +
+		`"inlayHintProvider":false,"positionEncoding":"utf-8"}`
+	if err := json.Unmarshal([]byte(raw), &capabilities); err != nil {
+		t.Fatalf("failed to unmarshal capabilities: %v", err)
+	}
+
+	if !capabilities.HoverProvider.Enabled {
+		t.Error("Expected boolean hover capability to be enabled")
+	}
+	if !capabilities.RenameProvider.Enabled || len(capabilities.RenameProvider.Options) == 0 {
+		t.Errorf("Expected rename options to enable the capability, got %+v", capabilities.RenameProvider)
+	}
+	if capabilities.InlayHintProvider.Enabled || capabilities.DefinitionProvider.Enabled {
+		t.Error("Expected false and missing capabilities to be disabled")
+	}
+
+	features := supportedFeatures(capabilities)
+	if len(features) != 2 || features[0] != "hover" || features[1] != "rename" {
+		t.Errorf("Unexpected features: %v", features)
+	}
+}
+
+func TestToolGating(t *testing.T) {
+	client := newClient("/test/workspace", workspaceConfig{}, newTestLogger())
+	tools := newMCPTools(map[string]*goplsClient{"/test/workspace": client}, newToolTimeouts(defaultRequestTimeout))
+
+	// Before initialization every tool is assumed to be supported
+	if !tools.toolAvailable(toolGetInlayHints) {
+		t.Error("Expected inlay hints to be available before capabilities are known")
+	}
+
+	client.initialized = true
+	client.serverCapabilities = ServerCapabilities{HoverProvider: ProviderCapability{Enabled: true}}
+
+	if tools.toolAvailable(toolGetInlayHints) {
+		t.Error("Expected inlay hints to be hidden when gopls does not support them")
+	}
+	if err := tools.requireTool(client, toolGetInlayHints); err == nil {
+		t.Error("Expected inlay hints calls to be rejected")
+	}
+	if !tools.toolAvailable(toolGetHoverInfo) || !tools.toolAvailable(toolListWorkspaces) {
+		t.Error("Expected supported and ungated tools to remain available")
+	}
+}
+
+func TestGoplsVersion(t *testing.T) {
+	buildInfo := `{"GoVersion":"go1.24.4","Path":"golang.org/x/tools/gopls",` +
+		`"Main":{"Path":"golang.org/x/tools/gopls","Version":"v0.19.1"}}`
+	if got := goplsVersion(buildInfo); got != "v0.19.1" {
+		t.Errorf("Expected v0.19.1, got %s", got)
+	}
+	if got := goplsVersion("v0.15.0"); got != "v0.15.0" {
+		t.Errorf("Expected plain version to be returned as-is, got %s", got)
+	}
+}
+
+func TestInitializeWaitsForResult(t *testing.T) {
+	client := newClient("/test/workspace", workspaceConfig{}, newTestLogger())
+
+	reader, writer := io.Pipe()
+	client.stdin = writer
+	bufReader := bufio.NewReader(reader)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- client.initialize(context.Background())
+	}()
+
+	request, err := client.readLSPMessage(bufReader)
+	if err != nil {
+		t.Fatalf("failed to read initialize request: %v", err)
+	}
+	if request.Method != "initialize" {
+		t.Fatalf("Expected initialize request, got %s", request.Method)
+	}
+
+	client.handleLSPMessage(&incomingMessage{
+		JSONRPC: jsonrpcVersion,
+		ID:      request.ID,
+		Result: json.RawMessage(`{"capabilities":{"inlayHintProvider":{}},` +
+			`"serverInfo":{"name":"gopls","version":"v0.19.1"}}`),
+	})
+
+	notification, err := client.readLSPMessage(bufReader)
+	if err != nil {
+		t.Fatalf("failed to read initialized notification: %v", err)
+	}
+	if notification.Method != "initialized" {
+		t.Errorf("Expected initialized notification, got %s", notification.Method)
+	}
+
+	if err := <-errCh; err != nil {
+		t.Fatalf("initialize failed: %v", err)
+	}
+
+	info := client.describeServer()
+	if !info.Initialized || info.ServerName != "gopls" || info.Version != "v0.19.1" {
+		t.Errorf("Unexpected server info: %+v", info)
+	}
+	if !client.supportsTool(toolGetInlayHints) {
+		t.Error("Expected inlay hints to be supported")
+	}
+}
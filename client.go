@@ -3,7 +3,7 @@ package main
 import (
 	"bufio"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -16,6 +16,9 @@ import (
 	"time"
 )
 
+// errGoplsExited is reported when gopls exits while the client waits for it.
+var errGoplsExited = fmt.Errorf("gopls exited")
+
 // goplsClient manages a gopls subprocess and handles basic LSP communication.
 type goplsClient struct {
 	cmd           *exec.Cmd
@@ -23,45 +26,126 @@ type goplsClient struct {
 	stdout        io.ReadCloser
 	stderr        io.ReadCloser
 	workspacePath string
+	config        workspaceConfig
 	logger        *slog.Logger
 
-	mu      sync.RWMutex
-	running bool
+	mu       sync.RWMutex
+	running  bool
+	stopping bool
+	// shuttingDown is closed when a shutdown in progress completes
+	shuttingDown    chan struct{}
+	restarting      bool
+	processCtx      context.Context
+	exited          chan struct{}
+	ready           chan struct{}
+	startedAt       time.Time
+	restartAttempts int
+	restarts        int
+
+	initialized        bool
+	serverCapabilities ServerCapabilities
+	serverInfo         ServerInfo
 
 	requestIDMux sync.Mutex
 	requestID    int
 
+	scheduler *requestScheduler
+	cache     *resultCache
+	watchdog  *processWatchdog
+
+	lifecycleMux   sync.Mutex
+	lifecycleState string
+	lifecycleCtx   context.Context
+	transition     *lifecycleTransition
+	lastUsed       time.Time
+
+	writeMux sync.Mutex
+	tracer   *lspTracer
+
 	responsesMux sync.Mutex
-	responses    map[int]chan map[string]any
+	responses    map[int]chan *ResponseMessage
+	// draining rejects new requests while gopls shuts down
+	draining bool
+
+	requestHandlers map[string]serverRequestHandler
+
+	registrationsMux sync.RWMutex
+	registrations    map[string]Registration
+
+	settingsMux sync.RWMutex
+	settings    map[string]any
+
+	messagesMux     sync.Mutex
+	messages        messageRing
+	messageListener func(serverMessage)
+
+	progressMux   sync.Mutex
+	progressTasks map[string]*progressTask
+	loaded        bool
+	loadedCh      chan struct{}
+
+	fileWatchersMux sync.RWMutex
+	fileWatchers    []fileWatcher
 
-	openFilesMux sync.RWMutex
-	openFiles    map[string]bool
+	watcherMux sync.Mutex
+	watcher    changeSource
 
-	diagnosticsMux        sync.RWMutex
-	diagnostics           map[string][]Diagnostic
-	diagnosticsTimestamps map[string]time.Time
+	openFilesMux sync.Mutex
+	openFiles    map[string]*openDocument
+
+	diagnosticsMux     sync.Mutex
+	diagnostics        map[string]*fileDiagnostics
+	diagnosticsUpdated map[string]*diagnosticsWaiter
+
+	codeActionsMux sync.Mutex
+	codeActions    map[string]listedCodeAction
+	codeActionIDs  []string
+
+	commandMux      sync.Mutex
+	commandEditsMux sync.Mutex
+	commandEdits    *commandEdits
 }
 
-// newClient creates a new gopls client with the specified workspace path.
-func newClient(workspacePath string, logger *slog.Logger) *goplsClient {
+// newClient creates a new gopls client with the specified workspace path and launch configuration.
+func newClient(workspacePath string, config workspaceConfig, logger *slog.Logger) *goplsClient {
 	c := &goplsClient{
-		workspacePath:         workspacePath,
-		logger:                logger,
-		responses:             make(map[int]chan map[string]any),
-		openFiles:             make(map[string]bool),
-		diagnostics:           make(map[string][]Diagnostic),
-		diagnosticsTimestamps: make(map[string]time.Time),
-	}
+		workspacePath:      workspacePath,
+		config:             config,
+		logger:             logger,
+		responses:          make(map[int]chan *ResponseMessage),
+		scheduler:          newRequestScheduler(config.MaxInFlight, config.MaxQueued),
+		cache:              newResultCache(config.CacheSize),
+		watchdog:           newProcessWatchdog(config.MaxMemoryMB),
+		lifecycleState:     lifecycleCold,
+		registrations:      make(map[string]Registration),
+		settings:           cloneSettings(config.Settings),
+		progressTasks:      make(map[string]*progressTask),
+		loadedCh:           make(chan struct{}),
+		openFiles:          make(map[string]*openDocument),
+		diagnostics:        make(map[string]*fileDiagnostics),
+		diagnosticsUpdated: make(map[string]*diagnosticsWaiter),
+		codeActions:        make(map[string]listedCodeAction),
+	}
+	if config.TraceDir != "" {
+		c.tracer = newLSPTracer(config.TraceDir, workspacePath, config.TraceMaxSizeMB, logger)
+	}
+	c.requestHandlers = c.serverRequestHandlers()
 	c.logger.Debug("created new gopls client", "workspacePath", workspacePath)
 	return c
 }
 
-// start starts the gopls subprocess and initializes it.
+// workspaceRoot returns the absolute path of the workspace served by the client.
+func (c *goplsClient) workspaceRoot() string {
+	return c.workspacePath
+}
+
+// start starts the gopls subprocess and initializes it. The process is
+// supervised and restarted if it exits unexpectedly until ctx is done.
 func (c *goplsClient) start(ctx context.Context) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if c.running {
+	if c.running || c.restarting {
 		return fmt.Errorf("gopls is already running")
 	}
 
@@ -78,85 +162,132 @@ func (c *goplsClient) start(ctx context.Context) error {
 		return fmt.Errorf("workspace path is not a directory: %s", c.workspacePath)
 	}
 
-	// Start gopls process
-	c.cmd = exec.CommandContext(ctx, "gopls")
-	c.cmd.Dir = c.workspacePath
+	c.stopping = false
+	c.processCtx = ctx
+	c.restartAttempts = 0
+	c.setLifecycleState(lifecycleStarting)
+	if err := c.launch(ctx); err != nil {
+		c.setLifecycleState(lifecycleStopped)
+		return err
+	}
+	c.startWatcher()
+	c.setLifecycleState(lifecycleReady)
 
-	c.stdin, err = c.cmd.StdinPipe()
+	c.logger.Info("gopls client started successfully")
+	return nil
+}
+
+// launch starts a gopls process and initializes it. Callers must hold c.mu.
+func (c *goplsClient) launch(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, c.config.goplsPath(), c.config.GoplsArgs...)
+	cmd.Dir = c.workspacePath
+	cmd.Env = c.config.environ()
+
+	stdin, err := cmd.StdinPipe()
 	if err != nil {
 		return fmt.Errorf("failed to create stdin pipe: %w", err)
 	}
 
-	c.stdout, err = c.cmd.StdoutPipe()
+	stdout, err := cmd.StdoutPipe()
 	if err != nil {
 		return fmt.Errorf("failed to create stdout pipe: %w", err)
 	}
 
-	c.stderr, err = c.cmd.StderrPipe()
+	stderr, err := cmd.StderrPipe()
 	if err != nil {
 		return fmt.Errorf("failed to create stderr pipe: %w", err)
 	}
 
-	if err := c.cmd.Start(); err != nil {
+	if err := cmd.Start(); err != nil {
 		return fmt.Errorf("failed to start gopls: %w", err)
 	}
 
+	c.writeMux.Lock()
+	c.stdin = stdin
+	c.writeMux.Unlock()
+	c.setDraining(false)
+	c.cmd = cmd
+	c.stdout = stdout
+	c.stderr = stderr
+	c.exited = make(chan struct{})
+	c.startedAt = time.Now()
 	c.running = true
-	c.logger.Info("gopls process started", "pid", c.cmd.Process.Pid)
+	c.logger.Info("gopls process started", "pid", cmd.Process.Pid, "path", cmd.Path, "args", c.config.GoplsArgs)
 
 	// Monitor stderr for gopls errors
-	go c.monitorStderr()
+	go c.monitorStderr(stderr)
 
 	// Start message reader for LSP responses
-	go c.messageReader()
+	go c.messageReader(stdout)
 
-	// Give gopls a moment to start
-	time.Sleep(100 * time.Millisecond)
+	// Restart gopls if the process exits unexpectedly
+	go c.superviseProcess(cmd, c.exited)
+
+	// Sample the process and recycle it above the memory ceiling
+	go c.watchProcess(cmd, c.exited, c.startedAt)
 
 	// Initialize gopls
-	if err := c.initialize(); err != nil {
+	if err := c.initialize(ctx); err != nil {
 		c.logger.Error("gopls initialization failed", "error", err)
-		_ = c.stop()
+		_ = c.terminate()
 		return fmt.Errorf("failed to initialize gopls: %w", err)
 	}
 
-	c.logger.Info("gopls client started successfully")
 	return nil
 }
 
-// stop stops the gopls subprocess.
+// stop shuts the gopls subprocess down gracefully and stops its supervisor.
 func (c *goplsClient) stop() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if !c.running {
+	c.stopping = true
+	c.finishRestart()
+	c.stopWatcher()
+	c.setLifecycleState(lifecycleStopped)
+	if !c.running && c.shuttingDown == nil {
 		return nil
 	}
 
+	err := c.shutdown()
+	c.tracer.close()
+	c.logger.Info("gopls client stopped")
+	return err
+}
+
+// terminate kills the gopls process and waits for it to exit. Callers must hold c.mu.
+func (c *goplsClient) terminate() error {
 	var err error
+	cmd, exited := c.cmd, c.exited
+
+	// Clearing cmd first tells the supervisor this exit was requested
+	c.cmd = nil
+	c.closePipes()
+	if cmd != nil && cmd.Process != nil {
+		err = cmd.Process.Kill()
+		<-exited
+	}
+
+	c.running = false
+	return err
+}
+
+// closePipes closes the pipes to the gopls process. Callers must hold c.mu.
+func (c *goplsClient) closePipes() {
+	c.writeMux.Lock()
 	if c.stdin != nil {
 		_ = c.stdin.Close()
 	}
+	c.stdin = nil
+	c.writeMux.Unlock()
 	if c.stdout != nil {
 		_ = c.stdout.Close()
 	}
 	if c.stderr != nil {
 		_ = c.stderr.Close()
 	}
-
-	if c.cmd != nil && c.cmd.Process != nil {
-		err = c.cmd.Process.Kill()
-		_ = c.cmd.Wait()
-	}
-
-	c.running = false
-	c.cmd = nil
-	c.stdin = nil
 	c.stdout = nil
 	c.stderr = nil
-
-	c.logger.Info("gopls client stopped")
-	return err
 }
 
 // isRunning returns true if gopls is currently running.
@@ -166,99 +297,117 @@ func (c *goplsClient) isRunning() bool {
 	return c.running
 }
 
-// initialize sends the LSP initialize request to gopls.
-func (c *goplsClient) initialize() error {
+// initialize performs the LSP initialize handshake and records the capabilities
+// gopls reports. Callers must hold c.mu.
+func (c *goplsClient) initialize(ctx context.Context) error {
 	c.logger.Info("initializing gopls", "workspacePath", c.workspacePath)
-
-	requestID := c.nextRequestID()
-	initRequest := map[string]any{
-		"jsonrpc": "2.0",
-		"id":      requestID,
-		"method":  "initialize",
-		"params": map[string]any{
-			"processId": os.Getpid(),
-			"rootUri":   fmt.Sprintf("file://%s", c.workspacePath),
-			"workspaceFolders": []map[string]any{
-				{
-					"uri":  fmt.Sprintf("file://%s", c.workspacePath),
-					"name": filepath.Base(c.workspacePath),
-				},
+	c.resetProgress()
+
+	workspaceURI := fmt.Sprintf("file://%s", c.workspacePath)
+	params := InitializeParams{
+		ProcessID:             os.Getpid(),
+		RootURI:               workspaceURI,
+		InitializationOptions: c.initializationOptions(),
+		WorkspaceFolders: []WorkspaceFolder{
+			{
+				URI:  workspaceURI,
+				Name: filepath.Base(c.workspacePath),
 			},
-			"capabilities": map[string]any{
-				"textDocument": map[string]any{
-					"hover": map[string]any{
-						"contentFormat": []string{"markdown", "plaintext"},
-					},
-					"definition": map[string]any{
-						"linkSupport": true,
+		},
+		Capabilities: ClientCapabilities{
+			General: &GeneralClientCapabilities{
+				PositionEncodings: []string{positionEncodingUTF8, positionEncodingUTF16},
+			},
+			TextDocument: TextDocumentClientCapabilities{
+				Synchronization: &TextDocumentSyncClientCapabilities{
+					DidSave: true,
+				},
+				Hover: &HoverClientCapabilities{
+					ContentFormat: []string{"markdown", "plaintext"},
+				},
+				Definition: &DefinitionClientCapabilities{
+					LinkSupport: true,
+				},
+				References: &ReferenceClientCapabilities{},
+				PublishDiagnostics: &PublishDiagnosticsClientCapabilities{
+					VersionSupport: true,
+					DataSupport:    true,
+				},
+				Diagnostic: &DiagnosticClientCapabilities{},
+				Rename: &RenameClientCapabilities{
+					PrepareSupport: true,
+				},
+				CodeAction: &CodeActionClientCapabilities{
+					CodeActionLiteralSupport: &CodeActionLiteralSupport{
+						CodeActionKind: CodeActionKindValueSet{ValueSet: codeActionKinds},
 					},
-					"references": map[string]any{},
+					IsPreferredSupport: true,
+					DataSupport:        true,
+					ResolveSupport:     &CodeActionResolveSupport{Properties: []string{"edit"}},
+				},
+			},
+			Workspace: WorkspaceClientCapabilities{
+				WorkspaceFolders: true,
+				Configuration:    true,
+				DidChangeWatchedFiles: &DidChangeWatchedFilesClientCapabilities{
+					DynamicRegistration:    true,
+					RelativePatternSupport: true,
 				},
-				"workspace": map[string]any{
-					"workspaceFolders": true,
+				WorkspaceEdit: &WorkspaceEditClientCapabilities{
+					DocumentChanges:    true,
+					ResourceOperations: []string{"create", "rename", "delete"},
+					FailureHandling:    "transactional",
 				},
 			},
+			Window: WindowClientCapabilities{
+				WorkDoneProgress: true,
+			},
 		},
 	}
 
-	// Send initialize request
-	if err := c.sendRequest(initRequest); err != nil {
+	// The supervisor cannot fail pending requests while the caller holds c.mu,
+	// so stop waiting for the initialize result as soon as gopls exits
+	initCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	exited := c.exited
+	go func() {
+		select {
+		case <-exited:
+			cancel(errGoplsExited)
+		case <-initCtx.Done():
+		}
+	}()
+
+	// Wait for the initialize result before announcing that the client is initialized
+	var result InitializeResult
+	if err := c.call(initCtx, "initialize", params, &result); err != nil {
+		if cause := context.Cause(initCtx); errors.Is(cause, errGoplsExited) {
+			err = cause
+		}
 		return fmt.Errorf("failed to send initialize request: %w", err)
 	}
-
-	// Send initialized notification
-	initializedNotification := map[string]any{
-		"jsonrpc": "2.0",
-		"method":  "initialized",
-		"params":  map[string]any{},
+	c.serverCapabilities = result.Capabilities
+	c.serverInfo = ServerInfo{}
+	if result.ServerInfo != nil {
+		c.serverInfo = *result.ServerInfo
 	}
+	c.initialized = true
 
-	if err := c.sendRequest(initializedNotification); err != nil {
+	// Send initialized notification
+	if err := c.notify("initialized", InitializedParams{}); err != nil {
 		return fmt.Errorf("failed to send initialized notification: %w", err)
 	}
+	c.expectInitialLoad(initialLoadGracePeriod)
 
-	c.logger.Info("gopls initialized successfully")
-	return nil
-}
-
-// sendRequest sends a JSON-RPC request to gopls.
-func (c *goplsClient) sendRequest(request map[string]any) error {
-	if !c.running {
-		return fmt.Errorf("gopls is not running")
-	}
-
-	data, err := json.Marshal(request)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	// LSP uses Content-Length header format
-	message := fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(data), data)
-
-	if method, ok := request["method"].(string); ok {
-		c.logger.Debug("sending LSP request", "method", method, "id", request["id"])
-	}
-
-	_, err = c.stdin.Write([]byte(message))
-	if err != nil {
-		return fmt.Errorf("failed to write request: %w", err)
-	}
-
+	c.logger.Info("gopls initialized successfully",
+		"server", c.serverInfo.Name, "version", goplsVersion(c.serverInfo.Version))
 	return nil
 }
 
-// nextRequestID generates the next request ID for LSP communication.
-func (c *goplsClient) nextRequestID() int {
-	c.requestIDMux.Lock()
-	defer c.requestIDMux.Unlock()
-	c.requestID++
-	return c.requestID
-}
-
 // monitorStderr monitors stderr output from gopls.
-func (c *goplsClient) monitorStderr() {
+func (c *goplsClient) monitorStderr(stderr io.Reader) {
 	c.logger.Debug("starting stderr monitor")
-	scanner := bufio.NewScanner(c.stderr)
+	scanner := bufio.NewScanner(stderr)
 	for scanner.Scan() {
 		line := scanner.Text()
 		c.logger.Debug("gopls stderr", "output", line)
@@ -284,175 +433,6 @@ func (c *goplsClient) isErrorLine(lowerLine string) bool {
 		strings.Contains(lowerLine, "fatal")
 }
 
-// messageReader continuously reads messages from gopls stdout.
-func (c *goplsClient) messageReader() {
-	c.logger.Debug("starting message reader")
-	reader := bufio.NewReader(c.stdout)
-
-	for c.isRunning() {
-		message, err := c.readLSPMessage(reader)
-		if err != nil {
-			if c.isRunning() {
-				c.logger.Error("error reading LSP message", "error", err)
-			}
-			return
-		}
-
-		c.handleLSPMessage(message)
-	}
-	c.logger.Debug("message reader exited")
-}
-
-// readLSPMessage reads a single LSP message from the reader.
-func (c *goplsClient) readLSPMessage(reader *bufio.Reader) (map[string]any, error) {
-	// Read headers
-	headers := make(map[string]string)
-	for {
-		line, err := reader.ReadString('\n')
-		if err != nil {
-			return nil, fmt.Errorf("failed to read header line: %w", err)
-		}
-
-		// Trim line ending
-		line = strings.TrimSuffix(line, "\n")
-		line = strings.TrimSuffix(line, "\r")
-
-		// Empty line marks end of headers
-		if line == "" {
-			break
-		}
-
-		// Parse header
-		parts := strings.SplitN(line, ": ", 2)
-		if len(parts) == 2 {
-			headers[parts[0]] = parts[1]
-		}
-	}
-
-	// Get content length
-	contentLengthStr, ok := headers["Content-Length"]
-	if !ok {
-		return nil, fmt.Errorf("missing Content-Length header")
-	}
-
-	var contentLength int
-	if _, err := fmt.Sscanf(contentLengthStr, "%d", &contentLength); err != nil {
-		return nil, fmt.Errorf("invalid Content-Length: %s", contentLengthStr)
-	}
-
-	// Read content
-	content := make([]byte, contentLength)
-	if _, err := io.ReadFull(reader, content); err != nil {
-		return nil, fmt.Errorf("failed to read message content: %w", err)
-	}
-
-	// Parse JSON
-	var message map[string]any
-	if err := json.Unmarshal(content, &message); err != nil {
-		return nil, fmt.Errorf("failed to parse LSP message: %w", err)
-	}
-
-	return message, nil
-}
-
-// handleLSPMessage routes an LSP message to the appropriate handler.
-func (c *goplsClient) handleLSPMessage(message map[string]any) {
-	id, hasID := message["id"]
-	method, hasMethod := message["method"]
-
-	c.logger.Debug("handleLSPMessage", "hasID", hasID, "hasMethod", hasMethod, "id", id, "method", method)
-
-	switch {
-	case hasID && !hasMethod:
-		// This is a response to our request
-		if idFloat, isFloat := id.(float64); isFloat {
-			c.logger.Debug("routing response", "requestID", int(idFloat))
-			c.routeResponse(int(idFloat), message)
-		} else {
-			c.logger.Debug("response with non-float ID", "id", id, "type", fmt.Sprintf("%T", id))
-		}
-	case hasMethod:
-		// This is a notification or request from server
-		if methodStr, isString := method.(string); isString {
-			switch methodStr {
-			case "textDocument/publishDiagnostics":
-				c.handlePublishDiagnostics(message)
-			default:
-				c.logger.Debug("received notification/request from gopls", "method", method)
-			}
-		} else {
-			c.logger.Debug("received notification/request from gopls", "method", method)
-		}
-	default:
-		c.logger.Debug("unhandled LSP message", "message", message)
-	}
-}
-
-// routeResponse routes a response to the appropriate request handler.
-func (c *goplsClient) routeResponse(id int, response map[string]any) {
-	c.responsesMux.Lock()
-	ch, ok := c.responses[id]
-	if ok {
-		delete(c.responses, id)
-	}
-	c.responsesMux.Unlock()
-
-	if ok {
-		select {
-		case ch <- response:
-			// Response delivered
-		default:
-			c.logger.Warn("response channel full", "requestID", id)
-		}
-	} else {
-		c.logger.Warn("received response for unknown request ID", "requestID", id)
-	}
-}
-
-// sendRequestAndWait sends a request and waits for the response.
-func (c *goplsClient) sendRequestAndWait(request map[string]any) (map[string]any, error) {
-	id, ok := request["id"].(int)
-	if !ok {
-		return nil, fmt.Errorf("request missing integer ID")
-	}
-
-	// Create response channel
-	responseCh := make(chan map[string]any, 1)
-	c.responsesMux.Lock()
-	c.responses[id] = responseCh
-	c.responsesMux.Unlock()
-
-	// Send the request
-	if err := c.sendRequest(request); err != nil {
-		c.responsesMux.Lock()
-		delete(c.responses, id)
-		c.responsesMux.Unlock()
-		return nil, err
-	}
-
-	// Wait for response with timeout
-	c.logger.Debug("waiting for response", "requestID", id)
-	select {
-	case response := <-responseCh:
-		c.logger.Debug("received response", "requestID", id)
-
-		// Check for LSP error
-		if errorField, hasError := response["error"]; hasError {
-			errorMap, _ := errorField.(map[string]any)
-			code, _ := errorMap["code"].(float64)
-			message, _ := errorMap["message"].(string)
-			return nil, fmt.Errorf("LSP error %d: %s", int(code), message)
-		}
-		return response, nil
-
-	case <-time.After(30 * time.Second):
-		c.responsesMux.Lock()
-		delete(c.responses, id)
-		c.responsesMux.Unlock()
-		return nil, fmt.Errorf("timeout waiting for response to request %d", id)
-	}
-}
-
 // relativePathToURI converts a workspace-relative path to a file:// URI.
 func (c *goplsClient) relativePathToURI(relativePath string) string {
 	absolutePath := filepath.Join(c.workspacePath, relativePath)
@@ -478,63 +458,3 @@ func (c *goplsClient) uriToRelativePath(uri string) string {
 
 	return relativePath
 }
-
-// ensureFileOpen ensures a file is opened in gopls before making requests about it.
-func (c *goplsClient) ensureFileOpen(relativePath string) error {
-	// Check if file is already open
-	c.openFilesMux.RLock()
-	isOpen := c.openFiles[relativePath]
-	c.openFilesMux.RUnlock()
-
-	if isOpen {
-		return nil // File already open
-	}
-
-	// Convert to absolute path and URI
-	absolutePath := filepath.Join(c.workspacePath, relativePath)
-	fileURI := c.relativePathToURI(relativePath)
-
-	// Read file content
-	content, err := os.ReadFile(absolutePath)
-	if err != nil {
-		return fmt.Errorf("failed to read file %s: %w", absolutePath, err)
-	}
-
-	// Determine language ID based on file extension
-	languageID := "go" // Default to Go
-	ext := filepath.Ext(relativePath)
-	switch ext {
-	case ".go":
-		languageID = "go"
-	case ".mod":
-		languageID = "go.mod"
-	case ".sum":
-		languageID = "go.sum"
-	}
-
-	// Send textDocument/didOpen notification
-	didOpenNotification := map[string]any{
-		"jsonrpc": "2.0",
-		"method":  "textDocument/didOpen",
-		"params": map[string]any{
-			"textDocument": map[string]any{
-				"uri":        fileURI,
-				"languageId": languageID,
-				"version":    1,
-				"text":       string(content),
-			},
-		},
-	}
-
-	if err := c.sendRequest(didOpenNotification); err != nil {
-		return fmt.Errorf("failed to send didOpen notification: %w", err)
-	}
-
-	// Mark file as open
-	c.openFilesMux.Lock()
-	c.openFiles[relativePath] = true
-	c.openFilesMux.Unlock()
-
-	c.logger.Debug("opened file in gopls", "relativePath", relativePath, "uri", fileURI)
-	return nil
-}
@@ -87,7 +87,7 @@ func TestGoplsClientLifecycle(t *testing.T) {
 	defer cleanup()
 
 	logger := newDebugLogger()
-	client := newClient(workspacePath, logger)
+	client := newClient(workspacePath, workspaceConfig{}, logger)
 
 	// Test initial state
 	if client.isRunning() {
@@ -118,6 +118,41 @@ func TestGoplsClientLifecycle(t *testing.T) {
 	}
 }
 
+func TestGoplsClientGracefulShutdown(t *testing.T) {
+	requireGopls(t)
+
+	workspacePath, cleanup := createTempGoWorkspace(t)
+	defer cleanup()
+
+	logger := newDebugLogger()
+	client := newClient(workspacePath, workspaceConfig{}, logger)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := client.start(ctx); err != nil {
+		t.Fatalf("failed to start client: %v", err)
+	}
+
+	client.mu.RLock()
+	cmd := client.cmd
+	client.mu.RUnlock()
+
+	if err := client.stop(); err != nil {
+		t.Fatalf("failed to stop client: %v", err)
+	}
+
+	// gopls exits on its own after the shutdown/exit handshake
+	if cmd.ProcessState == nil || !cmd.ProcessState.Success() {
+		t.Errorf("Expected gopls to exit cleanly, got state: %v", cmd.ProcessState)
+	}
+
+	// Requests after stop are rejected instead of hanging
+	if _, err := client.getHover(context.Background(), "main.go", 6, 11); err == nil {
+		t.Error("Expected request after stop to fail")
+	}
+}
+
 func TestGoplsClientDoubleStart(t *testing.T) {
 	requireGopls(t)
 
@@ -125,7 +160,7 @@ func TestGoplsClientDoubleStart(t *testing.T) {
 	defer cleanup()
 
 	logger := newDebugLogger()
-	client := newClient(workspacePath, logger)
+	client := newClient(workspacePath, workspaceConfig{}, logger)
 	defer func() { _ = client.stop() }()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -149,7 +184,7 @@ func TestGoplsClientProcessTracking(t *testing.T) {
 	defer cleanup()
 
 	logger := newDebugLogger()
-	client := newClient(workspacePath, logger)
+	client := newClient(workspacePath, workspaceConfig{}, logger)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -186,7 +221,7 @@ func TestGoplsClientInvalidWorkspace(t *testing.T) {
 
 	logger := newDebugLogger()
 	// Use non-existent workspace
-	client := newClient("/non/existent/workspace", logger)
+	client := newClient("/non/existent/workspace", workspaceConfig{}, logger)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -203,7 +238,7 @@ func TestGoplsClientStopNotRunning(t *testing.T) {
 	defer cleanup()
 
 	logger := newDebugLogger()
-	client := newClient(workspacePath, logger)
+	client := newClient(workspacePath, workspaceConfig{}, logger)
 
 	// Stop should not error when client is not running
 	if err := client.stop(); err != nil {
@@ -216,7 +251,7 @@ func TestGoplsClientCleanupOnFailure(t *testing.T) {
 
 	logger := newDebugLogger()
 	// Use invalid workspace to trigger initialization failure
-	client := newClient("/non/existent/workspace", logger)
+	client := newClient("/non/existent/workspace", workspaceConfig{}, logger)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -247,7 +282,7 @@ func TestGoplsClientInitialization(t *testing.T) {
 	defer cleanup()
 
 	logger := newDebugLogger()
-	client := newClient(workspacePath, logger)
+	client := newClient(workspacePath, workspaceConfig{}, logger)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -283,7 +318,7 @@ func TestGoplsClientStderrMonitoring(t *testing.T) {
 	defer cleanup()
 
 	logger := newDebugLogger()
-	client := newClient(workspacePath, logger)
+	client := newClient(workspacePath, workspaceConfig{}, logger)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -306,6 +341,179 @@ func TestGoplsClientStderrMonitoring(t *testing.T) {
 	// depends on gopls internal behavior.
 }
 
+func TestGoplsClientCrashRecovery(t *testing.T) {
+	requireGopls(t)
+
+	workspacePath, cleanup := createTempGoWorkspace(t)
+	defer cleanup()
+
+	logger := newDebugLogger()
+	client := newClient(workspacePath, workspaceConfig{}, logger)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	if err := client.start(ctx); err != nil {
+		t.Fatalf("failed to start client: %v", err)
+	}
+	defer func() { _ = client.stop() }()
+
+	if err := client.ensureFileOpen("main.go"); err != nil {
+		t.Fatalf("failed to open main.go: %v", err)
+	}
+
+	client.mu.RLock()
+	oldPid := client.cmd.Process.Pid
+	process := client.cmd.Process
+	client.mu.RUnlock()
+
+	// Simulate a gopls crash
+	if err := process.Kill(); err != nil {
+		t.Fatalf("failed to kill gopls: %v", err)
+	}
+
+	// Wait for the supervisor to notice the exit
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		client.mu.RLock()
+		noticed := client.restarting || client.restarts > 0
+		client.mu.RUnlock()
+		if noticed {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Requests made during the restart wait for the new process
+	locations, err := client.goToDefinition(ctx, "main.go", 6, 11)
+	if err != nil {
+		t.Fatalf("goToDefinition failed after crash: %v", err)
+	}
+	if len(locations) == 0 {
+		t.Error("Expected definition locations after restart")
+	}
+
+	client.mu.RLock()
+	newPid := client.cmd.Process.Pid
+	restarts := client.restarts
+	client.mu.RUnlock()
+
+	if newPid == oldPid {
+		t.Errorf("Expected a new gopls process, still running PID %d", oldPid)
+	}
+	if restarts != 1 {
+		t.Errorf("Expected 1 restart, got %d", restarts)
+	}
+
+	client.openFilesMux.Lock()
+	_, reopened := client.openFiles["main.go"]
+	client.openFilesMux.Unlock()
+	if !reopened {
+		t.Error("Expected main.go to be reopened after restart")
+	}
+}
+
+func TestGoplsClientDocumentSync(t *testing.T) {
+	requireGopls(t)
+
+	workspacePath, cleanup := createTempGoWorkspace(t)
+	defer cleanup()
+
+	logger := newDebugLogger()
+	client := newClient(workspacePath, workspaceConfig{}, logger)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := client.start(ctx); err != nil {
+		t.Fatalf("failed to start client: %v", err)
+	}
+	defer func() { _ = client.stop() }()
+
+	before, err := client.goToDefinition(ctx, "main.go", 6, 11)
+	if err != nil || len(before) == 0 {
+		t.Fatalf("goToDefinition failed before edit: %v (%d locations)", err, len(before))
+	}
+
+	// Shift every line down by one on disk
+	mainPath := filepath.Join(workspacePath, "main.go")
+	content, err := os.ReadFile(mainPath)
+	if err != nil {
+		t.Fatalf("failed to read main.go: %v", err)
+	}
+	if err := os.WriteFile(mainPath, append([]byte("// Edited on disk\n"), content...), 0644); err != nil {
+		t.Fatalf("failed to write main.go: %v", err)
+	}
+
+	after, err := client.goToDefinition(ctx, "main.go", 7, 11)
+	if err != nil || len(after) == 0 {
+		t.Fatalf("goToDefinition failed after edit: %v (%d locations)", err, len(after))
+	}
+	if after[0].Range.Start.Line != before[0].Range.Start.Line+1 {
+		t.Errorf("Expected definition to move from line %d to %d, got %d",
+			before[0].Range.Start.Line, before[0].Range.Start.Line+1, after[0].Range.Start.Line)
+	}
+}
+
+func TestGoplsClientWorkspaceReadiness(t *testing.T) {
+	requireGopls(t)
+
+	workspacePath, cleanup := createTempGoWorkspace(t)
+	defer cleanup()
+
+	logger := newDebugLogger()
+	client := newClient(workspacePath, workspaceConfig{}, logger)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := client.start(ctx); err != nil {
+		t.Fatalf("failed to start client: %v", err)
+	}
+	defer func() { _ = client.stop() }()
+
+	if err := client.waitForInitialLoad(ctx); err != nil {
+		t.Fatalf("waitForInitialLoad failed: %v", err)
+	}
+
+	status := client.workspaceStatus()
+	if !status.Ready {
+		t.Errorf("Expected workspace to be ready after the initial load, got %+v", status)
+	}
+}
+
+func TestGoplsClientSettings(t *testing.T) {
+	requireGopls(t)
+
+	workspacePath, cleanup := createTempGoWorkspace(t)
+	defer cleanup()
+
+	logger := newDebugLogger()
+	config := workspaceConfig{Settings: map[string]any{"hints": map[string]any{"parameterNames": true}}}
+	client := newClient(workspacePath, config, logger)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := client.start(ctx); err != nil {
+		t.Fatalf("failed to start client: %v", err)
+	}
+	defer func() { _ = client.stop() }()
+
+	settings, err := client.updateSettings(ctx, map[string]any{"gofumpt": true}, false)
+	if err != nil {
+		t.Fatalf("updateSettings failed: %v", err)
+	}
+	if settings["gofumpt"] != true || settings["hints"] == nil {
+		t.Errorf("Expected merged settings, got %v", settings)
+	}
+
+	// gopls keeps serving requests after reloading its configuration
+	if _, err := client.formatDocument(ctx, "main.go"); err != nil {
+		t.Errorf("formatDocument failed after settings update: %v", err)
+	}
+}
+
 func TestGoplsClientGoToDefinition(t *testing.T) {
 	requireGopls(t)
 
@@ -313,7 +521,7 @@ func TestGoplsClientGoToDefinition(t *testing.T) {
 	defer cleanup()
 
 	logger := newDebugLogger()
-	client := newClient(workspacePath, logger)
+	client := newClient(workspacePath, workspaceConfig{}, logger)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -330,7 +538,7 @@ func TestGoplsClientGoToDefinition(t *testing.T) {
 	// The function call is on line 6 (0-based), character 11 (start of "testFunction")
 	// result := testFunction()
 	//           ^-- position 11
-	locations, err := client.goToDefinition("main.go", 6, 11) // Position of "testFunction" call
+	locations, err := client.goToDefinition(context.Background(), "main.go", 6, 11) // Position of "testFunction" call
 	if err != nil {
 		t.Fatalf("goToDefinition failed: %v", err)
 	}
@@ -362,7 +570,8 @@ func TestGoplsClientGoToDefinition(t *testing.T) {
 		location.URI, location.Range.Start.Line, location.Range.Start.Character)
 
 	// Test go to definition on function definition itself (should return same location)
-	defLocations, err := client.goToDefinition("main.go", 11, 5) // Position of "testFunction" definition
+	// Position of "testFunction" definition
+	defLocations, err := client.goToDefinition(context.Background(), "main.go", 11, 5)
 	if err != nil {
 		t.Fatalf("goToDefinition on definition failed: %v", err)
 	}
@@ -382,7 +591,7 @@ func TestGoplsClientGoToDefinition(t *testing.T) {
 	}
 
 	// Test go to definition on non-existent symbol (should error or return empty)
-	_, err = client.goToDefinition("main.go", 0, 0) // Position with no symbol
+	_, err = client.goToDefinition(context.Background(), "main.go", 0, 0) // Position with no symbol
 	if err == nil {
 		t.Log("goToDefinition on empty position succeeded (gopls behavior may vary)")
 	} else {
@@ -397,7 +606,7 @@ func TestGoplsClientFindReferences(t *testing.T) {
 	defer cleanup()
 
 	logger := newDebugLogger()
-	client := newClient(workspacePath, logger)
+	client := newClient(workspacePath, workspaceConfig{}, logger)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -414,7 +623,7 @@ func TestGoplsClientFindReferences(t *testing.T) {
 	// The function definition is on line 11 (0-based), character 5 (start of "testFunction")
 	// func testFunction() int {
 	//      ^-- position 5
-	locationsWithDecl, err := client.findReferences("main.go", 11, 5, true) // Include declaration
+	locationsWithDecl, err := client.findReferences(context.Background(), "main.go", 11, 5, true) // Include declaration
 	if err != nil {
 		t.Fatalf("findReferences with declaration failed: %v", err)
 	}
@@ -426,7 +635,8 @@ func TestGoplsClientFindReferences(t *testing.T) {
 	}
 
 	// Test find references without declaration
-	locationsWithoutDecl, err := client.findReferences("main.go", 11, 5, false) // Exclude declaration
+	// Exclude declaration
+	locationsWithoutDecl, err := client.findReferences(context.Background(), "main.go", 11, 5, false)
 	if err != nil {
 		t.Fatalf("findReferences without declaration failed: %v", err)
 	}
@@ -485,7 +695,8 @@ func TestGoplsClientFindReferences(t *testing.T) {
 	// The function call is on line 6 (0-based), character 11 (start of "testFunction")
 	// result := testFunction()
 	//           ^-- position 11
-	callLocations, err := client.findReferences("main.go", 6, 11, true) // Position of "testFunction" call
+	// Position of "testFunction" call
+	callLocations, err := client.findReferences(context.Background(), "main.go", 6, 11, true)
 	if err != nil {
 		t.Fatalf("findReferences on call failed: %v", err)
 	}
@@ -524,7 +735,7 @@ func testHoverOnFunction(t *testing.T, client *goplsClient) {
 	// The function call is on line 6 (0-based), character 11 (start of "testFunction")
 	// result := testFunction()
 	//           ^-- position 11
-	hoverCall, err := client.getHover("main.go", 6, 11) // Position of "testFunction" call
+	hoverCall, err := client.getHover(context.Background(), "main.go", 6, 11) // Position of "testFunction" call
 	if err != nil {
 		t.Fatalf("getHover on function call failed: %v", err)
 	}
@@ -555,7 +766,7 @@ func testHoverOnFunction(t *testing.T, client *goplsClient) {
 	// The function definition is on line 11 (0-based), character 5 (start of "testFunction")
 	// func testFunction() int {
 	//      ^-- position 5
-	hoverDef, err := client.getHover("main.go", 11, 5) // Position of "testFunction" definition
+	hoverDef, err := client.getHover(context.Background(), "main.go", 11, 5) // Position of "testFunction" definition
 	if err != nil {
 		t.Fatalf("getHover on function definition failed: %v", err)
 	}
@@ -591,7 +802,7 @@ func testHoverOnPackage(t *testing.T, client *goplsClient) {
 	// The fmt.Println call is on line 5 (0-based), character 1 (start of "fmt")
 	// fmt.Println("Hello, World!")
 	// ^-- position 1
-	hoverFmt, err := client.getHover("main.go", 5, 1) // Position of "fmt" in fmt.Println
+	hoverFmt, err := client.getHover(context.Background(), "main.go", 5, 1) // Position of "fmt" in fmt.Println
 	if err != nil {
 		t.Fatalf("getHover on fmt failed: %v", err)
 	}
@@ -627,7 +838,7 @@ func testHoverOnVariableAndLiteral(t *testing.T, client *goplsClient) {
 	// The result variable is on line 7 (0-based), character 19 (position of "result")
 	// fmt.Println("Result:", result)
 	//                       ^-- position 19
-	hoverResult, err := client.getHover("main.go", 7, 19) // Position of "result" variable
+	hoverResult, err := client.getHover(context.Background(), "main.go", 7, 19) // Position of "result" variable
 	if err != nil {
 		t.Fatalf("getHover on result variable failed: %v", err)
 	}
@@ -657,7 +868,7 @@ func testHoverOnVariableAndLiteral(t *testing.T, client *goplsClient) {
 	// The return value is on line 12 (0-based), character 8 (start of "42")
 	// return 42
 	//        ^-- position 8
-	hoverReturnValue, err := client.getHover("main.go", 12, 8) // Position of "42"
+	hoverReturnValue, err := client.getHover(context.Background(), "main.go", 12, 8) // Position of "42"
 	if err != nil {
 		t.Fatalf("getHover on return value failed: %v", err)
 	}
@@ -689,7 +900,7 @@ func testHoverErrorCases(t *testing.T, client *goplsClient) {
 	t.Helper()
 
 	// Test hover on invalid position (may error or return nil/empty)
-	hoverInvalid, err := client.getHover("main.go", 100, 100) // Invalid position
+	hoverInvalid, err := client.getHover(context.Background(), "main.go", 100, 100) // Invalid position
 	if err != nil {
 		// This is acceptable - gopls may return an error for invalid positions
 		t.Logf("getHover on invalid position returned error (expected): %v", err)
@@ -699,7 +910,7 @@ func testHoverErrorCases(t *testing.T, client *goplsClient) {
 	}
 
 	// Test hover on non-existent file (should error)
-	_, err = client.getHover("nonexistent.go", 0, 0)
+	_, err = client.getHover(context.Background(), "nonexistent.go", 0, 0)
 	if err == nil {
 		t.Error("getHover on non-existent file should have failed")
 	}
@@ -712,7 +923,7 @@ func TestGoplsClientGetHover(t *testing.T) {
 	defer cleanup()
 
 	logger := newDebugLogger()
-	client := newClient(workspacePath, logger)
+	client := newClient(workspacePath, workspaceConfig{}, logger)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -880,7 +1091,7 @@ func TestGoplsClientGetDiagnostics(t *testing.T) {
 	defer cleanup()
 
 	logger := newDebugLogger()
-	client := newClient(workspacePath, logger)
+	client := newClient(workspacePath, workspaceConfig{}, logger)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -894,7 +1105,7 @@ func TestGoplsClientGetDiagnostics(t *testing.T) {
 	time.Sleep(3 * time.Second)
 
 	// Test getting diagnostics for main.go
-	diagnostics, err := client.getDiagnostics("main.go")
+	diagnostics, err := client.getDiagnostics(context.Background(), "main.go")
 	if err != nil {
 		t.Fatalf("getDiagnostics failed: %v", err)
 	}
@@ -907,7 +1118,7 @@ func TestGoplsClientGetDiagnostics(t *testing.T) {
 	}
 
 	// Test getting diagnostics for util.go
-	utilDiagnostics, err := client.getDiagnostics("util.go")
+	utilDiagnostics, err := client.getDiagnostics(context.Background(), "util.go")
 	if err != nil {
 		t.Fatalf("getDiagnostics for util.go failed: %v", err)
 	}
@@ -915,7 +1126,7 @@ func TestGoplsClientGetDiagnostics(t *testing.T) {
 	t.Logf("Found %d diagnostics for util.go", len(utilDiagnostics))
 
 	// Test getting diagnostics for non-existent file (should succeed but return empty or error)
-	_, err = client.getDiagnostics("nonexistent.go")
+	_, err = client.getDiagnostics(context.Background(), "nonexistent.go")
 	if err != nil {
 		t.Logf("getDiagnostics for non-existent file failed as expected: %v", err)
 	} else {
@@ -932,7 +1143,7 @@ func TestGoplsClientGetDocumentSymbols(t *testing.T) {
 	defer cleanup()
 
 	logger := newDebugLogger()
-	client := newClient(workspacePath, logger)
+	client := newClient(workspacePath, workspaceConfig{}, logger)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -946,7 +1157,7 @@ func TestGoplsClientGetDocumentSymbols(t *testing.T) {
 	time.Sleep(3 * time.Second)
 
 	// Test getting document symbols for main.go
-	symbols, err := client.getDocumentSymbols("main.go")
+	symbols, err := client.getDocumentSymbols(context.Background(), "main.go")
 	if err != nil {
 		t.Fatalf("getDocumentSymbols failed: %v", err)
 	}
@@ -997,7 +1208,7 @@ func TestGoplsClientGetDocumentSymbols(t *testing.T) {
 	}
 
 	// Test getting symbols for util.go
-	utilSymbols, err := client.getDocumentSymbols("util.go")
+	utilSymbols, err := client.getDocumentSymbols(context.Background(), "util.go")
 	if err != nil {
 		t.Fatalf("getDocumentSymbols for util.go failed: %v", err)
 	}
@@ -1005,7 +1216,7 @@ func TestGoplsClientGetDocumentSymbols(t *testing.T) {
 	t.Logf("Found %d document symbols in util.go", len(utilSymbols))
 
 	// Test error case - non-existent file
-	_, err = client.getDocumentSymbols("nonexistent.go")
+	_, err = client.getDocumentSymbols(context.Background(), "nonexistent.go")
 	if err != nil {
 		t.Logf("getDocumentSymbols for non-existent file failed as expected: %v", err)
 	} else {
@@ -1022,7 +1233,7 @@ func TestGoplsClientGetWorkspaceSymbols(t *testing.T) {
 	defer cleanup()
 
 	logger := newDebugLogger()
-	client := newClient(workspacePath, logger)
+	client := newClient(workspacePath, workspaceConfig{}, logger)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -1036,7 +1247,7 @@ func TestGoplsClientGetWorkspaceSymbols(t *testing.T) {
 	time.Sleep(3 * time.Second)
 
 	// Test searching for "Person" symbols
-	symbols, err := client.getWorkspaceSymbols("Person")
+	symbols, err := client.getWorkspaceSymbols(context.Background(), "Person")
 	if err != nil {
 		t.Fatalf("getWorkspaceSymbols failed: %v", err)
 	}
@@ -1049,7 +1260,7 @@ func TestGoplsClientGetWorkspaceSymbols(t *testing.T) {
 	}
 
 	// Test searching for "test" symbols (should find testFunction)
-	testSymbols, err := client.getWorkspaceSymbols("test")
+	testSymbols, err := client.getWorkspaceSymbols(context.Background(), "test")
 	if err != nil {
 		t.Fatalf("getWorkspaceSymbols for 'test' failed: %v", err)
 	}
@@ -1057,7 +1268,7 @@ func TestGoplsClientGetWorkspaceSymbols(t *testing.T) {
 	t.Logf("Found %d workspace symbols for 'test'", len(testSymbols))
 
 	// Test searching for "Math" symbols (should find MathUtils)
-	mathSymbols, err := client.getWorkspaceSymbols("Math")
+	mathSymbols, err := client.getWorkspaceSymbols(context.Background(), "Math")
 	if err != nil {
 		t.Fatalf("getWorkspaceSymbols for 'Math' failed: %v", err)
 	}
@@ -1065,7 +1276,7 @@ func TestGoplsClientGetWorkspaceSymbols(t *testing.T) {
 	t.Logf("Found %d workspace symbols for 'Math'", len(mathSymbols))
 
 	// Test fuzzy search
-	fuzzySymbols, err := client.getWorkspaceSymbols("Greet")
+	fuzzySymbols, err := client.getWorkspaceSymbols(context.Background(), "Greet")
 	if err != nil {
 		t.Fatalf("getWorkspaceSymbols fuzzy search failed: %v", err)
 	}
@@ -1073,7 +1284,7 @@ func TestGoplsClientGetWorkspaceSymbols(t *testing.T) {
 	t.Logf("Found %d workspace symbols for fuzzy 'Greet'", len(fuzzySymbols))
 
 	// Test empty query (should return all symbols or handle gracefully)
-	allSymbols, err := client.getWorkspaceSymbols("")
+	allSymbols, err := client.getWorkspaceSymbols(context.Background(), "")
 	if err != nil {
 		t.Logf("getWorkspaceSymbols with empty query failed: %v", err)
 	} else {
@@ -1090,7 +1301,7 @@ func TestGoplsClientGetSignatureHelp(t *testing.T) {
 	defer cleanup()
 
 	logger := newDebugLogger()
-	client := newClient(workspacePath, logger)
+	client := newClient(workspacePath, workspaceConfig{}, logger)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -1107,7 +1318,7 @@ func TestGoplsClientGetSignatureHelp(t *testing.T) {
 	// Position would be inside the function call parentheses
 	// result := testFunction(42)
 	//                       ^-- somewhere around here
-	signatureHelp, err := client.getSignatureHelp("main.go", 28, 22) // Inside testFunction call
+	signatureHelp, err := client.getSignatureHelp(context.Background(), "main.go", 28, 22) // Inside testFunction call
 	if err != nil {
 		t.Fatalf("getSignatureHelp failed: %v", err)
 	}
@@ -1128,7 +1339,7 @@ func TestGoplsClientGetSignatureHelp(t *testing.T) {
 
 	// Test signature help for fmt.Printf call
 	// Position would be inside the Printf call
-	signatureHelpPrintf, err := client.getSignatureHelp("main.go", 49, 15) // Inside fmt.Printf call
+	signatureHelpPrintf, err := client.getSignatureHelp(context.Background(), "main.go", 49, 15) // Inside fmt.Printf call
 	//nolint:gocritic // if-else chain is appropriate for test scenarios
 	if err != nil {
 		t.Logf("getSignatureHelp for Printf failed (may be expected): %v", err)
@@ -1139,7 +1350,7 @@ func TestGoplsClientGetSignatureHelp(t *testing.T) {
 	}
 
 	// Test signature help at invalid position
-	_, err = client.getSignatureHelp("main.go", 100, 100)
+	_, err = client.getSignatureHelp(context.Background(), "main.go", 100, 100)
 	if err != nil {
 		t.Logf("getSignatureHelp at invalid position failed as expected: %v", err)
 	} else {
@@ -1156,7 +1367,7 @@ func TestGoplsClientGetCompletions(t *testing.T) {
 	defer cleanup()
 
 	logger := newDebugLogger()
-	client := newClient(workspacePath, logger)
+	client := newClient(workspacePath, workspaceConfig{}, logger)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -1171,7 +1382,7 @@ func TestGoplsClientGetCompletions(t *testing.T) {
 
 	// Test completions after "fmt."
 	// Position would be right after "fmt." to get package method completions
-	completions, err := client.getCompletions("main.go", 27, 5) // After "fmt."
+	completions, err := client.getCompletions(context.Background(), "main.go", 27, 5) // After "fmt."
 	if err != nil {
 		t.Fatalf("getCompletions failed: %v", err)
 	}
@@ -1217,7 +1428,7 @@ func TestGoplsClientGetCompletions(t *testing.T) {
 
 	// Test completions for local symbols
 	// Position after partial typing of a local function
-	localCompletions, err := client.getCompletions("main.go", 29, 10) // Somewhere in main function
+	localCompletions, err := client.getCompletions(context.Background(), "main.go", 29, 10) // Somewhere in main function
 	if err != nil {
 		t.Logf("getCompletions for local symbols failed: %v", err)
 	} else if localCompletions != nil {
@@ -1225,7 +1436,7 @@ func TestGoplsClientGetCompletions(t *testing.T) {
 	}
 
 	// Test completions at invalid position
-	_, err = client.getCompletions("main.go", 1000, 1000)
+	_, err = client.getCompletions(context.Background(), "main.go", 1000, 1000)
 	if err != nil {
 		t.Logf("getCompletions at invalid position failed as expected: %v", err)
 	} else {
@@ -1242,7 +1453,7 @@ func TestGoplsClientGetTypeDefinition(t *testing.T) {
 	defer cleanup()
 
 	logger := newDebugLogger()
-	client := newClient(workspacePath, logger)
+	client := newClient(workspacePath, workspaceConfig{}, logger)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -1257,12 +1468,13 @@ func TestGoplsClientGetTypeDefinition(t *testing.T) {
 
 	// Test type definition for Person variable
 	// Position on "person" variable to find its type definition
-	locations, err := client.getTypeDefinition("main.go", 31, 2) // On "person" variable
+	locations, err := client.getTypeDefinition(context.Background(), "main.go", 31, 2) // On "person" variable
 	if err != nil {
 		// This might fail depending on gopls behavior and position accuracy
 		t.Logf("getTypeDefinition failed (position-dependent): %v", err)
 		// Try a different position - on the Person struct name in variable declaration
-		locations2, err2 := client.getTypeDefinition("main.go", 31, 12) // On "Person" type in declaration
+		// On "Person" type in declaration
+		locations2, err2 := client.getTypeDefinition(context.Background(), "main.go", 31, 12)
 		if err2 != nil {
 			t.Logf("getTypeDefinition also failed at second position: %v", err2)
 			// This is acceptable - type definition behavior varies
@@ -1288,7 +1500,7 @@ func TestGoplsClientGetTypeDefinition(t *testing.T) {
 	}
 
 	// Test type definition for greeter variable
-	greeterLocations, err := client.getTypeDefinition("main.go", 34, 2) // On "greeter" variable
+	greeterLocations, err := client.getTypeDefinition(context.Background(), "main.go", 34, 2) // On "greeter" variable
 	//nolint:gocritic // if-else chain is appropriate for test scenarios
 	if err != nil {
 		t.Logf("getTypeDefinition for greeter failed: %v", err)
@@ -1299,7 +1511,7 @@ func TestGoplsClientGetTypeDefinition(t *testing.T) {
 	}
 
 	// Test type definition at invalid position
-	_, err = client.getTypeDefinition("main.go", 1000, 1000)
+	_, err = client.getTypeDefinition(context.Background(), "main.go", 1000, 1000)
 	if err != nil {
 		t.Logf("getTypeDefinition at invalid position failed as expected: %v", err)
 	} else {
@@ -1316,7 +1528,7 @@ func TestGoplsClientFindImplementations(t *testing.T) {
 	defer cleanup()
 
 	logger := newDebugLogger()
-	client := newClient(workspacePath, logger)
+	client := newClient(workspacePath, workspaceConfig{}, logger)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -1331,7 +1543,7 @@ func TestGoplsClientFindImplementations(t *testing.T) {
 
 	// Test finding implementations of Greeter interface
 	// Position on the Greeter interface definition
-	locations, err := client.findImplementations("main.go", 16, 5) // On "Greeter" interface
+	locations, err := client.findImplementations(context.Background(), "main.go", 16, 5) // On "Greeter" interface
 	if err != nil {
 		t.Fatalf("findImplementations failed: %v", err)
 	}
@@ -1366,7 +1578,8 @@ func TestGoplsClientFindImplementations(t *testing.T) {
 	}
 
 	// Test finding implementations on method (should find interface implementations)
-	methodLocations, err := client.findImplementations("main.go", 17, 5) // On "Greet" method in interface
+	// On "Greet" method in interface
+	methodLocations, err := client.findImplementations(context.Background(), "main.go", 17, 5)
 	//nolint:gocritic // if-else chain is appropriate for test scenarios
 	if err != nil {
 		t.Logf("findImplementations for method failed: %v", err)
@@ -1377,7 +1590,7 @@ func TestGoplsClientFindImplementations(t *testing.T) {
 	}
 
 	// Test finding implementations at invalid position
-	_, err = client.findImplementations("main.go", 1000, 1000)
+	_, err = client.findImplementations(context.Background(), "main.go", 1000, 1000)
 	if err != nil {
 		t.Logf("findImplementations at invalid position failed as expected: %v", err)
 	} else {
@@ -1410,7 +1623,7 @@ return     42
 	}
 
 	logger := newDebugLogger()
-	client := newClient(workspacePath, logger)
+	client := newClient(workspacePath, workspaceConfig{}, logger)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -1424,7 +1637,7 @@ return     42
 	time.Sleep(3 * time.Second)
 
 	// Test formatting the unformatted file
-	textEdits, err := client.formatDocument("unformatted.go")
+	textEdits, err := client.formatDocument(context.Background(), "unformatted.go")
 	if err != nil {
 		t.Fatalf("formatDocument failed: %v", err)
 	}
@@ -1446,7 +1659,7 @@ return     42
 	}
 
 	// Test formatting main.go (should be already formatted)
-	mainEdits, err := client.formatDocument("main.go")
+	mainEdits, err := client.formatDocument(context.Background(), "main.go")
 	if err != nil {
 		t.Logf("formatDocument for main.go failed: %v", err)
 	} else {
@@ -1454,7 +1667,7 @@ return     42
 	}
 
 	// Test formatting non-existent file
-	_, err = client.formatDocument("nonexistent.go")
+	_, err = client.formatDocument(context.Background(), "nonexistent.go")
 	if err != nil {
 		t.Logf("formatDocument for non-existent file failed as expected: %v", err)
 	} else {
@@ -1492,7 +1705,7 @@ func main() {
 	}
 
 	logger := newDebugLogger()
-	client := newClient(workspacePath, logger)
+	client := newClient(workspacePath, workspaceConfig{}, logger)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -1506,7 +1719,7 @@ func main() {
 	time.Sleep(3 * time.Second)
 
 	// Test organizing imports for disorganized file
-	textEdits, err := client.organizeImports("disorganized.go")
+	textEdits, err := client.organizeImports(context.Background(), "disorganized.go")
 	if err != nil {
 		t.Fatalf("organizeImports failed: %v", err)
 	}
@@ -1528,7 +1741,7 @@ func main() {
 	}
 
 	// Test organizing imports for main.go
-	mainEdits, err := client.organizeImports("main.go")
+	mainEdits, err := client.organizeImports(context.Background(), "main.go")
 	if err != nil {
 		t.Logf("organizeImports for main.go failed: %v", err)
 	} else {
@@ -1536,7 +1749,7 @@ func main() {
 	}
 
 	// Test organizing imports for non-existent file
-	_, err = client.organizeImports("nonexistent.go")
+	_, err = client.organizeImports(context.Background(), "nonexistent.go")
 	if err != nil {
 		t.Logf("organizeImports for non-existent file failed as expected: %v", err)
 	} else {
@@ -1553,7 +1766,7 @@ func TestGoplsClientGetInlayHints(t *testing.T) {
 	defer cleanup()
 
 	logger := newDebugLogger()
-	client := newClient(workspacePath, logger)
+	client := newClient(workspacePath, workspaceConfig{}, logger)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -1568,7 +1781,7 @@ func TestGoplsClientGetInlayHints(t *testing.T) {
 
 	// Test getting inlay hints for a range in main.go
 	// This should include parameter names, type hints, etc.
-	hints, err := client.getInlayHints("main.go", 25, 0, 35, 0) // Range covering main function
+	hints, err := client.getInlayHints(context.Background(), "main.go", 25, 0, 35, 0) // Range covering main function
 	if err != nil {
 		t.Fatalf("getInlayHints failed: %v", err)
 	}
@@ -1586,7 +1799,7 @@ func TestGoplsClientGetInlayHints(t *testing.T) {
 	}
 
 	// Test getting inlay hints for testFunction
-	funcHints, err := client.getInlayHints("main.go", 40, 0, 45, 0) // Range covering testFunction
+	funcHints, err := client.getInlayHints(context.Background(), "main.go", 40, 0, 45, 0) // Range covering testFunction
 	if err != nil {
 		t.Logf("getInlayHints for testFunction failed: %v", err)
 	} else {
@@ -1594,7 +1807,7 @@ func TestGoplsClientGetInlayHints(t *testing.T) {
 	}
 
 	// Test getting inlay hints for util.go
-	utilHints, err := client.getInlayHints("util.go", 0, 0, 20, 0) // Range covering MathUtils
+	utilHints, err := client.getInlayHints(context.Background(), "util.go", 0, 0, 20, 0) // Range covering MathUtils
 	if err != nil {
 		t.Logf("getInlayHints for util.go failed: %v", err)
 	} else {
@@ -1602,7 +1815,7 @@ func TestGoplsClientGetInlayHints(t *testing.T) {
 	}
 
 	// Test invalid range
-	_, err = client.getInlayHints("main.go", 1000, 0, 1001, 0)
+	_, err = client.getInlayHints(context.Background(), "main.go", 1000, 0, 1001, 0)
 	if err != nil {
 		t.Logf("getInlayHints with invalid range failed as expected: %v", err)
 	} else {
@@ -1610,7 +1823,7 @@ func TestGoplsClientGetInlayHints(t *testing.T) {
 	}
 
 	// Test non-existent file
-	_, err = client.getInlayHints("nonexistent.go", 0, 0, 10, 0)
+	_, err = client.getInlayHints(context.Background(), "nonexistent.go", 0, 0, 10, 0)
 	if err != nil {
 		t.Logf("getInlayHints for non-existent file failed as expected: %v", err)
 	} else {
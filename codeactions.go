@@ -0,0 +1,338 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	// maxListedCodeActions bounds how many listed code actions are remembered
+	// so that they can be run by ID.
+	maxListedCodeActions = 256
+	// codeActionIDLength is the number of hex digits of a code action ID.
+	codeActionIDLength = 12
+)
+
+// codeActionKinds are the code action kinds advertised to gopls.
+var codeActionKinds = []string{
+	"quickfix",
+	"refactor",
+	"refactor.extract",
+	"refactor.inline",
+	"refactor.rewrite",
+	"source",
+	"source.organizeImports",
+	"source.fixAll",
+}
+
+// listedCodeAction is a code action offered by gopls and the document contents
+// it was computed for.
+type listedCodeAction struct {
+	id           string
+	relativePath string
+	documentHash [sha256.Size]byte
+	action       CodeAction
+}
+
+// codeActionOutcome is the result of running a listed code action.
+type codeActionOutcome struct {
+	action        CodeAction
+	files         []fileEdit
+	applied       bool
+	commandResult json.RawMessage
+}
+
+// commandEdits collects the workspace edits gopls requests while a command runs.
+// Edits are planned in the position encoding captured before the command was
+// sent, and gopls is told about the written files once the command returns.
+type commandEdits struct {
+	encoding string
+	files    []fileEdit
+	changes  []fileChange
+	err      error
+}
+
+// listCodeActions sends a textDocument/codeAction request for a range together
+// with the diagnostics overlapping it, and remembers the actions under stable IDs.
+func (c *goplsClient) listCodeActions(
+	ctx context.Context, relativePath string, actionRange Range, only []string,
+) ([]listedCodeAction, error) {
+	c.logger.Debug("listCodeActions called", "relativePath", relativePath, "range", actionRange, "only", only)
+
+	if err := c.waitUntilRunning(ctx); err != nil {
+		return nil, err
+	}
+
+	// Ensure file is open in gopls
+	if err := c.ensureFileOpen(relativePath); err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	documentHash, _ := c.documentHash(relativePath)
+
+	// Quick fixes are only offered for the diagnostics sent along with the request
+	diagnostics, err := c.rangeDiagnostics(ctx, relativePath, actionRange)
+	if err != nil {
+		return nil, err
+	}
+
+	params := CodeActionParams{
+		TextDocument: TextDocumentIdentifier{URI: c.relativePathToURI(relativePath)},
+		Range:        actionRange,
+		Context: CodeActionContext{
+			Diagnostics: diagnostics,
+			Only:        only,
+		},
+	}
+
+	// Send textDocument/codeAction request and wait for response
+	var result json.RawMessage
+	if err := c.call(ctx, "textDocument/codeAction", params, &result); err != nil {
+		return nil, fmt.Errorf("failed to get code actions: %w", err)
+	}
+
+	actions, err := decodeCodeActions(result)
+	if err != nil {
+		return nil, err
+	}
+
+	// IDs depend on the file, its contents and the action, so listing the same
+	// actions again yields the same IDs until the file changes
+	listed := make([]listedCodeAction, 0, len(actions))
+	occurrences := make(map[string]int)
+	for _, action := range actions {
+		key := fmt.Sprintf("%s\x00%x\x00%s\x00%s", relativePath, documentHash, action.Kind, action.Title)
+		occurrences[key]++
+		if n := occurrences[key]; n > 1 {
+			key = fmt.Sprintf("%s\x00%d", key, n)
+		}
+		listed = append(listed, listedCodeAction{
+			id:           contentHash(key)[:codeActionIDLength],
+			relativePath: relativePath,
+			documentHash: documentHash,
+			action:       action,
+		})
+	}
+
+	c.rememberCodeActions(listed)
+	return listed, nil
+}
+
+// rangeDiagnostics returns the diagnostics of a file that overlap a range.
+// Pushed diagnostics are used only when they describe the current document and
+// are not waited for.
+func (c *goplsClient) rangeDiagnostics(ctx context.Context, relativePath string, r Range) ([]Diagnostic, error) {
+	var diagnostics []Diagnostic
+	if capabilities, _ := c.capabilities(); capabilities.DiagnosticProvider.Enabled {
+		pulled, err := c.pullDiagnostics(ctx, relativePath)
+		if err != nil {
+			return nil, err
+		}
+		diagnostics = pulled
+	} else {
+		version, _ := c.documentVersion(relativePath)
+		if current := c.diagnosticsSnapshot(relativePath); current != nil && current.matches(version) {
+			diagnostics = current.items
+		}
+	}
+
+	overlapping := []Diagnostic{}
+	for _, diagnostic := range diagnostics {
+		if positionBefore(diagnostic.Range.End, r.Start) || positionBefore(r.End, diagnostic.Range.Start) {
+			continue
+		}
+		overlapping = append(overlapping, diagnostic)
+	}
+	return overlapping, nil
+}
+
+// rememberCodeActions stores listed code actions, forgetting the oldest ones
+// beyond maxListedCodeActions.
+func (c *goplsClient) rememberCodeActions(listed []listedCodeAction) {
+	c.codeActionsMux.Lock()
+	defer c.codeActionsMux.Unlock()
+
+	for _, entry := range listed {
+		if _, known := c.codeActions[entry.id]; !known {
+			c.codeActionIDs = append(c.codeActionIDs, entry.id)
+		}
+		c.codeActions[entry.id] = entry
+	}
+
+	for len(c.codeActionIDs) > maxListedCodeActions {
+		delete(c.codeActions, c.codeActionIDs[0])
+		c.codeActionIDs = c.codeActionIDs[1:]
+	}
+}
+
+// listedCodeAction returns a remembered code action by ID.
+func (c *goplsClient) listedCodeAction(id string) (listedCodeAction, bool) {
+	c.codeActionsMux.Lock()
+	defer c.codeActionsMux.Unlock()
+
+	entry, ok := c.codeActions[id]
+	return entry, ok
+}
+
+// runCodeAction resolves a listed code action and computes its edit and the
+// edits of its command. The edits are written to disk only when apply is set.
+// Commands run inside gopls and may change files on their own, so actions with
+// a command are only run when apply is set.
+func (c *goplsClient) runCodeAction(ctx context.Context, id string, apply bool) (codeActionOutcome, error) {
+	c.logger.Debug("runCodeAction called", "id", id, "apply", apply)
+
+	listed, ok := c.listedCodeAction(id)
+	if !ok {
+		return codeActionOutcome{}, fmt.Errorf("unknown code action %q: list the code actions again", id)
+	}
+
+	if err := c.waitUntilRunning(ctx); err != nil {
+		return codeActionOutcome{}, err
+	}
+
+	// Ensure file is open in gopls, which also picks up changes made on disk
+	if err := c.ensureFileOpen(listed.relativePath); err != nil {
+		return codeActionOutcome{}, fmt.Errorf("failed to open file: %w", err)
+	}
+	if documentHash, _ := c.documentHash(listed.relativePath); documentHash != listed.documentHash {
+		return codeActionOutcome{}, fmt.Errorf("%s changed since code action %q was listed: list the code actions again",
+			listed.relativePath, id)
+	}
+
+	action, err := c.resolveCodeAction(ctx, listed.action)
+	if err != nil {
+		return codeActionOutcome{}, err
+	}
+	if action.Edit == nil && action.Command == nil {
+		return codeActionOutcome{}, fmt.Errorf("code action %q has neither an edit nor a command", action.Title)
+	}
+	if action.Command != nil && !apply {
+		return codeActionOutcome{}, fmt.Errorf("code action %q runs gopls command %s, which cannot be previewed: "+
+			"run it with apply to execute the command and write its edits", action.Title, action.Command.Command)
+	}
+
+	outcome := codeActionOutcome{action: action, applied: apply}
+	if action.Edit != nil {
+		files, err := c.planWorkspaceEdit(action.Edit, nil)
+		if err != nil {
+			return codeActionOutcome{}, err
+		}
+		if apply {
+			if err := c.applyFileEdits(files); err != nil {
+				return codeActionOutcome{}, err
+			}
+		}
+		outcome.files = files
+	}
+
+	// The command runs after the edit, as the LSP specification requires
+	if action.Command != nil {
+		files, result, err := c.executeCommand(ctx, *action.Command)
+		if err != nil {
+			return codeActionOutcome{}, err
+		}
+		outcome.files = append(outcome.files, files...)
+		outcome.commandResult = result
+	}
+
+	return outcome, nil
+}
+
+// resolveCodeAction sends a codeAction/resolve request for an action whose
+// edit gopls deferred. Other actions are returned unchanged.
+func (c *goplsClient) resolveCodeAction(ctx context.Context, action CodeAction) (CodeAction, error) {
+	if action.Edit != nil || len(action.Data) == 0 || !c.supportsCodeActionResolve() {
+		return action, nil
+	}
+
+	// Send codeAction/resolve request and wait for response
+	var resolved CodeAction
+	if err := c.call(ctx, "codeAction/resolve", action, &resolved); err != nil {
+		return CodeAction{}, fmt.Errorf("failed to resolve code action: %w", err)
+	}
+	return resolved, nil
+}
+
+// supportsCodeActionResolve reports whether gopls advertised codeAction/resolve.
+func (c *goplsClient) supportsCodeActionResolve() bool {
+	capabilities, _ := c.capabilities()
+	var options struct {
+		ResolveProvider bool `json:"resolveProvider"`
+	}
+	if len(capabilities.CodeActionProvider.Options) == 0 ||
+		json.Unmarshal(capabilities.CodeActionProvider.Options, &options) != nil {
+		return false
+	}
+	return options.ResolveProvider
+}
+
+// executeCommand sends a workspace/executeCommand request and applies the
+// workspace edits gopls requests while the command runs.
+func (c *goplsClient) executeCommand(ctx context.Context, command Command) ([]fileEdit, json.RawMessage, error) {
+	c.logger.Debug("executeCommand called", "command", command.Command)
+
+	// Commands run one at a time so that edits are attributed to the right one
+	c.commandMux.Lock()
+	defer c.commandMux.Unlock()
+
+	// The edits are handled on the reader goroutine, which must not take c.mu
+	collected := &commandEdits{encoding: c.positionEncoding()}
+	c.commandEditsMux.Lock()
+	c.commandEdits = collected
+	c.commandEditsMux.Unlock()
+	defer func() {
+		c.commandEditsMux.Lock()
+		c.commandEdits = nil
+		c.commandEditsMux.Unlock()
+	}()
+
+	params := ExecuteCommandParams{Command: command.Command, Arguments: command.Arguments}
+
+	// Send workspace/executeCommand request and wait for response
+	var result json.RawMessage
+	callErr := c.call(ctx, "workspace/executeCommand", params, &result)
+
+	c.commandEditsMux.Lock()
+	files, changes, editErr := collected.files, collected.changes, collected.err
+	c.commandEditsMux.Unlock()
+
+	c.forwardFileChanges(changes)
+	if editErr != nil {
+		return nil, nil, fmt.Errorf("failed to apply edit of command %s: %w", command.Command, editErr)
+	}
+	if callErr != nil {
+		return nil, nil, fmt.Errorf("failed to execute command %s: %w", command.Command, callErr)
+	}
+	if isNullResult(result) {
+		result = nil
+	}
+	return files, result, nil
+}
+
+// collectCommandEdit writes a workspace edit requested by gopls while a command
+// runs. It reports false when no command is running. It is called on the
+// reader goroutine, so it neither takes c.mu nor waits for gopls.
+func (c *goplsClient) collectCommandEdit(edit *WorkspaceEdit) (ApplyWorkspaceEditResult, bool) {
+	c.commandEditsMux.Lock()
+	defer c.commandEditsMux.Unlock()
+
+	collected := c.commandEdits
+	if collected == nil {
+		return ApplyWorkspaceEditResult{}, false
+	}
+
+	files, err := c.planEncodedWorkspaceEdit(edit, nil, collected.encoding)
+	var changes []fileChange
+	if err == nil {
+		changes, err = c.writeFileEdits(files)
+	}
+	if err != nil {
+		collected.err = err
+		return ApplyWorkspaceEditResult{Applied: false, FailureReason: err.Error()}, true
+	}
+
+	collected.files = append(collected.files, files...)
+	collected.changes = append(collected.changes, changes...)
+	return ApplyWorkspaceEditResult{Applied: true}, true
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/MegaGrindStone/gopls-mcp/internal/fakegopls"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// codeActionsScript scripts a gopls that offers a quick fix for the scripted
+// warning in main.go, an inline refactoring resolved with codeAction/resolve and
+// a source action whose command creates main_test.go with workspace/applyEdit.
+func codeActionsScript() *fakegopls.Script {
+	script := fakeGoplsScript()
+	script.Initialize = json.RawMessage(`{"capabilities":{"positionEncoding":"utf-8",` +
+		`"textDocumentSync":{"openClose":true,"change":2},"codeActionProvider":{"resolveProvider":true},` +
+		`"executeCommandProvider":{"commands":["gopls.add_test"]}}}`)
+	script.Responses = append(script.Responses,
+		fakegopls.Response{
+			Method: "textDocument/codeAction",
+			Params: json.RawMessage(`{"context":{"diagnostics":[{"message":"scripted warning"}]}}`),
+			Result: json.RawMessage(`[` +
+				`{"title":"Remove call","kind":"quickfix","isPreferred":true,` +
+				`"diagnostics":[{"range":{"start":{"line":3,"character":1},"end":{"line":3,"character":7}},` +
+				`"message":"scripted warning"}],` +
+				`"edit":{"changes":{"${rootUri}/main.go":[` +
+				`{"range":{"start":{"line":3,"character":0},"end":{"line":4,"character":0}},"newText":""}]}}},` +
+				`{"title":"Inline call to helper","kind":"refactor.inline","data":{"action":"inline"}},` +
+				`{"title":"Add test for helper","kind":"source.addTest",` +
+				`"command":{"title":"Add test for helper","command":"gopls.add_test"}}]`),
+		},
+		fakegopls.Response{
+			Method: "textDocument/codeAction",
+			Result: json.RawMessage(`[]`),
+		},
+		fakegopls.Response{
+			Method: "codeAction/resolve",
+			Params: json.RawMessage(`{"data":{"action":"inline"}}`),
+			Result: json.RawMessage(`{"title":"Inline call to helper","kind":"refactor.inline",` +
+				`"edit":{"changes":{"${rootUri}/main.go":[` +
+				`{"range":{"start":{"line":3,"character":1},"end":{"line":3,"character":9}},"newText":"{}"}]}}}`),
+		},
+		fakegopls.Response{
+			Method: "workspace/executeCommand",
+			Params: json.RawMessage(`{"command":"gopls.add_test"}`),
+			Result: json.RawMessage(`null`),
+		},
+	)
+	script.Messages = append(script.Messages, fakegopls.Message{
+		After:   "workspace/executeCommand",
+		Method:  "workspace/applyEdit",
+		Request: true,
+		Params: json.RawMessage(`{"label":"Add test","edit":{"documentChanges":[` +
+			`{"kind":"create","uri":"${rootUri}/main_test.go"},` +
+			`{"textDocument":{"uri":"${rootUri}/main_test.go","version":null},"edits":[` +
+			`{"range":{"start":{"line":0,"character":0},"end":{"line":0,"character":0}},` +
+			`"newText":"package main\n"}]}]}}`),
+	})
+	return script
+}
+
+func TestCodeActions(t *testing.T) {
+	workspacePath := newFakeGoplsWorkspace(t)
+	config := fakeGoplsConfig(t, writeFakeGoplsScript(t, codeActionsScript()))
+	client := startFakeGoplsClient(t, workspacePath, config)
+	session := connectMCP(t, map[string]*goplsClient{workspacePath: client})
+
+	// Wait for the scripted warning so that it is sent along with the code action request
+	callTool[GetDiagnosticsResult](t, session, toolGetDiagnostics,
+		map[string]any{"workspace": workspacePath, "path": "main.go"})
+
+	listArguments := map[string]any{"workspace": workspacePath, "path": "main.go", "startLine": 4, "startChar": 1}
+	listed := callTool[CodeActionsResult](t, session, toolCodeActions, listArguments)
+	if len(listed.Actions) != 3 {
+		t.Fatalf("Expected 3 code actions, got %+v", listed)
+	}
+	quickFix, inline, addTest := listed.Actions[0], listed.Actions[1], listed.Actions[2]
+	if quickFix.Kind != "quickfix" || !quickFix.IsPreferred || len(quickFix.Diagnostics) != 1 ||
+		inline.Kind != "refactor.inline" || addTest.Command != "gopls.add_test" {
+		t.Errorf("Unexpected code actions: %+v", listed.Actions)
+	}
+	if again := callTool[CodeActionsResult](t, session, toolCodeActions, listArguments); len(again.Actions) != 3 ||
+		again.Actions[0].ID != quickFix.ID || again.Actions[1].ID != inline.ID || again.Actions[2].ID != addTest.ID {
+		t.Errorf("Expected listing the same actions again to keep their IDs, got %+v", again.Actions)
+	}
+
+	mainPath := filepath.Join(workspacePath, "main.go")
+	testPath := filepath.Join(workspacePath, "main_test.go")
+	run := func(id string, apply bool) CodeActionsResult {
+		return callTool[CodeActionsResult](t, session, toolCodeActions,
+			map[string]any{"workspace": workspacePath, "actionId": id, "apply": apply})
+	}
+
+	resolved := run(inline.ID, false)
+	if resolved.Applied || resolved.Diff != "--- a/main.go\n+++ b/main.go\n@@ -1,7 +1,7 @@\n package main\n \n"+
+		" func main() {\n-\thelper()\n+\t{}\n }\n \n func helper() {}\n" {
+		t.Errorf("Unexpected resolved action preview: %+v", resolved)
+	}
+
+	// Commands run in gopls, so they are refused without apply
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	preview, err := session.CallTool(ctx, &mcp.CallToolParams{
+		Name:      toolCodeActions,
+		Arguments: map[string]any{"workspace": workspacePath, "actionId": addTest.ID},
+	})
+	if err == nil && !preview.IsError {
+		t.Error("Expected previewing a command-backed code action to fail")
+	}
+	if _, err := os.Stat(testPath); !os.IsNotExist(err) {
+		t.Errorf("Expected a preview not to create main_test.go, got %v", err)
+	}
+
+	applied := run(addTest.ID, true)
+	content, err := os.ReadFile(testPath)
+	if !applied.Applied || err != nil || string(content) != "package main\n" ||
+		len(applied.Files) != 1 || applied.Files[0].Path != "main_test.go" || applied.Files[0].Change != "created" {
+		t.Errorf("Expected the command to create main_test.go, got %+v, %q, %v", applied, content, err)
+	}
+
+	fixed := run(quickFix.ID, true)
+	content, err = os.ReadFile(mainPath)
+	if !fixed.Applied || err != nil || string(content) != "package main\n\nfunc main() {\n}\n\nfunc helper() {}\n" {
+		t.Errorf("Expected the quick fix to be applied, got %+v, %q, %v", fixed, content, err)
+	}
+
+	// Actions listed for previous contents and unknown IDs are rejected
+	for _, id := range []string{inline.ID, "unknown"} {
+		result, err := session.CallTool(ctx, &mcp.CallToolParams{
+			Name:      toolCodeActions,
+			Arguments: map[string]any{"workspace": workspacePath, "actionId": id},
+		})
+		if err == nil && !result.IsError {
+			t.Errorf("Expected running code action %q to fail", id)
+		}
+	}
+}
+
+func TestCodeActionsRejectsInvalidArguments(t *testing.T) {
+	tools, mockClient := createTestMCPTools()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, arguments := range []CodeActionsParams{
+		{Workspace: "/test/workspace", StartLine: 1},
+		{Workspace: "/test/workspace", Path: "main.go"},
+		{Workspace: "/test/workspace", Path: "main.go", StartLine: 5, EndLine: 3},
+		{Workspace: "/test/workspace", Path: "main.go", StartLine: 5, StartChar: 4, EndLine: 5, EndChar: 2},
+	} {
+		if _, err := tools.HandleCodeActions(ctx, nil,
+			&mcp.CallToolParamsFor[CodeActionsParams]{Arguments: arguments}); err == nil {
+			t.Errorf("Expected %+v to be rejected", arguments)
+		}
+	}
+	if mockClient.listCodeActionsCalled {
+		t.Error("Expected invalid arguments to be rejected before listing code actions")
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// defaultGoplsPath is the gopls binary used when none is configured.
+const defaultGoplsPath = "gopls"
+
+// workspaceConfig holds how gopls is launched for a workspace.
+type workspaceConfig struct {
+	GoplsPath string            `json:"goplsPath,omitempty"`
+	GoplsArgs []string          `json:"goplsArgs,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+	// Settings are gopls settings such as staticcheck, buildFlags or hints.
+	Settings map[string]any `json:"settings,omitempty"`
+	// MaxInFlight and MaxQueued bound the requests sent to and waiting for gopls.
+	MaxInFlight int `json:"maxInFlight,omitempty"`
+	MaxQueued   int `json:"maxQueued,omitempty"`
+	// CacheSize bounds the cached query results; a negative size disables the cache.
+	CacheSize int `json:"cacheSize,omitempty"`
+	// TraceDir enables writing the LSP traffic to a JSONL file in this directory.
+	TraceDir       string `json:"traceDir,omitempty"`
+	TraceMaxSizeMB int    `json:"traceMaxSizeMB,omitempty"`
+	// MaxMemoryMB recycles gopls once its resident memory exceeds this many MB.
+	MaxMemoryMB int `json:"maxMemoryMB,omitempty"`
+}
+
+// serverConfig is the layout of the -config file. Defaults apply to every
+// workspace; entries in Workspaces override them for a single workspace.
+type serverConfig struct {
+	Defaults   workspaceConfig            `json:"defaults"`
+	Workspaces map[string]workspaceConfig `json:"workspaces"`
+}
+
+// goplsPath returns the gopls binary to run.
+func (w workspaceConfig) goplsPath() string {
+	if w.GoplsPath == "" {
+		return defaultGoplsPath
+	}
+	return w.GoplsPath
+}
+
+// environ returns the process environment for gopls: the inherited environment
+// with the configured overrides applied in a stable order.
+func (w workspaceConfig) environ() []string {
+	env := os.Environ()
+	keys := make([]string, 0, len(w.Env))
+	for key := range w.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		env = append(env, key+"="+w.Env[key])
+	}
+	return env
+}
+
+// merge returns w overridden by the fields set in override. Environment
+// variables and gopls settings are merged key by key.
+func (w workspaceConfig) merge(override workspaceConfig) workspaceConfig {
+	merged := workspaceConfig{
+		GoplsPath:      w.GoplsPath,
+		GoplsArgs:      w.GoplsArgs,
+		Env:            make(map[string]string, len(w.Env)+len(override.Env)),
+		MaxInFlight:    w.MaxInFlight,
+		MaxQueued:      w.MaxQueued,
+		CacheSize:      w.CacheSize,
+		TraceDir:       w.TraceDir,
+		TraceMaxSizeMB: w.TraceMaxSizeMB,
+		MaxMemoryMB:    w.MaxMemoryMB,
+	}
+	if override.GoplsPath != "" {
+		merged.GoplsPath = override.GoplsPath
+	}
+	if override.GoplsArgs != nil {
+		merged.GoplsArgs = override.GoplsArgs
+	}
+	if override.MaxInFlight > 0 {
+		merged.MaxInFlight = override.MaxInFlight
+	}
+	if override.MaxQueued > 0 {
+		merged.MaxQueued = override.MaxQueued
+	}
+	if override.CacheSize != 0 {
+		merged.CacheSize = override.CacheSize
+	}
+	if override.TraceDir != "" {
+		merged.TraceDir = override.TraceDir
+	}
+	if override.TraceMaxSizeMB > 0 {
+		merged.TraceMaxSizeMB = override.TraceMaxSizeMB
+	}
+	if override.MaxMemoryMB > 0 {
+		merged.MaxMemoryMB = override.MaxMemoryMB
+	}
+	for key, value := range w.Env {
+		merged.Env[key] = value
+	}
+	for key, value := range override.Env {
+		merged.Env[key] = value
+	}
+	if w.Settings != nil || override.Settings != nil {
+		merged.Settings = mergeSettings(w.Settings, override.Settings, false)
+	}
+	return merged
+}
+
+// loadServerConfig reads a JSON configuration file. An empty path yields an empty configuration.
+func loadServerConfig(path string) (serverConfig, error) {
+	var config serverConfig
+	if path == "" {
+		return config, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return config, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// resolveWorkspaceConfigs computes the launch configuration of every workspace.
+// Precedence, from lowest to highest: config file defaults, command line flags,
+// then the workspace's own entry in the config file.
+func resolveWorkspaceConfigs(
+	config serverConfig, flags workspaceConfig, workspacePaths []string,
+) (map[string]workspaceConfig, error) {
+	overrides := make(map[string]workspaceConfig, len(config.Workspaces))
+	for path, override := range config.Workspaces {
+		overrides[normalizeWorkspacePath(path)] = override
+	}
+
+	defaults := config.Defaults.merge(flags)
+	resolved := make(map[string]workspaceConfig, len(workspacePaths))
+	for _, workspacePath := range workspacePaths {
+		key := normalizeWorkspacePath(workspacePath)
+		resolved[workspacePath] = defaults.merge(overrides[key])
+		delete(overrides, key)
+	}
+
+	if len(overrides) > 0 {
+		unknown := make([]string, 0, len(overrides))
+		for path := range overrides {
+			unknown = append(unknown, path)
+		}
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("config file references unknown workspaces: %s", strings.Join(unknown, ", "))
+	}
+	return resolved, nil
+}
+
+// normalizeWorkspacePath makes workspace paths from flags and the config file comparable.
+func normalizeWorkspacePath(path string) string {
+	absolutePath, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	return absolutePath
+}
+
+// envFlag collects repeatable KEY=VALUE command line flags.
+type envFlag map[string]string
+
+// String returns the flag value in KEY=VALUE form.
+func (e envFlag) String() string {
+	pairs := make([]string, 0, len(e))
+	for key, value := range e {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// Set parses a single KEY=VALUE pair.
+func (e envFlag) Set(value string) error {
+	key, val, found := strings.Cut(value, "=")
+	if !found || strings.TrimSpace(key) == "" {
+		return fmt.Errorf("invalid environment variable %q: expected KEY=VALUE", value)
+	}
+	e[strings.TrimSpace(key)] = val
+	return nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveWorkspaceConfigs(t *testing.T) {
+	workspaceA, workspaceB := t.TempDir(), t.TempDir()
+
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	configJSON := `{
+		"defaults": {
+			"goplsPath": "/opt/gopls",
+			"env": {"GOFLAGS": "-mod=mod", "GOPRIVATE": "example.com"},
+			"settings": {"staticcheck": true, "gofumpt": true},
+			"maxInFlight": 8
+		},
+		"workspaces": {
+			"` + workspaceB + `": {
+				"goplsArgs": ["-rpc.trace"],
+				"env": {"GOOS": "js", "GOARCH": "wasm"},
+				"settings": {"staticcheck": false, "buildFlags": ["-tags=wasm"]},
+				"maxQueued": 4,
+				"cacheSize": -1,
+				"maxMemoryMB": 2048
+			}
+		}
+	}`
+	if err := os.WriteFile(configPath, []byte(configJSON), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	config, err := loadServerConfig(configPath)
+	if err != nil {
+		t.Fatalf("loadServerConfig failed: %v", err)
+	}
+
+	flags := workspaceConfig{GoplsArgs: []string{"-remote=auto"}, Env: map[string]string{"GOFLAGS": "-tags=integration"}}
+	resolved, err := resolveWorkspaceConfigs(config, flags, []string{workspaceA, workspaceB})
+	if err != nil {
+		t.Fatalf("resolveWorkspaceConfigs failed: %v", err)
+	}
+
+	a := resolved[workspaceA]
+	if a.goplsPath() != "/opt/gopls" || strings.Join(a.GoplsArgs, " ") != "-remote=auto" {
+		t.Errorf("Unexpected launch config for workspace A: %+v", a)
+	}
+	if a.Env["GOFLAGS"] != "-tags=integration" || a.Env["GOPRIVATE"] != "example.com" {
+		t.Errorf("Expected flags to override config defaults, got %v", a.Env)
+	}
+
+	b := resolved[workspaceB]
+	if strings.Join(b.GoplsArgs, " ") != "-rpc.trace" || b.Env["GOOS"] != "js" || b.Env["GOFLAGS"] != "-tags=integration" {
+		t.Errorf("Expected workspace entry to override defaults, got %+v", b)
+	}
+
+	if b.Settings["staticcheck"] != false || b.Settings["gofumpt"] != true || b.Settings["buildFlags"] == nil {
+		t.Errorf("Expected workspace settings to be merged over defaults, got %v", b.Settings)
+	}
+
+	if a.MaxInFlight != 8 || a.MaxQueued != 0 || b.MaxInFlight != 8 || b.MaxQueued != 4 {
+		t.Errorf("Unexpected request limits: A %d/%d, B %d/%d", a.MaxInFlight, a.MaxQueued, b.MaxInFlight, b.MaxQueued)
+	}
+
+	if a.CacheSize != 0 || b.CacheSize != -1 {
+		t.Errorf("Unexpected cache sizes: A %d, B %d", a.CacheSize, b.CacheSize)
+	}
+
+	if a.MaxMemoryMB != 0 || b.MaxMemoryMB != 2048 {
+		t.Errorf("Unexpected memory ceilings: A %d, B %d", a.MaxMemoryMB, b.MaxMemoryMB)
+	}
+
+	environ := b.environ()
+	if environ[len(environ)-1] != "GOPRIVATE=example.com" {
+		t.Errorf("Expected overrides to be appended after the inherited environment, got %v", environ[len(environ)-4:])
+	}
+
+	// Entries for workspaces that are not served are rejected
+	if _, err := resolveWorkspaceConfigs(config, workspaceConfig{}, []string{workspaceA}); err == nil {
+		t.Error("Expected error for config entry of unknown workspace")
+	}
+}
+
+func TestLoadServerConfigErrors(t *testing.T) {
+	if config, err := loadServerConfig(""); err != nil || config.Workspaces != nil {
+		t.Errorf("Expected empty config without a path, got %+v (%v)", config, err)
+	}
+
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"defaults": {"goplsPth": "typo"}}`), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	if _, err := loadServerConfig(configPath); err == nil {
+		t.Error("Expected error for unknown config field")
+	}
+}
+
+func TestEnvFlag(t *testing.T) {
+	env := envFlag{}
+	if err := env.Set("GOFLAGS=-tags=a,b"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if env["GOFLAGS"] != "-tags=a,b" {
+		t.Errorf("Expected value to keep commas and equals signs, got %q", env["GOFLAGS"])
+	}
+	if err := env.Set("MISSING"); err == nil {
+		t.Error("Expected error for value without '='")
+	}
+}
+
+func TestStartWithConfiguredGoplsPath(t *testing.T) {
+	config := workspaceConfig{GoplsPath: filepath.Join(t.TempDir(), "missing-gopls")}
+	client := newClient(t.TempDir(), config, newTestLogger())
+
+	err := client.start(context.Background())
+	if err == nil {
+		_ = client.stop()
+		t.Fatal("Expected start to fail with a missing gopls binary")
+	}
+	if !strings.Contains(err.Error(), "missing-gopls") {
+		t.Errorf("Expected error to mention the configured binary, got %v", err)
+	}
+}
@@ -1,100 +1,144 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 )
 
-// handlePublishDiagnostics handles publishDiagnostics notifications from gopls.
-func (c *goplsClient) handlePublishDiagnostics(message map[string]any) {
-	params, ok := message["params"].(map[string]any)
-	if !ok {
-		c.logger.Debug("invalid publishDiagnostics params")
-		return
-	}
+const (
+	// diagnosticsMaxWait caps how long getDiagnostics waits for gopls to publish
+	// diagnostics for the current contents of a file.
+	diagnosticsMaxWait = 5 * time.Second
+	// diagnosticsSettleDelay is how long a fresh publication is given to be
+	// followed by another one for the same version, as gopls may publish
+	// type-checking and analysis results separately.
+	diagnosticsSettleDelay = 100 * time.Millisecond
+	// diagnosticsBusyPollInterval is how often work-done progress is re-checked
+	// while gopls is still busy.
+	diagnosticsBusyPollInterval = 50 * time.Millisecond
+)
 
-	uri, ok := params["uri"].(string)
-	if !ok {
-		c.logger.Debug("invalid publishDiagnostics uri")
-		return
+// fileDiagnostics holds the diagnostics gopls last published for a file.
+type fileDiagnostics struct {
+	items    []Diagnostic
+	version  *int
+	received time.Time
+}
+
+// list returns the diagnostics, never nil.
+func (f *fileDiagnostics) list() []Diagnostic {
+	if f.items == nil {
+		return []Diagnostic{}
 	}
+	return f.items
+}
 
-	diagnosticsData, ok := params["diagnostics"].([]any)
-	if !ok {
-		c.logger.Debug("invalid publishDiagnostics diagnostics")
+// diagnosticsWaiter wakes up the callers waiting for new diagnostics of a file.
+type diagnosticsWaiter struct {
+	updated chan struct{}
+	waiters int
+}
+
+// matches reports whether the diagnostics describe the given document version.
+// Publications without a version are only kept while they are current, because
+// they are discarded whenever the document changes.
+func (f *fileDiagnostics) matches(version int) bool {
+	return f.version == nil || *f.version == version
+}
+
+// handlePublishDiagnostics handles publishDiagnostics notifications from gopls
+// and wakes up callers waiting for diagnostics of the file.
+func (c *goplsClient) handlePublishDiagnostics(rawParams json.RawMessage) {
+	var params PublishDiagnosticsParams
+	if err := json.Unmarshal(rawParams, &params); err != nil {
+		c.logger.Debug("invalid publishDiagnostics params", "error", err)
 		return
 	}
 
 	// Convert URI to relative path
-	relativePath := c.uriToRelativePath(uri)
-
-	// Parse diagnostics
-	var diagnostics []Diagnostic
-	for _, diagData := range diagnosticsData {
-		if diagMap, diagOk := diagData.(map[string]any); diagOk {
-			diagnostic := c.parseDiagnostic(diagMap)
-			diagnostics = append(diagnostics, diagnostic)
-		}
-	}
+	relativePath := c.uriToRelativePath(params.URI)
 
-	// Store diagnostics and timestamp
 	c.diagnosticsMux.Lock()
-	c.diagnostics[relativePath] = diagnostics
-	c.diagnosticsTimestamps[relativePath] = time.Now()
+	previous := c.diagnostics[relativePath]
+	c.diagnostics[relativePath] = &fileDiagnostics{
+		items:    params.Diagnostics,
+		version:  params.Version,
+		received: time.Now(),
+	}
+	if waiter, exists := c.diagnosticsUpdated[relativePath]; exists {
+		close(waiter.updated)
+		delete(c.diagnosticsUpdated, relativePath)
+	}
 	c.diagnosticsMux.Unlock()
 
-	c.logger.Debug("stored diagnostics", "relativePath", relativePath, "count", len(diagnostics))
+	c.invalidateCacheOnDiagnostics(previous, params.Diagnostics)
+
+	c.logger.Debug("stored diagnostics", "relativePath", relativePath, "count", len(params.Diagnostics))
 }
 
-// parseDiagnostic parses a diagnostic from a map.
-func (c *goplsClient) parseDiagnostic(diagMap map[string]any) Diagnostic {
-	var diagnostic Diagnostic
+// clearDiagnostics forgets the diagnostics of a file whose contents changed.
+func (c *goplsClient) clearDiagnostics(relativePath string) {
+	c.diagnosticsMux.Lock()
+	delete(c.diagnostics, relativePath)
+	c.diagnosticsMux.Unlock()
+}
 
-	if rangeMap, ok := diagMap["range"].(map[string]any); ok {
-		diagnostic.Range = c.parseRange(rangeMap)
-	}
+// resetDiagnostics forgets all diagnostics and wakes up every waiter.
+func (c *goplsClient) resetDiagnostics() {
+	c.diagnosticsMux.Lock()
+	defer c.diagnosticsMux.Unlock()
 
-	if severity, ok := diagMap["severity"].(float64); ok {
-		diagnostic.Severity = DiagnosticSeverity(int(severity))
+	c.diagnostics = make(map[string]*fileDiagnostics)
+	for _, waiter := range c.diagnosticsUpdated {
+		close(waiter.updated)
 	}
+	c.diagnosticsUpdated = make(map[string]*diagnosticsWaiter)
+}
 
-	if code, ok := diagMap["code"].(string); ok {
-		diagnostic.Code = code
-	}
+// diagnosticsSnapshot returns the stored diagnostics of a file.
+func (c *goplsClient) diagnosticsSnapshot(relativePath string) *fileDiagnostics {
+	c.diagnosticsMux.Lock()
+	defer c.diagnosticsMux.Unlock()
 
-	if source, ok := diagMap["source"].(string); ok {
-		diagnostic.Source = source
-	}
+	return c.diagnostics[relativePath]
+}
 
-	if message, ok := diagMap["message"].(string); ok {
-		diagnostic.Message = message
+// watchDiagnostics returns the stored diagnostics of a file, a channel that is
+// closed when gopls publishes new ones and a function that stops watching,
+// which callers must call once they no longer wait on the channel.
+func (c *goplsClient) watchDiagnostics(relativePath string) (*fileDiagnostics, <-chan struct{}, func()) {
+	c.diagnosticsMux.Lock()
+	defer c.diagnosticsMux.Unlock()
+
+	waiter, exists := c.diagnosticsUpdated[relativePath]
+	if !exists {
+		waiter = &diagnosticsWaiter{updated: make(chan struct{})}
+		c.diagnosticsUpdated[relativePath] = waiter
 	}
+	waiter.waiters++
 
-	//nolint:nestif // Complex JSON parsing requires nested type assertions
-	if relatedInfoList, ok := diagMap["relatedInformation"].([]any); ok {
-		for _, relatedData := range relatedInfoList {
-			if relatedMap, relatedOk := relatedData.(map[string]any); relatedOk {
-				var relatedInfo DiagnosticRelatedInformation
-				if location, locOk := relatedMap["location"].(map[string]any); locOk {
-					relatedInfo.Location = c.parseLocationFromMap(location)
-				}
-				if message, msgOk := relatedMap["message"].(string); msgOk {
-					relatedInfo.Message = message
-				}
-				diagnostic.RelatedInfo = append(diagnostic.RelatedInfo, relatedInfo)
-			}
+	release := func() {
+		c.diagnosticsMux.Lock()
+		defer c.diagnosticsMux.Unlock()
+
+		// A published or reset waiter is already gone
+		waiter.waiters--
+		if waiter.waiters == 0 && c.diagnosticsUpdated[relativePath] == waiter {
+			delete(c.diagnosticsUpdated, relativePath)
 		}
 	}
-
-	return diagnostic
+	return c.diagnostics[relativePath], waiter.updated, release
 }
 
 // getDiagnostics returns diagnostics for a specific file.
-func (c *goplsClient) getDiagnostics(relativePath string) ([]Diagnostic, error) {
+func (c *goplsClient) getDiagnostics(ctx context.Context, relativePath string) ([]Diagnostic, error) {
 	c.logger.Debug("getDiagnostics called", "relativePath", relativePath)
 
-	if !c.isRunning() {
-		return nil, fmt.Errorf("gopls is not running")
+	// Diagnostics published while gopls is still loading packages are incomplete
+	if err := c.waitForInitialLoad(ctx); err != nil {
+		return nil, err
 	}
 
 	// Ensure file is open in gopls to trigger diagnostics
@@ -102,69 +146,78 @@ func (c *goplsClient) getDiagnostics(relativePath string) ([]Diagnostic, error)
 		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 
-	// Wait for diagnostics to stabilize rather than using fixed timeout
-	const stabilityWindow = 200 * time.Millisecond
-	const maxWait = 5 * time.Second
-	const pollInterval = 50 * time.Millisecond
-	const minWaitForNonEmpty = 3 * time.Second // Don't accept empty diagnostics as stable for first 3 seconds
+	if capabilities, _ := c.capabilities(); capabilities.DiagnosticProvider.Enabled {
+		return c.pullDiagnostics(ctx, relativePath)
+	}
+	return c.awaitDiagnostics(ctx, relativePath)
+}
 
-	startTime := time.Now()
-	var lastTimestamp time.Time
-	var diagnostics []Diagnostic
+// pullDiagnostics requests diagnostics with textDocument/diagnostic.
+func (c *goplsClient) pullDiagnostics(ctx context.Context, relativePath string) ([]Diagnostic, error) {
+	params := DocumentDiagnosticParams{
+		TextDocument: TextDocumentIdentifier{URI: c.relativePathToURI(relativePath)},
+	}
 
-	for {
-		// Check if we've exceeded maximum wait time
-		if time.Since(startTime) > maxWait {
-			c.logger.Debug("diagnostics wait timeout reached", "relativePath", relativePath, "duration", time.Since(startTime))
-			break
-		}
+	var report DocumentDiagnosticReport
+	if err := c.call(ctx, "textDocument/diagnostic", params, &report); err != nil {
+		return nil, fmt.Errorf("failed to pull diagnostics: %w", err)
+	}
 
-		// Get current diagnostics and timestamp
-		c.diagnosticsMux.RLock()
-		currentDiagnostics, exists := c.diagnostics[relativePath]
-		currentTimestamp, hasTimestamp := c.diagnosticsTimestamps[relativePath]
-		c.diagnosticsMux.RUnlock()
+	if report.Items == nil {
+		return []Diagnostic{}, nil
+	}
+	return report.Items, nil
+}
 
-		if !exists || !hasTimestamp {
-			// Wait for first diagnostics to arrive
-			time.Sleep(pollInterval)
-			continue
-		}
+// awaitDiagnostics waits until gopls has published diagnostics for the current
+// version of a file and has no work in progress that could still change them.
+// When diagnosticsMaxWait elapses first, the diagnostics of the current version
+// are returned if gopls published any; otherwise the wait fails, as the file
+// must not be reported as clean.
+func (c *goplsClient) awaitDiagnostics(ctx context.Context, relativePath string) ([]Diagnostic, error) {
+	deadline := time.NewTimer(diagnosticsMaxWait)
+	defer deadline.Stop()
 
-		// New timestamp detected, update and continue waiting
-		if currentTimestamp.After(lastTimestamp) {
-			lastTimestamp = currentTimestamp
-			c.logger.Debug("diagnostics updated", "relativePath", relativePath, "count", len(currentDiagnostics))
-			time.Sleep(pollInterval)
-			continue
-		}
+	// Files gopls never publishes diagnostics for must not keep their waiter
+	release := func() {}
+	defer func() { release() }()
 
-		// Check if diagnostics have been stable for the stability window
-		if time.Since(currentTimestamp) < stabilityWindow {
-			time.Sleep(pollInterval)
-			continue
+	startTime := time.Now()
+	for {
+		release()
+		version, _ := c.documentVersion(relativePath)
+		var current *fileDiagnostics
+		var updated <-chan struct{}
+		current, updated, release = c.watchDiagnostics(relativePath)
+
+		var recheck <-chan time.Time
+		if current != nil && current.matches(version) {
+			settle := diagnosticsSettleDelay - time.Since(current.received)
+			switch {
+			case settle > 0:
+				recheck = time.After(settle)
+			case c.workspaceBusy():
+				recheck = time.After(diagnosticsBusyPollInterval)
+			default:
+				c.logger.Debug("diagnostics ready", "relativePath", relativePath,
+					"duration", time.Since(startTime), "count", len(current.items))
+				return current.list(), nil
+			}
 		}
 
-		// Don't accept empty diagnostics as stable within first 3 seconds
-		// This gives gopls time to complete its analysis
-		if len(currentDiagnostics) == 0 && time.Since(startTime) < minWaitForNonEmpty {
-			c.logger.Debug("ignoring empty diagnostics too early", "relativePath", relativePath,
-				"duration", time.Since(startTime), "minWait", minWaitForNonEmpty)
-			time.Sleep(pollInterval)
-			continue
+		select {
+		case <-updated:
+		case <-recheck:
+		case <-deadline.C:
+			if current != nil && current.matches(version) {
+				c.logger.Warn("gopls still busy after waiting for diagnostics", "relativePath", relativePath)
+				return current.list(), nil
+			}
+			c.logger.Warn("timed out waiting for diagnostics", "relativePath", relativePath, "version", version)
+			return nil, fmt.Errorf("timed out after %s waiting for gopls to publish diagnostics for %s",
+				diagnosticsMaxWait, relativePath)
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for diagnostics cancelled: %w", ctx.Err())
 		}
-
-		// Diagnostics are stable and ready
-		diagnostics = currentDiagnostics
-		c.logger.Debug("diagnostics stabilized", "relativePath", relativePath,
-			"duration", time.Since(startTime), "count", len(diagnostics))
-		break
-	}
-
-	// Return the diagnostics we found, or empty slice if none
-	if diagnostics == nil {
-		return []Diagnostic{}, nil
 	}
-
-	return diagnostics, nil
 }
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// newLoadedTestClient returns a client for a workspace containing main.go that
+// behaves as if gopls were running and had finished loading the workspace.
+func newLoadedTestClient(t *testing.T) (*goplsClient, <-chan *incomingMessage) {
+	t.Helper()
+
+	workspacePath := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workspacePath, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	client := newClient(workspacePath, workspaceConfig{}, newTestLogger())
+	client.running = true
+	client.initialized = true
+	client.progressMux.Lock()
+	client.markLoadedLocked()
+	client.progressMux.Unlock()
+
+	return client, captureNotifications(t, client)
+}
+
+// publishDiagnostics delivers a publishDiagnostics notification for main.go.
+func publishDiagnostics(client *goplsClient, version int, diagnostics string) {
+	client.handleLSPMessage(&incomingMessage{
+		JSONRPC: jsonrpcVersion,
+		Method:  "textDocument/publishDiagnostics",
+		Params: json.RawMessage(`{"uri":"` + client.relativePathToURI("main.go") + `","version":` +
+			strconv.Itoa(version) + `,"diagnostics":` + diagnostics + `}`),
+	})
+}
+
+func TestDiagnosticsForCleanFile(t *testing.T) {
+	client, messages := newLoadedTestClient(t)
+
+	type result struct {
+		diagnostics []Diagnostic
+		err         error
+	}
+	resultCh := make(chan result, 1)
+	go func() {
+		diagnostics, err := client.getDiagnostics(context.Background(), "main.go")
+		resultCh <- result{diagnostics, err}
+	}()
+
+	expectNotification(t, messages, "textDocument/didOpen")
+	start := time.Now()
+	publishDiagnostics(client, 1, `[]`)
+
+	got := <-resultCh
+	if got.err != nil {
+		t.Fatalf("getDiagnostics failed: %v", got.err)
+	}
+	if got.diagnostics == nil || len(got.diagnostics) != 0 {
+		t.Errorf("Expected empty diagnostics, got %v", got.diagnostics)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected clean file to return quickly, took %s", elapsed)
+	}
+}
+
+func TestAwaitDiagnosticsForgetsWaiters(t *testing.T) {
+	client, _ := newLoadedTestClient(t)
+	waiters := func() int {
+		client.diagnosticsMux.Lock()
+		defer client.diagnosticsMux.Unlock()
+		if waiter, exists := client.diagnosticsUpdated["main.go"]; exists {
+			return waiter.waiters
+		}
+		return 0
+	}
+
+	// gopls never publishes diagnostics for main.go
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 2)
+	for range 2 {
+		go func() {
+			_, err := client.awaitDiagnostics(ctx, "main.go")
+			errCh <- err
+		}()
+	}
+	deadline := time.Now().Add(time.Second)
+	for waiters() != 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := waiters(); n != 2 {
+		t.Fatalf("Expected 2 waiters, got %d", n)
+	}
+
+	cancel()
+	for range 2 {
+		if err := <-errCh; err == nil {
+			t.Error("Expected a cancelled wait to fail")
+		}
+	}
+	client.diagnosticsMux.Lock()
+	remaining := len(client.diagnosticsUpdated)
+	client.diagnosticsMux.Unlock()
+	if remaining != 0 {
+		t.Errorf("Expected cancelled waits to forget their waiter, got %d", remaining)
+	}
+}
+
+func TestDiagnosticsTimeoutIsNotReportedClean(t *testing.T) {
+	// A gopls that loads the workspace but never publishes diagnostics
+	script := fakeGoplsScript()
+	script.Messages = script.Messages[:2]
+
+	workspacePath := newFakeGoplsWorkspace(t)
+	client := startFakeGoplsClient(t, workspacePath, fakeGoplsConfig(t, writeFakeGoplsScript(t, script)))
+	session := connectMCP(t, map[string]*goplsClient{workspacePath: client})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*diagnosticsMaxWait)
+	defer cancel()
+	result, err := session.CallTool(ctx, &mcp.CallToolParams{
+		Name:      toolGetDiagnostics,
+		Arguments: map[string]any{"workspace": workspacePath, "path": "main.go"},
+	})
+	if err != nil {
+		t.Fatalf("CallTool failed: %v", err)
+	}
+	if !result.IsError {
+		t.Fatalf("Expected unpublished diagnostics to fail rather than report a clean file, got %+v", result.Content)
+	}
+	if text := result.Content[0].(*mcp.TextContent).Text; !strings.Contains(text, "timed out") {
+		t.Errorf("Expected a timeout error, got %q", text)
+	}
+}
+
+func TestDiagnosticsIgnoreStaleVersions(t *testing.T) {
+	client, messages := newLoadedTestClient(t)
+	if err := client.ensureFileOpen("main.go"); err != nil {
+		t.Fatalf("ensureFileOpen failed: %v", err)
+	}
+	expectNotification(t, messages, "textDocument/didOpen")
+	client.openFiles["main.go"].version = 2
+
+	resultCh := make(chan []Diagnostic, 1)
+	go func() {
+		diagnostics, _ := client.getDiagnostics(context.Background(), "main.go")
+		resultCh <- diagnostics
+	}()
+
+	// Diagnostics for the previous version must not be returned
+	publishDiagnostics(client, 1, `[{"range":{},"severity":1,"message":"stale"}]`)
+	select {
+	case diagnostics := <-resultCh:
+		t.Fatalf("Expected stale diagnostics to be ignored, got %v", diagnostics)
+	case <-time.After(2 * diagnosticsSettleDelay):
+	}
+
+	publishDiagnostics(client, 2, `[{"range":{},"severity":1,"message":"current"}]`)
+	diagnostics := <-resultCh
+	if len(diagnostics) != 1 || diagnostics[0].Message != "current" {
+		t.Errorf("Expected current diagnostics, got %v", diagnostics)
+	}
+}
+
+func TestPullDiagnostics(t *testing.T) {
+	client, messages := newLoadedTestClient(t)
+	client.serverCapabilities.DiagnosticProvider = ProviderCapability{Enabled: true}
+
+	resultCh := make(chan []Diagnostic, 1)
+	go func() {
+		diagnostics, _ := client.getDiagnostics(context.Background(), "main.go")
+		resultCh <- diagnostics
+	}()
+
+	expectNotification(t, messages, "textDocument/didOpen")
+	request := expectNotification(t, messages, "textDocument/diagnostic")
+	client.handleLSPMessage(&incomingMessage{
+		JSONRPC: jsonrpcVersion,
+		ID:      request.ID,
+		Result:  json.RawMessage(`{"kind":"full","items":[{"range":{},"severity":2,"message":"pulled"}]}`),
+	})
+
+	diagnostics := <-resultCh
+	if len(diagnostics) != 1 || diagnostics[0].Message != "pulled" {
+		t.Errorf("Expected pulled diagnostics, got %v", diagnostics)
+	}
+}
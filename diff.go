@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContextLines is how many unchanged lines surround each change in a unified diff.
+const diffContextLines = 3
+
+// Kinds of lines in a line-based diff.
+const (
+	diffEqual  = ' '
+	diffDelete = '-'
+	diffInsert = '+'
+)
+
+// diffLine is a line of a line-based diff together with its 0-based position
+// in the old and the new text.
+type diffLine struct {
+	kind     byte
+	text     string
+	oldIndex int
+	newIndex int
+}
+
+// splitLines splits text into lines that keep their line terminator.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines computes a shortest line-based edit script from a to b using
+// Myers' algorithm.
+func diffLines(a, b []string) []diffLine {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+
+search:
+	for d := 0; d <= n+m; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// Walk the trace backwards to recover the edit script
+	var reversed []diffLine
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		previousK := k - 1
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			previousK = k + 1
+		}
+		previousX := v[offset+previousK]
+		previousY := previousX - previousK
+
+		for x > previousX && y > previousY {
+			x--
+			y--
+			reversed = append(reversed, diffLine{kind: diffEqual, text: a[x], oldIndex: x, newIndex: y})
+		}
+		if d == 0 {
+			break
+		}
+		if x == previousX {
+			y--
+			reversed = append(reversed, diffLine{kind: diffInsert, text: b[y], oldIndex: x, newIndex: y})
+		} else {
+			x--
+			reversed = append(reversed, diffLine{kind: diffDelete, text: a[x], oldIndex: x, newIndex: y})
+		}
+	}
+
+	script := make([]diffLine, len(reversed))
+	for i, line := range reversed {
+		script[len(reversed)-1-i] = line
+	}
+	return script
+}
+
+// unifiedDiff renders the change from original to updated as a unified diff
+// with the given file names. It returns an empty string when nothing changed.
+func unifiedDiff(oldName, newName, original, updated string) string {
+	if original == updated {
+		return ""
+	}
+	script := diffLines(splitLines(original), splitLines(updated))
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "--- %s\n+++ %s\n", oldName, newName)
+
+	for start := 0; start < len(script); {
+		// Find the next change and extend the hunk while changes are close together
+		first := start
+		for first < len(script) && script[first].kind == diffEqual {
+			first++
+		}
+		if first == len(script) {
+			break
+		}
+		last := first
+		for next := first + 1; next < len(script); next++ {
+			if script[next].kind == diffEqual {
+				continue
+			}
+			if next-last-1 > 2*diffContextLines {
+				break
+			}
+			last = next
+		}
+
+		hunkStart := max(first-diffContextLines, start)
+		hunkEnd := min(last+1+diffContextLines, len(script))
+		writeHunk(&builder, script[hunkStart:hunkEnd])
+		start = hunkEnd
+	}
+	return builder.String()
+}
+
+// writeHunk writes a hunk header followed by its lines.
+func writeHunk(builder *strings.Builder, lines []diffLine) {
+	oldCount, newCount := 0, 0
+	for _, line := range lines {
+		if line.kind != diffInsert {
+			oldCount++
+		}
+		if line.kind != diffDelete {
+			newCount++
+		}
+	}
+
+	// Empty ranges are numbered after the line they follow
+	oldStart, newStart := lines[0].oldIndex, lines[0].newIndex
+	if oldCount > 0 {
+		oldStart++
+	}
+	if newCount > 0 {
+		newStart++
+	}
+	fmt.Fprintf(builder, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+
+	for _, line := range lines {
+		builder.WriteByte(line.kind)
+		builder.WriteString(line.text)
+		if !strings.HasSuffix(line.text, "\n") {
+			builder.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	original := splitLines("a\nb\nc\nd\n")
+	updated := splitLines("a\nc\nx\nd\n")
+
+	var kinds strings.Builder
+	for _, line := range diffLines(original, updated) {
+		kinds.WriteByte(line.kind)
+	}
+	if kinds.String() != " - + " {
+		t.Errorf("Expected edit script ' - + ', got %q", kinds.String())
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		updated  string
+		expected string
+	}{
+		{
+			name:     "unchanged",
+			original: "a\n",
+			updated:  "a\n",
+			expected: "",
+		},
+		{
+			name:     "single change with context",
+			original: "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			updated:  "1\n2\n3\n4\nfive\n6\n7\n8\n9\n",
+			expected: "--- a/f.go\n+++ b/f.go\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			name:     "distant changes make separate hunks",
+			original: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			updated:  "one\n2\n3\n4\n5\n6\n7\n8\n9\nten\n",
+			expected: "--- a/f.go\n+++ b/f.go\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n" +
+				"@@ -7,4 +7,4 @@\n 7\n 8\n 9\n-10\n+ten\n",
+		},
+		{
+			name:     "insertion into empty file",
+			original: "",
+			updated:  "package main\n",
+			expected: "--- a/f.go\n+++ b/f.go\n@@ -0,0 +1,1 @@\n+package main\n",
+		},
+		{
+			name:     "missing final newline",
+			original: "a\nb",
+			updated:  "a\nc",
+			expected: "--- a/f.go\n+++ b/f.go\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n" +
+				"+c\n\\ No newline at end of file\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := unifiedDiff("a/f.go", "b/f.go", tt.original, tt.updated)
+			if diff != tt.expected {
+				t.Errorf("Unexpected diff:\n%s\nexpected:\n%s", diff, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+)
+
+// nullResult is the JSON null result for requests that have nothing to return.
+var nullResult = json.RawMessage("null")
+
+// serverRequestHandler answers a request sent from gopls to the client.
+// Handlers run on the message reader goroutine, so they must never wait for
+// a gopls response themselves.
+type serverRequestHandler func(params json.RawMessage) (any, error)
+
+// serverRequestHandlers returns the handlers for every server-to-client request
+// the client supports.
+func (c *goplsClient) serverRequestHandlers() map[string]serverRequestHandler {
+	return map[string]serverRequestHandler{
+		"workspace/configuration":        c.handleWorkspaceConfiguration,
+		"workspace/workspaceFolders":     c.handleWorkspaceFolders,
+		"workspace/applyEdit":            c.handleApplyEdit,
+		"window/workDoneProgress/create": c.handleWorkDoneProgressCreate,
+		"window/showMessageRequest":      c.handleShowMessageRequest,
+		"window/showDocument":            c.handleShowDocument,
+		"client/registerCapability":      c.handleRegisterCapability,
+		"client/unregisterCapability":    c.handleUnregisterCapability,
+	}
+}
+
+// handleServerRequest dispatches a request from gopls and sends the response.
+func (c *goplsClient) handleServerRequest(message *incomingMessage) {
+	c.logger.Debug("received request from gopls", "method", message.Method, "id", string(message.ID))
+
+	handler, ok := c.requestHandlers[message.Method]
+	if !ok {
+		c.logger.Debug("unsupported request from gopls", "method", message.Method)
+		c.reply(message.ID, nil, &ResponseError{
+			Code:    jsonrpcMethodNotFound,
+			Message: fmt.Sprintf("method not supported: %s", message.Method),
+		})
+		return
+	}
+
+	result, err := handler(message.Params)
+	if err != nil {
+		c.logger.Warn("failed to handle request from gopls", "method", message.Method, "error", err)
+		var respErr *ResponseError
+		if !errors.As(err, &respErr) {
+			respErr = &ResponseError{Code: jsonrpcInternalError, Message: err.Error()}
+		}
+		c.reply(message.ID, nil, respErr)
+		return
+	}
+
+	c.reply(message.ID, result, nil)
+}
+
+// reply sends a response to a request received from gopls.
+func (c *goplsClient) reply(id json.RawMessage, result any, respErr *ResponseError) {
+	response := ResponseMessage{
+		JSONRPC: jsonrpcVersion,
+		ID:      id,
+		Error:   respErr,
+	}
+
+	if respErr == nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			c.logger.Error("failed to marshal response", "id", string(id), "error", err)
+			response.Error = &ResponseError{Code: jsonrpcInternalError, Message: err.Error()}
+		} else {
+			response.Result = data
+		}
+	}
+
+	if err := c.writeMessage(response); err != nil {
+		c.logger.Warn("failed to send response to gopls", "id", string(id), "error", err)
+	}
+}
+
+// decodeParams decodes request parameters, reporting failures as invalid params errors.
+func decodeParams(params json.RawMessage, target any) error {
+	if err := json.Unmarshal(params, target); err != nil {
+		return &ResponseError{Code: jsonrpcInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// handleWorkspaceConfiguration answers workspace/configuration with one entry per requested item.
+func (c *goplsClient) handleWorkspaceConfiguration(rawParams json.RawMessage) (any, error) {
+	var params ConfigurationParams
+	if err := decodeParams(rawParams, &params); err != nil {
+		return nil, err
+	}
+
+	// Only the gopls section is configured; other sections fall back to their defaults
+	result := make([]any, len(params.Items))
+	for i, item := range params.Items {
+		if item.Section == goplsSettingsSection {
+			result[i] = c.currentSettings()
+		}
+	}
+	return result, nil
+}
+
+// handleWorkspaceFolders answers workspace/workspaceFolders with the client's workspace.
+func (c *goplsClient) handleWorkspaceFolders(_ json.RawMessage) (any, error) {
+	return []WorkspaceFolder{
+		{
+			URI:  fmt.Sprintf("file://%s", c.workspacePath),
+			Name: filepath.Base(c.workspacePath),
+		},
+	}, nil
+}
+
+// handleApplyEdit answers workspace/applyEdit. Only edits requested while a
+// command run by gopls-mcp executes are collected; other edits are declined.
+func (c *goplsClient) handleApplyEdit(rawParams json.RawMessage) (any, error) {
+	var params ApplyWorkspaceEditParams
+	if err := decodeParams(rawParams, &params); err != nil {
+		return nil, err
+	}
+
+	if result, collected := c.collectCommandEdit(&params.Edit); collected {
+		c.logger.Debug("collected workspace edit from gopls", "label", params.Label, "applied", result.Applied)
+		return result, nil
+	}
+
+	c.logger.Info("declined workspace edit from gopls", "label", params.Label, "files", len(params.Edit.Changes))
+	return ApplyWorkspaceEditResult{
+		Applied:       false,
+		FailureReason: "gopls-mcp does not apply server-initiated edits",
+	}, nil
+}
+
+// handleShowMessageRequest answers window/showMessageRequest without selecting an action.
+func (c *goplsClient) handleShowMessageRequest(rawParams json.RawMessage) (any, error) {
+	var params ShowMessageRequestParams
+	if err := decodeParams(rawParams, &params); err != nil {
+		return nil, err
+	}
+
+	c.recordMessage(params.Type, messageKindShowRequest, params.Message)
+	return nullResult, nil
+}
+
+// handleShowDocument answers window/showDocument; there is no editor to show documents in.
+func (c *goplsClient) handleShowDocument(_ json.RawMessage) (any, error) {
+	return ShowDocumentResult{Success: false}, nil
+}
+
+// handleRegisterCapability records dynamic capability registrations from gopls.
+func (c *goplsClient) handleRegisterCapability(rawParams json.RawMessage) (any, error) {
+	var params RegistrationParams
+	if err := decodeParams(rawParams, &params); err != nil {
+		return nil, err
+	}
+
+	c.registrationsMux.Lock()
+	for _, registration := range params.Registrations {
+		c.registrations[registration.ID] = registration
+		c.logger.Debug("registered capability", "id", registration.ID, "method", registration.Method)
+	}
+	c.updateFileWatchersLocked()
+	c.registrationsMux.Unlock()
+
+	return nullResult, nil
+}
+
+// handleUnregisterCapability removes dynamic capability registrations.
+func (c *goplsClient) handleUnregisterCapability(rawParams json.RawMessage) (any, error) {
+	var params UnregistrationParams
+	if err := decodeParams(rawParams, &params); err != nil {
+		return nil, err
+	}
+
+	c.registrationsMux.Lock()
+	for _, unregistration := range params.Unregistrations {
+		delete(c.registrations, unregistration.ID)
+		c.logger.Debug("unregistered capability", "id", unregistration.ID, "method", unregistration.Method)
+	}
+	c.updateFileWatchersLocked()
+	c.registrationsMux.Unlock()
+
+	return nullResult, nil
+}
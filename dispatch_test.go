@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"testing"
+)
+
+// serveOneRequest dispatches a raw server request and returns the client's response.
+func serveOneRequest(t *testing.T, client *goplsClient, raw string) *incomingMessage {
+	t.Helper()
+
+	reader, writer := io.Pipe()
+	client.stdin = writer
+	defer func() { client.stdin = nil }()
+
+	var message incomingMessage
+	if err := json.Unmarshal([]byte(raw), &message); err != nil {
+		t.Fatalf("failed to unmarshal request: %v", err)
+	}
+
+	go client.handleLSPMessage(&message)
+
+	response, err := client.readLSPMessage(bufio.NewReader(reader))
+	if err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+	return response
+}
+
+func TestHandleServerRequests(t *testing.T) {
+	client := newClient("/test/workspace", workspaceConfig{}, newTestLogger())
+
+	// workspace/configuration returns one entry per item
+	response := serveOneRequest(t, client,
+		`{"jsonrpc":"2.0","id":1,"method":"workspace/configuration","params":{"items":[{"section":"gopls"},{}]}}`)
+	if response.Error != nil {
+		t.Fatalf("Unexpected error: %v", response.Error)
+	}
+	var items []any
+	if err := json.Unmarshal(response.Result, &items); err != nil || len(items) != 2 {
+		t.Errorf("Expected 2 configuration entries, got %s", response.Result)
+	}
+
+	// Progress token creation is acknowledged with a null result
+	response = serveOneRequest(t, client,
+		`{"jsonrpc":"2.0","id":"tok","method":"window/workDoneProgress/create","params":{"token":"abc"}}`)
+	if string(response.ID) != `"tok"` {
+		t.Errorf("Expected string ID to be echoed, got %s", response.ID)
+	}
+	if response.Error != nil || string(response.Result) != "null" {
+		t.Errorf("Expected null result, got %s (error %v)", response.Result, response.Error)
+	}
+
+	// Capability registrations are recorded
+	response = serveOneRequest(t, client,
+		`{"jsonrpc":"2.0","id":2,"method":"client/registerCapability",`+
+			`"params":{"registrations":[{"id":"w1","method":"workspace/didChangeWatchedFiles"}]}}`)
+	if response.Error != nil {
+		t.Fatalf("Unexpected error: %v", response.Error)
+	}
+	if _, ok := client.registrations["w1"]; !ok {
+		t.Error("Expected registration w1 to be recorded")
+	}
+
+	// Unknown methods are rejected with MethodNotFound
+	response = serveOneRequest(t, client, `{"jsonrpc":"2.0","id":3,"method":"custom/unknown","params":{}}`)
+	if response.Error == nil || response.Error.Code != jsonrpcMethodNotFound {
+		t.Errorf("Expected MethodNotFound error, got %+v", response.Error)
+	}
+}
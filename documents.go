@@ -0,0 +1,201 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	// maxOpenDocuments caps how many documents are kept open in gopls at once.
+	maxOpenDocuments = 50
+	// documentIdleTimeout is how long an unused document stays open in gopls.
+	documentIdleTimeout = 10 * time.Minute
+)
+
+// openDocument tracks the state of a document that is open in gopls.
+type openDocument struct {
+	version  int
+	hash     [sha256.Size]byte
+	lastUsed time.Time
+}
+
+// languageIDForPath returns the LSP language identifier for a file.
+func languageIDForPath(relativePath string) string {
+	switch filepath.Ext(relativePath) {
+	case ".mod":
+		return "go.mod"
+	case ".sum":
+		return "go.sum"
+	default:
+		return "go"
+	}
+}
+
+// ensureFileOpen makes sure gopls sees the current disk contents of a file before
+// requests are made about it. Files are opened on first use and re-synchronized
+// with didChange and didSave whenever their contents change on disk.
+func (c *goplsClient) ensureFileOpen(relativePath string) error {
+	c.openFilesMux.Lock()
+	defer c.openFilesMux.Unlock()
+
+	absolutePath := filepath.Join(c.workspacePath, relativePath)
+	content, err := os.ReadFile(absolutePath)
+	if err != nil {
+		if _, isOpen := c.openFiles[relativePath]; isOpen {
+			c.closeDocumentLocked(relativePath)
+		}
+		return fmt.Errorf("failed to read file %s: %w", absolutePath, err)
+	}
+
+	hash := sha256.Sum256(content)
+	document, isOpen := c.openFiles[relativePath]
+	switch {
+	case !isOpen:
+		if err := c.openDocumentLocked(relativePath, content, hash); err != nil {
+			return err
+		}
+		document = c.openFiles[relativePath]
+	case document.hash != hash:
+		if err := c.changeDocumentLocked(relativePath, document, content, hash); err != nil {
+			return err
+		}
+	}
+
+	document.lastUsed = time.Now()
+	c.evictDocumentsLocked(relativePath)
+	return nil
+}
+
+// documentVersion returns the version of an open document.
+func (c *goplsClient) documentVersion(relativePath string) (int, bool) {
+	c.openFilesMux.Lock()
+	defer c.openFilesMux.Unlock()
+
+	document, isOpen := c.openFiles[relativePath]
+	if !isOpen {
+		return 0, false
+	}
+	return document.version, true
+}
+
+// documentHash returns the hash of the contents last sent to gopls for an open document.
+func (c *goplsClient) documentHash(relativePath string) ([sha256.Size]byte, bool) {
+	c.openFilesMux.Lock()
+	defer c.openFilesMux.Unlock()
+
+	document, isOpen := c.openFiles[relativePath]
+	if !isOpen {
+		return [sha256.Size]byte{}, false
+	}
+	return document.hash, true
+}
+
+// forgetDocuments drops the open documents of a stopped gopls session without
+// notifying gopls, so they are opened again once gopls is started.
+func (c *goplsClient) forgetDocuments() {
+	c.openFilesMux.Lock()
+	defer c.openFilesMux.Unlock()
+
+	c.openFiles = make(map[string]*openDocument)
+}
+
+// openDocumentLocked sends textDocument/didOpen for a file. Callers must hold c.openFilesMux.
+func (c *goplsClient) openDocumentLocked(relativePath string, content []byte, hash [sha256.Size]byte) error {
+	fileURI := c.relativePathToURI(relativePath)
+	params := DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{
+			URI:        fileURI,
+			LanguageID: languageIDForPath(relativePath),
+			Version:    1,
+			Text:       string(content),
+		},
+	}
+	if err := c.notify("textDocument/didOpen", params); err != nil {
+		return fmt.Errorf("failed to send didOpen notification: %w", err)
+	}
+
+	c.openFiles[relativePath] = &openDocument{version: 1, hash: hash}
+	c.logger.Debug("opened file in gopls", "relativePath", relativePath, "uri", fileURI)
+	return nil
+}
+
+// changeDocumentLocked sends the new contents of a changed file to gopls with
+// textDocument/didChange followed by textDocument/didSave. Callers must hold c.openFilesMux.
+func (c *goplsClient) changeDocumentLocked(
+	relativePath string, document *openDocument, content []byte, hash [sha256.Size]byte,
+) error {
+	fileURI := c.relativePathToURI(relativePath)
+	version := document.version + 1
+	params := DidChangeTextDocumentParams{
+		TextDocument:   VersionedTextDocumentIdentifier{URI: fileURI, Version: version},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: string(content)}},
+	}
+	if err := c.notify("textDocument/didChange", params); err != nil {
+		return fmt.Errorf("failed to send didChange notification: %w", err)
+	}
+
+	document.version = version
+	document.hash = hash
+
+	// Any cached result may depend on the previous contents
+	c.cache.invalidate()
+
+	// Diagnostics for the previous contents are stale until gopls republishes them
+	c.clearDiagnostics(relativePath)
+
+	saveParams := DidSaveTextDocumentParams{TextDocument: TextDocumentIdentifier{URI: fileURI}}
+	if err := c.notify("textDocument/didSave", saveParams); err != nil {
+		return fmt.Errorf("failed to send didSave notification: %w", err)
+	}
+
+	c.logger.Debug("synchronized changed file with gopls", "relativePath", relativePath, "version", version)
+	return nil
+}
+
+// closeDocumentLocked sends textDocument/didClose and forgets the document.
+// Callers must hold c.openFilesMux.
+func (c *goplsClient) closeDocumentLocked(relativePath string) {
+	delete(c.openFiles, relativePath)
+
+	params := DidCloseTextDocumentParams{
+		TextDocument: TextDocumentIdentifier{URI: c.relativePathToURI(relativePath)},
+	}
+	if err := c.notify("textDocument/didClose", params); err != nil {
+		c.logger.Debug("failed to send didClose notification", "relativePath", relativePath, "error", err)
+		return
+	}
+	c.logger.Debug("closed file in gopls", "relativePath", relativePath)
+}
+
+// evictDocumentsLocked closes documents that have been idle too long and, when more
+// than maxOpenDocuments are open, the least recently used ones. The document named
+// by keep is never closed. Callers must hold c.openFilesMux.
+func (c *goplsClient) evictDocumentsLocked(keep string) {
+	paths := make([]string, 0, len(c.openFiles))
+	for path, document := range c.openFiles {
+		if path == keep {
+			continue
+		}
+		if time.Since(document.lastUsed) > documentIdleTimeout {
+			c.closeDocumentLocked(path)
+			continue
+		}
+		paths = append(paths, path)
+	}
+
+	excess := len(c.openFiles) - maxOpenDocuments
+	if excess <= 0 {
+		return
+	}
+
+	sort.Slice(paths, func(i, j int) bool {
+		return c.openFiles[paths[i]].lastUsed.Before(c.openFiles[paths[j]].lastUsed)
+	})
+	for _, path := range paths[:excess] {
+		c.closeDocumentLocked(path)
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// captureNotifications connects the client's stdin to a pipe and returns a
+// channel receiving every message the client sends.
+func captureNotifications(t *testing.T, client *goplsClient) <-chan *incomingMessage {
+	t.Helper()
+
+	reader, writer := io.Pipe()
+	client.stdin = writer
+	t.Cleanup(func() { _ = writer.Close() })
+
+	messages := make(chan *incomingMessage, 100)
+	go func() {
+		defer close(messages)
+		bufReader := bufio.NewReader(reader)
+		for {
+			message, err := client.readLSPMessage(bufReader)
+			if err != nil {
+				return
+			}
+			messages <- message
+		}
+	}()
+	return messages
+}
+
+// expectNotification waits for the next message and checks its method.
+func expectNotification(t *testing.T, messages <-chan *incomingMessage, method string) *incomingMessage {
+	t.Helper()
+
+	select {
+	case message := <-messages:
+		if message.Method != method {
+			t.Fatalf("Expected %s, got %s", method, message.Method)
+		}
+		return message
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for %s", method)
+		return nil
+	}
+}
+
+func TestDocumentSynchronization(t *testing.T) {
+	workspacePath := t.TempDir()
+	filePath := filepath.Join(workspacePath, "main.go")
+	if err := os.WriteFile(filePath, []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	client := newClient(workspacePath, workspaceConfig{}, newTestLogger())
+	messages := captureNotifications(t, client)
+
+	// First use opens the document
+	if err := client.ensureFileOpen("main.go"); err != nil {
+		t.Fatalf("ensureFileOpen failed: %v", err)
+	}
+	expectNotification(t, messages, "textDocument/didOpen")
+
+	// Unchanged contents send nothing
+	if err := client.ensureFileOpen("main.go"); err != nil {
+		t.Fatalf("ensureFileOpen failed: %v", err)
+	}
+	select {
+	case message := <-messages:
+		t.Fatalf("Expected no notification for unchanged file, got %s", message.Method)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Changed contents are sent with a new version
+	if err := os.WriteFile(filePath, []byte("package main\n\nfunc f() {}\n"), 0644); err != nil {
+		t.Fatalf("failed to update file: %v", err)
+	}
+	if err := client.ensureFileOpen("main.go"); err != nil {
+		t.Fatalf("ensureFileOpen failed: %v", err)
+	}
+	change := expectNotification(t, messages, "textDocument/didChange")
+	var params DidChangeTextDocumentParams
+	if err := json.Unmarshal(change.Params, &params); err != nil {
+		t.Fatalf("failed to decode didChange params: %v", err)
+	}
+	if params.TextDocument.Version != 2 || len(params.ContentChanges) != 1 {
+		t.Errorf("Unexpected didChange params: %+v", params)
+	}
+	expectNotification(t, messages, "textDocument/didSave")
+
+	// Deleted files are closed
+	if err := os.Remove(filePath); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+	if err := client.ensureFileOpen("main.go"); err == nil {
+		t.Error("Expected error for deleted file")
+	}
+	expectNotification(t, messages, "textDocument/didClose")
+	if len(client.openFiles) != 0 {
+		t.Errorf("Expected no open documents, got %d", len(client.openFiles))
+	}
+}
+
+func TestDocumentEviction(t *testing.T) {
+	workspacePath := t.TempDir()
+	for i := range maxOpenDocuments + 1 {
+		name := filepath.Join(workspacePath, fmt.Sprintf("file%d.go", i))
+		if err := os.WriteFile(name, []byte("package main\n"), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	client := newClient(workspacePath, workspaceConfig{}, newTestLogger())
+	messages := captureNotifications(t, client)
+
+	for i := range maxOpenDocuments {
+		if err := client.ensureFileOpen(fmt.Sprintf("file%d.go", i)); err != nil {
+			t.Fatalf("ensureFileOpen failed: %v", err)
+		}
+		expectNotification(t, messages, "textDocument/didOpen")
+	}
+
+	// Opening one more document closes the least recently used one
+	client.openFiles["file0.go"].lastUsed = time.Now().Add(-time.Minute)
+	if err := client.ensureFileOpen(fmt.Sprintf("file%d.go", maxOpenDocuments)); err != nil {
+		t.Fatalf("ensureFileOpen failed: %v", err)
+	}
+	expectNotification(t, messages, "textDocument/didOpen")
+	closed := expectNotification(t, messages, "textDocument/didClose")
+	if len(client.openFiles) != maxOpenDocuments {
+		t.Errorf("Expected %d open documents, got %d", maxOpenDocuments, len(client.openFiles))
+	}
+	if _, isOpen := client.openFiles["file0.go"]; isOpen {
+		t.Errorf("Expected file0.go to be evicted, closed %s", closed.Params)
+	}
+
+	// Idle documents are closed on the next sync
+	client.openFiles["file1.go"].lastUsed = time.Now().Add(-2 * documentIdleTimeout)
+	if err := client.ensureFileOpen("file2.go"); err != nil {
+		t.Fatalf("ensureFileOpen failed: %v", err)
+	}
+	expectNotification(t, messages, "textDocument/didClose")
+	if _, isOpen := client.openFiles["file1.go"]; isOpen {
+		t.Error("Expected idle file1.go to be closed")
+	}
+}
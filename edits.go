@@ -0,0 +1,549 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// defaultFileMode is the permission of files created by workspace edits.
+const defaultFileMode = 0644
+
+// fileEdit is the planned change of a single workspace file: its contents
+// before and after a workspace edit, and whether it exists before and after.
+type fileEdit struct {
+	relativePath string
+	absolutePath string
+	original     string
+	updated      string
+	existed      bool
+	exists       bool
+	mode         os.FileMode
+	edits        []TextEdit
+}
+
+// change describes what happens to the file: created, modified or deleted.
+func (f fileEdit) change() string {
+	switch {
+	case !f.existed:
+		return "created"
+	case !f.exists:
+		return "deleted"
+	default:
+		return "modified"
+	}
+}
+
+// hash returns the SHA-256 of the new contents in hex, or an empty string for a deleted file.
+func (f fileEdit) hash() string {
+	if !f.exists {
+		return ""
+	}
+	return contentHash(f.updated)
+}
+
+// diff renders the change to the file as a unified diff.
+func (f fileEdit) diff() string {
+	name := filepath.ToSlash(f.relativePath)
+	oldName, newName := "a/"+name, "b/"+name
+	if !f.existed {
+		oldName = "/dev/null"
+	}
+	if !f.exists {
+		newName = "/dev/null"
+	}
+	return unifiedDiff(oldName, newName, f.original, f.updated)
+}
+
+// contentHash returns the SHA-256 of file contents in hex.
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// textEdits returns the text edits a workspace edit makes to the document at uri.
+func (e *WorkspaceEdit) textEdits(uri string) []TextEdit {
+	edits := append([]TextEdit(nil), e.Changes[uri]...)
+	for _, change := range e.DocumentChanges {
+		if change.TextDocumentEdit != nil && change.TextDocumentEdit.TextDocument.URI == uri {
+			edits = append(edits, change.TextDocumentEdit.Edits...)
+		}
+	}
+	return edits
+}
+
+// applyTextEdits applies LSP text edits to content. Characters are counted in
+// unit, the column unit of the negotiated position encoding. Edits starting at
+// the same position are applied in the order given; overlapping edits are rejected.
+func applyTextEdits(content string, edits []TextEdit, unit string) (string, error) {
+	type span struct {
+		start, end int
+		text       string
+	}
+
+	lineStarts := []int{0}
+	for i := range len(content) {
+		if content[i] == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+
+	spans := make([]span, len(edits))
+	for i, edit := range edits {
+		start, err := positionOffset(content, lineStarts, edit.Range.Start, unit)
+		if err != nil {
+			return "", err
+		}
+		end, err := positionOffset(content, lineStarts, edit.Range.End, unit)
+		if err != nil {
+			return "", err
+		}
+		if end < start {
+			return "", fmt.Errorf("invalid edit range %d:%d-%d:%d", edit.Range.Start.Line,
+				edit.Range.Start.Character, edit.Range.End.Line, edit.Range.End.Character)
+		}
+		spans[i] = span{start: start, end: end, text: edit.NewText}
+	}
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	var builder strings.Builder
+	position := 0
+	for _, span := range spans {
+		if span.start < position {
+			return "", fmt.Errorf("overlapping edits at offset %d", span.start)
+		}
+		builder.WriteString(content[position:span.start])
+		builder.WriteString(span.text)
+		position = span.end
+	}
+	builder.WriteString(content[position:])
+	return builder.String(), nil
+}
+
+// positionOffset converts an LSP position into a byte offset in content.
+// Characters past the end of a line refer to the end of the line.
+func positionOffset(content string, lineStarts []int, position Position, unit string) (int, error) {
+	if position.Line < 0 || position.Character < 0 {
+		return 0, fmt.Errorf("invalid position %d:%d", position.Line, position.Character)
+	}
+	if position.Line >= len(lineStarts) {
+		// The position just past the last line ends the file
+		if position.Line == len(lineStarts) && position.Character == 0 {
+			return len(content), nil
+		}
+		return 0, fmt.Errorf("position %d:%d is past the end of the file", position.Line, position.Character)
+	}
+
+	lineStart := lineStarts[position.Line]
+	lineEnd := len(content)
+	if position.Line+1 < len(lineStarts) {
+		lineEnd = lineStarts[position.Line+1] - 1
+	}
+	line := content[lineStart:lineEnd]
+	offset := min(convertColumn(line, position.Character, unit, columnUnitByte), len(line))
+	return lineStart + offset, nil
+}
+
+// editPlanner computes the outcome of a workspace edit on a virtual copy of
+// the files it touches, without writing anything.
+type editPlanner struct {
+	client *goplsClient
+	unit   string
+	files  map[string]*fileEdit
+}
+
+// planWorkspaceEdit computes the changes a workspace edit makes to workspace
+// files, reading their current contents from disk. Edits to documents open in
+// gopls at a different version, and files whose SHA-256 differs from the hash
+// expected for their relative path, are rejected. Files left unchanged are omitted.
+func (c *goplsClient) planWorkspaceEdit(edit *WorkspaceEdit, expectedHashes map[string]string) ([]fileEdit, error) {
+	return c.planEncodedWorkspaceEdit(edit, expectedHashes, c.positionEncoding())
+}
+
+// planEncodedWorkspaceEdit is planWorkspaceEdit for an edit whose columns are in
+// the given position encoding. It does not take c.mu, so it can plan edits
+// requested by gopls on the reader goroutine.
+func (c *goplsClient) planEncodedWorkspaceEdit(
+	edit *WorkspaceEdit, expectedHashes map[string]string, encoding string,
+) ([]fileEdit, error) {
+	converter, err := newColumnConverter("", encoding, c.workspacePath)
+	if err != nil {
+		return nil, err
+	}
+	planner := &editPlanner{client: c, unit: converter.lspUnit, files: make(map[string]*fileEdit)}
+
+	// Hashes describe the files before any change is made
+	for relativePath, expected := range expectedHashes {
+		file, err := planner.file(c.relativePathToURI(relativePath))
+		if err != nil {
+			return nil, err
+		}
+		if !file.existed {
+			return nil, fmt.Errorf("file %s does not exist", file.relativePath)
+		}
+		if actual := contentHash(file.original); !strings.EqualFold(actual, expected) {
+			return nil, fmt.Errorf("file %s has hash %s, expected %s", file.relativePath, actual, expected)
+		}
+	}
+
+	uris := make([]string, 0, len(edit.Changes))
+	for uri := range edit.Changes {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+	for _, uri := range uris {
+		if err := planner.editDocument(uri, nil, edit.Changes[uri]); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, change := range edit.DocumentChanges {
+		var err error
+		switch {
+		case change.TextDocumentEdit != nil:
+			document := change.TextDocumentEdit.TextDocument
+			err = planner.editDocument(document.URI, document.Version, change.TextDocumentEdit.Edits)
+		case change.CreateFile != nil:
+			err = planner.createFile(change.CreateFile)
+		case change.RenameFile != nil:
+			err = planner.renameFile(change.RenameFile)
+		case change.DeleteFile != nil:
+			err = planner.deleteFile(change.DeleteFile)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return planner.changedFiles(), nil
+}
+
+// file returns the planned state of the file at uri, reading it on first use.
+func (p *editPlanner) file(uri string) (*fileEdit, error) {
+	absolutePath, relativePath, err := p.client.workspaceFile(uri)
+	if err != nil {
+		return nil, err
+	}
+	if file, ok := p.files[absolutePath]; ok {
+		return file, nil
+	}
+
+	file := &fileEdit{relativePath: relativePath, absolutePath: absolutePath, mode: defaultFileMode}
+	info, err := os.Stat(absolutePath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("failed to stat file %s: %w", relativePath, err)
+	case info.IsDir():
+		return nil, fmt.Errorf("%s is a directory: only files can be edited", relativePath)
+	default:
+		content, err := os.ReadFile(absolutePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file %s: %w", relativePath, err)
+		}
+		file.original = string(content)
+		file.updated = file.original
+		file.existed = true
+		file.exists = true
+		file.mode = info.Mode().Perm()
+	}
+	p.files[absolutePath] = file
+	return file, nil
+}
+
+// editDocument applies text edits to a file, checking the document version
+// the edits were computed for when one is given.
+func (p *editPlanner) editDocument(uri string, version *int, edits []TextEdit) error {
+	file, err := p.file(uri)
+	if err != nil {
+		return err
+	}
+	if !file.exists {
+		return fmt.Errorf("cannot edit %s: file does not exist", file.relativePath)
+	}
+
+	if version != nil {
+		// Documents gopls has not opened are at version 0
+		current, isOpen := p.client.documentVersion(file.relativePath)
+		if !isOpen {
+			current = 0
+		}
+		if *version != current {
+			return fmt.Errorf("document %s is at version %d, edits are for version %d",
+				file.relativePath, current, *version)
+		}
+	}
+
+	updated, err := applyTextEdits(file.updated, edits, p.unit)
+	if err != nil {
+		return fmt.Errorf("failed to apply edits to %s: %w", file.relativePath, err)
+	}
+	file.updated = updated
+	file.edits = append(file.edits, edits...)
+	return nil
+}
+
+// createFile plans a create file operation, which leaves an empty file.
+func (p *editPlanner) createFile(operation *CreateFile) error {
+	file, err := p.file(operation.URI)
+	if err != nil {
+		return err
+	}
+	options := CreateFileOptions{}
+	if operation.Options != nil {
+		options = *operation.Options
+	}
+
+	if file.exists && !options.Overwrite {
+		if options.IgnoreIfExists {
+			return nil
+		}
+		return fmt.Errorf("cannot create %s: file already exists", file.relativePath)
+	}
+	if !file.exists {
+		file.mode = defaultFileMode
+	}
+	file.exists = true
+	file.updated = ""
+	return nil
+}
+
+// renameFile plans a rename file operation, moving the contents to the new path.
+func (p *editPlanner) renameFile(operation *RenameFile) error {
+	source, err := p.file(operation.OldURI)
+	if err != nil {
+		return err
+	}
+	target, err := p.file(operation.NewURI)
+	if err != nil {
+		return err
+	}
+	options := RenameFileOptions{}
+	if operation.Options != nil {
+		options = *operation.Options
+	}
+
+	if !source.exists {
+		return fmt.Errorf("cannot rename %s: file does not exist", source.relativePath)
+	}
+	if source == target {
+		return nil
+	}
+	if target.exists && !options.Overwrite {
+		if options.IgnoreIfExists {
+			return nil
+		}
+		return fmt.Errorf("cannot rename %s to %s: file already exists", source.relativePath, target.relativePath)
+	}
+
+	target.exists = true
+	target.updated = source.updated
+	target.mode = source.mode
+	source.exists = false
+	source.updated = ""
+	return nil
+}
+
+// deleteFile plans a delete file operation.
+func (p *editPlanner) deleteFile(operation *DeleteFile) error {
+	file, err := p.file(operation.URI)
+	if err != nil {
+		return err
+	}
+	options := DeleteFileOptions{}
+	if operation.Options != nil {
+		options = *operation.Options
+	}
+
+	if !file.exists {
+		if options.IgnoreIfNotExists {
+			return nil
+		}
+		return fmt.Errorf("cannot delete %s: file does not exist", file.relativePath)
+	}
+	file.exists = false
+	file.updated = ""
+	return nil
+}
+
+// changedFiles returns the files whose existence or contents change, sorted by path.
+func (p *editPlanner) changedFiles() []fileEdit {
+	files := make([]fileEdit, 0, len(p.files))
+	for _, file := range p.files {
+		if file.existed != file.exists || file.original != file.updated {
+			files = append(files, *file)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].relativePath < files[j].relativePath })
+	return files
+}
+
+// workspaceFile resolves a file URI to its absolute and workspace-relative
+// path, rejecting files outside the workspace.
+func (c *goplsClient) workspaceFile(uri string) (string, string, error) {
+	parsedURI, err := url.Parse(uri)
+	if err != nil || parsedURI.Scheme != fileScheme {
+		return "", "", fmt.Errorf("unsupported document URI: %s", uri)
+	}
+	absolutePath := filepath.Clean(parsedURI.Path)
+	relativePath, err := filepath.Rel(c.workspacePath, absolutePath)
+	if err != nil || relativePath == ".." || strings.HasPrefix(relativePath, ".."+string(filepath.Separator)) {
+		return "", "", fmt.Errorf("edit outside the workspace: %s", absolutePath)
+	}
+	return absolutePath, relativePath, nil
+}
+
+// applyFileEdits writes planned file changes as one transaction and tells
+// gopls about them, re-synchronizing the documents it has open.
+func (c *goplsClient) applyFileEdits(files []fileEdit) error {
+	changes, err := c.writeFileEdits(files)
+	if err != nil {
+		return err
+	}
+	c.forwardFileChanges(changes)
+	return nil
+}
+
+// writeFileEdits writes planned file changes as one transaction and returns
+// them for forwarding to gopls. New contents are staged in temporary files and
+// renamed into place; if any step fails, the files already changed are
+// restored. Nothing is written when a file changed on disk since the changes
+// were planned.
+func (c *goplsClient) writeFileEdits(files []fileEdit) ([]fileChange, error) {
+	for _, file := range files {
+		content, err := os.ReadFile(file.absolutePath)
+		exists := err == nil
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read file %s: %w", file.relativePath, err)
+		}
+		if exists != file.existed || string(content) != file.original {
+			return nil, fmt.Errorf("file %s changed since the edits were computed", file.relativePath)
+		}
+	}
+
+	staged := make([]string, len(files))
+	var createdDirs []string
+	discardStaged := func() {
+		for i, stagedPath := range staged {
+			if stagedPath != "" {
+				_ = os.Remove(stagedPath)
+				staged[i] = ""
+			}
+		}
+	}
+	defer discardStaged()
+	for i, file := range files {
+		if !file.exists {
+			continue
+		}
+		dirs, err := createParentDirs(file.absolutePath)
+		createdDirs = append(createdDirs, dirs...)
+		if err == nil {
+			staged[i], err = stageFile(file.absolutePath, file.updated, file.mode)
+		}
+		if err != nil {
+			discardStaged()
+			removeDirs(createdDirs)
+			return nil, fmt.Errorf("failed to write file %s: %w", file.relativePath, err)
+		}
+	}
+
+	changes := make([]fileChange, 0, len(files))
+	for i, file := range files {
+		var err error
+		changeType := fileChanged
+		switch {
+		case !file.exists:
+			changeType = fileDeleted
+			err = os.Remove(file.absolutePath)
+		case !file.existed:
+			changeType = fileCreated
+			err = os.Rename(staged[i], file.absolutePath)
+		default:
+			err = os.Rename(staged[i], file.absolutePath)
+		}
+		if err != nil {
+			discardStaged()
+			c.rollbackFileEdits(files[:i])
+			removeDirs(createdDirs)
+			return nil, fmt.Errorf("failed to write file %s: %w", file.relativePath, err)
+		}
+		staged[i] = ""
+		changes = append(changes, fileChange{path: file.absolutePath, changeType: changeType})
+	}
+
+	return changes, nil
+}
+
+// rollbackFileEdits restores files changed by an interrupted transaction, newest first.
+func (c *goplsClient) rollbackFileEdits(files []fileEdit) {
+	for i := len(files) - 1; i >= 0; i-- {
+		file := files[i]
+		var err error
+		if file.existed {
+			var stagedPath string
+			stagedPath, err = stageFile(file.absolutePath, file.original, file.mode)
+			if err == nil {
+				err = os.Rename(stagedPath, file.absolutePath)
+			}
+		} else {
+			err = os.Remove(file.absolutePath)
+		}
+		if err != nil {
+			c.logger.Error("failed to restore file after failed edit", "path", file.absolutePath, "error", err)
+		}
+	}
+}
+
+// stageFile writes content to a temporary file next to path, so that it can
+// be renamed over path atomically.
+func stageFile(path, content string, mode os.FileMode) (string, error) {
+	// The name does not end in .go so that watchers of Go files ignore it
+	staged, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	_, err = staged.WriteString(content)
+	if err == nil {
+		err = staged.Chmod(mode)
+	}
+	if closeErr := staged.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(staged.Name())
+		return "", fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	return staged.Name(), nil
+}
+
+// createParentDirs creates the missing parent directories of path and returns
+// the directories it created, outermost first.
+func createParentDirs(path string) ([]string, error) {
+	var missing []string
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(dir); err == nil || dir == filepath.Dir(dir) {
+			break
+		}
+		missing = append([]string{dir}, missing...)
+	}
+	for i, dir := range missing {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			return missing[:i], fmt.Errorf("failed to create directory %s: %w", dir, err)
+		}
+	}
+	return missing, nil
+}
+
+// removeDirs removes directories created for a failed edit, innermost first.
+func removeDirs(dirs []string) {
+	for i := len(dirs) - 1; i >= 0; i-- {
+		_ = os.Remove(dirs[i])
+	}
+}
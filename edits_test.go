@@ -0,0 +1,380 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestApplyTextEdits(t *testing.T) {
+	edit := func(startLine, startChar, endLine, endChar int, newText string) TextEdit {
+		return TextEdit{
+			Range: Range{
+				Start: Position{Line: startLine, Character: startChar},
+				End:   Position{Line: endLine, Character: endChar},
+			},
+			NewText: newText,
+		}
+	}
+
+	tests := []struct {
+		name     string
+		content  string
+		edits    []TextEdit
+		unit     string
+		expected string
+	}{
+		{
+			name:     "replace in order given",
+			content:  "func helper() {}\nhelper()\n",
+			edits:    []TextEdit{edit(1, 0, 1, 6, "assist"), edit(0, 5, 0, 11, "assist")},
+			unit:     columnUnitByte,
+			expected: "func assist() {}\nassist()\n",
+		},
+		{
+			name:     "insertions at the same position keep their order",
+			content:  "x\n",
+			edits:    []TextEdit{edit(0, 0, 0, 0, "a"), edit(0, 0, 0, 0, "b")},
+			unit:     columnUnitByte,
+			expected: "abx\n",
+		},
+		{
+			name:     "utf-16 columns",
+			content:  "s := \"😀\" + name\n",
+			edits:    []TextEdit{edit(0, 12, 0, 16, "label")},
+			unit:     columnUnitUTF16,
+			expected: "s := \"😀\" + label\n",
+		},
+		{
+			name:     "append after the last line",
+			content:  "package main\n",
+			edits:    []TextEdit{edit(1, 0, 1, 0, "\nfunc main() {}\n")},
+			unit:     columnUnitByte,
+			expected: "package main\n\nfunc main() {}\n",
+		},
+		{
+			name:     "character past the end of the line",
+			content:  "ab\ncd\n",
+			edits:    []TextEdit{edit(0, 1, 0, 99, "")},
+			unit:     columnUnitByte,
+			expected: "a\ncd\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated, err := applyTextEdits(tt.content, tt.edits, tt.unit)
+			if err != nil {
+				t.Fatalf("applyTextEdits failed: %v", err)
+			}
+			if updated != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, updated)
+			}
+		})
+	}
+
+	if _, err := applyTextEdits("abcdef\n", []TextEdit{edit(0, 0, 0, 4, "x"), edit(0, 2, 0, 5, "y")},
+		columnUnitByte); err == nil {
+		t.Error("Expected error for overlapping edits")
+	}
+	if _, err := applyTextEdits("a\n", []TextEdit{edit(5, 0, 5, 1, "x")}, columnUnitByte); err == nil {
+		t.Error("Expected error for an edit past the end of the file")
+	}
+	if _, err := applyTextEdits("abc\n", []TextEdit{edit(0, 2, 0, 1, "x")}, columnUnitByte); err == nil {
+		t.Error("Expected error for a reversed range")
+	}
+}
+
+func TestWorkspaceFile(t *testing.T) {
+	client := newClient("/ws", workspaceConfig{}, newTestLogger())
+
+	absolutePath, relativePath, err := client.workspaceFile("file:///ws/pkg/a.go")
+	if err != nil || absolutePath != "/ws/pkg/a.go" || relativePath != "pkg/a.go" {
+		t.Errorf("Unexpected workspace file: %q, %q, %v", absolutePath, relativePath, err)
+	}
+
+	for _, uri := range []string{"file:///elsewhere/a.go", "file:///ws/../etc/passwd", "untitled:Untitled-1"} {
+		if _, _, err := client.workspaceFile(uri); err == nil {
+			t.Errorf("Expected %s to be rejected", uri)
+		}
+	}
+}
+
+func TestDecodeDocumentChanges(t *testing.T) {
+	raw := `{"documentChanges":[` +
+		`{"textDocument":{"uri":"file:///ws/a.go","version":3},"edits":[{"range":{"start":{"line":0,"character":0},` +
+		`"end":{"line":0,"character":0}},"newText":"x"}]},` +
+		`{"kind":"create","uri":"file:///ws/b.go","options":{"ignoreIfExists":true}},` +
+		`{"kind":"rename","oldUri":"file:///ws/b.go","newUri":"file:///ws/c.go"},` +
+		`{"kind":"delete","uri":"file:///ws/d.go"}]}`
+
+	var edit WorkspaceEdit
+	if err := json.Unmarshal([]byte(raw), &edit); err != nil {
+		t.Fatalf("failed to decode workspace edit: %v", err)
+	}
+	changes := edit.DocumentChanges
+	if len(changes) != 4 || changes[0].TextDocumentEdit == nil || *changes[0].TextDocumentEdit.TextDocument.Version != 3 ||
+		changes[1].CreateFile == nil || !changes[1].CreateFile.Options.IgnoreIfExists ||
+		changes[2].RenameFile == nil || changes[2].RenameFile.NewURI != "file:///ws/c.go" ||
+		changes[3].DeleteFile == nil {
+		t.Fatalf("Unexpected document changes: %+v", changes)
+	}
+	if edits := edit.textEdits("file:///ws/a.go"); len(edits) != 1 || edits[0].NewText != "x" {
+		t.Errorf("Unexpected text edits: %+v", edits)
+	}
+
+	encoded, err := json.Marshal(edit)
+	if err != nil {
+		t.Fatalf("failed to encode workspace edit: %v", err)
+	}
+	var decoded WorkspaceEdit
+	if err := json.Unmarshal(encoded, &decoded); err != nil || len(decoded.DocumentChanges) != 4 ||
+		decoded.DocumentChanges[3].DeleteFile == nil {
+		t.Errorf("Expected document changes to round-trip, got %s, %v", encoded, err)
+	}
+
+	if err := json.Unmarshal([]byte(`{"documentChanges":[{"kind":"copy"}]}`), &decoded); err == nil {
+		t.Error("Expected error for an unknown document change kind")
+	}
+}
+
+// writeWorkspaceFiles creates a workspace containing the given files.
+func writeWorkspaceFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	workspacePath := t.TempDir()
+	for relativePath, content := range files {
+		path := filepath.Join(workspacePath, relativePath)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", relativePath, err)
+		}
+	}
+	return workspacePath
+}
+
+// readWorkspaceFile returns the contents of a workspace file, or "<missing>".
+func readWorkspaceFile(t *testing.T, workspacePath, relativePath string) string {
+	t.Helper()
+
+	content, err := os.ReadFile(filepath.Join(workspacePath, relativePath))
+	if errors.Is(err, os.ErrNotExist) {
+		return "<missing>"
+	}
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", relativePath, err)
+	}
+	return string(content)
+}
+
+func TestPlanAndApplyWorkspaceEdit(t *testing.T) {
+	workspacePath := writeWorkspaceFiles(t, map[string]string{
+		"a.go":   "package a\n",
+		"old.go": "package old\n",
+		"del.go": "package del\n",
+	})
+	client := newClient(workspacePath, workspaceConfig{}, newTestLogger())
+	uri := func(relativePath string) string { return client.relativePathToURI(relativePath) }
+	version := 0
+
+	edit := &WorkspaceEdit{
+		Changes: map[string][]TextEdit{
+			uri("a.go"): {{Range: Range{Start: Position{Line: 0, Character: 8}, End: Position{Line: 0, Character: 9}},
+				NewText: "alpha"}},
+		},
+		DocumentChanges: []DocumentChange{
+			{CreateFile: &CreateFile{Kind: "create", URI: uri("pkg/new.go")}},
+			{TextDocumentEdit: &TextDocumentEdit{
+				TextDocument: OptionalVersionedTextDocumentIdentifier{URI: uri("pkg/new.go")},
+				Edits:        []TextEdit{{NewText: "package pkg\n"}},
+			}},
+			{RenameFile: &RenameFile{Kind: "rename", OldURI: uri("old.go"), NewURI: uri("renamed.go")}},
+			{TextDocumentEdit: &TextDocumentEdit{
+				TextDocument: OptionalVersionedTextDocumentIdentifier{URI: uri("renamed.go"), Version: &version},
+				Edits: []TextEdit{{Range: Range{Start: Position{Line: 0, Character: 8}, End: Position{Line: 0, Character: 11}},
+					NewText: "renamed"}},
+			}},
+			{DeleteFile: &DeleteFile{Kind: "delete", URI: uri("del.go")}},
+			{DeleteFile: &DeleteFile{Kind: "delete", URI: uri("gone.go"), Options: &DeleteFileOptions{IgnoreIfNotExists: true}}},
+		},
+	}
+
+	files, err := client.planWorkspaceEdit(edit, map[string]string{"a.go": contentHash("package a\n")})
+	if err != nil {
+		t.Fatalf("planWorkspaceEdit failed: %v", err)
+	}
+	var summary []string
+	for _, file := range files {
+		summary = append(summary, file.relativePath+":"+file.change())
+	}
+	expected := "a.go:modified del.go:deleted old.go:deleted pkg/new.go:created renamed.go:created"
+	if strings.Join(summary, " ") != expected {
+		t.Errorf("Expected planned changes %q, got %q", expected, strings.Join(summary, " "))
+	}
+	if readWorkspaceFile(t, workspacePath, "old.go") != "package old\n" {
+		t.Error("Expected planning to leave files untouched")
+	}
+
+	if err := client.applyFileEdits(files); err != nil {
+		t.Fatalf("applyFileEdits failed: %v", err)
+	}
+	for relativePath, content := range map[string]string{
+		"a.go":       "package alpha\n",
+		"pkg/new.go": "package pkg\n",
+		"renamed.go": "package renamed\n",
+		"old.go":     "<missing>",
+		"del.go":     "<missing>",
+	} {
+		if actual := readWorkspaceFile(t, workspacePath, relativePath); actual != content {
+			t.Errorf("Expected %s to contain %q, got %q", relativePath, content, actual)
+		}
+	}
+
+	// Applying the same plan again finds the files changed
+	if err := client.applyFileEdits(files); err == nil {
+		t.Error("Expected stale edits to be rejected")
+	}
+}
+
+func TestPlanWorkspaceEditRejectsConflicts(t *testing.T) {
+	workspacePath := writeWorkspaceFiles(t, map[string]string{"a.go": "package a\n", "b.go": "package b\n"})
+	client := newClient(workspacePath, workspaceConfig{}, newTestLogger())
+	uri := func(relativePath string) string { return client.relativePathToURI(relativePath) }
+	staleVersion := 4
+
+	tests := []struct {
+		name   string
+		edit   WorkspaceEdit
+		hashes map[string]string
+	}{
+		{
+			name:   "hash mismatch",
+			hashes: map[string]string{"a.go": contentHash("package other\n")},
+		},
+		{
+			name: "version mismatch",
+			edit: WorkspaceEdit{DocumentChanges: []DocumentChange{{TextDocumentEdit: &TextDocumentEdit{
+				TextDocument: OptionalVersionedTextDocumentIdentifier{URI: uri("a.go"), Version: &staleVersion},
+			}}}},
+		},
+		{
+			name: "create existing file",
+			edit: WorkspaceEdit{DocumentChanges: []DocumentChange{{CreateFile: &CreateFile{Kind: "create", URI: uri("a.go")}}}},
+		},
+		{
+			name: "rename onto existing file",
+			edit: WorkspaceEdit{DocumentChanges: []DocumentChange{
+				{RenameFile: &RenameFile{Kind: "rename", OldURI: uri("a.go"), NewURI: uri("b.go")}},
+			}},
+		},
+		{
+			name: "delete missing file",
+			edit: WorkspaceEdit{DocumentChanges: []DocumentChange{{DeleteFile: &DeleteFile{Kind: "delete", URI: uri("c.go")}}}},
+		},
+		{
+			name: "edit outside the workspace",
+			edit: WorkspaceEdit{Changes: map[string][]TextEdit{"file:///etc/hosts": {{NewText: "x"}}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := client.planWorkspaceEdit(&tt.edit, tt.hashes); err == nil {
+				t.Error("Expected the workspace edit to be rejected")
+			}
+		})
+	}
+}
+
+func TestApplyFileEditsRollsBack(t *testing.T) {
+	workspacePath := writeWorkspaceFiles(t, map[string]string{"a.go": "package a\n"})
+	client := newClient(workspacePath, workspaceConfig{}, newTestLogger())
+	uri := func(relativePath string) string { return client.relativePathToURI(relativePath) }
+
+	// Creating x/y.go creates the directory x, so the file x cannot be written
+	edit := &WorkspaceEdit{
+		Changes: map[string][]TextEdit{uri("a.go"): {{Range: Range{End: Position{Line: 1}}, NewText: "package b\n"}}},
+		DocumentChanges: []DocumentChange{
+			{CreateFile: &CreateFile{Kind: "create", URI: uri("x")}},
+			{CreateFile: &CreateFile{Kind: "create", URI: uri("x/y.go")}},
+		},
+	}
+	files, err := client.planWorkspaceEdit(edit, nil)
+	if err != nil {
+		t.Fatalf("planWorkspaceEdit failed: %v", err)
+	}
+	if err := client.applyFileEdits(files); err == nil {
+		t.Fatal("Expected the conflicting edit to fail")
+	}
+
+	if content := readWorkspaceFile(t, workspacePath, "a.go"); content != "package a\n" {
+		t.Errorf("Expected a.go to be restored, got %q", content)
+	}
+	entries, err := os.ReadDir(workspacePath)
+	if err != nil || len(entries) != 1 {
+		t.Errorf("Expected only a.go to remain, got %v, %v", entries, err)
+	}
+}
+
+func TestApplyWorkspaceEditTool(t *testing.T) {
+	workspacePath := newFakeGoplsWorkspace(t)
+	config := fakeGoplsConfig(t, writeFakeGoplsScript(t, fakeGoplsScript()))
+	client := startFakeGoplsClient(t, workspacePath, config)
+	session := connectMCP(t, map[string]*goplsClient{workspacePath: client})
+	if err := client.ensureFileOpen("main.go"); err != nil {
+		t.Fatalf("ensureFileOpen failed: %v", err)
+	}
+
+	mainGo := readWorkspaceFile(t, workspacePath, "main.go")
+	arguments := map[string]any{
+		"workspace": workspacePath,
+		"changes": []map[string]any{
+			{
+				"path":            "main.go",
+				"expectedHash":    contentHash(mainGo),
+				"expectedVersion": 1,
+				"edits": []map[string]any{
+					{"startLine": 4, "startChar": 1, "endLine": 4, "endChar": 7, "newText": "util.Help"},
+				},
+			},
+			{"kind": "create", "path": "util/util.go", "content": "package util\n\nfunc Help() {}\n"},
+		},
+		"dryRun": true,
+	}
+
+	preview := callTool[ApplyEditResult](t, session, toolApplyWorkspaceEdit, arguments)
+	if preview.Applied || len(preview.Files) != 2 || preview.Files[1].Change != "created" ||
+		!strings.Contains(preview.Diff, "+++ b/util/util.go") || !strings.Contains(preview.Diff, "+\tutil.Help()") {
+		t.Errorf("Unexpected preview: %+v", preview)
+	}
+	if readWorkspaceFile(t, workspacePath, "util/util.go") != "<missing>" {
+		t.Error("Expected a dry run to leave the workspace unchanged")
+	}
+
+	arguments["dryRun"] = false
+	applied := callTool[ApplyEditResult](t, session, toolApplyWorkspaceEdit, arguments)
+	updated := readWorkspaceFile(t, workspacePath, "main.go")
+	if !applied.Applied || applied.Files[0].Hash != contentHash(updated) || !strings.Contains(updated, "\tutil.Help()\n") {
+		t.Errorf("Unexpected applied edit: %+v, main.go %q", applied, updated)
+	}
+	if version, isOpen := client.documentVersion("main.go"); !isOpen || version != 2 {
+		t.Errorf("Expected gopls to see the new contents of main.go, got version %d", version)
+	}
+
+	// The same changes no longer match the hash and version of main.go
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	result, err := session.CallTool(ctx, &mcp.CallToolParams{Name: toolApplyWorkspaceEdit, Arguments: arguments})
+	if err == nil && !result.IsError {
+		t.Error("Expected a stale edit to be rejected")
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/MegaGrindStone/gopls-mcp/internal/fakegopls"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// fakeGoplsScriptEnv makes the test binary serve the named script as a fake
+// gopls instead of running the tests.
+const fakeGoplsScriptEnv = "GOPLS_MCP_FAKE_GOPLS_SCRIPT"
+
+func TestMain(m *testing.M) {
+	if scriptPath := os.Getenv(fakeGoplsScriptEnv); scriptPath != "" {
+		os.Exit(runFakeGopls(scriptPath))
+	}
+	os.Exit(m.Run())
+}
+
+// runFakeGopls serves a script on stdin and stdout and returns the exit code.
+func runFakeGopls(scriptPath string) int {
+	script, err := fakegopls.LoadScript(scriptPath)
+	if err == nil {
+		err = fakegopls.Serve(os.Stdin, os.Stdout, script)
+	}
+	if err != nil {
+		_, _ = os.Stderr.WriteString("fake gopls: " + err.Error() + "\n")
+		return 1
+	}
+	return 0
+}
+
+// fakeGoplsConfig returns a workspace configuration that runs the test binary
+// as a gopls serving the script at scriptPath.
+func fakeGoplsConfig(t *testing.T, scriptPath string) workspaceConfig {
+	t.Helper()
+
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatalf("failed to locate test binary: %v", err)
+	}
+	return workspaceConfig{
+		GoplsPath: executable,
+		Env:       map[string]string{fakeGoplsScriptEnv: scriptPath},
+	}
+}
+
+// writeFakeGoplsScript stores a script in a temporary file.
+func writeFakeGoplsScript(t *testing.T, script *fakegopls.Script) string {
+	t.Helper()
+
+	data, err := json.Marshal(script)
+	if err != nil {
+		t.Fatalf("failed to encode script: %v", err)
+	}
+	scriptPath := filepath.Join(t.TempDir(), "script.json")
+	if err := os.WriteFile(scriptPath, data, 0644); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+	return scriptPath
+}
+
+// newFakeGoplsWorkspace creates a workspace with a main.go file.
+func newFakeGoplsWorkspace(t *testing.T) string {
+	t.Helper()
+
+	workspacePath := t.TempDir()
+	mainGo := "package main\n\nfunc main() {\n\thelper()\n}\n\nfunc helper() {}\n"
+	if err := os.WriteFile(filepath.Join(workspacePath, "main.go"), []byte(mainGo), 0644); err != nil {
+		t.Fatalf("failed to write main.go: %v", err)
+	}
+	return workspacePath
+}
+
+// fakeGoplsScript scripts a gopls that loads the workspace, publishes a
+// diagnostic for main.go and answers definition and hover requests.
+func fakeGoplsScript() *fakegopls.Script {
+	return &fakegopls.Script{
+		Responses: []fakegopls.Response{
+			{
+				Method: "textDocument/definition",
+				Params: json.RawMessage(`{"position":{"line":3,"character":1}}`),
+				Result: json.RawMessage(`[{"uri":"${rootUri}/main.go",` +
+					`"range":{"start":{"line":6,"character":5},"end":{"line":6,"character":11}}}]`),
+			},
+			{
+				Method: "textDocument/hover",
+				Result: json.RawMessage(`{"contents":{"kind":"markdown","value":"func helper()"}}`),
+			},
+		},
+		Messages: []fakegopls.Message{
+			{
+				After:  "initialized",
+				Method: "$/progress",
+				Params: json.RawMessage(`{"token":"load","value":{"kind":"begin","title":"Loading packages"}}`),
+			},
+			{
+				After:  "initialized",
+				Method: "$/progress",
+				Params: json.RawMessage(`{"token":"load","value":{"kind":"end"}}`),
+			},
+			{
+				After:  "textDocument/didOpen",
+				Method: "textDocument/publishDiagnostics",
+				Params: json.RawMessage(`{"uri":"${rootUri}/main.go","version":1,"diagnostics":[` +
+					`{"range":{"start":{"line":3,"character":1},"end":{"line":3,"character":7}},` +
+					`"severity":2,"source":"fake","message":"scripted warning"}]}`),
+			},
+		},
+	}
+}
+
+// connectMCP serves the tools of the given clients to an in-memory MCP client session.
+func connectMCP(t *testing.T, clients map[string]*goplsClient) *mcp.ClientSession {
+	t.Helper()
+
+	server := setupMCPServer(t.Context(), clients, newToolTimeouts(defaultRequestTimeout))
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	if _, err := server.Connect(context.Background(), serverTransport); err != nil {
+		t.Fatalf("failed to connect server: %v", err)
+	}
+
+	session, err := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "v0.0.0"}, nil).
+		Connect(context.Background(), clientTransport)
+	if err != nil {
+		t.Fatalf("failed to connect client: %v", err)
+	}
+	t.Cleanup(func() { _ = session.Close() })
+	return session
+}
+
+// callTool calls an MCP tool and decodes its JSON result.
+func callTool[T any](t *testing.T, session *mcp.ClientSession, name string, arguments map[string]any) T {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := session.CallTool(ctx, &mcp.CallToolParams{Name: name, Arguments: arguments})
+	if err != nil {
+		t.Fatalf("%s failed: %v", name, err)
+	}
+	if result.IsError || len(result.Content) == 0 {
+		t.Fatalf("%s returned an error result: %+v", name, result.Content)
+	}
+	textContent, ok := result.Content[0].(*mcp.TextContent)
+	if !ok {
+		t.Fatalf("%s returned no text content", name)
+	}
+
+	var parsed T
+	if err := json.Unmarshal([]byte(textContent.Text), &parsed); err != nil {
+		t.Fatalf("failed to parse %s result %q: %v", name, textContent.Text, err)
+	}
+	return parsed
+}
+
+// startFakeGoplsClient starts a client for the workspace running a fake gopls.
+func startFakeGoplsClient(t *testing.T, workspacePath string, config workspaceConfig) *goplsClient {
+	t.Helper()
+
+	client := newClient(workspacePath, config, newTestLogger())
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if err := client.start(ctx); err != nil {
+		t.Fatalf("failed to start fake gopls: %v", err)
+	}
+	t.Cleanup(func() { _ = client.stop() })
+	return client
+}
+
+// queryFakeGopls runs definition, hover and diagnostics through the MCP server.
+func queryFakeGopls(t *testing.T, session *mcp.ClientSession, workspacePath string) {
+	t.Helper()
+
+	position := map[string]any{"workspace": workspacePath, "path": "main.go", "line": 4, "character": 1}
+
+	definition := callTool[GoToDefinitionResult](t, session, toolGoToDefinition, position)
+	if len(definition.Locations) != 1 || definition.Locations[0].URI != "main.go" ||
+		definition.Locations[0].Line != 7 || definition.Locations[0].Character != 5 {
+		t.Errorf("Unexpected definition: %+v", definition)
+	}
+
+	hover := callTool[GetHoverResult](t, session, toolGetHoverInfo, position)
+	if len(hover.Contents) != 1 || hover.Contents[0] != "func helper()" {
+		t.Errorf("Unexpected hover: %+v", hover)
+	}
+
+	diagnostics := callTool[GetDiagnosticsResult](t, session, toolGetDiagnostics,
+		map[string]any{"workspace": workspacePath, "path": "main.go"})
+	if len(diagnostics.Diagnostics) != 1 || diagnostics.Diagnostics[0].Message != "scripted warning" {
+		t.Errorf("Unexpected diagnostics: %+v", diagnostics)
+	}
+}
+
+func TestFakeGoplsEndToEnd(t *testing.T) {
+	workspacePath := newFakeGoplsWorkspace(t)
+	config := fakeGoplsConfig(t, writeFakeGoplsScript(t, fakeGoplsScript()))
+	client := startFakeGoplsClient(t, workspacePath, config)
+
+	if info := client.describeServer(); info.ServerName != "fakegopls" {
+		t.Errorf("Expected the fake gopls to be running, got %+v", info)
+	}
+
+	session := connectMCP(t, map[string]*goplsClient{workspacePath: client})
+	queryFakeGopls(t, session, workspacePath)
+}
+
+func TestFakeGoplsReplaysTrace(t *testing.T) {
+	// Record a conversation with the scripted gopls
+	recordedPath := newFakeGoplsWorkspace(t)
+	config := fakeGoplsConfig(t, writeFakeGoplsScript(t, fakeGoplsScript()))
+	config.TraceDir = t.TempDir()
+	recorder := startFakeGoplsClient(t, recordedPath, config)
+	queryFakeGopls(t, connectMCP(t, map[string]*goplsClient{recordedPath: recorder}), recordedPath)
+	if err := recorder.stop(); err != nil {
+		t.Fatalf("failed to stop recording client: %v", err)
+	}
+
+	// Replay the trace in another workspace
+	tracePath := filepath.Join(config.TraceDir, traceFileName(recordedPath))
+	workspacePath := newFakeGoplsWorkspace(t)
+	client := startFakeGoplsClient(t, workspacePath, fakeGoplsConfig(t, tracePath))
+	queryFakeGopls(t, connectMCP(t, map[string]*goplsClient{workspacePath: client}), workspacePath)
+}
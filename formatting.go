@@ -1,15 +1,17 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 )
 
 // formatDocument sends a textDocument/formatting request to gopls.
-func (c *goplsClient) formatDocument(relativePath string) ([]TextEdit, error) {
+func (c *goplsClient) formatDocument(ctx context.Context, relativePath string) ([]TextEdit, error) {
 	c.logger.Debug("formatDocument called", "relativePath", relativePath)
 
-	if !c.isRunning() {
-		return nil, fmt.Errorf("gopls is not running")
+	if err := c.waitUntilRunning(ctx); err != nil {
+		return nil, err
 	}
 
 	// Ensure file is open in gopls
@@ -17,46 +19,49 @@ func (c *goplsClient) formatDocument(relativePath string) ([]TextEdit, error) {
 		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 
-	// Convert relative path to URI for LSP request
-	fileURI := c.relativePathToURI(relativePath)
-
-	// Create textDocument/formatting request
-	request := map[string]any{
-		"jsonrpc": "2.0",
-		"id":      c.nextRequestID(),
-		"method":  "textDocument/formatting",
-		"params": map[string]any{
-			"textDocument": map[string]any{
-				"uri": fileURI,
-			},
-			"options": map[string]any{
-				"tabSize":      4,
-				"insertSpaces": false,
-			},
+	params := DocumentFormattingParams{
+		TextDocument: TextDocumentIdentifier{URI: c.relativePathToURI(relativePath)},
+		Options: FormattingOptions{
+			TabSize:      4,
+			InsertSpaces: false,
 		},
 	}
 
-	// Send request and wait for response
-	response, err := c.sendRequestAndWait(request)
-	if err != nil {
+	// Send textDocument/formatting request and wait for response
+	var textEdits []TextEdit
+	if err := c.call(ctx, "textDocument/formatting", params, &textEdits); err != nil {
 		return nil, fmt.Errorf("failed to format document: %w", err)
 	}
 
-	// Parse response to get text edits
-	textEdits, err := c.parseTextEditsFromResponse(response)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse text edits: %w", err)
+	if textEdits == nil {
+		return []TextEdit{}, nil
 	}
 
 	return textEdits, nil
 }
 
-// organizeImports sends a source.organizeImports code action request to gopls.
-func (c *goplsClient) organizeImports(relativePath string) ([]TextEdit, error) {
-	c.logger.Debug("organizeImports called", "relativePath", relativePath)
+// formatRange formats part of a document. It sends a textDocument/rangeFormatting
+// request when gopls supports it, and otherwise formats the whole document and
+// keeps the edits that lie within the range.
+func (c *goplsClient) formatRange(ctx context.Context, relativePath string, formatRange Range) ([]TextEdit, error) {
+	c.logger.Debug("formatRange called", "relativePath", relativePath, "range", formatRange)
+
+	if capabilities, _ := c.capabilities(); !capabilities.DocumentRangeFormattingProvider.Enabled {
+		textEdits, err := c.formatDocument(ctx, relativePath)
+		if err != nil {
+			return nil, err
+		}
+		inRange := make([]TextEdit, 0, len(textEdits))
+		for _, edit := range textEdits {
+			if !positionBefore(edit.Range.Start, formatRange.Start) && !positionBefore(formatRange.End, edit.Range.End) {
+				inRange = append(inRange, edit)
+			}
+		}
+		return inRange, nil
+	}
 
-	if !c.isRunning() {
-		return nil, fmt.Errorf("gopls is not running")
+	if err := c.waitUntilRunning(ctx); err != nil {
+		return nil, err
 	}
 
 	// Ensure file is open in gopls
@@ -64,63 +69,39 @@ func (c *goplsClient) organizeImports(relativePath string) ([]TextEdit, error) {
 		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 
-	// Convert relative path to URI for LSP request
-	fileURI := c.relativePathToURI(relativePath)
-
-	// Create textDocument/codeAction request for organizing imports
-	request := map[string]any{
-		"jsonrpc": "2.0",
-		"id":      c.nextRequestID(),
-		"method":  "textDocument/codeAction",
-		"params": map[string]any{
-			"textDocument": map[string]any{
-				"uri": fileURI,
-			},
-			"range": map[string]any{
-				"start": map[string]any{
-					"line":      0,
-					"character": 0,
-				},
-				"end": map[string]any{
-					"line":      0,
-					"character": 0,
-				},
-			},
-			"context": map[string]any{
-				"only": []string{"source.organizeImports"},
-			},
+	params := DocumentRangeFormattingParams{
+		TextDocument: TextDocumentIdentifier{URI: c.relativePathToURI(relativePath)},
+		Range:        formatRange,
+		Options: FormattingOptions{
+			TabSize:      4,
+			InsertSpaces: false,
 		},
 	}
 
-	// Send request and wait for response
-	response, err := c.sendRequestAndWait(request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to organize imports: %w", err)
+	// Send textDocument/rangeFormatting request and wait for response
+	var textEdits []TextEdit
+	if err := c.call(ctx, "textDocument/rangeFormatting", params, &textEdits); err != nil {
+		return nil, fmt.Errorf("failed to format range: %w", err)
 	}
 
-	// Parse response to get workspace edit
-	workspaceEdit, err := c.parseWorkspaceEditFromCodeActionResponse(response)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse workspace edit: %w", err)
+	if textEdits == nil {
+		return []TextEdit{}, nil
 	}
 
-	// Extract text edits for the current file
-	if changes, ok := workspaceEdit.Changes[fileURI]; ok {
-		return changes, nil
-	}
+	return textEdits, nil
+}
 
-	return []TextEdit{}, nil
+// positionBefore reports whether position a comes before position b.
+func positionBefore(a, b Position) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Character < b.Character)
 }
 
-// getInlayHints sends a textDocument/inlayHint request to gopls.
-func (c *goplsClient) getInlayHints(relativePath string, startLine, startChar, endLine, endChar int) (
-	[]InlayHint, error) {
-	c.logger.Debug("getInlayHints called",
-		"relativePath", relativePath, "startLine", startLine,
-		"startChar", startChar, "endLine", endLine, "endChar", endChar)
+// organizeImports sends a source.organizeImports code action request to gopls.
+func (c *goplsClient) organizeImports(ctx context.Context, relativePath string) ([]TextEdit, error) {
+	c.logger.Debug("organizeImports called", "relativePath", relativePath)
 
-	if !c.isRunning() {
-		return nil, fmt.Errorf("gopls is not running")
+	if err := c.waitUntilRunning(ctx); err != nil {
+		return nil, err
 	}
 
 	// Ensure file is open in gopls
@@ -131,187 +112,78 @@ func (c *goplsClient) getInlayHints(relativePath string, startLine, startChar, e
 	// Convert relative path to URI for LSP request
 	fileURI := c.relativePathToURI(relativePath)
 
-	// Create textDocument/inlayHint request
-	request := map[string]any{
-		"jsonrpc": "2.0",
-		"id":      c.nextRequestID(),
-		"method":  "textDocument/inlayHint",
-		"params": map[string]any{
-			"textDocument": map[string]any{
-				"uri": fileURI,
-			},
-			"range": map[string]any{
-				"start": map[string]any{
-					"line":      startLine,
-					"character": startChar,
-				},
-				"end": map[string]any{
-					"line":      endLine,
-					"character": endChar,
-				},
-			},
+	params := CodeActionParams{
+		TextDocument: TextDocumentIdentifier{URI: fileURI},
+		Range:        Range{},
+		Context: CodeActionContext{
+			Diagnostics: []Diagnostic{},
+			Only:        []string{"source.organizeImports"},
 		},
 	}
 
-	// Send request and wait for response
-	response, err := c.sendRequestAndWait(request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get inlay hints: %w", err)
+	// Send textDocument/codeAction request and wait for response
+	var result json.RawMessage
+	if err := c.call(ctx, "textDocument/codeAction", params, &result); err != nil {
+		return nil, fmt.Errorf("failed to organize imports: %w", err)
 	}
 
-	// Parse response to get inlay hints
-	inlayHints, err := c.parseInlayHintsFromResponse(response)
+	actions, err := decodeCodeActions(result)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse inlay hints: %w", err)
-	}
-
-	return inlayHints, nil
-}
-
-// parseTextEditsFromResponse extracts text edits from LSP response.
-func (c *goplsClient) parseTextEditsFromResponse(response map[string]any) ([]TextEdit, error) {
-	result, resultOk := response["result"]
-	if !resultOk {
-		return nil, fmt.Errorf("invalid response format")
-	}
-
-	if result == nil {
-		return []TextEdit{}, nil
-	}
-
-	editsData, editsOk := result.([]any)
-	if !editsOk {
-		return nil, fmt.Errorf("invalid response format")
+		return nil, fmt.Errorf("failed to parse workspace edit: %w", err)
 	}
 
-	var textEdits []TextEdit
-	for _, editData := range editsData {
-		if editMap, ok := editData.(map[string]any); ok {
-			textEdit := c.parseTextEdit(editMap)
-			textEdits = append(textEdits, textEdit)
+	// Extract text edits for the current file from the first action carrying an edit
+	for _, action := range actions {
+		if action, err = c.resolveCodeAction(ctx, action); err != nil {
+			return nil, err
 		}
-	}
-
-	return textEdits, nil
-}
-
-// parseTextEdit parses a text edit from a map.
-func (c *goplsClient) parseTextEdit(editMap map[string]any) TextEdit {
-	var textEdit TextEdit
-
-	if rangeMap, ok := editMap["range"].(map[string]any); ok {
-		textEdit.Range = c.parseRange(rangeMap)
-	}
-
-	if newText, ok := editMap["newText"].(string); ok {
-		textEdit.NewText = newText
-	}
-
-	return textEdit
-}
-
-// parseWorkspaceEditFromCodeActionResponse extracts workspace edit from code action response.
-func (c *goplsClient) parseWorkspaceEditFromCodeActionResponse(response map[string]any) (*WorkspaceEdit, error) {
-	result, resultOk := response["result"]
-	if !resultOk {
-		return nil, fmt.Errorf("invalid response format")
-	}
-
-	if result == nil {
-		return &WorkspaceEdit{Changes: make(map[string][]TextEdit)}, nil
-	}
-
-	actionsData, actionsOk := result.([]any)
-	if !actionsOk {
-		return nil, fmt.Errorf("invalid response format")
-	}
-
-	for _, actionData := range actionsData {
-		if actionMap, ok := actionData.(map[string]any); ok {
-			if editMap, editOk := actionMap["edit"].(map[string]any); editOk {
-				return c.parseWorkspaceEdit(editMap), nil
-			}
+		if action.Edit == nil {
+			continue
 		}
-	}
-
-	return &WorkspaceEdit{Changes: make(map[string][]TextEdit)}, nil
-}
-
-// parseWorkspaceEdit parses a workspace edit from a map.
-func (c *goplsClient) parseWorkspaceEdit(editMap map[string]any) *WorkspaceEdit {
-	workspaceEdit := &WorkspaceEdit{
-		Changes: make(map[string][]TextEdit),
-	}
-
-	if changesMap, ok := editMap["changes"].(map[string]any); ok {
-		for uri, changesData := range changesMap {
-			if editsData, editsOk := changesData.([]any); editsOk {
-				var textEdits []TextEdit
-				for _, editData := range editsData {
-					if editDataMap, editDataOk := editData.(map[string]any); editDataOk {
-						textEdit := c.parseTextEdit(editDataMap)
-						textEdits = append(textEdits, textEdit)
-					}
-				}
-				workspaceEdit.Changes[uri] = textEdits
-			}
+		if changes := action.Edit.textEdits(fileURI); len(changes) > 0 {
+			return changes, nil
 		}
+		break
 	}
 
-	return workspaceEdit
+	return []TextEdit{}, nil
 }
 
-// parseInlayHintsFromResponse extracts inlay hints from LSP response.
-func (c *goplsClient) parseInlayHintsFromResponse(response map[string]any) ([]InlayHint, error) {
-	result, resultOk := response["result"]
-	if !resultOk {
-		return nil, fmt.Errorf("invalid response format")
-	}
-
-	if result == nil {
-		return []InlayHint{}, nil
-	}
-
-	hintsData, hintsOk := result.([]any)
-	if !hintsOk {
-		return nil, fmt.Errorf("invalid response format")
-	}
+// getInlayHints sends a textDocument/inlayHint request to gopls.
+func (c *goplsClient) getInlayHints(
+	ctx context.Context, relativePath string, startLine, startChar, endLine, endChar int,
+) (
+	[]InlayHint, error) {
+	c.logger.Debug("getInlayHints called",
+		"relativePath", relativePath, "startLine", startLine,
+		"startChar", startChar, "endLine", endLine, "endChar", endChar)
 
-	var inlayHints []InlayHint
-	for _, hintData := range hintsData {
-		if hintMap, ok := hintData.(map[string]any); ok {
-			inlayHint := c.parseInlayHint(hintMap)
-			inlayHints = append(inlayHints, inlayHint)
-		}
+	if err := c.waitUntilRunning(ctx); err != nil {
+		return nil, err
 	}
 
-	return inlayHints, nil
-}
-
-// parseInlayHint parses an inlay hint from a map.
-func (c *goplsClient) parseInlayHint(hintMap map[string]any) InlayHint {
-	var inlayHint InlayHint
-
-	if positionMap, ok := hintMap["position"].(map[string]any); ok {
-		if line, lineOk := positionMap["line"].(float64); lineOk {
-			inlayHint.Position.Line = int(line)
-		}
-		if char, charOk := positionMap["character"].(float64); charOk {
-			inlayHint.Position.Character = int(char)
-		}
+	// Ensure file is open in gopls
+	if err := c.ensureFileOpen(relativePath); err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 
-	if label, ok := hintMap["label"].(string); ok {
-		inlayHint.Label = label
+	params := InlayHintParams{
+		TextDocument: TextDocumentIdentifier{URI: c.relativePathToURI(relativePath)},
+		Range: Range{
+			Start: Position{Line: startLine, Character: startChar},
+			End:   Position{Line: endLine, Character: endChar},
+		},
 	}
 
-	if kind, ok := hintMap["kind"].(float64); ok {
-		inlayHint.Kind = int(kind)
+	// Send textDocument/inlayHint request and wait for response
+	var inlayHints []InlayHint
+	if err := c.call(ctx, "textDocument/inlayHint", params, &inlayHints); err != nil {
+		return nil, fmt.Errorf("failed to get inlay hints: %w", err)
 	}
 
-	if tooltip, ok := hintMap["tooltip"].(string); ok {
-		inlayHint.Tooltip = tooltip
+	if inlayHints == nil {
+		return []InlayHint{}, nil
 	}
 
-	return inlayHint
+	return inlayHints, nil
 }
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/MegaGrindStone/gopls-mcp/internal/fakegopls"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// unformattedGo is a file with extra spaces before the body of both functions.
+const unformattedGo = "package main\n\nfunc a()  {}\n\nfunc b()  {}\n"
+
+// formattingScript scripts a gopls that removes the extra spaces in unformattedGo.
+// With rangeFormatting it also advertises and answers range formatting requests.
+func formattingScript(rangeFormatting bool) *fakegopls.Script {
+	edit := func(line int) string {
+		return fmt.Sprintf(`{"range":{"start":{"line":%d,"character":8},"end":{"line":%d,"character":10}},`+
+			`"newText":" "}`, line, line)
+	}
+
+	script := &fakegopls.Script{
+		Responses: []fakegopls.Response{
+			{
+				Method: "textDocument/formatting",
+				Params: json.RawMessage(`{"textDocument":{"uri":"${rootUri}/bad.go"}}`),
+				Result: json.RawMessage(`[` + edit(2) + `,` + edit(4) + `]`),
+			},
+			{
+				Method: "textDocument/formatting",
+				Result: json.RawMessage(`[]`),
+			},
+		},
+	}
+	if rangeFormatting {
+		script.Initialize = json.RawMessage(`{"capabilities":{"positionEncoding":"utf-8",` +
+			`"documentFormattingProvider":true,"documentRangeFormattingProvider":true}}`)
+		script.Responses = append(script.Responses, fakegopls.Response{
+			Method: "textDocument/rangeFormatting",
+			Params: json.RawMessage(`{"range":{"start":{"line":4},"end":{"line":5}}}`),
+			Result: json.RawMessage(`[` + edit(4) + `]`),
+		})
+	}
+	return script
+}
+
+func TestFormatDocumentModes(t *testing.T) {
+	tests := []struct {
+		name            string
+		rangeFormatting bool
+	}{
+		{name: "whole document edits filtered to the range", rangeFormatting: false},
+		{name: "range formatting", rangeFormatting: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspacePath := newFakeGoplsWorkspace(t)
+			badPath := filepath.Join(workspacePath, "bad.go")
+			if err := os.WriteFile(badPath, []byte(unformattedGo), 0644); err != nil {
+				t.Fatalf("failed to write bad.go: %v", err)
+			}
+			config := fakeGoplsConfig(t, writeFakeGoplsScript(t, formattingScript(tt.rangeFormatting)))
+			client := startFakeGoplsClient(t, workspacePath, config)
+			session := connectMCP(t, map[string]*goplsClient{workspacePath: client})
+
+			// Only the second function is in the range
+			arguments := map[string]any{
+				"workspace": workspacePath, "path": "bad.go", "startLine": 5, "endLine": 5, "mode": "check",
+			}
+			check := callTool[FormatDocumentResult](t, session, toolFormatDocument, arguments)
+			if check.Clean || check.Applied || len(check.Edits) != 1 || check.Edits[0].Range.Line != 5 {
+				t.Errorf("Unexpected check result: %+v", check)
+			}
+			if check.Diff != "--- a/bad.go\n+++ b/bad.go\n@@ -2,4 +2,4 @@\n \n func a()  {}\n \n-func b()  {}\n+func b() {}\n" {
+				t.Errorf("Unexpected check diff:\n%s", check.Diff)
+			}
+
+			arguments["mode"] = "apply"
+			applied := callTool[FormatDocumentResult](t, session, toolFormatDocument, arguments)
+			content, err := os.ReadFile(badPath)
+			if err != nil || !applied.Applied || string(content) != "package main\n\nfunc a()  {}\n\nfunc b() {}\n" {
+				t.Errorf("Expected the range to be formatted, got %+v, %q, %v", applied, content, err)
+			}
+
+			clean := callTool[FormatDocumentResult](t, session, toolFormatDocument,
+				map[string]any{"workspace": workspacePath, "path": "main.go", "mode": "check"})
+			if !clean.Clean || clean.Diff != "" || len(clean.Edits) != 0 {
+				t.Errorf("Expected main.go to be clean, got %+v", clean)
+			}
+		})
+	}
+}
+
+func TestFormatDocumentRejectsInvalidArguments(t *testing.T) {
+	tools, _ := createTestMCPTools()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, arguments := range []FormatDocumentParams{
+		{Workspace: "/test/workspace", Path: "main.go", Mode: "rewrite"},
+		{Workspace: "/test/workspace", Path: "main.go", StartLine: 5, EndLine: 3},
+		{Workspace: "/test/workspace", Path: "main.go", EndLine: 3},
+	} {
+		if _, err := tools.HandleFormatDocument(ctx, nil,
+			&mcp.CallToolParamsFor[FormatDocumentParams]{Arguments: arguments}); err == nil {
+			t.Errorf("Expected %+v to be rejected", arguments)
+		}
+	}
+}
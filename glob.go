@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// compileGlob compiles an LSP glob pattern into a regular expression matching
+// slash-separated paths. Supported syntax: "*" and "?" within a path segment,
+// "**" across segments, "{a,b}" alternatives and "[...]" character ranges.
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	var builder strings.Builder
+	builder.WriteString("^")
+
+	runes := []rune(pattern)
+	depth := 0
+	for i := 0; i < len(runes); i++ {
+		ch := runes[i]
+		switch ch {
+		case '*':
+			if i+1 < len(runes) && runes[i+1] == '*' {
+				i++
+				switch {
+				case i+1 < len(runes) && runes[i+1] == '/':
+					// "**/" matches zero or more leading directories
+					i++
+					builder.WriteString("(?:.*/)?")
+				default:
+					builder.WriteString(".*")
+				}
+				continue
+			}
+			builder.WriteString("[^/]*")
+		case '?':
+			builder.WriteString("[^/]")
+		case '{':
+			depth++
+			builder.WriteString("(?:")
+		case '}':
+			if depth == 0 {
+				builder.WriteString(regexp.QuoteMeta("}"))
+				continue
+			}
+			depth--
+			builder.WriteString(")")
+		case ',':
+			if depth == 0 {
+				builder.WriteString(",")
+				continue
+			}
+			builder.WriteString("|")
+		case '[':
+			class, ok := globCharClass(runes[i+1:])
+			if !ok {
+				builder.WriteString(regexp.QuoteMeta("["))
+				continue
+			}
+			builder.WriteString(class)
+			i += len([]rune(class)) - 1
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced braces in glob pattern: %s", pattern)
+	}
+
+	builder.WriteString("$")
+	re, err := regexp.Compile(builder.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob pattern %s: %w", pattern, err)
+	}
+	return re, nil
+}
+
+// globCharClass converts the body of a "[...]" range that follows an opening
+// bracket into a regular expression character class of the same rune length.
+// It reports false when the range is never closed.
+func globCharClass(runes []rune) (string, bool) {
+	for end, r := range runes {
+		if r != ']' {
+			continue
+		}
+		body := runes[:end]
+		if len(body) > 0 && body[0] == '!' {
+			body = append([]rune{'^'}, body[1:]...)
+		}
+		return "[" + string(body) + "]", true
+	}
+	return "", false
+}
@@ -0,0 +1,41 @@
+package main
+
+import "testing"
+
+func TestCompileGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		match   bool
+	}{
+		{pattern: "**/*.go", path: "main.go", match: true},
+		{pattern: "**/*.go", path: "pkg/sub/client.go", match: true},
+		{pattern: "**/*.go", path: "pkg/client.go.orig", match: false},
+		{pattern: "*.go", path: "pkg/client.go", match: false},
+		{pattern: "**/*.{go,mod,sum,work}", path: "go.work", match: true},
+		{pattern: "**/*.{go,mod,sum,work}", path: "docs/readme.md", match: false},
+		{pattern: "/ws/**", path: "/ws/a/b.go", match: true},
+		{pattern: "/ws/**", path: "/other/b.go", match: false},
+		{pattern: "file?.go", path: "file1.go", match: true},
+		{pattern: "file[0-9].go", path: "file7.go", match: true},
+		{pattern: "file[!0-9].go", path: "file7.go", match: false},
+		{pattern: "**/{pkg,cmd}", path: "internal/pkg", match: true},
+		{pattern: "données/*.go", path: "données/main.go", match: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			re, err := compileGlob(tt.pattern)
+			if err != nil {
+				t.Fatalf("compileGlob failed: %v", err)
+			}
+			if got := re.MatchString(tt.path); got != tt.match {
+				t.Errorf("match(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.match)
+			}
+		})
+	}
+
+	if _, err := compileGlob("**/*.{go,mod"); err == nil {
+		t.Error("Expected error for unbalanced braces")
+	}
+}
@@ -0,0 +1,346 @@
+// Package fakegopls implements a scripted stand-in for gopls. It speaks the
+// LSP base protocol over a reader and a writer, answers requests from a
+// script and pushes scripted server messages, so that the whole gopls-mcp
+// stack can be tested without a Go toolchain or a gopls binary.
+package fakegopls
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Placeholders replaced in scripts by the workspace the client initializes.
+const (
+	// RootURIPlaceholder stands for the rootUri of the initialize request.
+	RootURIPlaceholder = "${rootUri}"
+	// RootPathPlaceholder stands for the file system path of the rootUri.
+	RootPathPlaceholder = "${rootPath}"
+)
+
+// methodNotFound is the JSON-RPC error code for unscripted requests.
+const methodNotFound = -32601
+
+// defaultInitializeResult advertises the capabilities gopls-mcp relies on.
+const defaultInitializeResult = `{
+	"capabilities": {
+		"positionEncoding": "utf-8",
+		"textDocumentSync": {"openClose": true, "change": 2, "save": {}},
+		"hoverProvider": true,
+		"completionProvider": {},
+		"signatureHelpProvider": {},
+		"definitionProvider": true,
+		"typeDefinitionProvider": true,
+		"implementationProvider": true,
+		"referencesProvider": true,
+		"documentSymbolProvider": true,
+		"workspaceSymbolProvider": true,
+		"codeActionProvider": true,
+		"documentFormattingProvider": true,
+		"renameProvider": {"prepareProvider": true},
+		"callHierarchyProvider": true,
+		"inlayHintProvider": {}
+	},
+	"serverInfo": {"name": "fakegopls", "version": "v0.0.0"}
+}`
+
+// Script describes how the fake server talks to a client.
+type Script struct {
+	// Initialize is the result of the initialize request. A result advertising
+	// the capabilities gopls-mcp uses is sent when it is empty.
+	Initialize json.RawMessage `json:"initialize,omitempty"`
+	// Responses answer client requests. The first unused response whose
+	// method and params match the request is sent.
+	Responses []Response `json:"responses,omitempty"`
+	// Messages are pushed to the client when it sends a matching message.
+	Messages []Message `json:"messages,omitempty"`
+}
+
+// Response is a scripted answer to a client request.
+type Response struct {
+	Method string `json:"method"`
+	// Params, when set, must be contained in the request params: objects
+	// match when every scripted key matches, other values must be equal.
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *ResponseError  `json:"error,omitempty"`
+	// Once limits the response to the first matching request.
+	Once bool `json:"once,omitempty"`
+}
+
+// ResponseError is a JSON-RPC error sent instead of a result.
+type ResponseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Message is a notification or request the server sends on its own.
+type Message struct {
+	// After is the method of the client message that triggers this message.
+	After string `json:"after"`
+	// AfterParams, when set, must be contained in the trigger's params.
+	AfterParams json.RawMessage `json:"afterParams,omitempty"`
+	Method      string          `json:"method"`
+	Params      json.RawMessage `json:"params,omitempty"`
+	// Request sends the message as a request; the client's reply is ignored.
+	Request bool `json:"request,omitempty"`
+	// Once limits the message to the first matching trigger.
+	Once bool `json:"once,omitempty"`
+}
+
+// LoadScript reads a JSON script file. Files ending in .jsonl are read as
+// LSP traces recorded by gopls-mcp and converted with ScriptFromTrace.
+func LoadScript(path string) (*Script, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read script: %w", err)
+	}
+	if strings.HasSuffix(path, ".jsonl") {
+		return ScriptFromTrace(bytes.NewReader(data))
+	}
+
+	var script Script
+	if err := json.Unmarshal(data, &script); err != nil {
+		return nil, fmt.Errorf("failed to parse script %s: %w", path, err)
+	}
+	return &script, nil
+}
+
+// frame is a JSON-RPC message exchanged with the client.
+type frame struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *ResponseError  `json:"error,omitempty"`
+}
+
+// server holds the state of one conversation with a client.
+type server struct {
+	script    *Script
+	writer    io.Writer
+	writeMux  sync.Mutex
+	replacer  *strings.Replacer
+	used      []bool
+	fired     []bool
+	requestID int
+}
+
+// Serve answers the client on r and w according to script until the client
+// sends exit or closes r.
+func Serve(r io.Reader, w io.Writer, script *Script) error {
+	s := &server{
+		script:   script,
+		writer:   w,
+		replacer: strings.NewReplacer(),
+		used:     make([]bool, len(script.Responses)),
+		fired:    make([]bool, len(script.Messages)),
+	}
+
+	reader := bufio.NewReader(r)
+	for {
+		message, err := readFrame(reader)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if message.Method == "exit" {
+			return nil
+		}
+		if err := s.handle(message); err != nil {
+			return err
+		}
+	}
+}
+
+// readFrame reads one Content-Length framed message.
+func readFrame(reader *bufio.Reader) (*frame, error) {
+	header, err := textproto.NewReader(reader).ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length: %w", err)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+
+	var message frame
+	if err := json.Unmarshal(body, &message); err != nil {
+		return nil, fmt.Errorf("failed to parse message: %w", err)
+	}
+	return &message, nil
+}
+
+// handle answers a client message and sends the messages it triggers.
+func (s *server) handle(message *frame) error {
+	if message.Method == "" {
+		// Replies to requests of the server are not checked
+		return nil
+	}
+	if message.Method == "initialize" {
+		s.learnRoot(message.Params)
+	}
+
+	for i, scripted := range s.script.Messages {
+		if s.fired[i] || scripted.After != message.Method || !s.matches(scripted.AfterParams, message.Params) {
+			continue
+		}
+		s.fired[i] = scripted.Once
+		if err := s.send(scripted); err != nil {
+			return err
+		}
+	}
+
+	if len(message.ID) == 0 {
+		return nil
+	}
+	return s.reply(message)
+}
+
+// learnRoot sets up the placeholders for the workspace in the initialize params.
+func (s *server) learnRoot(params json.RawMessage) {
+	var initialize struct {
+		RootURI string `json:"rootUri"`
+	}
+	if json.Unmarshal(params, &initialize) != nil || initialize.RootURI == "" {
+		return
+	}
+	s.replacer = strings.NewReplacer(
+		RootURIPlaceholder, initialize.RootURI,
+		RootPathPlaceholder, strings.TrimPrefix(initialize.RootURI, "file://"),
+	)
+}
+
+// reply answers a client request with the first matching scripted response.
+func (s *server) reply(request *frame) error {
+	response := &frame{JSONRPC: "2.0", ID: request.ID}
+
+	switch scripted := s.response(request); {
+	case scripted != nil:
+		response.Result = s.expand(scripted.Result)
+		response.Error = scripted.Error
+		if response.Error == nil && len(response.Result) == 0 {
+			response.Result = json.RawMessage("null")
+		}
+	case request.Method == "initialize":
+		response.Result = s.expand(s.script.Initialize)
+		if len(response.Result) == 0 {
+			response.Result = json.RawMessage(defaultInitializeResult)
+		}
+	case request.Method == "shutdown":
+		response.Result = json.RawMessage("null")
+	default:
+		response.Error = &ResponseError{Code: methodNotFound, Message: "no scripted response for " + request.Method}
+	}
+	return s.write(response)
+}
+
+// response returns the scripted response for a request, if any.
+func (s *server) response(request *frame) *Response {
+	for i := range s.script.Responses {
+		scripted := &s.script.Responses[i]
+		if s.used[i] || scripted.Method != request.Method || !s.matches(scripted.Params, request.Params) {
+			continue
+		}
+		s.used[i] = scripted.Once
+		return scripted
+	}
+	return nil
+}
+
+// send pushes a scripted notification or request to the client.
+func (s *server) send(scripted Message) error {
+	message := &frame{JSONRPC: "2.0", Method: scripted.Method, Params: s.expand(scripted.Params)}
+	if scripted.Request {
+		s.requestID++
+		message.ID = json.RawMessage(strconv.Quote("fake-" + strconv.Itoa(s.requestID)))
+	}
+	return s.write(message)
+}
+
+// write sends a message to the client.
+func (s *server) write(message *frame) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	s.writeMux.Lock()
+	defer s.writeMux.Unlock()
+
+	if _, err := fmt.Fprintf(s.writer, "Content-Length: %d\r\n\r\n%s", len(data), data); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	return nil
+}
+
+// expand replaces the workspace placeholders in scripted JSON.
+func (s *server) expand(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 {
+		return nil
+	}
+	return json.RawMessage(s.replacer.Replace(string(raw)))
+}
+
+// matches reports whether the scripted params are contained in the actual params.
+func (s *server) matches(scripted, actual json.RawMessage) bool {
+	if len(scripted) == 0 {
+		return true
+	}
+
+	var want, got any
+	if json.Unmarshal(s.expand(scripted), &want) != nil || json.Unmarshal(actual, &got) != nil {
+		return false
+	}
+	return contains(got, want)
+}
+
+// contains reports whether got holds every key of the objects in want and
+// equals want everywhere else.
+func contains(got, want any) bool {
+	switch want := want.(type) {
+	case map[string]any:
+		gotObject, ok := got.(map[string]any)
+		if !ok {
+			return false
+		}
+		for key, value := range want {
+			if !contains(gotObject[key], value) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		gotArray, ok := got.([]any)
+		if !ok || len(gotArray) != len(want) {
+			return false
+		}
+		for i := range want {
+			if !contains(gotArray[i], want[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(got, want)
+	}
+}
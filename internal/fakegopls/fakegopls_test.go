@@ -0,0 +1,146 @@
+package fakegopls
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// testClient drives a fake server over pipes.
+type testClient struct {
+	t      *testing.T
+	writer *io.PipeWriter
+	reader *bufio.Reader
+	done   chan error
+}
+
+// startServer serves script to a test client.
+func startServer(t *testing.T, script *Script) *testClient {
+	t.Helper()
+
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- Serve(serverReader, serverWriter, script)
+		_ = serverWriter.Close()
+	}()
+	t.Cleanup(func() { _ = clientWriter.Close() })
+
+	return &testClient{t: t, writer: clientWriter, reader: bufio.NewReader(clientReader), done: done}
+}
+
+// send writes a message to the server.
+func (c *testClient) send(message string) {
+	c.t.Helper()
+
+	if _, err := fmt.Fprintf(c.writer, "Content-Length: %d\r\n\r\n%s", len(message), message); err != nil {
+		c.t.Fatalf("failed to send: %v", err)
+	}
+}
+
+// receive reads the next message from the server.
+func (c *testClient) receive() *frame {
+	c.t.Helper()
+
+	message, err := readFrame(c.reader)
+	if err != nil {
+		c.t.Fatalf("failed to receive: %v", err)
+	}
+	return message
+}
+
+func TestServeScript(t *testing.T) {
+	client := startServer(t, &Script{
+		Responses: []Response{
+			{
+				Method: "textDocument/hover",
+				Params: json.RawMessage(`{"position":{"line":1}}`),
+				Result: json.RawMessage(`{"contents":"${rootPath}/main.go"}`),
+				Once:   true,
+			},
+			{Method: "textDocument/hover", Result: json.RawMessage(`{"contents":"fallback"}`)},
+		},
+		Messages: []Message{{
+			After:  "textDocument/didOpen",
+			Method: "textDocument/publishDiagnostics",
+			Params: json.RawMessage(`{"uri":"${rootUri}/main.go","diagnostics":[]}`),
+		}},
+	})
+
+	client.send(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"rootUri":"file:///work"}}`)
+	if response := client.receive(); !strings.Contains(string(response.Result), `"fakegopls"`) {
+		t.Fatalf("Expected default initialize result, got %s", response.Result)
+	}
+
+	client.send(`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{}}`)
+	if message := client.receive(); message.Method != "textDocument/publishDiagnostics" ||
+		!strings.Contains(string(message.Params), `"file:///work/main.go"`) {
+		t.Fatalf("Expected diagnostics after didOpen, got %+v", message)
+	}
+
+	hover := `{"jsonrpc":"2.0","id":2,"method":"textDocument/hover","params":{"position":{"line":1,"character":4}}}`
+	client.send(hover)
+	if response := client.receive(); string(response.Result) != `{"contents":"/work/main.go"}` {
+		t.Errorf("Expected matching scripted hover, got %s", response.Result)
+	}
+	client.send(hover)
+	if response := client.receive(); string(response.Result) != `{"contents":"fallback"}` {
+		t.Errorf("Expected one-time response to be used up, got %s", response.Result)
+	}
+
+	client.send(`{"jsonrpc":"2.0","id":3,"method":"textDocument/rename","params":{}}`)
+	if response := client.receive(); response.Error == nil || response.Error.Code != methodNotFound {
+		t.Errorf("Expected method not found for unscripted request, got %+v", response)
+	}
+
+	client.send(`{"jsonrpc":"2.0","method":"exit"}`)
+	if err := <-client.done; err != nil {
+		t.Errorf("Expected clean exit, got %v", err)
+	}
+}
+
+func TestScriptFromTrace(t *testing.T) {
+	trace := strings.Join([]string{
+		`{"direction":"send","kind":"request","id":1,"method":"initialize",` +
+			`"message":{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"processId":7,"rootUri":"file:///rec"}}}`,
+		`{"direction":"receive","kind":"response","id":1,"method":"initialize",` +
+			`"message":{"jsonrpc":"2.0","id":1,"result":{"capabilities":{}}}}`,
+		`{"direction":"send","kind":"notification","method":"textDocument/didOpen",` +
+			`"message":{"jsonrpc":"2.0","method":"textDocument/didOpen",` +
+			`"params":{"textDocument":{"uri":"file:///rec/a.go","version":1,"text":"package a"}}}}`,
+		`{"direction":"receive","kind":"notification","method":"textDocument/publishDiagnostics",` +
+			`"message":{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics",` +
+			`"params":{"uri":"file:///rec/a.go","diagnostics":[]}}}`,
+		`{"direction":"send","kind":"request","id":2,"method":"textDocument/definition",` +
+			`"message":{"jsonrpc":"2.0","id":2,"method":"textDocument/definition",` +
+			`"params":{"textDocument":{"uri":"file:///rec/a.go"},"position":{"line":0,"character":8}}}}`,
+		`{"direction":"receive","kind":"response","id":2,"method":"textDocument/definition",` +
+			`"message":{"jsonrpc":"2.0","id":2,"result":[{"uri":"file:///rec/a.go"}]}}`,
+	}, "\n")
+
+	script, err := ScriptFromTrace(strings.NewReader(trace))
+	if err != nil {
+		t.Fatalf("ScriptFromTrace failed: %v", err)
+	}
+
+	if string(script.Initialize) != `{"capabilities":{}}` {
+		t.Errorf("Unexpected initialize result: %s", script.Initialize)
+	}
+	if len(script.Messages) != 1 || script.Messages[0].After != "textDocument/didOpen" ||
+		string(script.Messages[0].AfterParams) != `{"textDocument":{"uri":"${rootUri}/a.go"}}` {
+		t.Errorf("Unexpected messages: %+v", script.Messages)
+	}
+	if len(script.Responses) != 1 {
+		t.Fatalf("Expected 1 response, got %d", len(script.Responses))
+	}
+	response := script.Responses[0]
+	if response.Method != "textDocument/definition" || !response.Once ||
+		string(response.Params) != `{"position":{"line":0,"character":8},"textDocument":{"uri":"${rootUri}/a.go"}}` ||
+		string(response.Result) != `[{"uri":"${rootUri}/a.go"}]` {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+}
@@ -0,0 +1,173 @@
+package fakegopls
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxTraceLine bounds a single line of a trace file.
+const maxTraceLine = 64 << 20
+
+// identifyingParams are the request params used to match replayed requests.
+// Everything else, such as process IDs or document versions, may differ
+// between the recording and the replay.
+var identifyingParams = []string{"textDocument", "position", "range", "query", "command", "arguments", "item"}
+
+// traceEntry is one line of an LSP trace written by gopls-mcp.
+type traceEntry struct {
+	Direction string          `json:"direction"`
+	Kind      string          `json:"kind"`
+	ID        json.RawMessage `json:"id,omitempty"`
+	Method    string          `json:"method,omitempty"`
+	Message   json.RawMessage `json:"message"`
+}
+
+// sentMessage is a client message of the trace.
+type sentMessage struct {
+	method string
+	params json.RawMessage
+}
+
+// ScriptFromTrace converts an LSP trace recorded with -trace-dir into a script
+// that replays the recorded gopls. Each recorded response answers its request
+// once, and messages gopls sent on its own follow the client message that
+// preceded them. The recorded workspace is replaced by the root placeholders.
+func ScriptFromTrace(r io.Reader) (*Script, error) {
+	script := &Script{}
+	requests := make(map[string]sentMessage)
+	var last *sentMessage
+	var rootURI string
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxTraceLine)
+	for scanner.Scan() {
+		var entry traceEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse trace line: %w", err)
+		}
+		var message frame
+		if err := json.Unmarshal(entry.Message, &message); err != nil {
+			// Frames that were not valid JSON are recorded as strings
+			continue
+		}
+
+		switch {
+		case entry.Direction == "send" && entry.Kind != "response":
+			sent := sentMessage{method: message.Method, params: message.Params}
+			if message.Method == "initialize" {
+				rootURI = traceRootURI(message.Params)
+			}
+			if entry.Kind == "request" {
+				requests[string(entry.ID)] = sent
+			}
+			last = &sent
+		case entry.Direction == "receive" && entry.Kind == "response":
+			request, exists := requests[string(entry.ID)]
+			if !exists {
+				continue
+			}
+			delete(requests, string(entry.ID))
+			if request.method == "initialize" {
+				script.Initialize = message.Result
+				continue
+			}
+			script.Responses = append(script.Responses, Response{
+				Method: request.method,
+				Params: selectParams(request.params),
+				Result: message.Result,
+				Error:  message.Error,
+				Once:   true,
+			})
+		case entry.Direction == "receive":
+			if last == nil {
+				continue
+			}
+			script.Messages = append(script.Messages, Message{
+				After:       last.method,
+				AfterParams: selectParams(last.params),
+				Method:      message.Method,
+				Params:      message.Params,
+				Request:     entry.Kind == "request",
+				Once:        true,
+			})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read trace: %w", err)
+	}
+
+	if rootURI != "" {
+		if err := generalizeRoot(script, rootURI); err != nil {
+			return nil, err
+		}
+	}
+	return script, nil
+}
+
+// traceRootURI returns the rootUri of recorded initialize params.
+func traceRootURI(params json.RawMessage) string {
+	var initialize struct {
+		RootURI string `json:"rootUri"`
+	}
+	_ = json.Unmarshal(params, &initialize)
+	return initialize.RootURI
+}
+
+// selectParams keeps the identifying params of a recorded client message.
+// Document identifiers are reduced to their URI.
+func selectParams(params json.RawMessage) json.RawMessage {
+	var object map[string]json.RawMessage
+	if json.Unmarshal(params, &object) != nil {
+		return nil
+	}
+
+	selected := make(map[string]json.RawMessage)
+	for _, key := range identifyingParams {
+		value, exists := object[key]
+		if !exists {
+			continue
+		}
+		if key == "textDocument" {
+			var document struct {
+				URI string `json:"uri"`
+			}
+			if json.Unmarshal(value, &document) != nil {
+				continue
+			}
+			value, _ = json.Marshal(map[string]string{"uri": document.URI})
+		}
+		selected[key] = value
+	}
+	if len(selected) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(selected)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// generalizeRoot replaces the recorded workspace with the root placeholders so
+// that the script can be replayed in any directory.
+func generalizeRoot(script *Script, rootURI string) error {
+	replacer := strings.NewReplacer(
+		rootURI, RootURIPlaceholder,
+		strings.TrimPrefix(rootURI, "file://"), RootPathPlaceholder,
+	)
+
+	data, err := json.Marshal(script)
+	if err != nil {
+		return fmt.Errorf("failed to encode script: %w", err)
+	}
+	generalized := Script{}
+	if err := json.Unmarshal([]byte(replacer.Replace(string(data))), &generalized); err != nil {
+		return fmt.Errorf("failed to generalize script: %w", err)
+	}
+	*script = generalized
+	return nil
+}
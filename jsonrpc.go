@@ -0,0 +1,351 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const jsonrpcVersion = "2.0"
+
+// defaultRequestTimeout bounds LSP requests whose context carries no deadline.
+const defaultRequestTimeout = 30 * time.Second
+
+// JSON-RPC and LSP error codes.
+const (
+	jsonrpcInvalidParams    = -32602
+	jsonrpcMethodNotFound   = -32601
+	jsonrpcInternalError    = -32603
+	jsonrpcRequestCancelled = -32800
+)
+
+// JSON-RPC 2.0 message types for gopls communication
+
+// RequestMessage represents a JSON-RPC request sent to gopls.
+type RequestMessage struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      int    `json:"id"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+// NotificationMessage represents a JSON-RPC notification sent to gopls.
+type NotificationMessage struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+// ResponseMessage represents a JSON-RPC response to a request.
+type ResponseMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *ResponseError  `json:"error,omitempty"`
+}
+
+// ResponseError represents the error object of a JSON-RPC response.
+type ResponseError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// Error implements the error interface.
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("LSP error %d: %s", e.Code, e.Message)
+}
+
+// incomingMessage is any JSON-RPC message read from gopls before it is classified.
+type incomingMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *ResponseError  `json:"error,omitempty"`
+}
+
+// isResponse reports whether the message is a response to one of our requests.
+func (m *incomingMessage) isResponse() bool {
+	return m.Method == "" && m.ID != nil
+}
+
+// isRequest reports whether the message is a request that expects a response.
+func (m *incomingMessage) isRequest() bool {
+	return m.Method != "" && m.ID != nil
+}
+
+// isNotification reports whether the message is a notification.
+func (m *incomingMessage) isNotification() bool {
+	return m.Method != "" && m.ID == nil
+}
+
+// call sends a request to gopls, waits for its response and decodes the result into result.
+// A nil result discards the response payload. When ctx is cancelled before gopls answers,
+// the request is cancelled on the gopls side with $/cancelRequest.
+func (c *goplsClient) call(ctx context.Context, method string, params, result any) error {
+	// Fall back to the default timeout when the caller did not set a deadline
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultRequestTimeout)
+		defer cancel()
+	}
+
+	// Wait for a slot so heavy requests cannot pile up inside gopls
+	release, err := c.scheduleRequest(ctx, method)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	id := c.nextRequestID()
+
+	// Create response channel
+	responseCh := make(chan *ResponseMessage, 1)
+	c.responsesMux.Lock()
+	if c.draining && method != "shutdown" {
+		c.responsesMux.Unlock()
+		return errShuttingDown
+	}
+	c.responses[id] = responseCh
+	c.responsesMux.Unlock()
+
+	request := RequestMessage{
+		JSONRPC: jsonrpcVersion,
+		ID:      id,
+		Method:  method,
+		Params:  params,
+	}
+
+	c.logger.Debug("sending LSP request", "method", method, "id", id)
+	if err := c.writeMessage(request); err != nil {
+		c.forgetResponse(id)
+		return err
+	}
+
+	// Wait for response or cancellation
+	c.logger.Debug("waiting for response", "requestID", id)
+	select {
+	case response := <-responseCh:
+		c.logger.Debug("received response", "requestID", id)
+		if response.Error != nil {
+			return response.Error
+		}
+		if result == nil || len(response.Result) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(response.Result, result); err != nil {
+			return fmt.Errorf("failed to decode %s result: %w", method, err)
+		}
+		return nil
+
+	case <-ctx.Done():
+		c.forgetResponse(id)
+		c.cancelRequest(id)
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("timeout waiting for response to request %d (%s): %w", id, method, ctx.Err())
+		}
+		return fmt.Errorf("request %d (%s) cancelled: %w", id, method, ctx.Err())
+	}
+}
+
+// cancelRequest asks gopls to stop working on an abandoned request.
+func (c *goplsClient) cancelRequest(id int) {
+	c.logger.Debug("cancelling LSP request", "requestID", id)
+	if err := c.notify("$/cancelRequest", CancelParams{ID: id}); err != nil {
+		c.logger.Debug("failed to send cancel request", "requestID", id, "error", err)
+	}
+}
+
+// notify sends a notification to gopls.
+func (c *goplsClient) notify(method string, params any) error {
+	notification := NotificationMessage{
+		JSONRPC: jsonrpcVersion,
+		Method:  method,
+		Params:  params,
+	}
+
+	c.logger.Debug("sending LSP notification", "method", method)
+	return c.writeMessage(notification)
+}
+
+// writeMessage serializes a JSON-RPC message and writes it to gopls stdin.
+// Writes are serialized so concurrent callers never interleave frames.
+func (c *goplsClient) writeMessage(message any) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	c.writeMux.Lock()
+	defer c.writeMux.Unlock()
+
+	if c.stdin == nil {
+		return fmt.Errorf("gopls is not running")
+	}
+	c.tracer.record(traceDirectionSend, data)
+
+	// LSP uses Content-Length header format
+	header := fmt.Sprintf("Content-Length: %d\r\n\r\n", len(data))
+	if _, err := io.WriteString(c.stdin, header); err != nil {
+		return fmt.Errorf("failed to write message header: %w", err)
+	}
+	if _, err := c.stdin.Write(data); err != nil {
+		return fmt.Errorf("failed to write message body: %w", err)
+	}
+
+	return nil
+}
+
+// forgetResponse removes a pending response channel.
+func (c *goplsClient) forgetResponse(id int) {
+	c.responsesMux.Lock()
+	delete(c.responses, id)
+	c.responsesMux.Unlock()
+}
+
+// nextRequestID generates the next request ID for LSP communication.
+func (c *goplsClient) nextRequestID() int {
+	c.requestIDMux.Lock()
+	defer c.requestIDMux.Unlock()
+	c.requestID++
+	return c.requestID
+}
+
+// messageReader continuously reads messages from gopls stdout.
+func (c *goplsClient) messageReader(stdout io.Reader) {
+	c.logger.Debug("starting message reader")
+	reader := bufio.NewReader(stdout)
+
+	for {
+		message, err := c.readLSPMessage(reader)
+		if err != nil {
+			if c.isRunning() {
+				c.logger.Error("error reading LSP message", "error", err)
+			}
+			c.logger.Debug("message reader exited")
+			return
+		}
+
+		c.handleLSPMessage(message)
+	}
+}
+
+// readLSPMessage reads a single LSP message from the reader.
+func (c *goplsClient) readLSPMessage(reader *bufio.Reader) (*incomingMessage, error) {
+	// Read headers
+	contentLength := -1
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("failed to read header line: %w", err)
+		}
+
+		// Trim line ending
+		line = strings.TrimSuffix(line, "\n")
+		line = strings.TrimSuffix(line, "\r")
+
+		// Empty line marks end of headers
+		if line == "" {
+			break
+		}
+
+		// Parse header
+		name, value, found := strings.Cut(line, ":")
+		if !found || !strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			continue
+		}
+		contentLength, err = strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || contentLength < 0 {
+			return nil, fmt.Errorf("invalid Content-Length: %s", value)
+		}
+	}
+
+	if contentLength < 0 {
+		return nil, fmt.Errorf("missing Content-Length header")
+	}
+
+	// Read content
+	content := make([]byte, contentLength)
+	if _, err := io.ReadFull(reader, content); err != nil {
+		return nil, fmt.Errorf("failed to read message content: %w", err)
+	}
+	c.tracer.record(traceDirectionReceive, content)
+
+	// Parse JSON
+	var message incomingMessage
+	if err := json.Unmarshal(content, &message); err != nil {
+		return nil, fmt.Errorf("failed to parse LSP message: %w", err)
+	}
+
+	return &message, nil
+}
+
+// handleLSPMessage routes an LSP message to the appropriate handler.
+func (c *goplsClient) handleLSPMessage(message *incomingMessage) {
+	c.logger.Debug("handleLSPMessage", "id", string(message.ID), "method", message.Method)
+
+	switch {
+	case message.isResponse():
+		// This is a response to our request
+		id, err := strconv.Atoi(string(message.ID))
+		if err != nil {
+			c.logger.Debug("response with non-integer ID", "id", string(message.ID))
+			return
+		}
+		c.logger.Debug("routing response", "requestID", id)
+		c.routeResponse(id, &ResponseMessage{
+			JSONRPC: message.JSONRPC,
+			ID:      message.ID,
+			Result:  message.Result,
+			Error:   message.Error,
+		})
+	case message.isNotification():
+		switch message.Method {
+		case "textDocument/publishDiagnostics":
+			c.handlePublishDiagnostics(message.Params)
+		case "$/progress":
+			c.handleProgress(message.Params)
+		case "window/showMessage":
+			c.handleShowMessage(message.Params)
+		case "window/logMessage":
+			c.handleLogMessage(message.Params)
+		default:
+			c.logger.Debug("received notification from gopls", "method", message.Method)
+		}
+	case message.isRequest():
+		c.handleServerRequest(message)
+	default:
+		c.logger.Debug("unhandled LSP message", "id", string(message.ID), "method", message.Method)
+	}
+}
+
+// routeResponse routes a response to the appropriate request handler.
+func (c *goplsClient) routeResponse(id int, response *ResponseMessage) {
+	c.responsesMux.Lock()
+	ch, ok := c.responses[id]
+	if ok {
+		delete(c.responses, id)
+	}
+	c.responsesMux.Unlock()
+
+	if ok {
+		select {
+		case ch <- response:
+			// Response delivered
+		default:
+			c.logger.Warn("response channel full", "requestID", id)
+		}
+	} else if response.Error != nil && response.Error.Code == jsonrpcRequestCancelled {
+		c.logger.Debug("received cancellation response for abandoned request", "requestID", id)
+	} else {
+		c.logger.Warn("received response for unknown request ID", "requestID", id)
+	}
+}
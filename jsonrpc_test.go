@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestReadLSPMessage(t *testing.T) {
+	client := newClient("/test/workspace", workspaceConfig{}, newTestLogger())
+
+	body := `{"jsonrpc":"2.0","id":3,"result":{"contents":"hello"}}`
+	frame := fmt.Sprintf("Content-Type: application/vscode-jsonrpc\r\nContent-Length: %d\r\n\r\n%s", len(body), body)
+
+	message, err := client.readLSPMessage(bufio.NewReader(strings.NewReader(frame)))
+	if err != nil {
+		t.Fatalf("readLSPMessage failed: %v", err)
+	}
+
+	if !message.isResponse() {
+		t.Error("Expected message to be classified as a response")
+	}
+	if string(message.ID) != "3" {
+		t.Errorf("Expected ID 3, got %s", message.ID)
+	}
+
+	// Missing Content-Length header must be rejected
+	_, err = client.readLSPMessage(bufio.NewReader(strings.NewReader("Content-Type: x\r\n\r\n{}")))
+	if err == nil {
+		t.Error("Expected error for missing Content-Length header")
+	}
+}
+
+func TestIncomingMessageClassification(t *testing.T) {
+	tests := []struct {
+		name         string
+		raw          string
+		response     bool
+		request      bool
+		notification bool
+	}{
+		{
+			name:     "response",
+			raw:      `{"jsonrpc":"2.0","id":1,"result":null}`,
+			response: true,
+		},
+		{
+			name:    "server request",
+			raw:     `{"jsonrpc":"2.0","id":"a","method":"workspace/configuration","params":{}}`,
+			request: true,
+		},
+		{
+			name:         "notification",
+			raw:          `{"jsonrpc":"2.0","method":"window/logMessage","params":{}}`,
+			notification: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var message incomingMessage
+			if err := json.Unmarshal([]byte(tt.raw), &message); err != nil {
+				t.Fatalf("failed to unmarshal message: %v", err)
+			}
+			if message.isResponse() != tt.response {
+				t.Errorf("isResponse = %v, want %v", message.isResponse(), tt.response)
+			}
+			if message.isRequest() != tt.request {
+				t.Errorf("isRequest = %v, want %v", message.isRequest(), tt.request)
+			}
+			if message.isNotification() != tt.notification {
+				t.Errorf("isNotification = %v, want %v", message.isNotification(), tt.notification)
+			}
+		})
+	}
+}
+
+func TestWriteMessageConcurrent(t *testing.T) {
+	client := newClient("/test/workspace", workspaceConfig{}, newTestLogger())
+
+	reader, writer := io.Pipe()
+	client.stdin = writer
+
+	const writers = 20
+	var wg sync.WaitGroup
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			params := map[string]string{"text": strings.Repeat("x", 4096)}
+			request := RequestMessage{JSONRPC: jsonrpcVersion, ID: i, Method: "test", Params: params}
+			if err := client.writeMessage(request); err != nil {
+				t.Errorf("writeMessage failed: %v", err)
+			}
+		}()
+	}
+
+	// Every frame must decode cleanly, which fails if writes interleave
+	bufReader := bufio.NewReader(reader)
+	seen := make(map[string]bool)
+	for range writers {
+		message, err := client.readLSPMessage(bufReader)
+		if err != nil {
+			t.Fatalf("readLSPMessage failed: %v", err)
+		}
+		seen[string(message.ID)] = true
+	}
+	wg.Wait()
+
+	if len(seen) != writers {
+		t.Errorf("Expected %d distinct messages, got %d", writers, len(seen))
+	}
+}
+
+func TestDecodeLocations(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		expected int
+		uri      string
+	}{
+		{name: "null", raw: `null`, expected: 0},
+		{name: "single location", raw: `{"uri":"file:///a.go","range":{}}`, expected: 1, uri: "file:///a.go"},
+		{
+			name:     "location array",
+			raw:      `[{"uri":"file:///a.go","range":{}},{"uri":"file:///b.go","range":{}}]`,
+			expected: 2,
+			uri:      "file:///a.go",
+		},
+		{
+			name: "location links",
+			raw: `[{"targetUri":"file:///c.go","targetRange":{},` +
+				`"targetSelectionRange":{"start":{"line":4,"character":1}}}]`,
+			expected: 1,
+			uri:      "file:///c.go",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			locations, err := decodeLocations(json.RawMessage(tt.raw))
+			if err != nil {
+				t.Fatalf("decodeLocations failed: %v", err)
+			}
+			if len(locations) != tt.expected {
+				t.Fatalf("Expected %d locations, got %d", tt.expected, len(locations))
+			}
+			if tt.expected > 0 && locations[0].URI != tt.uri {
+				t.Errorf("Expected URI %s, got %s", tt.uri, locations[0].URI)
+			}
+		})
+	}
+}
+
+func TestParseHoverContents(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		expected []string
+	}{
+		{name: "markup content", raw: `{"kind":"markdown","value":"func f()"}`, expected: []string{"func f()"}},
+		{name: "plain string", raw: `"text"`, expected: []string{"text"}},
+		{name: "marked strings", raw: `["a",{"language":"go","value":"b"}]`, expected: []string{"a", "b"}},
+		{name: "null", raw: `null`, expected: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contents := parseHoverContents(json.RawMessage(tt.raw))
+			if strings.Join(contents, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("Expected %v, got %v", tt.expected, contents)
+			}
+		})
+	}
+}
+
+func TestDecodeUnionFields(t *testing.T) {
+	var hint InlayHint
+	raw := `{"position":{"line":1,"character":2},"label":[{"value":"x"},{"value":":"}],` +
+		`"kind":2,"tooltip":{"kind":"markdown","value":"tip"}}`
+	if err := json.Unmarshal([]byte(raw), &hint); err != nil {
+		t.Fatalf("failed to unmarshal inlay hint: %v", err)
+	}
+	if hint.Label != "x:" || hint.Tooltip != "tip" || hint.Position.Character != 2 {
+		t.Errorf("Unexpected inlay hint: %+v", hint)
+	}
+
+	var diagnostic Diagnostic
+	if err := json.Unmarshal([]byte(`{"range":{},"severity":1,"code":42,"message":"m"}`), &diagnostic); err != nil {
+		t.Fatalf("failed to unmarshal diagnostic: %v", err)
+	}
+	if diagnostic.Code != "42" || diagnostic.Message != "m" {
+		t.Errorf("Unexpected diagnostic: %+v", diagnostic)
+	}
+
+	actions, err := decodeCodeActions(json.RawMessage(
+		`[{"title":"cmd","command":"gopls.tidy"},{"title":"fix","kind":"quickfix","edit":{"changes":{}}}]`))
+	if err != nil {
+		t.Fatalf("decodeCodeActions failed: %v", err)
+	}
+	if len(actions) != 2 || actions[0].Command == nil || actions[0].Command.Command != "gopls.tidy" {
+		t.Errorf("Unexpected bare command decoding: %+v", actions)
+	}
+	if actions[1].Edit == nil || actions[1].Kind != "quickfix" {
+		t.Errorf("Unexpected code action decoding: %+v", actions[1])
+	}
+}
+
+func TestCallCancellationSendsCancelRequest(t *testing.T) {
+	client := newClient("/test/workspace", workspaceConfig{}, newTestLogger())
+
+	reader, writer := io.Pipe()
+	client.stdin = writer
+	bufReader := bufio.NewReader(reader)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- client.call(ctx, "textDocument/hover", nil, nil)
+	}()
+
+	request, err := client.readLSPMessage(bufReader)
+	if err != nil {
+		t.Fatalf("failed to read request: %v", err)
+	}
+	if request.Method != "textDocument/hover" {
+		t.Fatalf("Expected hover request, got %s", request.Method)
+	}
+
+	cancel()
+
+	notification, err := client.readLSPMessage(bufReader)
+	if err != nil {
+		t.Fatalf("failed to read cancel notification: %v", err)
+	}
+	if notification.Method != "$/cancelRequest" {
+		t.Errorf("Expected $/cancelRequest, got %s", notification.Method)
+	}
+	var params CancelParams
+	if err := json.Unmarshal(notification.Params, &params); err != nil {
+		t.Fatalf("failed to decode cancel params: %v", err)
+	}
+	if fmt.Sprint(params.ID) != string(request.ID) {
+		t.Errorf("Expected cancel for request %s, got %d", request.ID, params.ID)
+	}
+
+	if err := <-errCh; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	client.responsesMux.Lock()
+	pending := len(client.responses)
+	client.responsesMux.Unlock()
+	if pending != 0 {
+		t.Errorf("Expected pending responses to be cleaned up, got %d", pending)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// minIdleCheckInterval bounds how often idle workspaces are looked for.
+const minIdleCheckInterval = time.Second
+
+// Workspace lifecycle states reported by list_workspaces.
+const (
+	lifecycleCold     = "cold"
+	lifecycleStarting = "starting"
+	lifecycleReady    = "ready"
+	lifecycleStopped  = "stopped"
+)
+
+// lifecycleTransition is a start or stop of gopls in progress.
+type lifecycleTransition struct {
+	starting bool
+	done     chan struct{}
+	err      error
+}
+
+// configureLifecycle lets tool calls start gopls on demand with ctx as the
+// process context and, when idleTimeout is positive, stops gopls once no tool
+// call has used it for idleTimeout.
+func (c *goplsClient) configureLifecycle(ctx context.Context, idleTimeout time.Duration) {
+	c.lifecycleMux.Lock()
+	c.lifecycleCtx = ctx
+	c.lastUsed = time.Now()
+	c.lifecycleMux.Unlock()
+
+	if idleTimeout > 0 {
+		go c.monitorIdle(ctx, idleTimeout)
+	}
+}
+
+// setLifecycleState records the lifecycle state of the workspace.
+func (c *goplsClient) setLifecycleState(state string) {
+	c.lifecycleMux.Lock()
+	defer c.lifecycleMux.Unlock()
+
+	c.lifecycleState = state
+}
+
+// lifecycle returns the lifecycle state of the workspace.
+func (c *goplsClient) lifecycle() string {
+	c.lifecycleMux.Lock()
+	defer c.lifecycleMux.Unlock()
+
+	return c.lifecycleState
+}
+
+// markUsed records that the workspace was just used, postponing an idle stop.
+func (c *goplsClient) markUsed() {
+	c.lifecycleMux.Lock()
+	defer c.lifecycleMux.Unlock()
+
+	c.lastUsed = time.Now()
+}
+
+// activate prepares the workspace for a tool call: it marks the workspace as
+// used and starts gopls if it is cold or was stopped while idle. Clients
+// without a configured lifecycle are left alone.
+func (c *goplsClient) activate(ctx context.Context) error {
+	for {
+		c.lifecycleMux.Lock()
+		c.lastUsed = time.Now()
+		transition := c.transition
+		if transition == nil {
+			if c.lifecycleState == lifecycleReady || c.lifecycleCtx == nil {
+				c.lifecycleMux.Unlock()
+				return nil
+			}
+			c.logger.Info("starting gopls on demand", "workspacePath", c.workspacePath)
+			processCtx := c.lifecycleCtx
+			transition = c.beginTransitionLocked(true, func() error { return c.start(processCtx) })
+		}
+		c.lifecycleMux.Unlock()
+
+		select {
+		case <-transition.done:
+		case <-ctx.Done():
+			return fmt.Errorf("cancelled waiting for gopls to start: %w", ctx.Err())
+		}
+
+		// After a stop, loop to start gopls again
+		if transition.starting {
+			return transition.err
+		}
+	}
+}
+
+// beginTransitionLocked runs a start or stop in the background. Callers hold lifecycleMux.
+func (c *goplsClient) beginTransitionLocked(starting bool, run func() error) *lifecycleTransition {
+	transition := &lifecycleTransition{starting: starting, done: make(chan struct{})}
+	c.transition = transition
+
+	go func() {
+		err := run()
+
+		c.lifecycleMux.Lock()
+		transition.err = err
+		c.transition = nil
+		c.lifecycleMux.Unlock()
+		close(transition.done)
+	}()
+	return transition
+}
+
+// monitorIdle stops gopls whenever it has been idle for idleTimeout, until ctx is done.
+func (c *goplsClient) monitorIdle(ctx context.Context, idleTimeout time.Duration) {
+	interval := max(idleTimeout/4, minIdleCheckInterval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.stopIfIdle(idleTimeout)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// stopIfIdle stops gopls when it is ready, has no requests in flight or
+// queued and has not been used for idleTimeout. It reports whether a stop began.
+func (c *goplsClient) stopIfIdle(idleTimeout time.Duration) bool {
+	c.lifecycleMux.Lock()
+	defer c.lifecycleMux.Unlock()
+
+	if c.transition != nil || c.lifecycleState != lifecycleReady {
+		return false
+	}
+	idle := time.Since(c.lastUsed)
+	if idle < idleTimeout {
+		return false
+	}
+	if stats := c.scheduler.stats(); stats.InFlight > 0 || stats.QueuedInteractive+stats.QueuedBackground > 0 {
+		return false
+	}
+
+	c.logger.Info("stopping idle gopls", "workspacePath", c.workspacePath, "idle", idle)
+	c.beginTransitionLocked(false, c.stopIdle)
+	return true
+}
+
+// stopIdle stops gopls and forgets the state of its session so that the next
+// start begins from a clean slate.
+func (c *goplsClient) stopIdle() error {
+	err := c.stop()
+	c.resetSessionState()
+	c.forgetDocuments()
+	if err != nil {
+		return fmt.Errorf("failed to stop idle gopls: %w", err)
+	}
+	return nil
+}
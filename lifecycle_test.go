@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestActivateStartsOnDemand(t *testing.T) {
+	workspacePath := t.TempDir()
+	client := newClient(workspacePath, workspaceConfig{GoplsPath: "missing-gopls"}, newTestLogger())
+
+	// Clients without a lifecycle are never started implicitly
+	if err := client.activate(context.Background()); err != nil {
+		t.Fatalf("Expected activate without lifecycle to do nothing, got %v", err)
+	}
+	if state := client.lifecycle(); state != lifecycleCold {
+		t.Errorf("Expected cold workspace, got %s", state)
+	}
+
+	client.configureLifecycle(context.Background(), 0)
+	err := client.activate(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "missing-gopls") {
+		t.Fatalf("Expected on-demand start to run the configured gopls, got %v", err)
+	}
+	if state := client.lifecycle(); state != lifecycleStopped {
+		t.Errorf("Expected stopped workspace after failed start, got %s", state)
+	}
+}
+
+func TestToolTimeoutBoundsOnDemandStart(t *testing.T) {
+	// A gopls that never answers initialize
+	client := newClient(t.TempDir(), workspaceConfig{GoplsPath: "sleep", GoplsArgs: []string{"60"}}, newTestLogger())
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() { _ = client.stop() })
+	t.Cleanup(cancel)
+	client.configureLifecycle(ctx, 0)
+
+	tools := newMCPTools(map[string]*goplsClient{client.workspacePath: client}, newToolTimeouts(200*time.Millisecond))
+	started := time.Now()
+	_, err := tools.HandleGetHover(context.Background(), nil, &mcp.CallToolParamsFor[GetHoverParams]{
+		Arguments: GetHoverParams{Workspace: client.workspacePath, Path: "main.go", Line: 1},
+	})
+	if err == nil {
+		t.Fatal("Expected the tool call to fail while gopls is starting")
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("Expected the tool timeout to bound the on-demand start, took %s", elapsed)
+	}
+}
+
+func TestWorkspaceStatusWaitStartsColdWorkspace(t *testing.T) {
+	workspacePath := newFakeGoplsWorkspace(t)
+	config := fakeGoplsConfig(t, writeFakeGoplsScript(t, fakeGoplsScript()))
+	client := newClient(workspacePath, config, newTestLogger())
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	t.Cleanup(func() { _ = client.stop() })
+	client.configureLifecycle(ctx, 0)
+
+	tools := newMCPTools(map[string]*goplsClient{workspacePath: client}, newToolTimeouts(defaultRequestTimeout))
+	result, err := tools.HandleWorkspaceStatus(context.Background(), nil,
+		&mcp.CallToolParamsFor[WorkspaceStatusParams]{Arguments: WorkspaceStatusParams{Workspace: workspacePath, Wait: true}})
+	if err != nil {
+		t.Fatalf("Expected waiting for a cold workspace to start it, got %v", err)
+	}
+
+	var status WorkspaceStatusResult
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &status); err != nil {
+		t.Fatalf("failed to decode workspace status: %v", err)
+	}
+	if len(status.Workspaces) != 1 || status.Workspaces[0].State != lifecycleReady || !status.Workspaces[0].Ready {
+		t.Errorf("Expected a ready workspace, got %+v", status.Workspaces)
+	}
+}
+
+func TestRequestDuringIdleStopStartsGoplsAgain(t *testing.T) {
+	workspacePath := newFakeGoplsWorkspace(t)
+	config := fakeGoplsConfig(t, writeFakeGoplsScript(t, fakeGoplsScript()))
+	client := newClient(workspacePath, config, newTestLogger())
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	t.Cleanup(func() { _ = client.stop() })
+	client.configureLifecycle(ctx, 0)
+	if err := client.activate(ctx); err != nil {
+		t.Fatalf("failed to start fake gopls: %v", err)
+	}
+
+	release := holdDrain(client)
+	if !client.stopIfIdle(0) {
+		t.Fatal("Expected idle workspace to be stopped")
+	}
+	waitUntilDraining(t, client)
+
+	type hoverResult struct {
+		hover *Hover
+		err   error
+	}
+	hoverCh := make(chan hoverResult, 1)
+	go func() {
+		hover, err := client.getHover(context.Background(), "main.go", 3, 1)
+		hoverCh <- hoverResult{hover, err}
+	}()
+	select {
+	case got := <-hoverCh:
+		t.Fatalf("Expected the request to wait for the idle stop, got %+v", got)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	release()
+	got := <-hoverCh
+	if got.err != nil || len(got.hover.Contents) != 1 {
+		t.Fatalf("Expected the request to start gopls again, got %+v, %v", got.hover, got.err)
+	}
+	if state := client.lifecycle(); state != lifecycleReady {
+		t.Errorf("Expected ready workspace, got %s", state)
+	}
+}
+
+func TestStopIfIdle(t *testing.T) {
+	client, messages := newLoadedTestClient(t)
+	client.configureLifecycle(context.Background(), 0)
+	client.setLifecycleState(lifecycleReady)
+
+	if err := client.ensureFileOpen("main.go"); err != nil {
+		t.Fatalf("ensureFileOpen failed: %v", err)
+	}
+	expectNotification(t, messages, "textDocument/didOpen")
+
+	if client.stopIfIdle(time.Hour) {
+		t.Fatal("Expected recently used workspace to stay running")
+	}
+
+	// Requests in flight keep the workspace running
+	if err := client.scheduler.acquire(context.Background(), priorityInteractive); err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+	if client.stopIfIdle(0) {
+		t.Fatal("Expected workspace with requests in flight to stay running")
+	}
+	client.scheduler.release(priorityInteractive)
+
+	// Without a gopls process the stop skips the shutdown handshake
+	client.mu.Lock()
+	client.running = false
+	client.mu.Unlock()
+	if !client.stopIfIdle(0) {
+		t.Fatal("Expected idle workspace to be stopped")
+	}
+	client.lifecycleMux.Lock()
+	transition := client.transition
+	client.lifecycleMux.Unlock()
+	if transition != nil {
+		<-transition.done
+	}
+
+	if state := client.lifecycle(); state != lifecycleStopped {
+		t.Errorf("Expected stopped workspace, got %s", state)
+	}
+	if _, isOpen := client.documentVersion("main.go"); isOpen {
+		t.Error("Expected documents of the stopped session to be forgotten")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -27,6 +28,26 @@ func main() {
 	// Parse command line flags
 	workspaceFlag := flag.String("workspace", "", "Comma-separated list of Go workspace directories (required)")
 	transportType := flag.String("transport", "http", "Transport type: http or stdio")
+	toolTimeout := flag.Duration("tool-timeout", defaultRequestTimeout, "Default deadline for each tool call")
+	toolTimeoutOverrides := flag.String("tool-timeouts", "",
+		"Comma-separated per-tool deadlines (e.g. find_references=60s,get_workspace_symbols=2m)")
+	configPath := flag.String("config", "", "Path to a JSON config file with gopls defaults and per-workspace overrides")
+	goplsPath := flag.String("gopls-path", "", "Path to the gopls binary (default \"gopls\" from PATH)")
+	goplsArgs := flag.String("gopls-args", "", "Space-separated extra arguments for gopls (e.g. \"-remote=auto\")")
+	maxInFlight := flag.Int("max-inflight", 0, "Maximum concurrent requests sent to each gopls (default 4)")
+	maxQueued := flag.Int("max-queued", 0,
+		"Maximum requests waiting for each gopls before new ones are rejected (default 32)")
+	cacheSize := flag.Int("cache-size", 0,
+		"Maximum cached query results per workspace, negative to disable caching (default 1000)")
+	lazyStart := flag.Bool("lazy", false, "Start each workspace's gopls on the first tool call that targets it")
+	idleTimeout := flag.Duration("idle-timeout", 0,
+		"Stop a workspace's gopls after this long without tool calls; it restarts on demand (0 keeps it running)")
+	traceDir := flag.String("trace-dir", "", "Directory to record each workspace's LSP traffic to as JSONL")
+	traceMaxSize := flag.Int("trace-max-size", 0, "Size in MB at which a trace file is rotated (default 10)")
+	maxMemory := flag.Int("max-memory", 0,
+		"Recycle a workspace's gopls when its resident memory exceeds this many MB (0 disables recycling)")
+	goplsEnv := envFlag{}
+	flag.Var(goplsEnv, "gopls-env", "Environment variable KEY=VALUE for gopls (repeatable)")
 	flag.Parse()
 
 	// Validate that workspace path is provided
@@ -44,30 +65,70 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Parse tool deadlines
+	timeouts, err := parseToolTimeouts(*toolTimeout, *toolTimeoutOverrides)
+	if err != nil {
+		logger.Error("invalid tool timeouts", "error", err)
+		os.Exit(1)
+	}
+
+	// Resolve how gopls is launched for each workspace
+	config, err := loadServerConfig(*configPath)
+	if err != nil {
+		logger.Error("invalid config file", "error", err)
+		os.Exit(1)
+	}
+	if *maxInFlight < 0 || *maxQueued < 0 {
+		logger.Error("request limits must not be negative", "maxInFlight", *maxInFlight, "maxQueued", *maxQueued)
+		os.Exit(1)
+	}
+	if *maxMemory < 0 {
+		logger.Error("memory ceiling must not be negative", "maxMemory", *maxMemory)
+		os.Exit(1)
+	}
+	flagConfig := workspaceConfig{
+		GoplsPath:      *goplsPath,
+		Env:            goplsEnv,
+		MaxInFlight:    *maxInFlight,
+		MaxQueued:      *maxQueued,
+		CacheSize:      *cacheSize,
+		TraceDir:       *traceDir,
+		TraceMaxSizeMB: *traceMaxSize,
+		MaxMemoryMB:    *maxMemory,
+	}
+	if *goplsArgs != "" {
+		flagConfig.GoplsArgs = strings.Fields(*goplsArgs)
+	}
+	workspaceConfigs, err := resolveWorkspaceConfigs(config, flagConfig, workspacePaths)
+	if err != nil {
+		logger.Error("invalid workspace configuration", "error", err)
+		os.Exit(1)
+	}
+
 	// Create gopls clients for each workspace
 	goplsClients := make(map[string]*goplsClient)
 	for _, workspacePath := range workspacePaths {
-		goplsClients[workspacePath] = newClient(workspacePath, logger)
+		goplsClients[workspacePath] = newClient(workspacePath, workspaceConfigs[workspacePath], logger)
 	}
 
-	// Start all gopls clients
+	// Start all gopls clients unless they are started on demand
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	for workspacePath, client := range goplsClients {
+		client.configureLifecycle(ctx, *idleTimeout)
+		if *lazyStart {
+			continue
+		}
 		if err := client.start(ctx); err != nil {
 			logger.Error("failed to start gopls", "workspace", workspacePath, "error", err)
 			return
 		}
 	}
-	defer func() {
-		for _, client := range goplsClients {
-			_ = client.stop()
-		}
-	}()
+	defer stopClients(goplsClients)
 
 	// Create and setup MCP server
-	server := setupMCPServer(goplsClients)
+	server := setupMCPServer(ctx, goplsClients, timeouts)
 
 	// Handle graceful shutdown
 	go func() {
@@ -75,10 +136,8 @@ func main() {
 		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 		<-sigChan
 		logger.Info("shutting down server")
+		stopClients(goplsClients)
 		cancel()
-		for _, client := range goplsClients {
-			_ = client.stop()
-		}
 		os.Exit(0)
 	}()
 
@@ -145,3 +204,16 @@ func parseAndValidateWorkspaces(workspaceFlag string, logger *slog.Logger) []str
 
 	return workspacePaths
 }
+
+// stopClients shuts down all gopls clients concurrently.
+func stopClients(clients map[string]*goplsClient) {
+	var wg sync.WaitGroup
+	for _, client := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = client.stop()
+		}()
+	}
+	wg.Wait()
+}
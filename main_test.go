@@ -101,7 +101,7 @@ func TestWorkspacePathParsing(t *testing.T) {
 func TestClientCreation(t *testing.T) {
 	workspacePath := "/test/workspace"
 	logger := newTestLogger()
-	client := newClient(workspacePath, logger)
+	client := newClient(workspacePath, workspaceConfig{}, logger)
 
 	if client == nil {
 		t.Fatal("Expected non-nil client")
@@ -120,11 +120,11 @@ func TestMCPToolsCreation(t *testing.T) {
 	// Test that we can create the basic components without errors
 	workspacePath := "/test/workspace"
 	logger := newTestLogger()
-	client := newClient(workspacePath, logger)
+	client := newClient(workspacePath, workspaceConfig{}, logger)
 
 	// Create mcpTools wrapper with client map
 	clients := map[string]*goplsClient{workspacePath: client}
-	mcpToolsWrapper := newMCPTools(clients)
+	mcpToolsWrapper := newMCPTools(clients, newToolTimeouts(defaultRequestTimeout))
 
 	// Test that mcpTools wrapper was created successfully
 	if mcpToolsWrapper.clients == nil {
@@ -314,11 +314,11 @@ func TestSetupMCPServer(t *testing.T) {
 	// Create a test client
 	workspacePath := "/test/workspace"
 	logger := newTestLogger()
-	client := newClient(workspacePath, logger)
+	client := newClient(workspacePath, workspaceConfig{}, logger)
 
 	// Test server setup with client map
 	clients := map[string]*goplsClient{workspacePath: client}
-	server := setupMCPServer(clients)
+	server := setupMCPServer(t.Context(), clients, newToolTimeouts(defaultRequestTimeout))
 
 	if server == nil {
 		t.Fatal("Expected non-nil MCP server")
@@ -5,18 +5,48 @@ import (
 	"encoding/json"
 	"fmt"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
+// MCP tool names
+const (
+	toolListWorkspaces      = "list_workspaces"
+	toolServerInfo          = "server_info"
+	toolUpdateSettings      = "update_settings"
+	toolWorkspaceStatus     = "workspace_status"
+	toolGetServerMessages   = "get_server_messages"
+	toolGoToDefinition      = "go_to_definition"
+	toolFindReferences      = "find_references"
+	toolGetHoverInfo        = "get_hover_info"
+	toolGetDiagnostics      = "get_diagnostics"
+	toolGetDocumentSymbols  = "get_document_symbols"
+	toolGetWorkspaceSymbols = "get_workspace_symbols"
+	toolGetSignatureHelp    = "get_signature_help"
+	toolGetCompletions      = "get_completions"
+	toolGetTypeDefinition   = "get_type_definition"
+	toolFindImplementations = "find_implementations"
+	toolCallHierarchy       = "call_hierarchy"
+	toolFormatDocument      = "format_document"
+	toolOrganizeImports     = "organize_imports"
+	toolGetInlayHints       = "get_inlay_hints"
+	toolRenameSymbol        = "rename_symbol"
+	toolApplyWorkspaceEdit  = "apply_workspace_edit"
+	toolCodeActions         = "code_actions"
+)
+
 // MCP tool parameter types
 
 // GoToDefinitionParams represents parameters for go to definition requests.
 type GoToDefinitionParams struct {
-	Workspace string `json:"workspace" mcp:"Workspace path to use for this request"`
-	Path      string `json:"path" mcp:"Relative path to Go file (e.g., main.go, pkg/client.go)"`
-	Line      int    `json:"line" mcp:"Line number (1-based)"`
-	Character int    `json:"character" mcp:"Character position (0-based)"`
+	Workspace  string `json:"workspace" mcp:"Workspace path to use for this request"`
+	Path       string `json:"path" mcp:"Relative path to Go file (e.g., main.go, pkg/client.go)"`
+	Line       int    `json:"line" mcp:"Line number (1-based)"`
+	Character  int    `json:"character" mcp:"Character position (0-based)"`
+	ColumnUnit string `json:"columnUnit,omitempty" mcp:"Unit of character positions: byte (default), rune, utf16"`
 }
 
 // FindReferencesParams represents parameters for find references requests.
@@ -26,86 +56,176 @@ type FindReferencesParams struct {
 	Line               int    `json:"line" mcp:"Line number (1-based)"`
 	Character          int    `json:"character" mcp:"Character position (0-based)"`
 	IncludeDeclaration bool   `json:"includeDeclaration" mcp:"Include declaration in results"`
+	ColumnUnit         string `json:"columnUnit,omitempty" mcp:"Unit of character positions: byte (default), rune, utf16"`
 }
 
 // GetHoverParams represents parameters for get hover info requests.
 type GetHoverParams struct {
-	Workspace string `json:"workspace" mcp:"Workspace path to use for this request"`
-	Path      string `json:"path" mcp:"Relative path to Go file (e.g., main.go, pkg/client.go)"`
-	Line      int    `json:"line" mcp:"Line number (1-based)"`
-	Character int    `json:"character" mcp:"Character position (0-based)"`
+	Workspace  string `json:"workspace" mcp:"Workspace path to use for this request"`
+	Path       string `json:"path" mcp:"Relative path to Go file (e.g., main.go, pkg/client.go)"`
+	Line       int    `json:"line" mcp:"Line number (1-based)"`
+	Character  int    `json:"character" mcp:"Character position (0-based)"`
+	ColumnUnit string `json:"columnUnit,omitempty" mcp:"Unit of character positions: byte (default), rune, utf16"`
 }
 
 // GetDiagnosticsParams represents parameters for get diagnostics requests.
 type GetDiagnosticsParams struct {
-	Workspace string `json:"workspace" mcp:"Workspace path to use for this request"`
-	Path      string `json:"path" mcp:"Relative path to Go file (e.g., main.go, pkg/client.go)"`
+	Workspace  string `json:"workspace" mcp:"Workspace path to use for this request"`
+	Path       string `json:"path" mcp:"Relative path to Go file (e.g., main.go, pkg/client.go)"`
+	ColumnUnit string `json:"columnUnit,omitempty" mcp:"Unit of character positions: byte (default), rune, utf16"`
 }
 
 // GetDocumentSymbolsParams represents parameters for get document symbols requests.
 type GetDocumentSymbolsParams struct {
-	Workspace string `json:"workspace" mcp:"Workspace path to use for this request"`
-	Path      string `json:"path" mcp:"Relative path to Go file (e.g., main.go, pkg/client.go)"`
+	Workspace  string `json:"workspace" mcp:"Workspace path to use for this request"`
+	Path       string `json:"path" mcp:"Relative path to Go file (e.g., main.go, pkg/client.go)"`
+	ColumnUnit string `json:"columnUnit,omitempty" mcp:"Unit of character positions: byte (default), rune, utf16"`
 }
 
 // GetWorkspaceSymbolsParams represents parameters for get workspace symbols requests.
 type GetWorkspaceSymbolsParams struct {
-	Workspace string `json:"workspace" mcp:"Workspace path to use for this request"`
-	Query     string `json:"query" mcp:"Search query for symbol names (supports fuzzy matching)"`
+	Workspace  string `json:"workspace" mcp:"Workspace path to use for this request"`
+	Query      string `json:"query" mcp:"Search query for symbol names (supports fuzzy matching)"`
+	ColumnUnit string `json:"columnUnit,omitempty" mcp:"Unit of character positions: byte (default), rune, utf16"`
 }
 
 // GetSignatureHelpParams represents parameters for get signature help requests.
 type GetSignatureHelpParams struct {
-	Workspace string `json:"workspace" mcp:"Workspace path to use for this request"`
-	Path      string `json:"path" mcp:"Relative path to Go file (e.g., main.go, pkg/client.go)"`
-	Line      int    `json:"line" mcp:"Line number (1-based)"`
-	Character int    `json:"character" mcp:"Character position (0-based)"`
+	Workspace  string `json:"workspace" mcp:"Workspace path to use for this request"`
+	Path       string `json:"path" mcp:"Relative path to Go file (e.g., main.go, pkg/client.go)"`
+	Line       int    `json:"line" mcp:"Line number (1-based)"`
+	Character  int    `json:"character" mcp:"Character position (0-based)"`
+	ColumnUnit string `json:"columnUnit,omitempty" mcp:"Unit of character positions: byte (default), rune, utf16"`
 }
 
 // GetCompletionsParams represents parameters for get completions requests.
 type GetCompletionsParams struct {
-	Workspace string `json:"workspace" mcp:"Workspace path to use for this request"`
-	Path      string `json:"path" mcp:"Relative path to Go file (e.g., main.go, pkg/client.go)"`
-	Line      int    `json:"line" mcp:"Line number (1-based)"`
-	Character int    `json:"character" mcp:"Character position (0-based)"`
+	Workspace  string `json:"workspace" mcp:"Workspace path to use for this request"`
+	Path       string `json:"path" mcp:"Relative path to Go file (e.g., main.go, pkg/client.go)"`
+	Line       int    `json:"line" mcp:"Line number (1-based)"`
+	Character  int    `json:"character" mcp:"Character position (0-based)"`
+	ColumnUnit string `json:"columnUnit,omitempty" mcp:"Unit of character positions: byte (default), rune, utf16"`
 }
 
 // GetTypeDefinitionParams represents parameters for get type definition requests.
 type GetTypeDefinitionParams struct {
-	Workspace string `json:"workspace" mcp:"Workspace path to use for this request"`
-	Path      string `json:"path" mcp:"Relative path to Go file (e.g., main.go, pkg/client.go)"`
-	Line      int    `json:"line" mcp:"Line number (1-based)"`
-	Character int    `json:"character" mcp:"Character position (0-based)"`
+	Workspace  string `json:"workspace" mcp:"Workspace path to use for this request"`
+	Path       string `json:"path" mcp:"Relative path to Go file (e.g., main.go, pkg/client.go)"`
+	Line       int    `json:"line" mcp:"Line number (1-based)"`
+	Character  int    `json:"character" mcp:"Character position (0-based)"`
+	ColumnUnit string `json:"columnUnit,omitempty" mcp:"Unit of character positions: byte (default), rune, utf16"`
 }
 
 // FindImplementationsParams represents parameters for find implementations requests.
 type FindImplementationsParams struct {
-	Workspace string `json:"workspace" mcp:"Workspace path to use for this request"`
-	Path      string `json:"path" mcp:"Relative path to Go file (e.g., main.go, pkg/client.go)"`
-	Line      int    `json:"line" mcp:"Line number (1-based)"`
-	Character int    `json:"character" mcp:"Character position (0-based)"`
+	Workspace  string `json:"workspace" mcp:"Workspace path to use for this request"`
+	Path       string `json:"path" mcp:"Relative path to Go file (e.g., main.go, pkg/client.go)"`
+	Line       int    `json:"line" mcp:"Line number (1-based)"`
+	Character  int    `json:"character" mcp:"Character position (0-based)"`
+	ColumnUnit string `json:"columnUnit,omitempty" mcp:"Unit of character positions: byte (default), rune, utf16"`
+}
+
+// CallHierarchyParams represents parameters for call hierarchy requests.
+type CallHierarchyParams struct {
+	Workspace  string `json:"workspace" mcp:"Workspace path to use for this request"`
+	Path       string `json:"path" mcp:"Relative path to Go file (e.g., main.go, pkg/client.go)"`
+	Line       int    `json:"line" mcp:"Line number (1-based)"`
+	Character  int    `json:"character" mcp:"Character position (0-based)"`
+	Direction  string `json:"direction,omitempty" mcp:"Calls to follow: incoming, outgoing or both (default)"`
+	Depth      int    `json:"depth,omitempty" mcp:"Levels of calls to expand (default 3, at most 10)"`
+	ColumnUnit string `json:"columnUnit,omitempty" mcp:"Unit of character positions: byte (default), rune, utf16"`
 }
 
+// Modes of the format_document tool.
+const (
+	formatModeEdits = "edits"
+	formatModeApply = "apply"
+	formatModeCheck = "check"
+)
+
 // FormatDocumentParams represents parameters for format document requests.
 type FormatDocumentParams struct {
-	Workspace string `json:"workspace" mcp:"Workspace path to use for this request"`
-	Path      string `json:"path" mcp:"Relative path to Go file (e.g., main.go, pkg/client.go)"`
+	Workspace  string `json:"workspace" mcp:"Workspace path to use for this request"`
+	Path       string `json:"path" mcp:"Relative path to Go file (e.g., main.go, pkg/client.go)"`
+	StartLine  int    `json:"startLine,omitempty" mcp:"First line to format (1-based, whole file when omitted)"`
+	EndLine    int    `json:"endLine,omitempty" mcp:"Last line to format (1-based, inclusive)"`
+	Mode       string `json:"mode,omitempty" mcp:"edits (default), apply (write to disk) or check (report only)"`
+	ColumnUnit string `json:"columnUnit,omitempty" mcp:"Unit of character positions: byte (default), rune, utf16"`
 }
 
 // OrganizeImportsParams represents parameters for organize imports requests.
 type OrganizeImportsParams struct {
-	Workspace string `json:"workspace" mcp:"Workspace path to use for this request"`
-	Path      string `json:"path" mcp:"Relative path to Go file (e.g., main.go, pkg/client.go)"`
+	Workspace  string `json:"workspace" mcp:"Workspace path to use for this request"`
+	Path       string `json:"path" mcp:"Relative path to Go file (e.g., main.go, pkg/client.go)"`
+	ColumnUnit string `json:"columnUnit,omitempty" mcp:"Unit of character positions: byte (default), rune, utf16"`
 }
 
 // GetInlayHintsParams represents parameters for get inlay hints requests.
 type GetInlayHintsParams struct {
-	Workspace string `json:"workspace" mcp:"Workspace path to use for this request"`
-	Path      string `json:"path" mcp:"Relative path to Go file (e.g., main.go, pkg/client.go)"`
+	Workspace  string `json:"workspace" mcp:"Workspace path to use for this request"`
+	Path       string `json:"path" mcp:"Relative path to Go file (e.g., main.go, pkg/client.go)"`
+	StartLine  int    `json:"startLine" mcp:"Start line number (1-based)"`
+	StartChar  int    `json:"startChar" mcp:"Start character position (0-based)"`
+	EndLine    int    `json:"endLine" mcp:"End line number (1-based)"`
+	EndChar    int    `json:"endChar" mcp:"End character position (0-based)"`
+	ColumnUnit string `json:"columnUnit,omitempty" mcp:"Unit of character positions: byte (default), rune, utf16"`
+}
+
+// RenameSymbolParams represents parameters for rename symbol requests.
+type RenameSymbolParams struct {
+	Workspace  string `json:"workspace" mcp:"Workspace path to use for this request"`
+	Path       string `json:"path" mcp:"Relative path to Go file (e.g., main.go, pkg/client.go)"`
+	Line       int    `json:"line" mcp:"Line number (1-based)"`
+	Character  int    `json:"character" mcp:"Character position (0-based)"`
+	NewName    string `json:"newName" mcp:"New name for the symbol"`
+	Apply      bool   `json:"apply,omitempty" mcp:"Write the edits to disk instead of only previewing them"`
+	ColumnUnit string `json:"columnUnit,omitempty" mcp:"Unit of character positions: byte (default), rune, utf16"`
+}
+
+// TextEditParams represents a text edit to apply to a file.
+type TextEditParams struct {
 	StartLine int    `json:"startLine" mcp:"Start line number (1-based)"`
 	StartChar int    `json:"startChar" mcp:"Start character position (0-based)"`
 	EndLine   int    `json:"endLine" mcp:"End line number (1-based)"`
 	EndChar   int    `json:"endChar" mcp:"End character position (0-based)"`
+	NewText   string `json:"newText" mcp:"Text replacing the range"`
+}
+
+// ChangeParams represents a change to a single file in a workspace edit.
+type ChangeParams struct {
+	Kind              string           `json:"kind,omitempty" mcp:"Change kind: edit (default), create, rename or delete"`
+	Path              string           `json:"path" mcp:"Relative path of the file to change"`
+	NewPath           string           `json:"newPath,omitempty" mcp:"Relative path to move the file to (rename)"`
+	Edits             []TextEditParams `json:"edits,omitempty" mcp:"Text edits to apply to the file (edit)"`
+	Content           string           `json:"content,omitempty" mcp:"Contents of the new file (create)"`
+	ExpectedHash      string           `json:"expectedHash,omitempty" mcp:"SHA-256 (hex) the file must have beforehand"`
+	ExpectedVersion   *int             `json:"expectedVersion,omitempty" mcp:"Expected version of the open document"`
+	Overwrite         bool             `json:"overwrite,omitempty" mcp:"Replace an existing file (create, rename)"`
+	IgnoreIfExists    bool             `json:"ignoreIfExists,omitempty" mcp:"Skip when the file exists (create, rename)"`
+	IgnoreIfNotExists bool             `json:"ignoreIfNotExists,omitempty" mcp:"Skip when the file is missing (delete)"`
+}
+
+// ApplyEditParams represents parameters for apply workspace edit requests.
+type ApplyEditParams struct {
+	Workspace  string         `json:"workspace" mcp:"Workspace path to use for this request"`
+	Changes    []ChangeParams `json:"changes" mcp:"Changes to apply in order"`
+	DryRun     bool           `json:"dryRun,omitempty" mcp:"Return the diff without writing any file"`
+	ColumnUnit string         `json:"columnUnit,omitempty" mcp:"Unit of character positions: byte (default), rune, utf16"`
+}
+
+// CodeActionsParams represents parameters for code actions requests. Actions are
+// listed for a range unless ActionID names a listed action to run.
+type CodeActionsParams struct {
+	Workspace  string   `json:"workspace" mcp:"Workspace path to use for this request"`
+	Path       string   `json:"path,omitempty" mcp:"Relative path to Go file (required to list actions)"`
+	StartLine  int      `json:"startLine,omitempty" mcp:"Start line number (1-based, required to list actions)"`
+	StartChar  int      `json:"startChar,omitempty" mcp:"Start character position (0-based)"`
+	EndLine    int      `json:"endLine,omitempty" mcp:"End line number (1-based, defaults to startLine)"`
+	EndChar    int      `json:"endChar,omitempty" mcp:"End character position (0-based)"`
+	Kinds      []string `json:"kinds,omitempty" mcp:"Only list these kinds, e.g. quickfix, refactor.extract, source"`
+	ActionID   string   `json:"actionId,omitempty" mcp:"ID of a listed action to run instead of listing actions"`
+	Apply      bool     `json:"apply,omitempty" mcp:"Write the edits to disk instead of previewing; required for commands"`
+	ColumnUnit string   `json:"columnUnit,omitempty" mcp:"Unit of character positions: byte (default), rune, utf16"`
 }
 
 // ListWorkspacesParams represents parameters for list workspaces requests.
@@ -113,6 +233,31 @@ type ListWorkspacesParams struct {
 	// No parameters needed
 }
 
+// ServerInfoParams represents parameters for server info requests.
+type ServerInfoParams struct {
+	Workspace string `json:"workspace,omitempty" mcp:"Workspace path to report on (all workspaces when empty)"`
+}
+
+// WorkspaceStatusParams represents parameters for workspace status requests.
+type WorkspaceStatusParams struct {
+	Workspace string `json:"workspace,omitempty" mcp:"Workspace path to report on (all workspaces when empty)"`
+	Wait      bool   `json:"wait,omitempty" mcp:"Start gopls if needed and wait until it has loaded the workspace"`
+}
+
+// GetServerMessagesParams represents parameters for get server messages requests.
+type GetServerMessagesParams struct {
+	Workspace string `json:"workspace,omitempty" mcp:"Workspace path to read messages from (all workspaces when empty)"`
+	Severity  string `json:"severity,omitempty" mcp:"Minimum severity: error, warning, info, log or debug (default debug)"`
+	Limit     int    `json:"limit,omitempty" mcp:"Maximum number of most recent messages to return (default 50)"`
+}
+
+// UpdateSettingsParams represents parameters for update settings requests.
+type UpdateSettingsParams struct {
+	Workspace string         `json:"workspace" mcp:"Workspace path to use for this request"`
+	Settings  map[string]any `json:"settings" mcp:"gopls settings to change, e.g. staticcheck (null removes a setting)"`
+	Replace   bool           `json:"replace,omitempty" mcp:"Replace all settings instead of merging into the current ones"`
+}
+
 // MCP tool result types
 
 // LocationResult represents a location result.
@@ -233,6 +378,38 @@ type FindImplementationsResult struct {
 	Locations []LocationResult `json:"locations"`
 }
 
+// CallHierarchyCallResult represents a function or method in a call tree.
+// CallSites are the calls linking it to its parent: in this function for
+// incoming calls, in the parent for outgoing calls.
+type CallHierarchyCallResult struct {
+	Name      string           `json:"name"`
+	Kind      int              `json:"kind"`
+	Detail    string           `json:"detail,omitempty"`
+	Location  LocationResult   `json:"location"`
+	CallSites []LocationResult `json:"callSites"`
+	Cycle     bool             `json:"cycle,omitempty"`
+	Truncated bool             `json:"truncated,omitempty"`
+	Calls     any              `json:"calls,omitempty"`
+}
+
+// CallHierarchyItemResult represents a function or method at the requested
+// position with the trees of its incoming and outgoing calls.
+type CallHierarchyItemResult struct {
+	Name              string                    `json:"name"`
+	Kind              int                       `json:"kind"`
+	Detail            string                    `json:"detail,omitempty"`
+	Location          LocationResult            `json:"location"`
+	Incoming          []CallHierarchyCallResult `json:"incoming,omitempty"`
+	IncomingTruncated bool                      `json:"incomingTruncated,omitempty"`
+	Outgoing          []CallHierarchyCallResult `json:"outgoing,omitempty"`
+	OutgoingTruncated bool                      `json:"outgoingTruncated,omitempty"`
+}
+
+// CallHierarchyResult represents the result of a call hierarchy request.
+type CallHierarchyResult struct {
+	Items []CallHierarchyItemResult `json:"items"`
+}
+
 // TextEditResult represents a text edit result.
 type TextEditResult struct {
 	Range   LocationResult `json:"range"`
@@ -241,7 +418,10 @@ type TextEditResult struct {
 
 // FormatDocumentResult represents the result of a format document request.
 type FormatDocumentResult struct {
-	Edits []TextEditResult `json:"edits"`
+	Edits   []TextEditResult `json:"edits"`
+	Clean   bool             `json:"clean"`
+	Diff    string           `json:"diff,omitempty"`
+	Applied bool             `json:"applied,omitempty"`
 }
 
 // OrganizeImportsResult represents the result of an organize imports request.
@@ -249,6 +429,54 @@ type OrganizeImportsResult struct {
 	Edits []TextEditResult `json:"edits"`
 }
 
+// FileEditsResult represents the edits made to a single file.
+type FileEditsResult struct {
+	Path  string           `json:"path"`
+	Edits []TextEditResult `json:"edits"`
+}
+
+// RenameSymbolResult represents the result of a rename symbol request.
+type RenameSymbolResult struct {
+	Files   []FileEditsResult `json:"files"`
+	Diff    string            `json:"diff"`
+	Applied bool              `json:"applied"`
+}
+
+// FileChangeResult represents a file written by a workspace edit.
+type FileChangeResult struct {
+	Path   string `json:"path"`
+	Change string `json:"change"`
+	Hash   string `json:"hash,omitempty"`
+}
+
+// ApplyEditResult represents the result of an apply workspace edit request.
+type ApplyEditResult struct {
+	Files   []FileChangeResult `json:"files"`
+	Diff    string             `json:"diff"`
+	Applied bool               `json:"applied"`
+}
+
+// CodeActionResult represents a code action offered by gopls.
+type CodeActionResult struct {
+	ID          string   `json:"id"`
+	Title       string   `json:"title"`
+	Kind        string   `json:"kind,omitempty"`
+	IsPreferred bool     `json:"isPreferred,omitempty"`
+	Diagnostics []string `json:"diagnostics,omitempty"`
+	Command     string   `json:"command,omitempty"`
+}
+
+// CodeActionsResult represents the result of a code actions request. Listing
+// fills Actions; running an action fills the other fields.
+type CodeActionsResult struct {
+	Actions       []CodeActionResult `json:"actions,omitempty"`
+	Action        *CodeActionResult  `json:"action,omitempty"`
+	Files         []FileChangeResult `json:"files,omitempty"`
+	Diff          string             `json:"diff,omitempty"`
+	Applied       bool               `json:"applied,omitempty"`
+	CommandResult json.RawMessage    `json:"commandResult,omitempty"`
+}
+
 // InlayHintResult represents an inlay hint result.
 type InlayHintResult struct {
 	Position LocationResult `json:"position"`
@@ -264,8 +492,9 @@ type GetInlayHintsResult struct {
 
 // WorkspaceInfo represents information about a workspace.
 type WorkspaceInfo struct {
-	Path string `json:"path"`
-	Name string `json:"name"`
+	Path  string `json:"path"`
+	Name  string `json:"name"`
+	State string `json:"state"`
 }
 
 // ListWorkspacesResult represents the result of a list workspaces request.
@@ -273,6 +502,108 @@ type ListWorkspacesResult struct {
 	Workspaces []WorkspaceInfo `json:"workspaces"`
 }
 
+// WorkspaceServerInfo represents the gopls server serving a workspace.
+type WorkspaceServerInfo struct {
+	Workspace        string             `json:"workspace"`
+	Running          bool               `json:"running"`
+	Initialized      bool               `json:"initialized"`
+	ServerName       string             `json:"serverName,omitempty"`
+	Version          string             `json:"version,omitempty"`
+	PositionEncoding string             `json:"positionEncoding,omitempty"`
+	Features         []string           `json:"features"`
+	Capabilities     ServerCapabilities `json:"capabilities"`
+	Settings         map[string]any     `json:"settings,omitempty"`
+}
+
+// ServerInfoResult represents the result of a server info request.
+type ServerInfoResult struct {
+	Servers []WorkspaceServerInfo `json:"servers"`
+}
+
+// ProgressTaskInfo represents a work-done progress operation running in gopls.
+type ProgressTaskInfo struct {
+	Title      string `json:"title,omitempty"`
+	Message    string `json:"message,omitempty"`
+	Percentage *int   `json:"percentage,omitempty"`
+	ElapsedMs  int64  `json:"elapsedMs"`
+}
+
+// WorkspaceStatus represents the readiness of a workspace.
+type WorkspaceStatus struct {
+	Workspace string             `json:"workspace"`
+	State     string             `json:"state"`
+	Ready     bool               `json:"ready"`
+	Tasks     []ProgressTaskInfo `json:"tasks"`
+	Requests  RequestQueueStats  `json:"requests"`
+	Cache     CacheStats         `json:"cache"`
+	Process   ProcessStats       `json:"process"`
+}
+
+// ProcessStats represents the resource usage of a workspace's gopls process.
+type ProcessStats struct {
+	PID         int           `json:"pid,omitempty"`
+	RSSMB       float64       `json:"rssMB"`
+	CPUPercent  float64       `json:"cpuPercent"`
+	SampledAt   string        `json:"sampledAt,omitempty"`
+	MaxMemoryMB int           `json:"maxMemoryMB,omitempty"`
+	Recycles    int           `json:"recycles"`
+	LastRecycle *RecycleEvent `json:"lastRecycle,omitempty"`
+}
+
+// RecycleEvent represents a restart of gopls for crossing the memory ceiling.
+type RecycleEvent struct {
+	Time        string  `json:"time"`
+	RSSMB       float64 `json:"rssMB"`
+	MaxMemoryMB int     `json:"maxMemoryMB"`
+}
+
+// CacheStats represents the query result cache of a workspace.
+type CacheStats struct {
+	Entries       int     `json:"entries"`
+	MaxEntries    int     `json:"maxEntries"`
+	Hits          int64   `json:"hits"`
+	Misses        int64   `json:"misses"`
+	HitRate       float64 `json:"hitRate"`
+	Evictions     int64   `json:"evictions"`
+	Invalidations int64   `json:"invalidations"`
+}
+
+// RequestQueueStats represents the request scheduler of a workspace.
+type RequestQueueStats struct {
+	MaxInFlight       int   `json:"maxInFlight"`
+	MaxQueued         int   `json:"maxQueued"`
+	InFlight          int   `json:"inFlight"`
+	QueuedInteractive int   `json:"queuedInteractive"`
+	QueuedBackground  int   `json:"queuedBackground"`
+	PeakQueued        int   `json:"peakQueued"`
+	Started           int64 `json:"started"`
+	Rejected          int64 `json:"rejected"`
+}
+
+// WorkspaceStatusResult represents the result of a workspace status request.
+type WorkspaceStatusResult struct {
+	Workspaces []WorkspaceStatus `json:"workspaces"`
+}
+
+// ServerMessageResult represents a window message sent by gopls.
+type ServerMessageResult struct {
+	Workspace string `json:"workspace"`
+	Time      string `json:"time"`
+	Severity  string `json:"severity"`
+	Kind      string `json:"kind"`
+	Message   string `json:"message"`
+}
+
+// GetServerMessagesResult represents the result of a get server messages request.
+type GetServerMessagesResult struct {
+	Messages []ServerMessageResult `json:"messages"`
+}
+
+// UpdateSettingsResult represents the result of an update settings request.
+type UpdateSettingsResult struct {
+	Settings map[string]any `json:"settings"`
+}
+
 // Line number conversion functions for MCP layer (1-based) to LSP layer (0-based)
 
 // convertLineToLSP converts a 1-based line number from MCP to 0-based for LSP.
@@ -288,102 +619,151 @@ func convertLineFromLSP(line int) int {
 	return line + 1
 }
 
-// mcpTools wraps multiple goplsClients to provide MCP tool functionality.
+// goplsClientInterface is the set of gopls operations the MCP tools depend on.
+// goplsClient implements it against a gopls process; tests substitute mocks.
+type goplsClientInterface interface {
+	workspaceRoot() string
+	activate(ctx context.Context) error
+	waitUntilRunning(ctx context.Context) error
+	waitForInitialLoad(ctx context.Context) error
+	lifecycle() string
+	positionEncoding() string
+	supportsTool(tool string) bool
+	describeServer() WorkspaceServerInfo
+	workspaceStatus() WorkspaceStatus
+	serverMessages(maxType int) []serverMessage
+	setMessageListener(listener func(serverMessage))
+	updateSettings(ctx context.Context, settings map[string]any, replace bool) (map[string]any, error)
+
+	goToDefinition(ctx context.Context, relativePath string, line, character int) ([]Location, error)
+	findReferences(
+		ctx context.Context, relativePath string, line, character int, includeDeclaration bool,
+	) ([]Location, error)
+	getHover(ctx context.Context, relativePath string, line, character int) (*Hover, error)
+	getDiagnostics(ctx context.Context, relativePath string) ([]Diagnostic, error)
+	getDocumentSymbols(ctx context.Context, relativePath string) ([]DocumentSymbol, error)
+	getWorkspaceSymbols(ctx context.Context, query string) ([]SymbolInformation, error)
+	getSignatureHelp(ctx context.Context, relativePath string, line, character int) (*SignatureHelp, error)
+	getCompletions(ctx context.Context, relativePath string, line, character int) (*CompletionList, error)
+	getTypeDefinition(ctx context.Context, relativePath string, line, character int) ([]Location, error)
+	findImplementations(ctx context.Context, relativePath string, line, character int) ([]Location, error)
+	formatDocument(ctx context.Context, relativePath string) ([]TextEdit, error)
+	formatRange(ctx context.Context, relativePath string, formatRange Range) ([]TextEdit, error)
+	organizeImports(ctx context.Context, relativePath string) ([]TextEdit, error)
+	getInlayHints(
+		ctx context.Context, relativePath string, startLine, startChar, endLine, endChar int,
+	) ([]InlayHint, error)
+	renameSymbol(ctx context.Context, relativePath string, line, character int, newName string) ([]fileEdit, error)
+	planWorkspaceEdit(edit *WorkspaceEdit, expectedHashes map[string]string) ([]fileEdit, error)
+	applyFileEdits(files []fileEdit) error
+	listCodeActions(
+		ctx context.Context, relativePath string, actionRange Range, only []string,
+	) ([]listedCodeAction, error)
+	runCodeAction(ctx context.Context, id string, apply bool) (codeActionOutcome, error)
+	callHierarchy(
+		ctx context.Context, relativePath string, line, character int, direction string, depth int,
+	) ([]callHierarchyRoot, error)
+}
+
+// mcpTools wraps multiple gopls clients to provide MCP tool functionality.
 type mcpTools struct {
-	clients map[string]*goplsClient
+	clients  map[string]goplsClientInterface
+	timeouts toolTimeouts
 }
 
-// newMCPTools creates a new MCP tools instance wrapping the given goplsClients.
-func newMCPTools(clients map[string]*goplsClient) mcpTools {
+// newMCPTools creates a new MCP tools instance wrapping the given gopls clients.
+func newMCPTools[C goplsClientInterface](clients map[string]C, timeouts toolTimeouts) mcpTools {
+	wrapped := make(map[string]goplsClientInterface, len(clients))
+	for workspacePath, client := range clients {
+		wrapped[workspacePath] = client
+	}
 	return mcpTools{
-		clients: clients,
+		clients:  wrapped,
+		timeouts: timeouts,
 	}
 }
 
-// getClient returns the goplsClient for the specified workspace.
-func (m mcpTools) getClient(workspace string) (*goplsClient, error) {
+// getClient returns the client for the specified workspace, starting gopls
+// if it is not running yet and waiting briefly if gopls is being restarted.
+// Handlers apply the tool timeout first so that it also bounds a cold start.
+func (m mcpTools) getClient(ctx context.Context, workspace string) (goplsClientInterface, error) {
 	client, exists := m.clients[workspace]
 	if !exists {
 		return nil, fmt.Errorf("workspace not found: %s", workspace)
 	}
-	if !client.isRunning() {
-		return nil, fmt.Errorf("gopls is not running for workspace: %s", workspace)
+	if err := client.activate(ctx); err != nil {
+		return nil, fmt.Errorf("failed to start gopls for workspace %s: %w", workspace, err)
+	}
+	if err := client.waitUntilRunning(ctx); err != nil {
+		return nil, fmt.Errorf("gopls is not running for workspace %s: %w", workspace, err)
 	}
 	return client, nil
 }
 
+// selectWorkspaces returns the requested workspace, or every workspace in
+// sorted order when workspace is empty.
+func (m mcpTools) selectWorkspaces(workspace string) ([]string, error) {
+	if workspace != "" {
+		if _, exists := m.clients[workspace]; !exists {
+			return nil, fmt.Errorf("workspace not found: %s", workspace)
+		}
+		return []string{workspace}, nil
+	}
+
+	workspacePaths := make([]string, 0, len(m.clients))
+	for workspacePath := range m.clients {
+		workspacePaths = append(workspacePaths, workspacePath)
+	}
+	sort.Strings(workspacePaths)
+	return workspacePaths, nil
+}
+
 // convertLocationsToResults converts Location structs to LocationResult structs.
-func (m mcpTools) convertLocationsToResults(locations []Location) []LocationResult {
+func (m mcpTools) convertLocationsToResults(columns *columnConverter, locations []Location) []LocationResult {
 	results := make([]LocationResult, len(locations))
 	for i, loc := range locations {
-		results[i] = LocationResult{
-			URI:          loc.URI,
-			Line:         convertLineFromLSP(loc.Range.Start.Line),
-			Character:    loc.Range.Start.Character,
-			EndLine:      convertLineFromLSP(loc.Range.End.Line),
-			EndCharacter: loc.Range.End.Character,
-		}
+		results[i] = columns.rangeResult(loc.URI, loc.Range)
 	}
 	return results
 }
 
 // convertLocationToResult converts a Location struct to LocationResult struct.
-func (m mcpTools) convertLocationToResult(location Location) LocationResult {
-	return LocationResult{
-		URI:          location.URI,
-		Line:         convertLineFromLSP(location.Range.Start.Line),
-		Character:    location.Range.Start.Character,
-		EndLine:      convertLineFromLSP(location.Range.End.Line),
-		EndCharacter: location.Range.End.Character,
-	}
+func (m mcpTools) convertLocationToResult(columns *columnConverter, location Location) LocationResult {
+	return columns.rangeResult(location.URI, location.Range)
 }
 
 // convertDocumentSymbolToResult converts a DocumentSymbol struct to DocumentSymbolResult struct.
-func (m mcpTools) convertDocumentSymbolToResult(symbol DocumentSymbol) DocumentSymbolResult {
+func (m mcpTools) convertDocumentSymbolToResult(
+	columns *columnConverter, relativePath string, symbol DocumentSymbol,
+) DocumentSymbolResult {
 	var children any
 	if len(symbol.Children) > 0 {
 		childResults := make([]DocumentSymbolResult, len(symbol.Children))
 		for i, child := range symbol.Children {
-			childResults[i] = m.convertDocumentSymbolToResult(child)
+			childResults[i] = m.convertDocumentSymbolToResult(columns, relativePath, child)
 		}
 		children = childResults
 	}
 
 	return DocumentSymbolResult{
-		Name:       symbol.Name,
-		Detail:     symbol.Detail,
-		Kind:       symbol.Kind,
-		Deprecated: symbol.Deprecated,
-		Range: LocationResult{
-			URI:          "",
-			Line:         convertLineFromLSP(symbol.Range.Start.Line),
-			Character:    symbol.Range.Start.Character,
-			EndLine:      convertLineFromLSP(symbol.Range.End.Line),
-			EndCharacter: symbol.Range.End.Character,
-		},
-		SelectionRange: LocationResult{
-			URI:          "",
-			Line:         convertLineFromLSP(symbol.SelectionRange.Start.Line),
-			Character:    symbol.SelectionRange.Start.Character,
-			EndLine:      convertLineFromLSP(symbol.SelectionRange.End.Line),
-			EndCharacter: symbol.SelectionRange.End.Character,
-		},
-		Children: children,
+		Name:           symbol.Name,
+		Detail:         symbol.Detail,
+		Kind:           symbol.Kind,
+		Deprecated:     symbol.Deprecated,
+		Range:          columns.documentRangeResult(relativePath, symbol.Range),
+		SelectionRange: columns.documentRangeResult(relativePath, symbol.SelectionRange),
+		Children:       children,
 	}
 }
 
 // convertTextEditsToResults converts TextEdit structs to TextEditResult structs.
-func (m mcpTools) convertTextEditsToResults(textEdits []TextEdit) []TextEditResult {
+func (m mcpTools) convertTextEditsToResults(
+	columns *columnConverter, relativePath string, textEdits []TextEdit,
+) []TextEditResult {
 	results := make([]TextEditResult, len(textEdits))
 	for i, edit := range textEdits {
 		results[i] = TextEditResult{
-			Range: LocationResult{
-				URI:          "",
-				Line:         convertLineFromLSP(edit.Range.Start.Line),
-				Character:    edit.Range.Start.Character,
-				EndLine:      convertLineFromLSP(edit.Range.End.Line),
-				EndCharacter: edit.Range.End.Character,
-			},
+			Range:   columns.documentRangeResult(relativePath, edit.Range),
 			NewText: edit.NewText,
 		}
 	}
@@ -391,25 +771,188 @@ func (m mcpTools) convertTextEditsToResults(textEdits []TextEdit) []TextEditResu
 }
 
 // convertInlayHintsToResults converts InlayHint structs to InlayHintResult structs.
-func (m mcpTools) convertInlayHintsToResults(inlayHints []InlayHint) []InlayHintResult {
+func (m mcpTools) convertInlayHintsToResults(
+	columns *columnConverter, relativePath string, inlayHints []InlayHint,
+) []InlayHintResult {
 	results := make([]InlayHintResult, len(inlayHints))
 	for i, hint := range inlayHints {
+		position := columns.documentRangeResult(relativePath, Range{Start: hint.Position, End: hint.Position})
 		results[i] = InlayHintResult{
-			Position: LocationResult{
-				URI:          "",
-				Line:         convertLineFromLSP(hint.Position.Line),
-				Character:    hint.Position.Character,
-				EndLine:      convertLineFromLSP(hint.Position.Line),
-				EndCharacter: hint.Position.Character,
-			},
-			Label:   hint.Label,
-			Kind:    hint.Kind,
-			Tooltip: hint.Tooltip,
+			Position: position,
+			Label:    hint.Label,
+			Kind:     hint.Kind,
+			Tooltip:  hint.Tooltip,
+		}
+	}
+	return results
+}
+
+// convertFileEditsToResults converts file edits to FileEditsResult structs and
+// joins their unified diffs. Columns are read from the files as they are before
+// the edits are applied.
+func (m mcpTools) convertFileEditsToResults(columns *columnConverter, files []fileEdit) ([]FileEditsResult, string) {
+	results := make([]FileEditsResult, len(files))
+	var diff strings.Builder
+	for i, file := range files {
+		results[i] = FileEditsResult{
+			Path:  file.relativePath,
+			Edits: m.convertTextEditsToResults(columns, file.relativePath, file.edits),
+		}
+		diff.WriteString(file.diff())
+	}
+	return results, diff.String()
+}
+
+// workspaceFileURI returns the file URI of a workspace-relative path.
+func workspaceFileURI(workspacePath, relativePath string) string {
+	return fmt.Sprintf("file://%s", filepath.Join(workspacePath, relativePath))
+}
+
+// workspaceEditFromParams converts the changes of an apply workspace edit
+// request into a workspace edit and the file hashes it expects.
+func (m mcpTools) workspaceEditFromParams(
+	columns *columnConverter, changes []ChangeParams,
+) (*WorkspaceEdit, map[string]string, error) {
+	fileURI := func(relativePath string) string {
+		return workspaceFileURI(columns.workspacePath, relativePath)
+	}
+
+	edit := &WorkspaceEdit{}
+	expectedHashes := make(map[string]string)
+	for i, change := range changes {
+		if change.Path == "" {
+			return nil, nil, fmt.Errorf("change %d: path is required", i+1)
+		}
+		if change.ExpectedHash != "" {
+			expectedHashes[filepath.Clean(change.Path)] = change.ExpectedHash
+		}
+
+		switch change.Kind {
+		case "", "edit":
+			textEdits := make([]TextEdit, len(change.Edits))
+			for j, textEdit := range change.Edits {
+				startLine, endLine := convertLineToLSP(textEdit.StartLine), convertLineToLSP(textEdit.EndLine)
+				textEdits[j] = TextEdit{
+					Range: Range{
+						Start: Position{Line: startLine, Character: columns.toLSP(change.Path, startLine, textEdit.StartChar)},
+						End:   Position{Line: endLine, Character: columns.toLSP(change.Path, endLine, textEdit.EndChar)},
+					},
+					NewText: textEdit.NewText,
+				}
+			}
+			edit.DocumentChanges = append(edit.DocumentChanges, DocumentChange{TextDocumentEdit: &TextDocumentEdit{
+				TextDocument: OptionalVersionedTextDocumentIdentifier{URI: fileURI(change.Path), Version: change.ExpectedVersion},
+				Edits:        textEdits,
+			}})
+		case "create":
+			edit.DocumentChanges = append(edit.DocumentChanges, DocumentChange{CreateFile: &CreateFile{
+				Kind:    "create",
+				URI:     fileURI(change.Path),
+				Options: &CreateFileOptions{Overwrite: change.Overwrite, IgnoreIfExists: change.IgnoreIfExists},
+			}})
+			if change.Content != "" {
+				edit.DocumentChanges = append(edit.DocumentChanges, DocumentChange{TextDocumentEdit: &TextDocumentEdit{
+					TextDocument: OptionalVersionedTextDocumentIdentifier{URI: fileURI(change.Path)},
+					Edits:        []TextEdit{{NewText: change.Content}},
+				}})
+			}
+		case "rename":
+			if change.NewPath == "" {
+				return nil, nil, fmt.Errorf("change %d: newPath is required to rename %s", i+1, change.Path)
+			}
+			edit.DocumentChanges = append(edit.DocumentChanges, DocumentChange{RenameFile: &RenameFile{
+				Kind:    "rename",
+				OldURI:  fileURI(change.Path),
+				NewURI:  fileURI(change.NewPath),
+				Options: &RenameFileOptions{Overwrite: change.Overwrite, IgnoreIfExists: change.IgnoreIfExists},
+			}})
+		case "delete":
+			edit.DocumentChanges = append(edit.DocumentChanges, DocumentChange{DeleteFile: &DeleteFile{
+				Kind:    "delete",
+				URI:     fileURI(change.Path),
+				Options: &DeleteFileOptions{IgnoreIfNotExists: change.IgnoreIfNotExists},
+			}})
+		default:
+			return nil, nil, fmt.Errorf("change %d: unknown kind %q: expected edit, create, rename or delete",
+				i+1, change.Kind)
+		}
+	}
+	return edit, expectedHashes, nil
+}
+
+// convertFileChangesToResults converts file edits to FileChangeResult structs
+// and joins their unified diffs.
+func (m mcpTools) convertFileChangesToResults(files []fileEdit) ([]FileChangeResult, string) {
+	results := make([]FileChangeResult, len(files))
+	var diff strings.Builder
+	for i, file := range files {
+		results[i] = FileChangeResult{
+			Path:   file.relativePath,
+			Change: file.change(),
+			Hash:   file.hash(),
+		}
+		diff.WriteString(file.diff())
+	}
+	return results, diff.String()
+}
+
+// pathRangeResult converts an LSP range in a file given by its relative path
+// into a 1-based LocationResult whose URI is that path.
+func (m mcpTools) pathRangeResult(columns *columnConverter, relativePath string, r Range) LocationResult {
+	result := columns.documentRangeResult(relativePath, r)
+	result.URI = relativePath
+	return result
+}
+
+// convertCallHierarchyToResults converts call trees to the MCP result format.
+func (m mcpTools) convertCallHierarchyToResults(
+	columns *columnConverter, nodes []*callHierarchyNode,
+) []CallHierarchyCallResult {
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	results := make([]CallHierarchyCallResult, len(nodes))
+	for i, node := range nodes {
+		callSites := make([]LocationResult, len(node.callRanges))
+		for j, callRange := range node.callRanges {
+			callSites[j] = m.pathRangeResult(columns, node.callPath, callRange)
+		}
+		var calls any
+		if len(node.calls) > 0 {
+			calls = m.convertCallHierarchyToResults(columns, node.calls)
+		}
+		results[i] = CallHierarchyCallResult{
+			Name:      node.item.Name,
+			Kind:      node.item.Kind,
+			Detail:    node.item.Detail,
+			Location:  m.pathRangeResult(columns, node.path, node.item.SelectionRange),
+			CallSites: callSites,
+			Cycle:     node.cycle,
+			Truncated: node.truncated,
+			Calls:     calls,
 		}
 	}
 	return results
 }
 
+// convertCodeActionToResult converts a code action to the MCP result format.
+func (m mcpTools) convertCodeActionToResult(id string, action CodeAction) CodeActionResult {
+	result := CodeActionResult{
+		ID:          id,
+		Title:       action.Title,
+		Kind:        action.Kind,
+		IsPreferred: action.IsPreferred,
+	}
+	for _, diagnostic := range action.Diagnostics {
+		result.Diagnostics = append(result.Diagnostics, diagnostic.Message)
+	}
+	if action.Command != nil {
+		result.Command = action.Command.Command
+	}
+	return result
+}
+
 // MCP tool handlers
 
 // HandleListWorkspaces handles list workspaces requests.
@@ -419,10 +962,11 @@ func (m mcpTools) HandleListWorkspaces(
 	_ *mcp.CallToolParamsFor[ListWorkspacesParams],
 ) (*mcp.CallToolResultFor[ListWorkspacesResult], error) {
 	workspaces := make([]WorkspaceInfo, 0, len(m.clients))
-	for workspacePath := range m.clients {
+	for workspacePath, client := range m.clients {
 		workspaces = append(workspaces, WorkspaceInfo{
-			Path: workspacePath,
-			Name: filepath.Base(workspacePath),
+			Path:  workspacePath,
+			Name:  filepath.Base(workspacePath),
+			State: client.lifecycle(),
 		})
 	}
 
@@ -444,30 +988,229 @@ func (m mcpTools) HandleListWorkspaces(
 	}, nil
 }
 
+// HandleServerInfo handles server info requests.
+func (m mcpTools) HandleServerInfo(
+	_ context.Context,
+	_ *mcp.ServerSession,
+	params *mcp.CallToolParamsFor[ServerInfoParams],
+) (*mcp.CallToolResultFor[ServerInfoResult], error) {
+	workspacePaths, err := m.selectWorkspaces(params.Arguments.Workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	servers := make([]WorkspaceServerInfo, 0, len(workspacePaths))
+	for _, workspacePath := range workspacePaths {
+		servers = append(servers, m.clients[workspacePath].describeServer())
+	}
+
+	result := ServerInfoResult{
+		Servers: servers,
+	}
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal result: %w", err)
+	}
+
+	return &mcp.CallToolResultFor[ServerInfoResult]{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(jsonData),
+			},
+		},
+	}, nil
+}
+
+// HandleWorkspaceStatus handles workspace status requests.
+func (m mcpTools) HandleWorkspaceStatus(
+	ctx context.Context,
+	_ *mcp.ServerSession,
+	params *mcp.CallToolParamsFor[WorkspaceStatusParams],
+) (*mcp.CallToolResultFor[WorkspaceStatusResult], error) {
+	workspacePaths, err := m.selectWorkspaces(params.Arguments.Workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := m.withToolTimeout(ctx, toolWorkspaceStatus)
+	defer cancel()
+
+	statuses := make([]WorkspaceStatus, 0, len(workspacePaths))
+	for _, workspacePath := range workspacePaths {
+		client := m.clients[workspacePath]
+		if params.Arguments.Wait {
+			// Waiting for a workspace that is cold starts gopls for it
+			if err := client.activate(ctx); err != nil {
+				return nil, fmt.Errorf("failed to start gopls for workspace %s: %w", workspacePath, err)
+			}
+			if err := client.waitForInitialLoad(ctx); err != nil {
+				return nil, fmt.Errorf("failed to wait for workspace %s: %w", workspacePath, err)
+			}
+		}
+		statuses = append(statuses, client.workspaceStatus())
+	}
+
+	result := WorkspaceStatusResult{
+		Workspaces: statuses,
+	}
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal result: %w", err)
+	}
+
+	return &mcp.CallToolResultFor[WorkspaceStatusResult]{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(jsonData),
+			},
+		},
+	}, nil
+}
+
+// HandleGetServerMessages handles get server messages requests.
+func (m mcpTools) HandleGetServerMessages(
+	_ context.Context,
+	_ *mcp.ServerSession,
+	params *mcp.CallToolParamsFor[GetServerMessagesParams],
+) (*mcp.CallToolResultFor[GetServerMessagesResult], error) {
+	workspacePaths, err := m.selectWorkspaces(params.Arguments.Workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	maxType, err := parseMessageSeverity(params.Arguments.Severity)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := params.Arguments.Limit
+	if limit <= 0 {
+		limit = defaultServerMessageLimit
+	}
+
+	type workspaceMessage struct {
+		workspace string
+		message   serverMessage
+	}
+	var collected []workspaceMessage
+	for _, workspacePath := range workspacePaths {
+		for _, message := range m.clients[workspacePath].serverMessages(maxType) {
+			collected = append(collected, workspaceMessage{workspace: workspacePath, message: message})
+		}
+	}
+	sort.SliceStable(collected, func(i, j int) bool {
+		return collected[i].message.time.Before(collected[j].message.time)
+	})
+	if len(collected) > limit {
+		collected = collected[len(collected)-limit:]
+	}
+
+	messages := make([]ServerMessageResult, len(collected))
+	for i, item := range collected {
+		messages[i] = ServerMessageResult{
+			Workspace: item.workspace,
+			Time:      item.message.time.Format(time.RFC3339Nano),
+			Severity:  item.message.severity(),
+			Kind:      item.message.kind,
+			Message:   item.message.text,
+		}
+	}
+
+	result := GetServerMessagesResult{
+		Messages: messages,
+	}
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal result: %w", err)
+	}
+
+	return &mcp.CallToolResultFor[GetServerMessagesResult]{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(jsonData),
+			},
+		},
+	}, nil
+}
+
+// HandleUpdateSettings handles update settings requests.
+func (m mcpTools) HandleUpdateSettings(
+	ctx context.Context,
+	_ *mcp.ServerSession,
+	params *mcp.CallToolParamsFor[UpdateSettingsParams],
+) (*mcp.CallToolResultFor[UpdateSettingsResult], error) {
+	ctx, cancel := m.withToolTimeout(ctx, toolUpdateSettings)
+	defer cancel()
+
+	client, err := m.getClient(ctx, params.Arguments.Workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := client.updateSettings(ctx, params.Arguments.Settings, params.Arguments.Replace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update settings: %w", err)
+	}
+
+	result := UpdateSettingsResult{
+		Settings: settings,
+	}
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal result: %w", err)
+	}
+
+	return &mcp.CallToolResultFor[UpdateSettingsResult]{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(jsonData),
+			},
+		},
+	}, nil
+}
+
 // HandleGoToDefinition handles go to definition requests.
 //
 //nolint:dupl // Similar pattern across location-based handlers is acceptable
 func (m mcpTools) HandleGoToDefinition(
-	_ context.Context,
+	ctx context.Context,
 	_ *mcp.ServerSession,
 	params *mcp.CallToolParamsFor[GoToDefinitionParams],
 ) (*mcp.CallToolResultFor[GoToDefinitionResult], error) {
-	client, err := m.getClient(params.Arguments.Workspace)
+	ctx, cancel := m.withToolTimeout(ctx, toolGoToDefinition)
+	defer cancel()
+
+	client, err := m.getClient(ctx, params.Arguments.Workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.requireTool(client, toolGoToDefinition); err != nil {
+		return nil, err
+	}
+
+	columns, err := m.columnConverter(client, params.Arguments.ColumnUnit)
 	if err != nil {
 		return nil, err
 	}
+	line := convertLineToLSP(params.Arguments.Line)
 
 	locations, err := client.goToDefinition(
+		ctx,
 		params.Arguments.Path,
-		convertLineToLSP(params.Arguments.Line),
-		params.Arguments.Character,
+		line,
+		columns.toLSP(params.Arguments.Path, line, params.Arguments.Character),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get definition: %w", err)
 	}
 
 	result := GoToDefinitionResult{
-		Locations: m.convertLocationsToResults(locations),
+		Locations: m.convertLocationsToResults(columns, locations),
 	}
 
 	jsonData, err := json.Marshal(result)
@@ -486,19 +1229,33 @@ func (m mcpTools) HandleGoToDefinition(
 
 // HandleFindReferences handles find references requests.
 func (m mcpTools) HandleFindReferences(
-	_ context.Context,
+	ctx context.Context,
 	_ *mcp.ServerSession,
 	params *mcp.CallToolParamsFor[FindReferencesParams],
 ) (*mcp.CallToolResultFor[FindReferencesResult], error) {
-	client, err := m.getClient(params.Arguments.Workspace)
+	ctx, cancel := m.withToolTimeout(ctx, toolFindReferences)
+	defer cancel()
+
+	client, err := m.getClient(ctx, params.Arguments.Workspace)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := m.requireTool(client, toolFindReferences); err != nil {
+		return nil, err
+	}
+
+	columns, err := m.columnConverter(client, params.Arguments.ColumnUnit)
+	if err != nil {
+		return nil, err
+	}
+	line := convertLineToLSP(params.Arguments.Line)
+
 	locations, err := client.findReferences(
+		ctx,
 		params.Arguments.Path,
-		convertLineToLSP(params.Arguments.Line),
-		params.Arguments.Character,
+		line,
+		columns.toLSP(params.Arguments.Path, line, params.Arguments.Character),
 		params.Arguments.IncludeDeclaration,
 	)
 	if err != nil {
@@ -506,7 +1263,7 @@ func (m mcpTools) HandleFindReferences(
 	}
 
 	result := FindReferencesResult{
-		Locations: m.convertLocationsToResults(locations),
+		Locations: m.convertLocationsToResults(columns, locations),
 	}
 
 	jsonData, err := json.Marshal(result)
@@ -525,19 +1282,33 @@ func (m mcpTools) HandleFindReferences(
 
 // HandleGetHover handles get hover info requests.
 func (m mcpTools) HandleGetHover(
-	_ context.Context,
+	ctx context.Context,
 	_ *mcp.ServerSession,
 	params *mcp.CallToolParamsFor[GetHoverParams],
 ) (*mcp.CallToolResultFor[GetHoverResult], error) {
-	client, err := m.getClient(params.Arguments.Workspace)
+	ctx, cancel := m.withToolTimeout(ctx, toolGetHoverInfo)
+	defer cancel()
+
+	client, err := m.getClient(ctx, params.Arguments.Workspace)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := m.requireTool(client, toolGetHoverInfo); err != nil {
+		return nil, err
+	}
+
+	columns, err := m.columnConverter(client, params.Arguments.ColumnUnit)
+	if err != nil {
+		return nil, err
+	}
+	line := convertLineToLSP(params.Arguments.Line)
+
 	hover, err := client.getHover(
+		ctx,
 		params.Arguments.Path,
-		convertLineToLSP(params.Arguments.Line),
-		params.Arguments.Character,
+		line,
+		columns.toLSP(params.Arguments.Path, line, params.Arguments.Character),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get hover info: %w", err)
@@ -549,13 +1320,9 @@ func (m mcpTools) HandleGetHover(
 	}
 
 	if hover.Range != nil {
-		result.Range = &LocationResult{
-			URI:          params.Arguments.Path,
-			Line:         convertLineFromLSP(hover.Range.Start.Line),
-			Character:    hover.Range.Start.Character,
-			EndLine:      convertLineFromLSP(hover.Range.End.Line),
-			EndCharacter: hover.Range.End.Character,
-		}
+		hoverRange := columns.documentRangeResult(params.Arguments.Path, *hover.Range)
+		hoverRange.URI = params.Arguments.Path
+		result.Range = &hoverRange
 	}
 
 	jsonData, err := json.Marshal(result)
@@ -574,16 +1341,24 @@ func (m mcpTools) HandleGetHover(
 
 // HandleGetDiagnostics handles get diagnostics requests.
 func (m mcpTools) HandleGetDiagnostics(
-	_ context.Context,
+	ctx context.Context,
 	_ *mcp.ServerSession,
 	params *mcp.CallToolParamsFor[GetDiagnosticsParams],
 ) (*mcp.CallToolResultFor[GetDiagnosticsResult], error) {
-	client, err := m.getClient(params.Arguments.Workspace)
+	ctx, cancel := m.withToolTimeout(ctx, toolGetDiagnostics)
+	defer cancel()
+
+	client, err := m.getClient(ctx, params.Arguments.Workspace)
 	if err != nil {
 		return nil, err
 	}
 
-	diagnostics, err := client.getDiagnostics(params.Arguments.Path)
+	columns, err := m.columnConverter(client, params.Arguments.ColumnUnit)
+	if err != nil {
+		return nil, err
+	}
+
+	diagnostics, err := client.getDiagnostics(ctx, params.Arguments.Path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get diagnostics: %w", err)
 	}
@@ -591,14 +1366,10 @@ func (m mcpTools) HandleGetDiagnostics(
 	// Convert diagnostics to results
 	diagResults := make([]DiagnosticResult, len(diagnostics))
 	for i, diag := range diagnostics {
+		diagRange := columns.documentRangeResult(params.Arguments.Path, diag.Range)
+		diagRange.URI = params.Arguments.Path
 		diagResults[i] = DiagnosticResult{
-			Range: LocationResult{
-				URI:          params.Arguments.Path,
-				Line:         convertLineFromLSP(diag.Range.Start.Line),
-				Character:    diag.Range.Start.Character,
-				EndLine:      convertLineFromLSP(diag.Range.End.Line),
-				EndCharacter: diag.Range.End.Character,
-			},
+			Range:    diagRange,
 			Severity: int(diag.Severity),
 			Code:     diag.Code,
 			Source:   diag.Source,
@@ -626,16 +1397,28 @@ func (m mcpTools) HandleGetDiagnostics(
 
 // HandleGetDocumentSymbols handles get document symbols requests.
 func (m mcpTools) HandleGetDocumentSymbols(
-	_ context.Context,
+	ctx context.Context,
 	_ *mcp.ServerSession,
 	params *mcp.CallToolParamsFor[GetDocumentSymbolsParams],
 ) (*mcp.CallToolResultFor[GetDocumentSymbolsResult], error) {
-	client, err := m.getClient(params.Arguments.Workspace)
+	ctx, cancel := m.withToolTimeout(ctx, toolGetDocumentSymbols)
+	defer cancel()
+
+	client, err := m.getClient(ctx, params.Arguments.Workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.requireTool(client, toolGetDocumentSymbols); err != nil {
+		return nil, err
+	}
+
+	columns, err := m.columnConverter(client, params.Arguments.ColumnUnit)
 	if err != nil {
 		return nil, err
 	}
 
-	symbols, err := client.getDocumentSymbols(params.Arguments.Path)
+	symbols, err := client.getDocumentSymbols(ctx, params.Arguments.Path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get document symbols: %w", err)
 	}
@@ -643,7 +1426,7 @@ func (m mcpTools) HandleGetDocumentSymbols(
 	// Convert symbols to results
 	symbolResults := make([]DocumentSymbolResult, len(symbols))
 	for i, sym := range symbols {
-		symbolResults[i] = m.convertDocumentSymbolToResult(sym)
+		symbolResults[i] = m.convertDocumentSymbolToResult(columns, params.Arguments.Path, sym)
 	}
 
 	result := GetDocumentSymbolsResult{
@@ -666,16 +1449,28 @@ func (m mcpTools) HandleGetDocumentSymbols(
 
 // HandleGetWorkspaceSymbols handles get workspace symbols requests.
 func (m mcpTools) HandleGetWorkspaceSymbols(
-	_ context.Context,
+	ctx context.Context,
 	_ *mcp.ServerSession,
 	params *mcp.CallToolParamsFor[GetWorkspaceSymbolsParams],
 ) (*mcp.CallToolResultFor[GetWorkspaceSymbolsResult], error) {
-	client, err := m.getClient(params.Arguments.Workspace)
+	ctx, cancel := m.withToolTimeout(ctx, toolGetWorkspaceSymbols)
+	defer cancel()
+
+	client, err := m.getClient(ctx, params.Arguments.Workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.requireTool(client, toolGetWorkspaceSymbols); err != nil {
+		return nil, err
+	}
+
+	columns, err := m.columnConverter(client, params.Arguments.ColumnUnit)
 	if err != nil {
 		return nil, err
 	}
 
-	symbols, err := client.getWorkspaceSymbols(params.Arguments.Query)
+	symbols, err := client.getWorkspaceSymbols(ctx, params.Arguments.Query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get workspace symbols: %w", err)
 	}
@@ -687,7 +1482,7 @@ func (m mcpTools) HandleGetWorkspaceSymbols(
 			Name:          sym.Name,
 			Kind:          sym.Kind,
 			Deprecated:    sym.Deprecated,
-			Location:      m.convertLocationToResult(sym.Location),
+			Location:      m.convertLocationToResult(columns, sym.Location),
 			ContainerName: sym.ContainerName,
 		}
 	}
@@ -712,17 +1507,30 @@ func (m mcpTools) HandleGetWorkspaceSymbols(
 
 // HandleGetSignatureHelp handles get signature help requests.
 func (m mcpTools) HandleGetSignatureHelp(
-	_ context.Context,
+	ctx context.Context,
 	_ *mcp.ServerSession,
 	params *mcp.CallToolParamsFor[GetSignatureHelpParams],
 ) (*mcp.CallToolResultFor[GetSignatureHelpResult], error) {
-	client, err := m.getClient(params.Arguments.Workspace)
+	ctx, cancel := m.withToolTimeout(ctx, toolGetSignatureHelp)
+	defer cancel()
+
+	client, err := m.getClient(ctx, params.Arguments.Workspace)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := m.requireTool(client, toolGetSignatureHelp); err != nil {
+		return nil, err
+	}
+
+	columns, err := m.columnConverter(client, params.Arguments.ColumnUnit)
+	if err != nil {
+		return nil, err
+	}
+	line := convertLineToLSP(params.Arguments.Line)
+
 	signatureHelp, err := client.getSignatureHelp(
-		params.Arguments.Path, convertLineToLSP(params.Arguments.Line), params.Arguments.Character)
+		ctx, params.Arguments.Path, line, columns.toLSP(params.Arguments.Path, line, params.Arguments.Character))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get signature help: %w", err)
 	}
@@ -763,19 +1571,33 @@ func (m mcpTools) HandleGetSignatureHelp(
 
 // HandleGetCompletions handles get completions requests.
 func (m mcpTools) HandleGetCompletions(
-	_ context.Context,
+	ctx context.Context,
 	_ *mcp.ServerSession,
 	params *mcp.CallToolParamsFor[GetCompletionsParams],
 ) (*mcp.CallToolResultFor[GetCompletionsResult], error) {
-	client, err := m.getClient(params.Arguments.Workspace)
+	ctx, cancel := m.withToolTimeout(ctx, toolGetCompletions)
+	defer cancel()
+
+	client, err := m.getClient(ctx, params.Arguments.Workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.requireTool(client, toolGetCompletions); err != nil {
+		return nil, err
+	}
+
+	columns, err := m.columnConverter(client, params.Arguments.ColumnUnit)
 	if err != nil {
 		return nil, err
 	}
+	line := convertLineToLSP(params.Arguments.Line)
 
 	completions, err := client.getCompletions(
+		ctx,
 		params.Arguments.Path,
-		convertLineToLSP(params.Arguments.Line),
-		params.Arguments.Character,
+		line,
+		columns.toLSP(params.Arguments.Path, line, params.Arguments.Character),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get completions: %w", err)
@@ -810,26 +1632,40 @@ func (m mcpTools) HandleGetCompletions(
 //
 //nolint:dupl // Similar pattern across location-based handlers is acceptable
 func (m mcpTools) HandleGetTypeDefinition(
-	_ context.Context,
+	ctx context.Context,
 	_ *mcp.ServerSession,
 	params *mcp.CallToolParamsFor[GetTypeDefinitionParams],
 ) (*mcp.CallToolResultFor[GetTypeDefinitionResult], error) {
-	client, err := m.getClient(params.Arguments.Workspace)
+	ctx, cancel := m.withToolTimeout(ctx, toolGetTypeDefinition)
+	defer cancel()
+
+	client, err := m.getClient(ctx, params.Arguments.Workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.requireTool(client, toolGetTypeDefinition); err != nil {
+		return nil, err
+	}
+
+	columns, err := m.columnConverter(client, params.Arguments.ColumnUnit)
 	if err != nil {
 		return nil, err
 	}
+	line := convertLineToLSP(params.Arguments.Line)
 
 	locations, err := client.getTypeDefinition(
+		ctx,
 		params.Arguments.Path,
-		convertLineToLSP(params.Arguments.Line),
-		params.Arguments.Character,
+		line,
+		columns.toLSP(params.Arguments.Path, line, params.Arguments.Character),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get type definition: %w", err)
 	}
 
 	result := GetTypeDefinitionResult{
-		Locations: m.convertLocationsToResults(locations),
+		Locations: m.convertLocationsToResults(columns, locations),
 	}
 
 	jsonData, err := json.Marshal(result)
@@ -850,23 +1686,36 @@ func (m mcpTools) HandleGetTypeDefinition(
 //
 //nolint:dupl // Similar pattern across location-based handlers is acceptable
 func (m mcpTools) HandleFindImplementations(
-	_ context.Context,
+	ctx context.Context,
 	_ *mcp.ServerSession,
 	params *mcp.CallToolParamsFor[FindImplementationsParams],
 ) (*mcp.CallToolResultFor[FindImplementationsResult], error) {
-	client, err := m.getClient(params.Arguments.Workspace)
+	ctx, cancel := m.withToolTimeout(ctx, toolFindImplementations)
+	defer cancel()
+
+	client, err := m.getClient(ctx, params.Arguments.Workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.requireTool(client, toolFindImplementations); err != nil {
+		return nil, err
+	}
+
+	columns, err := m.columnConverter(client, params.Arguments.ColumnUnit)
 	if err != nil {
 		return nil, err
 	}
+	line := convertLineToLSP(params.Arguments.Line)
 
 	locations, err := client.findImplementations(
-		params.Arguments.Path, convertLineToLSP(params.Arguments.Line), params.Arguments.Character)
+		ctx, params.Arguments.Path, line, columns.toLSP(params.Arguments.Path, line, params.Arguments.Character))
 	if err != nil {
 		return nil, fmt.Errorf("failed to find implementations: %w", err)
 	}
 
 	result := FindImplementationsResult{
-		Locations: m.convertLocationsToResults(locations),
+		Locations: m.convertLocationsToResults(columns, locations),
 	}
 
 	jsonData, err := json.Marshal(result)
@@ -883,24 +1732,156 @@ func (m mcpTools) HandleFindImplementations(
 	}, nil
 }
 
+// HandleCallHierarchy handles call hierarchy requests.
+func (m mcpTools) HandleCallHierarchy(
+	ctx context.Context,
+	_ *mcp.ServerSession,
+	params *mcp.CallToolParamsFor[CallHierarchyParams],
+) (*mcp.CallToolResultFor[CallHierarchyResult], error) {
+	direction := params.Arguments.Direction
+	switch direction {
+	case "":
+		direction = callDirectionBoth
+	case callDirectionIncoming, callDirectionOutgoing, callDirectionBoth:
+	default:
+		return nil, fmt.Errorf("unknown direction %q: use %s, %s or %s",
+			direction, callDirectionIncoming, callDirectionOutgoing, callDirectionBoth)
+	}
+
+	depth := params.Arguments.Depth
+	if depth == 0 {
+		depth = defaultCallHierarchyDepth
+	}
+	if depth < 1 || depth > maxCallHierarchyDepth {
+		return nil, fmt.Errorf("depth must be between 1 and %d", maxCallHierarchyDepth)
+	}
+
+	ctx, cancel := m.withToolTimeout(ctx, toolCallHierarchy)
+	defer cancel()
+
+	client, err := m.getClient(ctx, params.Arguments.Workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.requireTool(client, toolCallHierarchy); err != nil {
+		return nil, err
+	}
+
+	columns, err := m.columnConverter(client, params.Arguments.ColumnUnit)
+	if err != nil {
+		return nil, err
+	}
+	line := convertLineToLSP(params.Arguments.Line)
+
+	roots, err := client.callHierarchy(ctx, params.Arguments.Path, line,
+		columns.toLSP(params.Arguments.Path, line, params.Arguments.Character), direction, depth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get call hierarchy: %w", err)
+	}
+
+	result := CallHierarchyResult{
+		Items: make([]CallHierarchyItemResult, len(roots)),
+	}
+	for i, root := range roots {
+		result.Items[i] = CallHierarchyItemResult{
+			Name:              root.item.Name,
+			Kind:              root.item.Kind,
+			Detail:            root.item.Detail,
+			Location:          m.pathRangeResult(columns, root.path, root.item.SelectionRange),
+			Incoming:          m.convertCallHierarchyToResults(columns, root.incoming),
+			Outgoing:          m.convertCallHierarchyToResults(columns, root.outgoing),
+			IncomingTruncated: root.incomingTruncated,
+			OutgoingTruncated: root.outgoingTruncated,
+		}
+	}
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal result: %w", err)
+	}
+
+	return &mcp.CallToolResultFor[CallHierarchyResult]{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(jsonData),
+			},
+		},
+	}, nil
+}
+
 // HandleFormatDocument handles format document requests.
 func (m mcpTools) HandleFormatDocument(
-	_ context.Context,
+	ctx context.Context,
 	_ *mcp.ServerSession,
 	params *mcp.CallToolParamsFor[FormatDocumentParams],
 ) (*mcp.CallToolResultFor[FormatDocumentResult], error) {
-	client, err := m.getClient(params.Arguments.Workspace)
+	mode := params.Arguments.Mode
+	switch mode {
+	case "":
+		mode = formatModeEdits
+	case formatModeEdits, formatModeApply, formatModeCheck:
+	default:
+		return nil, fmt.Errorf("invalid mode %q: expected edits, apply or check", mode)
+	}
+
+	startLine, endLine := params.Arguments.StartLine, params.Arguments.EndLine
+	hasRange := startLine != 0 || endLine != 0
+	if hasRange && (startLine < 1 || endLine < startLine) {
+		return nil, fmt.Errorf("invalid line range %d-%d", startLine, endLine)
+	}
+
+	ctx, cancel := m.withToolTimeout(ctx, toolFormatDocument)
+	defer cancel()
+
+	client, err := m.getClient(ctx, params.Arguments.Workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.requireTool(client, toolFormatDocument); err != nil {
+		return nil, err
+	}
+
+	columns, err := m.columnConverter(client, params.Arguments.ColumnUnit)
 	if err != nil {
 		return nil, err
 	}
 
-	textEdits, err := client.formatDocument(params.Arguments.Path)
+	var textEdits []TextEdit
+	if hasRange {
+		// The range covers whole lines, up to the start of the line after endLine
+		formatRange := Range{
+			Start: Position{Line: convertLineToLSP(startLine)},
+			End:   Position{Line: endLine},
+		}
+		textEdits, err = client.formatRange(ctx, params.Arguments.Path, formatRange)
+	} else {
+		textEdits, err = client.formatDocument(ctx, params.Arguments.Path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to format document: %w", err)
+	}
+
+	edit := &WorkspaceEdit{Changes: map[string][]TextEdit{
+		workspaceFileURI(client.workspaceRoot(), params.Arguments.Path): textEdits,
+	}}
+	files, err := client.planWorkspaceEdit(edit, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to format document: %w", err)
 	}
 
+	_, diff := m.convertFileChangesToResults(files)
 	result := FormatDocumentResult{
-		Edits: m.convertTextEditsToResults(textEdits),
+		Edits: m.convertTextEditsToResults(columns, params.Arguments.Path, textEdits),
+		Clean: len(files) == 0,
+		Diff:  diff,
+	}
+	if mode == formatModeApply && len(files) > 0 {
+		if err := client.applyFileEdits(files); err != nil {
+			return nil, fmt.Errorf("failed to write formatted document: %w", err)
+		}
+		result.Applied = true
 	}
 
 	jsonData, err := json.Marshal(result)
@@ -919,22 +1900,34 @@ func (m mcpTools) HandleFormatDocument(
 
 // HandleOrganizeImports handles organize imports requests.
 func (m mcpTools) HandleOrganizeImports(
-	_ context.Context,
+	ctx context.Context,
 	_ *mcp.ServerSession,
 	params *mcp.CallToolParamsFor[OrganizeImportsParams],
 ) (*mcp.CallToolResultFor[OrganizeImportsResult], error) {
-	client, err := m.getClient(params.Arguments.Workspace)
+	ctx, cancel := m.withToolTimeout(ctx, toolOrganizeImports)
+	defer cancel()
+
+	client, err := m.getClient(ctx, params.Arguments.Workspace)
 	if err != nil {
 		return nil, err
 	}
 
-	textEdits, err := client.organizeImports(params.Arguments.Path)
+	if err := m.requireTool(client, toolOrganizeImports); err != nil {
+		return nil, err
+	}
+
+	columns, err := m.columnConverter(client, params.Arguments.ColumnUnit)
+	if err != nil {
+		return nil, err
+	}
+
+	textEdits, err := client.organizeImports(ctx, params.Arguments.Path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to organize imports: %w", err)
 	}
 
 	result := OrganizeImportsResult{
-		Edits: m.convertTextEditsToResults(textEdits),
+		Edits: m.convertTextEditsToResults(columns, params.Arguments.Path, textEdits),
 	}
 
 	jsonData, err := json.Marshal(result)
@@ -953,28 +1946,42 @@ func (m mcpTools) HandleOrganizeImports(
 
 // HandleGetInlayHints handles get inlay hints requests.
 func (m mcpTools) HandleGetInlayHints(
-	_ context.Context,
+	ctx context.Context,
 	_ *mcp.ServerSession,
 	params *mcp.CallToolParamsFor[GetInlayHintsParams],
 ) (*mcp.CallToolResultFor[GetInlayHintsResult], error) {
-	client, err := m.getClient(params.Arguments.Workspace)
+	ctx, cancel := m.withToolTimeout(ctx, toolGetInlayHints)
+	defer cancel()
+
+	client, err := m.getClient(ctx, params.Arguments.Workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.requireTool(client, toolGetInlayHints); err != nil {
+		return nil, err
+	}
+
+	columns, err := m.columnConverter(client, params.Arguments.ColumnUnit)
 	if err != nil {
 		return nil, err
 	}
 
+	startLine, endLine := convertLineToLSP(params.Arguments.StartLine), convertLineToLSP(params.Arguments.EndLine)
 	inlayHints, err := client.getInlayHints(
+		ctx,
 		params.Arguments.Path,
-		convertLineToLSP(params.Arguments.StartLine),
-		params.Arguments.StartChar,
-		convertLineToLSP(params.Arguments.EndLine),
-		params.Arguments.EndChar,
+		startLine,
+		columns.toLSP(params.Arguments.Path, startLine, params.Arguments.StartChar),
+		endLine,
+		columns.toLSP(params.Arguments.Path, endLine, params.Arguments.EndChar),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get inlay hints: %w", err)
 	}
 
 	result := GetInlayHintsResult{
-		Hints: m.convertInlayHintsToResults(inlayHints),
+		Hints: m.convertInlayHintsToResults(columns, params.Arguments.Path, inlayHints),
 	}
 
 	jsonData, err := json.Marshal(result)
@@ -991,110 +1998,361 @@ func (m mcpTools) HandleGetInlayHints(
 	}, nil
 }
 
-// setupMCPServer creates and configures the MCP server with gopls tools.
-func setupMCPServer(clients map[string]*goplsClient) *mcp.Server {
+// HandleRenameSymbol handles rename symbol requests.
+func (m mcpTools) HandleRenameSymbol(
+	ctx context.Context,
+	_ *mcp.ServerSession,
+	params *mcp.CallToolParamsFor[RenameSymbolParams],
+) (*mcp.CallToolResultFor[RenameSymbolResult], error) {
+	if params.Arguments.NewName == "" {
+		return nil, fmt.Errorf("newName is required")
+	}
+
+	ctx, cancel := m.withToolTimeout(ctx, toolRenameSymbol)
+	defer cancel()
+
+	client, err := m.getClient(ctx, params.Arguments.Workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.requireTool(client, toolRenameSymbol); err != nil {
+		return nil, err
+	}
+
+	columns, err := m.columnConverter(client, params.Arguments.ColumnUnit)
+	if err != nil {
+		return nil, err
+	}
+	line := convertLineToLSP(params.Arguments.Line)
+
+	files, err := client.renameSymbol(ctx, params.Arguments.Path, line,
+		columns.toLSP(params.Arguments.Path, line, params.Arguments.Character), params.Arguments.NewName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rename symbol: %w", err)
+	}
+
+	// Convert the edits before applying them, while columns still refer to the old contents
+	fileResults, diff := m.convertFileEditsToResults(columns, files)
+	result := RenameSymbolResult{
+		Files: fileResults,
+		Diff:  diff,
+	}
+	if params.Arguments.Apply {
+		if err := client.applyFileEdits(files); err != nil {
+			return nil, fmt.Errorf("failed to apply rename: %w", err)
+		}
+		result.Applied = true
+	}
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal result: %w", err)
+	}
+
+	return &mcp.CallToolResultFor[RenameSymbolResult]{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(jsonData),
+			},
+		},
+	}, nil
+}
+
+// HandleApplyWorkspaceEdit handles apply workspace edit requests.
+func (m mcpTools) HandleApplyWorkspaceEdit(
+	ctx context.Context,
+	_ *mcp.ServerSession,
+	params *mcp.CallToolParamsFor[ApplyEditParams],
+) (*mcp.CallToolResultFor[ApplyEditResult], error) {
+	ctx, cancel := m.withToolTimeout(ctx, toolApplyWorkspaceEdit)
+	defer cancel()
+
+	client, err := m.getClient(ctx, params.Arguments.Workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	columns, err := m.columnConverter(client, params.Arguments.ColumnUnit)
+	if err != nil {
+		return nil, err
+	}
+
+	edit, expectedHashes, err := m.workspaceEditFromParams(columns, params.Arguments.Changes)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := client.planWorkspaceEdit(edit, expectedHashes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply workspace edit: %w", err)
+	}
+
+	fileResults, diff := m.convertFileChangesToResults(files)
+	result := ApplyEditResult{
+		Files: fileResults,
+		Diff:  diff,
+	}
+	if !params.Arguments.DryRun {
+		if err := client.applyFileEdits(files); err != nil {
+			return nil, fmt.Errorf("failed to apply workspace edit: %w", err)
+		}
+		result.Applied = true
+	}
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal result: %w", err)
+	}
+
+	return &mcp.CallToolResultFor[ApplyEditResult]{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(jsonData),
+			},
+		},
+	}, nil
+}
+
+// HandleCodeActions handles code actions requests.
+func (m mcpTools) HandleCodeActions(
+	ctx context.Context,
+	_ *mcp.ServerSession,
+	params *mcp.CallToolParamsFor[CodeActionsParams],
+) (*mcp.CallToolResultFor[CodeActionsResult], error) {
+	ctx, cancel := m.withToolTimeout(ctx, toolCodeActions)
+	defer cancel()
+
+	arguments := params.Arguments
+	client, err := m.getClient(ctx, arguments.Workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.requireTool(client, toolCodeActions); err != nil {
+		return nil, err
+	}
+
+	var result CodeActionsResult
+	if arguments.ActionID != "" {
+		outcome, err := client.runCodeAction(ctx, arguments.ActionID, arguments.Apply)
+		if err != nil {
+			return nil, fmt.Errorf("failed to run code action: %w", err)
+		}
+
+		action := m.convertCodeActionToResult(arguments.ActionID, outcome.action)
+		result.Action = &action
+		result.Files, result.Diff = m.convertFileChangesToResults(outcome.files)
+		result.Applied = outcome.applied
+		result.CommandResult = outcome.commandResult
+	} else {
+		if arguments.Path == "" || arguments.StartLine < 1 {
+			return nil, fmt.Errorf("path and startLine are required to list code actions")
+		}
+		endLine, endChar := arguments.EndLine, arguments.EndChar
+		if endLine == 0 {
+			endLine, endChar = arguments.StartLine, max(endChar, arguments.StartChar)
+		}
+		if endLine < arguments.StartLine || (endLine == arguments.StartLine && endChar < arguments.StartChar) {
+			return nil, fmt.Errorf("end position %d:%d is before start position %d:%d",
+				endLine, endChar, arguments.StartLine, arguments.StartChar)
+		}
+
+		columns, err := m.columnConverter(client, arguments.ColumnUnit)
+		if err != nil {
+			return nil, err
+		}
+		startLine, lspEndLine := convertLineToLSP(arguments.StartLine), convertLineToLSP(endLine)
+		actionRange := Range{
+			Start: Position{Line: startLine, Character: columns.toLSP(arguments.Path, startLine, arguments.StartChar)},
+			End:   Position{Line: lspEndLine, Character: columns.toLSP(arguments.Path, lspEndLine, endChar)},
+		}
+
+		listed, err := client.listCodeActions(ctx, arguments.Path, actionRange, arguments.Kinds)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list code actions: %w", err)
+		}
+		for _, entry := range listed {
+			result.Actions = append(result.Actions, m.convertCodeActionToResult(entry.id, entry.action))
+		}
+	}
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal result: %w", err)
+	}
+
+	return &mcp.CallToolResultFor[CodeActionsResult]{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(jsonData),
+			},
+		},
+	}, nil
+}
+
+// setupMCPServer creates and configures the MCP server with gopls tools. Server
+// messages are forwarded to MCP clients until ctx is done.
+func setupMCPServer[C goplsClientInterface](
+	ctx context.Context, clients map[string]C, timeouts toolTimeouts,
+) *mcp.Server {
 	// Create MCP server
 	server := mcp.NewServer(&mcp.Implementation{Name: "gopls-mcp", Version: "v0.3.0"}, nil)
 
 	// Create MCP tools wrapper
-	tools := newMCPTools(clients)
+	tools := newMCPTools(clients, timeouts)
+
+	// Forward gopls window messages as MCP logging notifications
+	forwardServerMessages(ctx, server, tools.clients)
 
 	// Add gopls tools using new v0.2.0 API
 	// Workspace management tools
-	mcp.AddTool(server,
+	addTool(server, tools,
 		&mcp.Tool{
-			Name:        "list_workspaces",
+			Name:        toolListWorkspaces,
 			Description: "List all available Go workspaces configured in the server",
 		},
 		tools.HandleListWorkspaces)
+	addTool(server, tools,
+		&mcp.Tool{
+			Name:        toolServerInfo,
+			Description: "Report the gopls version and negotiated capabilities for each workspace",
+		},
+		tools.HandleServerInfo)
+	addTool(server, tools,
+		&mcp.Tool{
+			Name:        toolWorkspaceStatus,
+			Description: "Report whether gopls has finished loading each workspace and what it is currently doing",
+		},
+		tools.HandleWorkspaceStatus)
+	addTool(server, tools,
+		&mcp.Tool{
+			Name:        toolGetServerMessages,
+			Description: "Read recent messages from gopls (go list failures, missing modules, toolchain problems)",
+		},
+		tools.HandleGetServerMessages)
+	addTool(server, tools,
+		&mcp.Tool{
+			Name:        toolUpdateSettings,
+			Description: "Change gopls settings (staticcheck, analyses, hints, buildFlags) without restarting gopls",
+		},
+		tools.HandleUpdateSettings)
 
 	// Core navigation tools
-	mcp.AddTool(server,
+	addTool(server, tools,
 		&mcp.Tool{
-			Name:        "go_to_definition",
+			Name:        toolGoToDefinition,
 			Description: "Navigate to the definition of a symbol at the specified position in a Go file",
 		},
 		tools.HandleGoToDefinition)
-	mcp.AddTool(server,
+	addTool(server, tools,
 		&mcp.Tool{
-			Name:        "find_references",
+			Name:        toolFindReferences,
 			Description: "Find all references to a symbol at the specified position in a Go file",
 		},
 		tools.HandleFindReferences)
-	mcp.AddTool(server,
+	addTool(server, tools,
 		&mcp.Tool{
-			Name:        "get_hover_info",
+			Name:        toolGetHoverInfo,
 			Description: "Get hover information (documentation, type info) for a symbol at the specified position",
 		},
 		tools.HandleGetHover)
 
 	// Diagnostic and analysis tools
-	mcp.AddTool(server,
+	addTool(server, tools,
 		&mcp.Tool{
-			Name:        "get_diagnostics",
+			Name:        toolGetDiagnostics,
 			Description: "Get compilation errors, warnings, and other diagnostics for a Go file",
 		},
 		tools.HandleGetDiagnostics)
-	mcp.AddTool(server,
+	addTool(server, tools,
 		&mcp.Tool{
-			Name:        "get_document_symbols",
+			Name:        toolGetDocumentSymbols,
 			Description: "Get outline of symbols (functions, types, etc.) defined in a Go file",
 		},
 		tools.HandleGetDocumentSymbols)
-	mcp.AddTool(server,
+	addTool(server, tools,
 		&mcp.Tool{
-			Name:        "get_workspace_symbols",
+			Name:        toolGetWorkspaceSymbols,
 			Description: "Search for symbols across the entire Go workspace/project",
 		},
 		tools.HandleGetWorkspaceSymbols)
 
 	// Code assistance tools
-	mcp.AddTool(server,
+	addTool(server, tools,
 		&mcp.Tool{
-			Name:        "get_signature_help",
+			Name:        toolGetSignatureHelp,
 			Description: "Get function signature help (parameter information) at the specified position",
 		},
 		tools.HandleGetSignatureHelp)
-	mcp.AddTool(server,
+	addTool(server, tools,
 		&mcp.Tool{
-			Name:        "get_completions",
+			Name:        toolGetCompletions,
 			Description: "Get code completion suggestions at the specified position",
 		},
 		tools.HandleGetCompletions)
 
 	// Advanced navigation tools
-	mcp.AddTool(server,
+	addTool(server, tools,
 		&mcp.Tool{
-			Name:        "get_type_definition",
+			Name:        toolGetTypeDefinition,
 			Description: "Navigate to the type definition of a symbol at the specified position",
 		},
 		tools.HandleGetTypeDefinition)
-	mcp.AddTool(server,
+	addTool(server, tools,
 		&mcp.Tool{
-			Name:        "find_implementations",
+			Name:        toolFindImplementations,
 			Description: "Find all implementations of an interface or method at the specified position",
 		},
 		tools.HandleFindImplementations)
+	addTool(server, tools,
+		&mcp.Tool{
+			Name:        toolCallHierarchy,
+			Description: "Show the tree of functions calling, or called by, the function at the specified position",
+		},
+		tools.HandleCallHierarchy)
 
 	// Code maintenance tools
-	mcp.AddTool(server,
+	addTool(server, tools,
 		&mcp.Tool{
-			Name:        "format_document",
+			Name:        toolFormatDocument,
 			Description: "Format a Go source file according to gofmt standards",
 		},
 		tools.HandleFormatDocument)
-	mcp.AddTool(server,
+	addTool(server, tools,
 		&mcp.Tool{
-			Name:        "organize_imports",
+			Name:        toolOrganizeImports,
 			Description: "Organize and clean up import statements in a Go file",
 		},
 		tools.HandleOrganizeImports)
-	mcp.AddTool(server,
+	addTool(server, tools,
 		&mcp.Tool{
-			Name:        "get_inlay_hints",
+			Name:        toolGetInlayHints,
 			Description: "Get inlay hints (implicit parameter names, type information) for a range in a Go file",
 		},
 		tools.HandleGetInlayHints)
 
+	// Refactoring tools
+	addTool(server, tools,
+		&mcp.Tool{
+			Name:        toolRenameSymbol,
+			Description: "Rename a symbol across the workspace, previewing the edits as a diff or applying them",
+		},
+		tools.HandleRenameSymbol)
+	addTool(server, tools,
+		&mcp.Tool{
+			Name:        toolApplyWorkspaceEdit,
+			Description: "Apply text edits and create, rename or delete files as one atomic workspace edit",
+		},
+		tools.HandleApplyWorkspaceEdit)
+	addTool(server, tools,
+		&mcp.Tool{
+			Name: toolCodeActions,
+			Description: "List the quick fixes, refactorings and source actions gopls offers for a range, " +
+				"then run one by ID to preview or apply its edits. Actions backed by a gopls command " +
+				"can only be run with apply, since the command executes in gopls",
+		},
+		tools.HandleCodeActions)
+
 	return server
 }
@@ -43,8 +43,8 @@ func TestMCPGoToDefinitionIntegration(t *testing.T) {
 	defer cleanup()
 
 	// Create MCP tools wrapper to access handlers
-	clients := map[string]*goplsClient{workspacePath: newClient(workspacePath, newDebugLogger())}
-	tools := newMCPTools(clients)
+	clients := map[string]*goplsClient{workspacePath: newClient(workspacePath, workspaceConfig{}, newDebugLogger())}
+	tools := newMCPTools(clients, newToolTimeouts(defaultRequestTimeout))
 
 	// Start the client for testing
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -98,8 +98,8 @@ func TestMCPFindReferencesIntegration(t *testing.T) {
 	defer cleanup()
 
 	// Create clients and tools
-	clients := map[string]*goplsClient{workspacePath: newClient(workspacePath, newDebugLogger())}
-	tools := newMCPTools(clients)
+	clients := map[string]*goplsClient{workspacePath: newClient(workspacePath, workspaceConfig{}, newDebugLogger())}
+	tools := newMCPTools(clients, newToolTimeouts(defaultRequestTimeout))
 
 	// Start the client
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -149,8 +149,8 @@ func TestMCPGetHoverIntegration(t *testing.T) {
 	defer cleanup()
 
 	// Create clients and tools
-	clients := map[string]*goplsClient{workspacePath: newClient(workspacePath, newDebugLogger())}
-	tools := newMCPTools(clients)
+	clients := map[string]*goplsClient{workspacePath: newClient(workspacePath, workspaceConfig{}, newDebugLogger())}
+	tools := newMCPTools(clients, newToolTimeouts(defaultRequestTimeout))
 
 	// Start the client
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -228,8 +228,8 @@ func testFunction() int {
 	}
 
 	// Create clients and tools
-	clients := map[string]*goplsClient{workspacePath: newClient(workspacePath, newDebugLogger())}
-	tools := newMCPTools(clients)
+	clients := map[string]*goplsClient{workspacePath: newClient(workspacePath, workspaceConfig{}, newDebugLogger())}
+	tools := newMCPTools(clients, newToolTimeouts(defaultRequestTimeout))
 
 	// Start the client
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -283,10 +283,10 @@ func TestMCPListWorkspacesIntegration(t *testing.T) {
 
 	// Create clients and tools for multiple workspaces
 	clients := map[string]*goplsClient{
-		workspace1: newClient(workspace1, newDebugLogger()),
-		workspace2: newClient(workspace2, newDebugLogger()),
+		workspace1: newClient(workspace1, workspaceConfig{}, newDebugLogger()),
+		workspace2: newClient(workspace2, workspaceConfig{}, newDebugLogger()),
 	}
-	tools := newMCPTools(clients)
+	tools := newMCPTools(clients, newToolTimeouts(defaultRequestTimeout))
 
 	// Start clients
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -338,8 +338,8 @@ func TestMCPGetDocumentSymbolsIntegration(t *testing.T) {
 	defer cleanup()
 
 	// Create clients and tools
-	clients := map[string]*goplsClient{workspacePath: newClient(workspacePath, newDebugLogger())}
-	tools := newMCPTools(clients)
+	clients := map[string]*goplsClient{workspacePath: newClient(workspacePath, workspaceConfig{}, newDebugLogger())}
+	tools := newMCPTools(clients, newToolTimeouts(defaultRequestTimeout))
 
 	// Start the client
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -418,3 +418,110 @@ func findSubstring(s, substr string) bool {
 	}
 	return false
 }
+
+func TestMCPServerInfoIntegration(t *testing.T) {
+	requireGopls(t)
+
+	workspacePath, cleanup := createTempGoWorkspace(t)
+	defer cleanup()
+
+	clients := map[string]*goplsClient{workspacePath: newClient(workspacePath, workspaceConfig{}, newDebugLogger())}
+	tools := newMCPTools(clients, newToolTimeouts(defaultRequestTimeout))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	client := clients[workspacePath]
+	if err := client.start(ctx); err != nil {
+		t.Fatalf("failed to start client: %v", err)
+	}
+	defer func() { _ = client.stop() }()
+
+	params := &mcp.CallToolParamsFor[ServerInfoParams]{
+		Arguments: ServerInfoParams{Workspace: workspacePath},
+	}
+
+	result, err := tools.HandleServerInfo(context.Background(), nil, params)
+	if err != nil {
+		t.Fatalf("HandleServerInfo failed: %v", err)
+	}
+
+	infoResult := parseJSONResult(t, result)
+	if len(infoResult.Servers) != 1 {
+		t.Fatalf("Expected 1 server, got %d", len(infoResult.Servers))
+	}
+
+	server := infoResult.Servers[0]
+	if !server.Running || !server.Initialized {
+		t.Errorf("Expected running, initialized server, got %+v", server)
+	}
+	if server.ServerName != "gopls" || server.Version == "" {
+		t.Errorf("Expected gopls name and version, got %q %q", server.ServerName, server.Version)
+	}
+	if !server.Capabilities.DefinitionProvider.Enabled {
+		t.Error("Expected gopls to support go to definition")
+	}
+
+	t.Logf("gopls %s features: %v", server.Version, server.Features)
+}
+
+func TestMCPLazyStartIntegration(t *testing.T) {
+	requireGopls(t)
+
+	workspacePath, cleanup := createTempGoWorkspace(t)
+	defer cleanup()
+
+	clients := map[string]*goplsClient{workspacePath: newClient(workspacePath, workspaceConfig{}, newDebugLogger())}
+	tools := newMCPTools(clients, newToolTimeouts(defaultRequestTimeout))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := clients[workspacePath]
+	client.configureLifecycle(ctx, 0)
+	defer func() { _ = client.stop() }()
+
+	listWorkspaceState := func() string {
+		result, err := tools.HandleListWorkspaces(context.Background(), nil,
+			&mcp.CallToolParamsFor[ListWorkspacesParams]{})
+		if err != nil {
+			t.Fatalf("HandleListWorkspaces failed: %v", err)
+		}
+		return parseJSONResult(t, result).Workspaces[0].State
+	}
+	if state := listWorkspaceState(); state != lifecycleCold {
+		t.Fatalf("Expected cold workspace before the first tool call, got %s", state)
+	}
+
+	params := &mcp.CallToolParamsFor[GetHoverParams]{
+		Arguments: GetHoverParams{Workspace: workspacePath, Path: "main.go", Line: 7, Character: 11},
+	}
+
+	// The first tool call starts gopls
+	if _, err := tools.HandleGetHover(context.Background(), nil, params); err != nil {
+		t.Fatalf("HandleGetHover failed: %v", err)
+	}
+	if state := listWorkspaceState(); state != lifecycleReady {
+		t.Fatalf("Expected ready workspace after the first tool call, got %s", state)
+	}
+
+	// An idle workspace is stopped and started again by the next tool call
+	if !client.stopIfIdle(0) {
+		t.Fatal("Expected idle workspace to be stopped")
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for listWorkspaceState() != lifecycleStopped && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if state := listWorkspaceState(); state != lifecycleStopped {
+		t.Fatalf("Expected stopped workspace, got %s", state)
+	}
+
+	result, err := tools.HandleGetHover(context.Background(), nil, params)
+	if err != nil {
+		t.Fatalf("HandleGetHover after idle stop failed: %v", err)
+	}
+	if hoverResult := parseJSONResult(t, result); len(hoverResult.Contents) == 0 {
+		t.Error("Expected hover contents after restarting gopls")
+	}
+}
@@ -1,33 +1,20 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"encoding/json"
+	"strings"
 	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
-// Mock interfaces and implementations for testing
-
-// goplsClientInterface defines the interface for gopls client operations.
-type goplsClientInterface interface {
-	isRunning() bool
-	goToDefinition(path string, line, character int) ([]Location, error)
-	findReferences(path string, line, character int, includeDeclaration bool) ([]Location, error)
-	getHover(path string, line, character int) (*Hover, error)
-	getDiagnostics(path string) ([]Diagnostic, error)
-	getDocumentSymbols(path string) ([]DocumentSymbol, error)
-	getWorkspaceSymbols(query string) ([]SymbolInformation, error)
-	getSignatureHelp(path string, line, character int) (*SignatureHelp, error)
-	getCompletions(path string, line, character int) (*CompletionList, error)
-	getTypeDefinition(path string, line, character int) ([]Location, error)
-	findImplementations(path string, line, character int) ([]Location, error)
-	formatDocument(path string) ([]TextEdit, error)
-	organizeImports(path string) ([]TextEdit, error)
-	getInlayHints(path string, startLine, startChar, endLine, endChar int) ([]InlayHint, error)
-}
+// Mock implementations for testing
 
 // mockGoplsClient implements the goplsClientInterface for testing.
 type mockGoplsClient struct {
-	running bool
+	workspacePath string
+	running       bool
 	// Method call tracking
 	goToDefinitionCalled      bool
 	findReferencesCalled      bool
@@ -42,6 +29,11 @@ type mockGoplsClient struct {
 	formatDocumentCalled      bool
 	organizeImportsCalled     bool
 	getInlayHintsCalled       bool
+	renameSymbolCalled        bool
+	applyFileEditsCalled      bool
+	listCodeActionsCalled     bool
+	runCodeActionCalled       bool
+	callHierarchyCalled       bool
 
 	// Mock responses
 	mockLocations        []Location
@@ -53,6 +45,8 @@ type mockGoplsClient struct {
 	mockCompletions      *CompletionList
 	mockTextEdits        []TextEdit
 	mockInlayHints       []InlayHint
+	mockFileEdits        []fileEdit
+	mockCodeActions      []listedCodeAction
 
 	// Error responses
 	shouldError  bool
@@ -61,7 +55,8 @@ type mockGoplsClient struct {
 
 func newMockGoplsClient(running bool) *mockGoplsClient {
 	return &mockGoplsClient{
-		running: running,
+		workspacePath: "/test/workspace",
+		running:       running,
 		// Default mock responses
 		mockLocations: []Location{
 			{
@@ -163,11 +158,64 @@ func newMockGoplsClient(running bool) *mockGoplsClient {
 	}
 }
 
-func (m *mockGoplsClient) isRunning() bool {
-	return m.running
+func (m *mockGoplsClient) workspaceRoot() string {
+	return m.workspacePath
+}
+
+func (m *mockGoplsClient) activate(_ context.Context) error {
+	return nil
+}
+
+func (m *mockGoplsClient) waitUntilRunning(_ context.Context) error {
+	if !m.running {
+		return &mockError{"gopls is not running"}
+	}
+	return nil
+}
+
+func (m *mockGoplsClient) waitForInitialLoad(ctx context.Context) error {
+	return m.waitUntilRunning(ctx)
+}
+
+func (m *mockGoplsClient) lifecycle() string {
+	if m.running {
+		return lifecycleReady
+	}
+	return lifecycleStopped
+}
+
+func (m *mockGoplsClient) positionEncoding() string {
+	return positionEncodingUTF8
+}
+
+func (m *mockGoplsClient) supportsTool(_ string) bool {
+	return true
+}
+
+func (m *mockGoplsClient) describeServer() WorkspaceServerInfo {
+	return WorkspaceServerInfo{Workspace: m.workspacePath, Running: m.running, Initialized: m.running}
+}
+
+func (m *mockGoplsClient) workspaceStatus() WorkspaceStatus {
+	return WorkspaceStatus{Workspace: m.workspacePath, State: m.lifecycle(), Ready: m.running}
+}
+
+func (m *mockGoplsClient) serverMessages(_ int) []serverMessage {
+	return nil
 }
 
-func (m *mockGoplsClient) goToDefinition(_ string, _, _ int) ([]Location, error) {
+func (m *mockGoplsClient) setMessageListener(_ func(serverMessage)) {}
+
+func (m *mockGoplsClient) updateSettings(
+	_ context.Context, settings map[string]any, _ bool,
+) (map[string]any, error) {
+	if m.shouldError {
+		return nil, &mockError{m.errorMessage}
+	}
+	return settings, nil
+}
+
+func (m *mockGoplsClient) goToDefinition(_ context.Context, _ string, _, _ int) ([]Location, error) {
 	m.goToDefinitionCalled = true
 	if m.shouldError {
 		return nil, &mockError{m.errorMessage}
@@ -175,7 +223,7 @@ func (m *mockGoplsClient) goToDefinition(_ string, _, _ int) ([]Location, error)
 	return m.mockLocations, nil
 }
 
-func (m *mockGoplsClient) findReferences(_ string, _, _ int, _ bool) ([]Location, error) {
+func (m *mockGoplsClient) findReferences(_ context.Context, _ string, _, _ int, _ bool) ([]Location, error) {
 	m.findReferencesCalled = true
 	if m.shouldError {
 		return nil, &mockError{m.errorMessage}
@@ -183,7 +231,7 @@ func (m *mockGoplsClient) findReferences(_ string, _, _ int, _ bool) ([]Location
 	return m.mockLocations, nil
 }
 
-func (m *mockGoplsClient) getHover(_ string, _, _ int) (*Hover, error) {
+func (m *mockGoplsClient) getHover(_ context.Context, _ string, _, _ int) (*Hover, error) {
 	m.getHoverCalled = true
 	if m.shouldError {
 		return nil, &mockError{m.errorMessage}
@@ -191,7 +239,7 @@ func (m *mockGoplsClient) getHover(_ string, _, _ int) (*Hover, error) {
 	return m.mockHover, nil
 }
 
-func (m *mockGoplsClient) getDiagnostics(_ string) ([]Diagnostic, error) {
+func (m *mockGoplsClient) getDiagnostics(_ context.Context, _ string) ([]Diagnostic, error) {
 	m.getDiagnosticsCalled = true
 	if m.shouldError {
 		return nil, &mockError{m.errorMessage}
@@ -199,7 +247,7 @@ func (m *mockGoplsClient) getDiagnostics(_ string) ([]Diagnostic, error) {
 	return m.mockDiagnostics, nil
 }
 
-func (m *mockGoplsClient) getDocumentSymbols(_ string) ([]DocumentSymbol, error) {
+func (m *mockGoplsClient) getDocumentSymbols(_ context.Context, _ string) ([]DocumentSymbol, error) {
 	m.getDocumentSymbolsCalled = true
 	if m.shouldError {
 		return nil, &mockError{m.errorMessage}
@@ -207,7 +255,7 @@ func (m *mockGoplsClient) getDocumentSymbols(_ string) ([]DocumentSymbol, error)
 	return m.mockDocumentSymbols, nil
 }
 
-func (m *mockGoplsClient) getWorkspaceSymbols(_ string) ([]SymbolInformation, error) {
+func (m *mockGoplsClient) getWorkspaceSymbols(_ context.Context, _ string) ([]SymbolInformation, error) {
 	m.getWorkspaceSymbolsCalled = true
 	if m.shouldError {
 		return nil, &mockError{m.errorMessage}
@@ -215,7 +263,7 @@ func (m *mockGoplsClient) getWorkspaceSymbols(_ string) ([]SymbolInformation, er
 	return m.mockWorkspaceSymbols, nil
 }
 
-func (m *mockGoplsClient) getSignatureHelp(_ string, _, _ int) (*SignatureHelp, error) {
+func (m *mockGoplsClient) getSignatureHelp(_ context.Context, _ string, _, _ int) (*SignatureHelp, error) {
 	m.getSignatureHelpCalled = true
 	if m.shouldError {
 		return nil, &mockError{m.errorMessage}
@@ -223,7 +271,7 @@ func (m *mockGoplsClient) getSignatureHelp(_ string, _, _ int) (*SignatureHelp,
 	return m.mockSignatureHelp, nil
 }
 
-func (m *mockGoplsClient) getCompletions(_ string, _, _ int) (*CompletionList, error) {
+func (m *mockGoplsClient) getCompletions(_ context.Context, _ string, _, _ int) (*CompletionList, error) {
 	m.getCompletionsCalled = true
 	if m.shouldError {
 		return nil, &mockError{m.errorMessage}
@@ -231,7 +279,7 @@ func (m *mockGoplsClient) getCompletions(_ string, _, _ int) (*CompletionList, e
 	return m.mockCompletions, nil
 }
 
-func (m *mockGoplsClient) getTypeDefinition(_ string, _, _ int) ([]Location, error) {
+func (m *mockGoplsClient) getTypeDefinition(_ context.Context, _ string, _, _ int) ([]Location, error) {
 	m.getTypeDefinitionCalled = true
 	if m.shouldError {
 		return nil, &mockError{m.errorMessage}
@@ -239,7 +287,7 @@ func (m *mockGoplsClient) getTypeDefinition(_ string, _, _ int) ([]Location, err
 	return m.mockLocations, nil
 }
 
-func (m *mockGoplsClient) findImplementations(_ string, _, _ int) ([]Location, error) {
+func (m *mockGoplsClient) findImplementations(_ context.Context, _ string, _, _ int) ([]Location, error) {
 	m.findImplementationsCalled = true
 	if m.shouldError {
 		return nil, &mockError{m.errorMessage}
@@ -247,7 +295,7 @@ func (m *mockGoplsClient) findImplementations(_ string, _, _ int) ([]Location, e
 	return m.mockLocations, nil
 }
 
-func (m *mockGoplsClient) formatDocument(_ string) ([]TextEdit, error) {
+func (m *mockGoplsClient) formatDocument(_ context.Context, _ string) ([]TextEdit, error) {
 	m.formatDocumentCalled = true
 	if m.shouldError {
 		return nil, &mockError{m.errorMessage}
@@ -255,7 +303,15 @@ func (m *mockGoplsClient) formatDocument(_ string) ([]TextEdit, error) {
 	return m.mockTextEdits, nil
 }
 
-func (m *mockGoplsClient) organizeImports(_ string) ([]TextEdit, error) {
+func (m *mockGoplsClient) formatRange(_ context.Context, _ string, _ Range) ([]TextEdit, error) {
+	m.formatDocumentCalled = true
+	if m.shouldError {
+		return nil, &mockError{m.errorMessage}
+	}
+	return m.mockTextEdits, nil
+}
+
+func (m *mockGoplsClient) organizeImports(_ context.Context, _ string) ([]TextEdit, error) {
 	m.organizeImportsCalled = true
 	if m.shouldError {
 		return nil, &mockError{m.errorMessage}
@@ -263,7 +319,7 @@ func (m *mockGoplsClient) organizeImports(_ string) ([]TextEdit, error) {
 	return m.mockTextEdits, nil
 }
 
-func (m *mockGoplsClient) getInlayHints(_ string, _, _, _, _ int) ([]InlayHint, error) {
+func (m *mockGoplsClient) getInlayHints(_ context.Context, _ string, _, _, _, _ int) ([]InlayHint, error) {
 	m.getInlayHintsCalled = true
 	if m.shouldError {
 		return nil, &mockError{m.errorMessage}
@@ -271,152 +327,96 @@ func (m *mockGoplsClient) getInlayHints(_ string, _, _, _, _ int) ([]InlayHint,
 	return m.mockInlayHints, nil
 }
 
-// mockError implements error interface for testing.
-type mockError struct {
-	message string
-}
-
-func (e *mockError) Error() string {
-	return e.message
+func (m *mockGoplsClient) renameSymbol(_ context.Context, _ string, _, _ int, _ string) ([]fileEdit, error) {
+	m.renameSymbolCalled = true
+	if m.shouldError {
+		return nil, &mockError{m.errorMessage}
+	}
+	return m.mockFileEdits, nil
 }
 
-// Test utilities
-
-// testMCPTools is a test-specific version of mcpTools that uses interfaces.
-type testMCPTools struct {
-	clients map[string]goplsClientInterface
+func (m *mockGoplsClient) planWorkspaceEdit(_ *WorkspaceEdit, _ map[string]string) ([]fileEdit, error) {
+	if m.shouldError {
+		return nil, &mockError{m.errorMessage}
+	}
+	return m.mockFileEdits, nil
 }
 
-// newTestMCPTools creates a new test MCP tools instance.
-func newTestMCPTools(clients map[string]goplsClientInterface) testMCPTools {
-	return testMCPTools{
-		clients: clients,
+func (m *mockGoplsClient) applyFileEdits(_ []fileEdit) error {
+	m.applyFileEditsCalled = true
+	if m.shouldError {
+		return &mockError{m.errorMessage}
 	}
+	return nil
 }
 
-// getClient returns the goplsClient for the specified workspace (test version).
-func (m testMCPTools) getClient(workspace string) (goplsClientInterface, error) {
-	client, exists := m.clients[workspace]
-	if !exists {
-		return nil, fmt.Errorf("workspace not found: %s", workspace)
-	}
-	if !client.isRunning() {
-		return nil, fmt.Errorf("gopls is not running for workspace: %s", workspace)
+func (m *mockGoplsClient) listCodeActions(
+	_ context.Context, _ string, _ Range, _ []string,
+) ([]listedCodeAction, error) {
+	m.listCodeActionsCalled = true
+	if m.shouldError {
+		return nil, &mockError{m.errorMessage}
 	}
-	return client, nil
+	return m.mockCodeActions, nil
 }
 
-// Add the same conversion methods as mcpTools.
-func (m testMCPTools) convertLocationsToResults(locations []Location) []LocationResult {
-	results := make([]LocationResult, len(locations))
-	for i, loc := range locations {
-		results[i] = LocationResult{
-			URI:          loc.URI,
-			Line:         loc.Range.Start.Line + 1,
-			Character:    loc.Range.Start.Character,
-			EndLine:      loc.Range.End.Line + 1,
-			EndCharacter: loc.Range.End.Character,
-		}
+func (m *mockGoplsClient) runCodeAction(_ context.Context, _ string, apply bool) (codeActionOutcome, error) {
+	m.runCodeActionCalled = true
+	if m.shouldError {
+		return codeActionOutcome{}, &mockError{m.errorMessage}
 	}
-	return results
+	return codeActionOutcome{files: m.mockFileEdits, applied: apply}, nil
 }
 
-func (m testMCPTools) convertLocationToResult(location Location) LocationResult {
-	return LocationResult{
-		URI:          location.URI,
-		Line:         location.Range.Start.Line + 1,
-		Character:    location.Range.Start.Character,
-		EndLine:      location.Range.End.Line + 1,
-		EndCharacter: location.Range.End.Character,
+func (m *mockGoplsClient) callHierarchy(
+	_ context.Context, _ string, _, _ int, _ string, _ int,
+) ([]callHierarchyRoot, error) {
+	m.callHierarchyCalled = true
+	if m.shouldError {
+		return nil, &mockError{m.errorMessage}
 	}
+	return []callHierarchyRoot{}, nil
 }
 
-func (m testMCPTools) convertDocumentSymbolToResult(symbol DocumentSymbol) DocumentSymbolResult {
-	var children any
-	if len(symbol.Children) > 0 {
-		childResults := make([]DocumentSymbolResult, len(symbol.Children))
-		for i, child := range symbol.Children {
-			childResults[i] = m.convertDocumentSymbolToResult(child)
-		}
-		children = childResults
-	}
+// mockError implements error interface for testing.
+type mockError struct {
+	message string
+}
 
-	return DocumentSymbolResult{
-		Name:       symbol.Name,
-		Detail:     symbol.Detail,
-		Kind:       symbol.Kind,
-		Deprecated: symbol.Deprecated,
-		Range: LocationResult{
-			URI:          "",
-			Line:         symbol.Range.Start.Line + 1,
-			Character:    symbol.Range.Start.Character,
-			EndLine:      symbol.Range.End.Line + 1,
-			EndCharacter: symbol.Range.End.Character,
-		},
-		SelectionRange: LocationResult{
-			URI:          "",
-			Line:         symbol.SelectionRange.Start.Line + 1,
-			Character:    symbol.SelectionRange.Start.Character,
-			EndLine:      symbol.SelectionRange.End.Line + 1,
-			EndCharacter: symbol.SelectionRange.End.Character,
-		},
-		Children: children,
-	}
+func (e *mockError) Error() string {
+	return e.message
 }
 
-func (m testMCPTools) convertTextEditsToResults(textEdits []TextEdit) []TextEditResult {
-	results := make([]TextEditResult, len(textEdits))
-	for i, edit := range textEdits {
-		results[i] = TextEditResult{
-			Range: LocationResult{
-				URI:          "",
-				Line:         edit.Range.Start.Line + 1,
-				Character:    edit.Range.Start.Character,
-				EndLine:      edit.Range.End.Line + 1,
-				EndCharacter: edit.Range.End.Character,
-			},
-			NewText: edit.NewText,
-		}
-	}
-	return results
-}
-
-func (m testMCPTools) convertInlayHintsToResults(inlayHints []InlayHint) []InlayHintResult {
-	results := make([]InlayHintResult, len(inlayHints))
-	for i, hint := range inlayHints {
-		results[i] = InlayHintResult{
-			Position: LocationResult{
-				URI:          "",
-				Line:         hint.Position.Line + 1,
-				Character:    hint.Position.Character,
-				EndLine:      hint.Position.Line + 1,
-				EndCharacter: hint.Position.Character,
-			},
-			Label:   hint.Label,
-			Kind:    hint.Kind,
-			Tooltip: hint.Tooltip,
-		}
+// Test utilities
+
+// identityColumns returns a column converter that keeps gopls columns unchanged.
+func identityColumns(t *testing.T) *columnConverter {
+	t.Helper()
+
+	columns, err := newColumnConverter("", positionEncodingUTF8, "")
+	if err != nil {
+		t.Fatalf("failed to create column converter: %v", err)
 	}
-	return results
+	return columns
 }
 
-func createTestMCPTools() testMCPTools {
+func createTestMCPTools() (mcpTools, *mockGoplsClient) {
 	mockClient := newMockGoplsClient(true)
-	clients := map[string]goplsClientInterface{
+	clients := map[string]*mockGoplsClient{
 		"/test/workspace": mockClient,
 	}
-	return newTestMCPTools(clients)
+	return newMCPTools(clients, newToolTimeouts(defaultRequestTimeout)), mockClient
 }
 
-// Tests for testMCPTools struct
+// Tests for mcpTools struct
 
-func TestNewTestMCPTools(t *testing.T) {
+func TestNewMCPTools(t *testing.T) {
 	mockClient := newMockGoplsClient(true)
-	clients := map[string]goplsClientInterface{
+	clients := map[string]*mockGoplsClient{
 		"/test/workspace": mockClient,
 	}
 
-	tools := newTestMCPTools(clients)
+	tools := newMCPTools(clients, newToolTimeouts(defaultRequestTimeout))
 
 	if tools.clients == nil {
 		t.Fatal("Expected non-nil clients map")
@@ -432,10 +432,10 @@ func TestNewTestMCPTools(t *testing.T) {
 }
 
 func TestGetClient(t *testing.T) {
-	tools := createTestMCPTools()
+	tools, _ := createTestMCPTools()
 
 	// Test valid workspace
-	client, err := tools.getClient("/test/workspace")
+	client, err := tools.getClient(context.Background(), "/test/workspace")
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -444,28 +444,67 @@ func TestGetClient(t *testing.T) {
 	}
 
 	// Test invalid workspace
-	_, err = tools.getClient("/invalid/workspace")
+	_, err = tools.getClient(context.Background(), "/invalid/workspace")
 	if err == nil {
 		t.Error("Expected error for invalid workspace")
 	}
 
 	// Test workspace with non-running client
 	mockClient := newMockGoplsClient(false) // not running
-	clients := map[string]goplsClientInterface{
+	clients := map[string]*mockGoplsClient{
 		"/stopped/workspace": mockClient,
 	}
-	stoppedTools := newTestMCPTools(clients)
+	stoppedTools := newMCPTools(clients, newToolTimeouts(defaultRequestTimeout))
 
-	_, err = stoppedTools.getClient("/stopped/workspace")
+	_, err = stoppedTools.getClient(context.Background(), "/stopped/workspace")
 	if err == nil {
 		t.Error("Expected error for non-running client")
 	}
 }
 
+func TestHandleGoToDefinitionWithMock(t *testing.T) {
+	tools, mockClient := createTestMCPTools()
+
+	result, err := tools.HandleGoToDefinition(context.Background(), nil,
+		&mcp.CallToolParamsFor[GoToDefinitionParams]{Arguments: GoToDefinitionParams{
+			Workspace: "/test/workspace",
+			Path:      "test.go",
+			Line:      11,
+			Character: 5,
+		}})
+	if err != nil {
+		t.Fatalf("HandleGoToDefinition failed: %v", err)
+	}
+	if !mockClient.goToDefinitionCalled {
+		t.Error("Expected the handler to query the client")
+	}
+
+	var definition GoToDefinitionResult
+	text := result.Content[0].(*mcp.TextContent).Text
+	if err := json.Unmarshal([]byte(text), &definition); err != nil {
+		t.Fatalf("failed to parse result: %v", err)
+	}
+	if len(definition.Locations) != 1 || definition.Locations[0].Line != 11 {
+		t.Errorf("Unexpected definition result: %s", text)
+	}
+
+	mockClient.shouldError = true
+	mockClient.errorMessage = "gopls failed"
+	_, err = tools.HandleGoToDefinition(context.Background(), nil,
+		&mcp.CallToolParamsFor[GoToDefinitionParams]{Arguments: GoToDefinitionParams{
+			Workspace: "/test/workspace",
+			Path:      "test.go",
+			Line:      11,
+		}})
+	if err == nil || !strings.Contains(err.Error(), "gopls failed") {
+		t.Errorf("Expected client error to be returned, got %v", err)
+	}
+}
+
 // Tests for conversion methods
 
 func TestConvertLocationsToResults(t *testing.T) {
-	tools := createTestMCPTools()
+	tools, _ := createTestMCPTools()
 
 	locations := []Location{
 		{
@@ -484,7 +523,7 @@ func TestConvertLocationsToResults(t *testing.T) {
 		},
 	}
 
-	results := tools.convertLocationsToResults(locations)
+	results := tools.convertLocationsToResults(identityColumns(t), locations)
 
 	if len(results) != 2 {
 		t.Errorf("Expected 2 results, got %d", len(results))
@@ -509,7 +548,7 @@ func TestConvertLocationsToResults(t *testing.T) {
 }
 
 func TestConvertLocationToResult(t *testing.T) {
-	tools := createTestMCPTools()
+	tools, _ := createTestMCPTools()
 
 	location := Location{
 		URI: "test.go",
@@ -519,7 +558,7 @@ func TestConvertLocationToResult(t *testing.T) {
 		},
 	}
 
-	result := tools.convertLocationToResult(location)
+	result := tools.convertLocationToResult(identityColumns(t), location)
 
 	if result.URI != "test.go" {
 		t.Errorf("Expected URI 'test.go', got '%s'", result.URI)
@@ -539,7 +578,7 @@ func TestConvertLocationToResult(t *testing.T) {
 }
 
 func TestConvertDocumentSymbolToResult(t *testing.T) {
-	tools := createTestMCPTools()
+	tools, _ := createTestMCPTools()
 
 	symbol := DocumentSymbol{
 		Name:   "TestFunction",
@@ -569,7 +608,7 @@ func TestConvertDocumentSymbolToResult(t *testing.T) {
 		},
 	}
 
-	result := tools.convertDocumentSymbolToResult(symbol)
+	result := tools.convertDocumentSymbolToResult(identityColumns(t), "test.go", symbol)
 
 	if result.Name != "TestFunction" {
 		t.Errorf("Expected name 'TestFunction', got '%s'", result.Name)
@@ -607,7 +646,7 @@ func TestConvertDocumentSymbolToResult(t *testing.T) {
 }
 
 func TestConvertTextEditsToResults(t *testing.T) {
-	tools := createTestMCPTools()
+	tools, _ := createTestMCPTools()
 
 	edits := []TextEdit{
 		{
@@ -626,7 +665,7 @@ func TestConvertTextEditsToResults(t *testing.T) {
 		},
 	}
 
-	results := tools.convertTextEditsToResults(edits)
+	results := tools.convertTextEditsToResults(identityColumns(t), "test.go", edits)
 
 	if len(results) != 2 {
 		t.Errorf("Expected 2 results, got %d", len(results))
@@ -644,7 +683,7 @@ func TestConvertTextEditsToResults(t *testing.T) {
 }
 
 func TestConvertInlayHintsToResults(t *testing.T) {
-	tools := createTestMCPTools()
+	tools, _ := createTestMCPTools()
 
 	hints := []InlayHint{
 		{
@@ -660,7 +699,7 @@ func TestConvertInlayHintsToResults(t *testing.T) {
 		},
 	}
 
-	results := tools.convertInlayHintsToResults(hints)
+	results := tools.convertInlayHintsToResults(identityColumns(t), "test.go", hints)
 
 	if len(results) != 2 {
 		t.Errorf("Expected 2 results, got %d", len(results))
@@ -686,17 +725,17 @@ func TestMockClientMethodTracking(t *testing.T) {
 	mockClient := newMockGoplsClient(true)
 
 	// Test that methods are called and tracked
-	_, _ = mockClient.goToDefinition("test.go", 10, 5)
+	_, _ = mockClient.goToDefinition(context.Background(), "test.go", 10, 5)
 	if !mockClient.goToDefinitionCalled {
 		t.Error("Expected goToDefinitionCalled to be true")
 	}
 
-	_, _ = mockClient.findReferences("test.go", 10, 5, true)
+	_, _ = mockClient.findReferences(context.Background(), "test.go", 10, 5, true)
 	if !mockClient.findReferencesCalled {
 		t.Error("Expected findReferencesCalled to be true")
 	}
 
-	_, _ = mockClient.getHover("test.go", 10, 5)
+	_, _ = mockClient.getHover(context.Background(), "test.go", 10, 5)
 	if !mockClient.getHoverCalled {
 		t.Error("Expected getHoverCalled to be true")
 	}
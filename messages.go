@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	// maxServerMessages is how many window messages are kept per workspace.
+	maxServerMessages = 200
+	// defaultServerMessageLimit is how many messages get_server_messages returns by default.
+	defaultServerMessageLimit = 50
+	// serverMessageQueueSize bounds the messages waiting to be forwarded to MCP clients.
+	serverMessageQueueSize = 100
+	// messageForwardTimeout bounds sending one logging notification to an MCP client.
+	messageForwardTimeout = time.Second
+)
+
+// LSP message types, from most to least severe.
+const (
+	messageTypeError   = 1
+	messageTypeWarning = 2
+	messageTypeInfo    = 3
+	messageTypeLog     = 4
+	messageTypeDebug   = 5
+)
+
+// Kinds of window messages sent by gopls.
+const (
+	messageKindShow        = "showMessage"
+	messageKindLog         = "logMessage"
+	messageKindShowRequest = "showMessageRequest"
+)
+
+// messageSeverityNames maps LSP message types to their names.
+var messageSeverityNames = map[int]string{
+	messageTypeError:   "error",
+	messageTypeWarning: "warning",
+	messageTypeInfo:    "info",
+	messageTypeLog:     "log",
+	messageTypeDebug:   "debug",
+}
+
+// serverMessage is a window message received from gopls.
+type serverMessage struct {
+	time        time.Time
+	messageType int
+	kind        string
+	text        string
+}
+
+// severity returns the name of the message type.
+func (m serverMessage) severity() string {
+	if name, exists := messageSeverityNames[m.messageType]; exists {
+		return name
+	}
+	return messageSeverityNames[messageTypeLog]
+}
+
+// messageRing is a fixed-size buffer keeping the most recent messages.
+type messageRing struct {
+	entries []serverMessage
+	next    int
+	full    bool
+}
+
+// add stores a message, overwriting the oldest one when the ring is full.
+func (r *messageRing) add(message serverMessage) {
+	if r.entries == nil {
+		r.entries = make([]serverMessage, maxServerMessages)
+	}
+	r.entries[r.next] = message
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// snapshot returns the stored messages from oldest to newest.
+func (r *messageRing) snapshot() []serverMessage {
+	if !r.full {
+		return append([]serverMessage(nil), r.entries[:r.next]...)
+	}
+	messages := make([]serverMessage, 0, len(r.entries))
+	messages = append(messages, r.entries[r.next:]...)
+	return append(messages, r.entries[:r.next]...)
+}
+
+// setMessageListener registers a function called for every window message from gopls.
+// The listener runs on the LSP reader goroutine and must not block.
+func (c *goplsClient) setMessageListener(listener func(serverMessage)) {
+	c.messagesMux.Lock()
+	defer c.messagesMux.Unlock()
+
+	c.messageListener = listener
+}
+
+// recordMessage stores a window message and passes it to the listener.
+func (c *goplsClient) recordMessage(messageType int, kind, text string) {
+	message := serverMessage{time: time.Now(), messageType: messageType, kind: kind, text: text}
+
+	switch messageType {
+	case messageTypeError:
+		c.logger.Warn("gopls reported an error", "kind", kind, "message", text)
+	case messageTypeWarning:
+		c.logger.Info("gopls reported a warning", "kind", kind, "message", text)
+	default:
+		c.logger.Debug("gopls message", "kind", kind, "type", messageType, "message", text)
+	}
+
+	c.messagesMux.Lock()
+	c.messages.add(message)
+	listener := c.messageListener
+	c.messagesMux.Unlock()
+
+	if listener != nil {
+		listener(message)
+	}
+}
+
+// serverMessages returns the recorded window messages at least as severe as
+// maxType, oldest first.
+func (c *goplsClient) serverMessages(maxType int) []serverMessage {
+	c.messagesMux.Lock()
+	all := c.messages.snapshot()
+	c.messagesMux.Unlock()
+
+	messages := make([]serverMessage, 0, len(all))
+	for _, message := range all {
+		if message.messageType <= maxType {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+// handleShowMessage handles window/showMessage notifications.
+func (c *goplsClient) handleShowMessage(rawParams json.RawMessage) {
+	var params ShowMessageParams
+	if err := json.Unmarshal(rawParams, &params); err != nil {
+		c.logger.Debug("invalid showMessage params", "error", err)
+		return
+	}
+	c.recordMessage(params.Type, messageKindShow, params.Message)
+}
+
+// handleLogMessage handles window/logMessage notifications.
+func (c *goplsClient) handleLogMessage(rawParams json.RawMessage) {
+	var params LogMessageParams
+	if err := json.Unmarshal(rawParams, &params); err != nil {
+		c.logger.Debug("invalid logMessage params", "error", err)
+		return
+	}
+	c.recordMessage(params.Type, messageKindLog, params.Message)
+}
+
+// parseMessageSeverity converts a severity name into the least severe LSP
+// message type to include. An empty name includes every message.
+func parseMessageSeverity(name string) (int, error) {
+	if name == "" {
+		return messageTypeDebug, nil
+	}
+	for messageType, severity := range messageSeverityNames {
+		if severity == name {
+			return messageType, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown severity %q: expected error, warning, info, log or debug", name)
+}
+
+// mcpLoggingLevel maps an LSP message type to an MCP logging level.
+func mcpLoggingLevel(messageType int) mcp.LoggingLevel {
+	switch messageType {
+	case messageTypeError:
+		return "error"
+	case messageTypeWarning:
+		return "warning"
+	case messageTypeInfo:
+		return "info"
+	default:
+		return "debug"
+	}
+}
+
+// forwardServerMessages sends every window message from gopls to the
+// connected MCP clients as logging notifications until ctx is done. Messages are
+// queued so a slow MCP client never stalls the LSP reader; they are dropped when
+// the queue is full.
+func forwardServerMessages(ctx context.Context, server *mcp.Server, clients map[string]goplsClientInterface) {
+	type forwardedMessage struct {
+		workspace string
+		message   serverMessage
+	}
+	queue := make(chan forwardedMessage, serverMessageQueueSize)
+
+	for workspacePath, client := range clients {
+		client.setMessageListener(func(message serverMessage) {
+			select {
+			case queue <- forwardedMessage{workspace: workspacePath, message: message}:
+			default:
+				// Drop the message rather than stall the LSP reader
+			}
+		})
+	}
+
+	go func() {
+		defer func() {
+			for _, client := range clients {
+				client.setMessageListener(nil)
+			}
+		}()
+
+		for {
+			var forwarded forwardedMessage
+			select {
+			case forwarded = <-queue:
+			case <-ctx.Done():
+				return
+			}
+
+			params := &mcp.LoggingMessageParams{
+				Logger: "gopls",
+				Level:  mcpLoggingLevel(forwarded.message.messageType),
+				Data: map[string]string{
+					"workspace": forwarded.workspace,
+					"kind":      forwarded.message.kind,
+					"message":   forwarded.message.text,
+				},
+			}
+			for session := range server.Sessions() {
+				logCtx, cancel := context.WithTimeout(ctx, messageForwardTimeout)
+				_ = session.Log(logCtx, params)
+				cancel()
+			}
+		}
+	}()
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// sendWindowMessage delivers a window/showMessage or window/logMessage notification to the client.
+func sendWindowMessage(client *goplsClient, method string, messageType int, text string) {
+	client.handleLSPMessage(&incomingMessage{
+		JSONRPC: jsonrpcVersion,
+		Method:  method,
+		Params:  json.RawMessage(fmt.Sprintf(`{"type":%d,"message":%q}`, messageType, text)),
+	})
+}
+
+func TestMessageRing(t *testing.T) {
+	var ring messageRing
+	for i := range maxServerMessages + 5 {
+		ring.add(serverMessage{text: fmt.Sprint(i)})
+	}
+
+	messages := ring.snapshot()
+	if len(messages) != maxServerMessages {
+		t.Fatalf("Expected %d messages, got %d", maxServerMessages, len(messages))
+	}
+	if messages[0].text != "5" || messages[len(messages)-1].text != fmt.Sprint(maxServerMessages+4) {
+		t.Errorf("Expected oldest messages to be dropped, got %s..%s", messages[0].text, messages[len(messages)-1].text)
+	}
+}
+
+func TestServerMessages(t *testing.T) {
+	client := newClient("/test/workspace", workspaceConfig{}, newTestLogger())
+
+	var forwarded []serverMessage
+	client.setMessageListener(func(message serverMessage) {
+		forwarded = append(forwarded, message)
+	})
+
+	sendWindowMessage(client, "window/showMessage", messageTypeError, "go list failed")
+	sendWindowMessage(client, "window/logMessage", messageTypeLog, "loaded 3 packages")
+	sendWindowMessage(client, "window/logMessage", messageTypeWarning, "toolchain mismatch")
+
+	if len(forwarded) != 3 {
+		t.Errorf("Expected every message to reach the listener, got %d", len(forwarded))
+	}
+
+	warnings := client.serverMessages(messageTypeWarning)
+	if len(warnings) != 2 || warnings[0].text != "go list failed" || warnings[1].kind != messageKindLog {
+		t.Errorf("Unexpected filtered messages: %+v", warnings)
+	}
+
+	tools := newMCPTools(map[string]*goplsClient{"/test/workspace": client}, newToolTimeouts(defaultRequestTimeout))
+	params := &mcp.CallToolParamsFor[GetServerMessagesParams]{
+		Arguments: GetServerMessagesParams{Severity: "error"},
+	}
+	result, err := tools.HandleGetServerMessages(context.Background(), nil, params)
+	if err != nil {
+		t.Fatalf("HandleGetServerMessages failed: %v", err)
+	}
+	var messages GetServerMessagesResult
+	if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &messages); err != nil {
+		t.Fatalf("failed to decode result: %v", err)
+	}
+	if len(messages.Messages) != 1 || messages.Messages[0].Severity != "error" ||
+		messages.Messages[0].Workspace != "/test/workspace" {
+		t.Errorf("Unexpected messages: %+v", messages.Messages)
+	}
+
+	if _, err := parseMessageSeverity("fatal"); err == nil {
+		t.Error("Expected error for unknown severity")
+	}
+}
+
+func TestForwardServerMessagesStopsWithContext(t *testing.T) {
+	client := newClient("/test/workspace", workspaceConfig{}, newTestLogger())
+	ctx, cancel := context.WithCancel(context.Background())
+	clients := map[string]*goplsClient{"/test/workspace": client}
+	server := setupMCPServer(ctx, clients, newToolTimeouts(defaultRequestTimeout))
+
+	hasListener := func() bool {
+		client.messagesMux.Lock()
+		defer client.messagesMux.Unlock()
+		return client.messageListener != nil
+	}
+	if server == nil || !hasListener() {
+		t.Fatal("Expected server messages to be forwarded")
+	}
+
+	// The forwarder exits and detaches from the client once the server is done
+	cancel()
+	deadline := time.Now().Add(time.Second)
+	for hasListener() {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the forwarder to stop with its context")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
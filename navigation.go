@@ -1,17 +1,22 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"strconv"
 )
 
 // goToDefinition sends a textDocument/definition request to gopls using relative paths.
 //
 //nolint:dupl // LSP methods follow similar request/response patterns
-func (c *goplsClient) goToDefinition(relativePath string, line, character int) ([]Location, error) {
+func (c *goplsClient) goToDefinition(
+	ctx context.Context, relativePath string, line, character int,
+) ([]Location, error) {
 	c.logger.Debug("goToDefinition called", "relativePath", relativePath, "line", line, "character", character)
 
-	if !c.isRunning() {
-		return nil, fmt.Errorf("gopls is not running")
+	if err := c.waitUntilRunning(ctx); err != nil {
+		return nil, err
 	}
 
 	// Ensure file is open in gopls
@@ -19,48 +24,22 @@ func (c *goplsClient) goToDefinition(relativePath string, line, character int) (
 		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 
-	// Convert relative path to URI for LSP request
-	fileURI := c.relativePathToURI(relativePath)
-
-	// Create textDocument/definition request
-	request := map[string]any{
-		"jsonrpc": "2.0",
-		"id":      c.nextRequestID(),
-		"method":  "textDocument/definition",
-		"params": map[string]any{
-			"textDocument": map[string]any{
-				"uri": fileURI,
-			},
-			"position": map[string]any{
-				"line":      line,
-				"character": character,
-			},
-		},
-	}
-
-	// Send request and wait for response
-	response, err := c.sendRequestAndWait(request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get definition: %w", err)
-	}
+	method := "textDocument/definition"
+	return cachedQuery(c, method, relativePath, line, character, "", func() ([]Location, error) {
+		// Send textDocument/definition request and wait for response
+		var result json.RawMessage
+		params := c.positionParams(relativePath, line, character)
+		if err := c.call(ctx, method, params, &result); err != nil {
+			return nil, fmt.Errorf("failed to get definition: %w", err)
+		}
 
-	// Parse response to get locations
-	locations, err := c.parseLocationsFromResponse(response)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse locations: %w", err)
-	}
-
-	// Convert URIs back to relative paths
-	for i := range locations {
-		locations[i].URI = c.uriToRelativePath(locations[i].URI)
-	}
-
-	return locations, nil
+		return c.decodeRelativeLocations(result)
+	})
 }
 
 // findReferences sends a textDocument/references request to gopls using relative paths.
 func (c *goplsClient) findReferences(
-	relativePath string, line, character int, includeDeclaration bool,
+	ctx context.Context, relativePath string, line, character int, includeDeclaration bool,
 ) ([]Location, error) {
 	c.logger.Debug("findReferences called",
 		"relativePath", relativePath,
@@ -68,8 +47,9 @@ func (c *goplsClient) findReferences(
 		"character", character,
 		"includeDeclaration", includeDeclaration)
 
-	if !c.isRunning() {
-		return nil, fmt.Errorf("gopls is not running")
+	// Workspace-wide results are partial until gopls has loaded every package
+	if err := c.waitForInitialLoad(ctx); err != nil {
+		return nil, err
 	}
 
 	// Ensure file is open in gopls
@@ -77,56 +57,31 @@ func (c *goplsClient) findReferences(
 		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 
-	// Convert relative path to URI for LSP request
-	fileURI := c.relativePathToURI(relativePath)
-
-	// Create textDocument/references request
-	request := map[string]any{
-		"jsonrpc": "2.0",
-		"id":      c.nextRequestID(),
-		"method":  "textDocument/references",
-		"params": ReferenceParams{
-			TextDocumentPositionParams: TextDocumentPositionParams{
-				TextDocument: TextDocumentIdentifier{URI: fileURI},
-				Position: Position{
-					Line:      line,
-					Character: character,
-				},
-			},
-			Context: ReferenceContext{
-				IncludeDeclaration: includeDeclaration,
-			},
+	params := ReferenceParams{
+		TextDocumentPositionParams: c.positionParams(relativePath, line, character),
+		Context: ReferenceContext{
+			IncludeDeclaration: includeDeclaration,
 		},
 	}
 
-	// Send request and wait for response
-	response, err := c.sendRequestAndWait(request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to find references: %w", err)
-	}
-
-	// Parse response to get locations
-	locations, err := c.parseLocationsFromResponse(response)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse locations: %w", err)
-	}
+	method, extra := "textDocument/references", strconv.FormatBool(includeDeclaration)
+	return cachedQuery(c, method, relativePath, line, character, extra, func() ([]Location, error) {
+		// Send textDocument/references request and wait for response
+		var result json.RawMessage
+		if err := c.call(ctx, method, params, &result); err != nil {
+			return nil, fmt.Errorf("failed to find references: %w", err)
+		}
 
-	// Convert URIs back to relative paths
-	for i := range locations {
-		locations[i].URI = c.uriToRelativePath(locations[i].URI)
-	}
-
-	return locations, nil
+		return c.decodeRelativeLocations(result)
+	})
 }
 
 // getHover sends a textDocument/hover request to gopls using relative paths.
-//
-//nolint:dupl // LSP methods follow similar request/response patterns
-func (c *goplsClient) getHover(relativePath string, line, character int) (*Hover, error) {
+func (c *goplsClient) getHover(ctx context.Context, relativePath string, line, character int) (*Hover, error) {
 	c.logger.Debug("getHover called", "relativePath", relativePath, "line", line, "character", character)
 
-	if !c.isRunning() {
-		return nil, fmt.Errorf("gopls is not running")
+	if err := c.waitUntilRunning(ctx); err != nil {
+		return nil, err
 	}
 
 	// Ensure file is open in gopls
@@ -134,48 +89,37 @@ func (c *goplsClient) getHover(relativePath string, line, character int) (*Hover
 		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 
-	// Convert relative path to URI for LSP request
-	fileURI := c.relativePathToURI(relativePath)
-
-	// Create textDocument/hover request
-	request := map[string]any{
-		"jsonrpc": "2.0",
-		"id":      c.nextRequestID(),
-		"method":  "textDocument/hover",
-		"params": map[string]any{
-			"textDocument": map[string]any{
-				"uri": fileURI,
-			},
-			"position": map[string]any{
-				"line":      line,
-				"character": character,
-			},
-		},
-	}
-
-	// Send request and wait for response
-	response, err := c.sendRequestAndWait(request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get hover info: %w", err)
-	}
-
-	// Parse response to get hover info
-	hover, err := c.parseHoverFromResponse(response)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse hover info: %w", err)
-	}
-
-	return hover, nil
+	method := "textDocument/hover"
+	return cachedQuery(c, method, relativePath, line, character, "", func() (*Hover, error) {
+		// Send textDocument/hover request and wait for response
+		var result *hoverResult
+		params := c.positionParams(relativePath, line, character)
+		if err := c.call(ctx, method, params, &result); err != nil {
+			return nil, fmt.Errorf("failed to get hover info: %w", err)
+		}
+
+		// Handle null result (no hover info available)
+		if result == nil {
+			return &Hover{Contents: []string{}}, nil
+		}
+
+		return &Hover{
+			Contents: parseHoverContents(result.Contents),
+			Range:    result.Range,
+		}, nil
+	})
 }
 
 // getTypeDefinition sends a textDocument/typeDefinition request to gopls.
 //
 //nolint:dupl // LSP methods follow similar request/response patterns
-func (c *goplsClient) getTypeDefinition(relativePath string, line, character int) ([]Location, error) {
+func (c *goplsClient) getTypeDefinition(
+	ctx context.Context, relativePath string, line, character int,
+) ([]Location, error) {
 	c.logger.Debug("getTypeDefinition called", "relativePath", relativePath, "line", line, "character", character)
 
-	if !c.isRunning() {
-		return nil, fmt.Errorf("gopls is not running")
+	if err := c.waitUntilRunning(ctx); err != nil {
+		return nil, err
 	}
 
 	// Ensure file is open in gopls
@@ -183,53 +127,30 @@ func (c *goplsClient) getTypeDefinition(relativePath string, line, character int
 		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 
-	// Convert relative path to URI for LSP request
-	fileURI := c.relativePathToURI(relativePath)
-
-	// Create textDocument/typeDefinition request
-	request := map[string]any{
-		"jsonrpc": "2.0",
-		"id":      c.nextRequestID(),
-		"method":  "textDocument/typeDefinition",
-		"params": map[string]any{
-			"textDocument": map[string]any{
-				"uri": fileURI,
-			},
-			"position": map[string]any{
-				"line":      line,
-				"character": character,
-			},
-		},
-	}
-
-	// Send request and wait for response
-	response, err := c.sendRequestAndWait(request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get type definition: %w", err)
-	}
-
-	// Parse response to get locations
-	locations, err := c.parseLocationsFromResponse(response)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse locations: %w", err)
-	}
-
-	// Convert URIs back to relative paths
-	for i := range locations {
-		locations[i].URI = c.uriToRelativePath(locations[i].URI)
-	}
+	method := "textDocument/typeDefinition"
+	return cachedQuery(c, method, relativePath, line, character, "", func() ([]Location, error) {
+		// Send textDocument/typeDefinition request and wait for response
+		var result json.RawMessage
+		params := c.positionParams(relativePath, line, character)
+		if err := c.call(ctx, method, params, &result); err != nil {
+			return nil, fmt.Errorf("failed to get type definition: %w", err)
+		}
 
-	return locations, nil
+		return c.decodeRelativeLocations(result)
+	})
 }
 
 // findImplementations sends a textDocument/implementation request to gopls.
 //
 //nolint:dupl // LSP methods follow similar request/response patterns
-func (c *goplsClient) findImplementations(relativePath string, line, character int) ([]Location, error) {
+func (c *goplsClient) findImplementations(
+	ctx context.Context, relativePath string, line, character int,
+) ([]Location, error) {
 	c.logger.Debug("findImplementations called", "relativePath", relativePath, "line", line, "character", character)
 
-	if !c.isRunning() {
-		return nil, fmt.Errorf("gopls is not running")
+	// Workspace-wide results are partial until gopls has loaded every package
+	if err := c.waitForInitialLoad(ctx); err != nil {
+		return nil, err
 	}
 
 	// Ensure file is open in gopls
@@ -237,38 +158,37 @@ func (c *goplsClient) findImplementations(relativePath string, line, character i
 		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 
-	// Convert relative path to URI for LSP request
-	fileURI := c.relativePathToURI(relativePath)
-
-	// Create textDocument/implementation request
-	request := map[string]any{
-		"jsonrpc": "2.0",
-		"id":      c.nextRequestID(),
-		"method":  "textDocument/implementation",
-		"params": map[string]any{
-			"textDocument": map[string]any{
-				"uri": fileURI,
-			},
-			"position": map[string]any{
-				"line":      line,
-				"character": character,
-			},
-		},
-	}
+	method := "textDocument/implementation"
+	return cachedQuery(c, method, relativePath, line, character, "", func() ([]Location, error) {
+		// Send textDocument/implementation request and wait for response
+		var result json.RawMessage
+		params := c.positionParams(relativePath, line, character)
+		if err := c.call(ctx, method, params, &result); err != nil {
+			return nil, fmt.Errorf("failed to find implementations: %w", err)
+		}
 
-	// Send request and wait for response
-	response, err := c.sendRequestAndWait(request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to find implementations: %w", err)
+		return c.decodeRelativeLocations(result)
+	})
+}
+
+// positionParams builds text document position parameters for a workspace-relative path.
+func (c *goplsClient) positionParams(relativePath string, line, character int) TextDocumentPositionParams {
+	return TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: c.relativePathToURI(relativePath)},
+		Position: Position{
+			Line:      line,
+			Character: character,
+		},
 	}
+}
 
-	// Parse response to get locations
-	locations, err := c.parseLocationsFromResponse(response)
+// decodeRelativeLocations decodes a locations result and converts URIs back to relative paths.
+func (c *goplsClient) decodeRelativeLocations(result json.RawMessage) ([]Location, error) {
+	locations, err := decodeLocations(result)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse locations: %w", err)
 	}
 
-	// Convert URIs back to relative paths
 	for i := range locations {
 		locations[i].URI = c.uriToRelativePath(locations[i].URI)
 	}
@@ -1,136 +1,394 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"strings"
 )
 
-// parseLocationsFromResponse extracts locations from LSP response.
-func (c *goplsClient) parseLocationsFromResponse(response map[string]any) ([]Location, error) {
-	result, resultOk := response["result"]
-	if !resultOk {
-		return nil, fmt.Errorf("invalid response format")
+// Decoding helpers for LSP result shapes that are unions of several JSON types.
+
+// isNullResult reports whether a raw LSP result is absent or null.
+func isNullResult(raw json.RawMessage) bool {
+	trimmed := bytes.TrimSpace(raw)
+	return len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null"))
+}
+
+// locationLink represents the LocationLink shape gopls may return for definition requests.
+type locationLink struct {
+	TargetURI            string `json:"targetUri"`
+	TargetRange          Range  `json:"targetRange"`
+	TargetSelectionRange Range  `json:"targetSelectionRange"`
+}
+
+// decodeLocations decodes a Location, Location[] or LocationLink[] result.
+func decodeLocations(raw json.RawMessage) ([]Location, error) {
+	if isNullResult(raw) {
+		return []Location{}, nil
+	}
+
+	trimmed := bytes.TrimSpace(raw)
+	if trimmed[0] == '{' {
+		var location Location
+		if err := json.Unmarshal(trimmed, &location); err != nil {
+			return nil, fmt.Errorf("invalid location: %w", err)
+		}
+		return []Location{location}, nil
 	}
 
-	locations, locationsOk := result.([]any)
-	if !locationsOk {
-		return nil, fmt.Errorf("invalid response format")
+	var items []json.RawMessage
+	if err := json.Unmarshal(trimmed, &items); err != nil {
+		return nil, fmt.Errorf("invalid locations: %w", err)
 	}
 
-	var locs []Location
-	for _, loc := range locations {
-		if locMap, locMapOk := loc.(map[string]any); locMapOk {
-			location := c.parseLocationFromMap(locMap)
-			locs = append(locs, location)
+	locations := make([]Location, 0, len(items))
+	for _, item := range items {
+		var link locationLink
+		if err := json.Unmarshal(item, &link); err != nil {
+			return nil, fmt.Errorf("invalid location: %w", err)
+		}
+		if link.TargetURI != "" {
+			locations = append(locations, Location{URI: link.TargetURI, Range: link.TargetSelectionRange})
+			continue
+		}
+
+		var location Location
+		if err := json.Unmarshal(item, &location); err != nil {
+			return nil, fmt.Errorf("invalid location: %w", err)
 		}
+		locations = append(locations, location)
 	}
-	return locs, nil
+	return locations, nil
 }
 
-// parseLocationFromMap parses a single location from a map.
-func (c *goplsClient) parseLocationFromMap(locMap map[string]any) Location {
-	var location Location
-	if locURI, uriOk := locMap["uri"].(string); uriOk {
-		location.URI = locURI
+// decodeMarkup decodes a value that is either a plain string or a MarkupContent object.
+func decodeMarkup(raw json.RawMessage) string {
+	if isNullResult(raw) {
+		return ""
 	}
-	if rangeMap, rangeMapOk := locMap["range"].(map[string]any); rangeMapOk {
-		location.Range = c.parseRange(rangeMap)
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
 	}
-	return location
+
+	var markup MarkupContent
+	if err := json.Unmarshal(raw, &markup); err == nil {
+		return markup.Value
+	}
+	return ""
 }
 
-// parseRange parses a range from a map.
-func (c *goplsClient) parseRange(rangeMap map[string]any) Range {
-	var rng Range
-	if startMap, startMapOk := rangeMap["start"].(map[string]any); startMapOk {
-		if line, lineOk := startMap["line"].(float64); lineOk {
-			rng.Start.Line = int(line)
-		}
-		if char, charOk := startMap["character"].(float64); charOk {
-			rng.Start.Character = int(char)
-		}
+// hoverResult represents the raw result of a textDocument/hover request.
+type hoverResult struct {
+	Contents json.RawMessage `json:"contents"`
+	Range    *Range          `json:"range,omitempty"`
+}
+
+// parseHoverContents parses hover contents given as MarkupContent, MarkedString or MarkedString[].
+func parseHoverContents(raw json.RawMessage) []string {
+	result := []string{}
+	if isNullResult(raw) {
+		return result
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		items = []json.RawMessage{raw}
 	}
-	if endMap, endMapOk := rangeMap["end"].(map[string]any); endMapOk {
-		if line, lineOk := endMap["line"].(float64); lineOk {
-			rng.End.Line = int(line)
+
+	for _, item := range items {
+		var text string
+		if err := json.Unmarshal(item, &text); err == nil {
+			result = append(result, text)
+			continue
 		}
-		if char, charOk := endMap["character"].(float64); charOk {
-			rng.End.Character = int(char)
+
+		// MarkupContent and MarkedString objects both carry their text in value
+		var content struct {
+			Value string `json:"value"`
+		}
+		if err := json.Unmarshal(item, &content); err == nil && content.Value != "" {
+			result = append(result, content.Value)
 		}
 	}
-	return rng
+	return result
 }
 
-// parseHoverContents parses hover contents from any type.
-func (c *goplsClient) parseHoverContents(contents any) []string {
-	var result []string
+// UnmarshalJSON decodes a diagnostic whose code may be either a string or a number.
+func (d *Diagnostic) UnmarshalJSON(data []byte) error {
+	type diagnosticAlias Diagnostic
+	aux := struct {
+		*diagnosticAlias
+		Code json.RawMessage `json:"code,omitempty"`
+	}{diagnosticAlias: (*diagnosticAlias)(d)}
 
-	// Handle string directly
-	if contentStr, contentStrOk := contents.(string); contentStrOk {
-		result = append(result, contentStr)
-		return result
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
 	}
 
-	// Handle single MarkupContent object
-	if contentMap, contentMapOk := contents.(map[string]any); contentMapOk {
-		if _, kindOk := contentMap["kind"].(string); kindOk {
-			if value, valueOk := contentMap["value"].(string); valueOk {
-				result = append(result, value)
-			}
+	d.Code = ""
+	if isNullResult(aux.Code) {
+		return nil
+	}
+	var code string
+	if err := json.Unmarshal(aux.Code, &code); err == nil {
+		d.Code = code
+		return nil
+	}
+	var number json.Number
+	if err := json.Unmarshal(aux.Code, &number); err == nil {
+		d.Code = number.String()
+	}
+	return nil
+}
+
+// UnmarshalJSON decodes signature information whose documentation may be MarkupContent.
+func (s *SignatureInformation) UnmarshalJSON(data []byte) error {
+	type signatureAlias SignatureInformation
+	aux := struct {
+		*signatureAlias
+		Documentation json.RawMessage `json:"documentation,omitempty"`
+	}{signatureAlias: (*signatureAlias)(s)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	s.Documentation = decodeMarkup(aux.Documentation)
+	return nil
+}
+
+// UnmarshalJSON decodes parameter information whose label may be an offset pair
+// and whose documentation may be MarkupContent. Offset labels are left empty.
+func (p *ParameterInformation) UnmarshalJSON(data []byte) error {
+	var aux struct {
+		Label         json.RawMessage `json:"label"`
+		Documentation json.RawMessage `json:"documentation,omitempty"`
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	p.Label = ""
+	var label string
+	if err := json.Unmarshal(aux.Label, &label); err == nil {
+		p.Label = label
+	}
+	p.Documentation = decodeMarkup(aux.Documentation)
+	return nil
+}
+
+// UnmarshalJSON decodes a completion item whose documentation may be MarkupContent.
+func (i *CompletionItem) UnmarshalJSON(data []byte) error {
+	type completionItemAlias CompletionItem
+	aux := struct {
+		*completionItemAlias
+		Documentation json.RawMessage `json:"documentation,omitempty"`
+	}{completionItemAlias: (*completionItemAlias)(i)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	i.Documentation = decodeMarkup(aux.Documentation)
+	return nil
+}
+
+// decodeCompletionList decodes a CompletionList or CompletionItem[] result.
+func decodeCompletionList(raw json.RawMessage) (*CompletionList, error) {
+	if isNullResult(raw) {
+		return &CompletionList{}, nil
+	}
+
+	var completionList CompletionList
+	if bytes.TrimSpace(raw)[0] == '[' {
+		if err := json.Unmarshal(raw, &completionList.Items); err != nil {
+			return nil, fmt.Errorf("invalid completion items: %w", err)
 		}
-		return result
+		return &completionList, nil
 	}
 
-	// Handle array of contents
-	if contentList, contentListOk := contents.([]any); contentListOk {
-		for _, content := range contentList {
-			if contentStr, contentStrOk := content.(string); contentStrOk {
-				result = append(result, contentStr)
-				continue
-			}
+	if err := json.Unmarshal(raw, &completionList); err != nil {
+		return nil, fmt.Errorf("invalid completion list: %w", err)
+	}
+	return &completionList, nil
+}
 
-			contentMap, contentMapOk := content.(map[string]any)
-			if !contentMapOk {
-				continue
-			}
+// UnmarshalJSON decodes an inlay hint whose label may be a list of label parts
+// and whose tooltip may be MarkupContent.
+func (h *InlayHint) UnmarshalJSON(data []byte) error {
+	var aux struct {
+		Position Position        `json:"position"`
+		Label    json.RawMessage `json:"label"`
+		Kind     int             `json:"kind,omitempty"`
+		Tooltip  json.RawMessage `json:"tooltip,omitempty"`
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
 
-			// Handle MarkupContent format
-			if _, kindOk := contentMap["kind"].(string); !kindOk {
-				continue
-			}
+	h.Position = aux.Position
+	h.Kind = aux.Kind
+	h.Tooltip = decodeMarkup(aux.Tooltip)
+
+	h.Label = ""
+	var label string
+	if err := json.Unmarshal(aux.Label, &label); err == nil {
+		h.Label = label
+		return nil
+	}
+	var parts []struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(aux.Label, &parts); err == nil {
+		var builder strings.Builder
+		for _, part := range parts {
+			builder.WriteString(part.Value)
+		}
+		h.Label = builder.String()
+	}
+	return nil
+}
+
+// decodeCodeActions decodes a (Command | CodeAction)[] result, wrapping bare commands
+// into code actions.
+func decodeCodeActions(raw json.RawMessage) ([]CodeAction, error) {
+	if isNullResult(raw) {
+		return []CodeAction{}, nil
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, fmt.Errorf("invalid code actions: %w", err)
+	}
+
+	actions := make([]CodeAction, 0, len(items))
+	for _, item := range items {
+		var probe struct {
+			Command json.RawMessage `json:"command"`
+		}
+		if err := json.Unmarshal(item, &probe); err != nil {
+			return nil, fmt.Errorf("invalid code action: %w", err)
+		}
 
-			if value, valueOk := contentMap["value"].(string); valueOk {
-				result = append(result, value)
+		// A bare Command has a string command field
+		if trimmed := bytes.TrimSpace(probe.Command); len(trimmed) > 0 && trimmed[0] == '"' {
+			var command Command
+			if err := json.Unmarshal(item, &command); err != nil {
+				return nil, fmt.Errorf("invalid command: %w", err)
 			}
+			actions = append(actions, CodeAction{Title: command.Title, Command: &command})
+			continue
+		}
+
+		var action CodeAction
+		if err := json.Unmarshal(item, &action); err != nil {
+			return nil, fmt.Errorf("invalid code action: %w", err)
 		}
+		actions = append(actions, action)
 	}
+	return actions, nil
+}
 
-	return result
+// decodePrepareRename decodes a Range, {range, placeholder} or {defaultBehavior}
+// prepareRename result. It reports false when the position cannot be renamed.
+func decodePrepareRename(raw json.RawMessage) (PrepareRenameResult, bool, error) {
+	if isNullResult(raw) {
+		return PrepareRenameResult{}, false, nil
+	}
+
+	var probe struct {
+		Range           *Range    `json:"range"`
+		Placeholder     string    `json:"placeholder"`
+		DefaultBehavior bool      `json:"defaultBehavior"`
+		Start           *Position `json:"start"`
+		End             *Position `json:"end"`
+	}
+	if err := json.Unmarshal(raw, &probe); err != nil {
+		return PrepareRenameResult{}, false, fmt.Errorf("invalid prepare rename result: %w", err)
+	}
+
+	switch {
+	case probe.Range != nil:
+		return PrepareRenameResult{Range: probe.Range, Placeholder: probe.Placeholder}, true, nil
+	case probe.Start != nil && probe.End != nil:
+		return PrepareRenameResult{Range: &Range{Start: *probe.Start, End: *probe.End}}, true, nil
+	default:
+		return PrepareRenameResult{}, probe.DefaultBehavior, nil
+	}
 }
 
-// parseHoverFromResponse extracts hover information from LSP response.
-func (c *goplsClient) parseHoverFromResponse(response map[string]any) (*Hover, error) {
-	result, resultOk := response["result"]
-	if !resultOk {
-		return nil, fmt.Errorf("invalid hover response format")
+// UnmarshalJSON decodes a documentChanges entry, telling file operations apart
+// from text document edits by their kind.
+func (d *DocumentChange) UnmarshalJSON(data []byte) error {
+	*d = DocumentChange{}
+
+	var probe struct {
+		Kind string `json:"kind"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return fmt.Errorf("invalid document change: %w", err)
 	}
 
-	// Handle null result (no hover info available)
-	if result == nil {
-		return &Hover{Contents: []string{}}, nil
+	var target any
+	switch probe.Kind {
+	case "":
+		d.TextDocumentEdit = &TextDocumentEdit{}
+		target = d.TextDocumentEdit
+	case "create":
+		d.CreateFile = &CreateFile{}
+		target = d.CreateFile
+	case "rename":
+		d.RenameFile = &RenameFile{}
+		target = d.RenameFile
+	case "delete":
+		d.DeleteFile = &DeleteFile{}
+		target = d.DeleteFile
+	default:
+		return fmt.Errorf("unknown document change kind: %s", probe.Kind)
 	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return fmt.Errorf("invalid %s document change: %w", probe.Kind, err)
+	}
+	return nil
+}
 
-	hoverMap, hoverMapOk := result.(map[string]any)
-	if !hoverMapOk {
-		return nil, fmt.Errorf("invalid hover response format")
+// MarshalJSON encodes a documentChanges entry as the operation it holds.
+func (d DocumentChange) MarshalJSON() ([]byte, error) {
+	switch {
+	case d.CreateFile != nil:
+		return json.Marshal(d.CreateFile)
+	case d.RenameFile != nil:
+		return json.Marshal(d.RenameFile)
+	case d.DeleteFile != nil:
+		return json.Marshal(d.DeleteFile)
+	default:
+		return json.Marshal(d.TextDocumentEdit)
 	}
+}
 
-	var hover Hover
-	if contents, contentsOk := hoverMap["contents"]; contentsOk {
-		hover.Contents = c.parseHoverContents(contents)
+// UnmarshalJSON decodes a provider capability that may be a boolean or an options object.
+func (p *ProviderCapability) UnmarshalJSON(data []byte) error {
+	*p = ProviderCapability{}
+	if isNullResult(data) {
+		return nil
 	}
-	if rangeMap, rangeMapOk := hoverMap["range"].(map[string]any); rangeMapOk {
-		rng := c.parseRange(rangeMap)
-		hover.Range = &rng
+
+	var enabled bool
+	if err := json.Unmarshal(data, &enabled); err == nil {
+		p.Enabled = enabled
+		return nil
+	}
+
+	p.Enabled = true
+	p.Options = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+// MarshalJSON encodes a provider capability as its options object, or as a boolean when it has none.
+func (p ProviderCapability) MarshalJSON() ([]byte, error) {
+	if p.Enabled && len(p.Options) > 0 {
+		return p.Options, nil
 	}
-	return &hover, nil
+	return json.Marshal(p.Enabled)
 }
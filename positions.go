@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Column units accepted by the columnUnit tool parameter.
+const (
+	columnUnitByte  = "byte"
+	columnUnitRune  = "rune"
+	columnUnitUTF16 = "utf16"
+)
+
+// Position encodings defined by LSP 3.17, in order of preference.
+const (
+	positionEncodingUTF8  = "utf-8"
+	positionEncodingUTF32 = "utf-32"
+	positionEncodingUTF16 = "utf-16"
+)
+
+// positionEncoding returns the position encoding negotiated with gopls.
+// UTF-16 is the LSP default when the server does not choose one.
+func (c *goplsClient) positionEncoding() string {
+	capabilities, _ := c.capabilities()
+	if capabilities.PositionEncoding == "" {
+		return positionEncodingUTF16
+	}
+	return capabilities.PositionEncoding
+}
+
+// columnConverter converts character offsets between the unit a tool caller
+// counts in and the position encoding negotiated with gopls. Conversions that
+// are not the identity read the line the offset refers to from disk.
+type columnConverter struct {
+	unit          string
+	lspUnit       string
+	workspacePath string
+	files         map[string][]string
+}
+
+// newColumnConverter creates a converter for a caller unit and a gopls position
+// encoding. An empty unit means bytes, the unit used by the Go toolchain.
+func newColumnConverter(unit, encoding, workspacePath string) (*columnConverter, error) {
+	if unit == "" {
+		unit = columnUnitByte
+	}
+	if unit != columnUnitByte && unit != columnUnitRune && unit != columnUnitUTF16 {
+		return nil, fmt.Errorf("invalid column unit %q: expected byte, rune or utf16", unit)
+	}
+
+	lspUnit := columnUnitUTF16
+	switch encoding {
+	case positionEncodingUTF8:
+		lspUnit = columnUnitByte
+	case positionEncodingUTF32:
+		lspUnit = columnUnitRune
+	}
+
+	return &columnConverter{
+		unit:          unit,
+		lspUnit:       lspUnit,
+		workspacePath: workspacePath,
+		files:         make(map[string][]string),
+	}, nil
+}
+
+// columnConverter creates a converter for tool results and parameters of a workspace.
+func (m mcpTools) columnConverter(client goplsClientInterface, unit string) (*columnConverter, error) {
+	return newColumnConverter(unit, client.positionEncoding(), client.workspaceRoot())
+}
+
+// toLSP converts a 0-based character offset on a line of a workspace file to gopls units.
+func (cc *columnConverter) toLSP(relativePath string, line, character int) int {
+	if cc.unit == cc.lspUnit {
+		return character
+	}
+	text, ok := cc.lineText(filepath.Join(cc.workspacePath, relativePath), line)
+	if !ok {
+		return character
+	}
+	return convertColumn(text, character, cc.unit, cc.lspUnit)
+}
+
+// fromLSP converts a 0-based character offset reported by gopls for a document
+// to the caller's unit. The document is either a file:// URI or a path relative
+// to the workspace, as navigation results report it.
+func (cc *columnConverter) fromLSP(uri string, line, character int) int {
+	if cc.unit == cc.lspUnit {
+		return character
+	}
+	if !strings.Contains(uri, "://") {
+		path := uri
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(cc.workspacePath, path)
+		}
+		return cc.fromLSPPath(path, line, character)
+	}
+	parsedURI, err := url.Parse(uri)
+	if err != nil || parsedURI.Scheme != fileScheme {
+		return character
+	}
+	return cc.fromLSPPath(parsedURI.Path, line, character)
+}
+
+// fromLSPPath converts a 0-based character offset reported by gopls for a file to the caller's unit.
+func (cc *columnConverter) fromLSPPath(path string, line, character int) int {
+	if cc.unit == cc.lspUnit {
+		return character
+	}
+	text, ok := cc.lineText(path, line)
+	if !ok {
+		return character
+	}
+	return convertColumn(text, character, cc.lspUnit, cc.unit)
+}
+
+// rangeResult converts an LSP range in the document at uri into a 1-based
+// LocationResult in the caller's unit.
+func (cc *columnConverter) rangeResult(uri string, r Range) LocationResult {
+	return LocationResult{
+		URI:          uri,
+		Line:         convertLineFromLSP(r.Start.Line),
+		Character:    cc.fromLSP(uri, r.Start.Line, r.Start.Character),
+		EndLine:      convertLineFromLSP(r.End.Line),
+		EndCharacter: cc.fromLSP(uri, r.End.Line, r.End.Character),
+	}
+}
+
+// documentRangeResult converts an LSP range in a workspace file into a 1-based
+// LocationResult in the caller's unit. The URI is left empty as the file is
+// implied by the request.
+func (cc *columnConverter) documentRangeResult(relativePath string, r Range) LocationResult {
+	path := filepath.Join(cc.workspacePath, relativePath)
+	return LocationResult{
+		Line:         convertLineFromLSP(r.Start.Line),
+		Character:    cc.fromLSPPath(path, r.Start.Line, r.Start.Character),
+		EndLine:      convertLineFromLSP(r.End.Line),
+		EndCharacter: cc.fromLSPPath(path, r.End.Line, r.End.Character),
+	}
+}
+
+// lineText returns a 0-based line of a file, reading each file once per converter.
+func (cc *columnConverter) lineText(path string, line int) (string, bool) {
+	lines, cached := cc.files[path]
+	if !cached {
+		content, err := os.ReadFile(path)
+		if err == nil {
+			lines = strings.Split(string(content), "\n")
+		}
+		cc.files[path] = lines
+	}
+	if line < 0 || line >= len(lines) {
+		return "", false
+	}
+	return strings.TrimSuffix(lines[line], "\r"), true
+}
+
+// convertColumn converts a character offset on a line from one unit to another.
+// Offsets inside a multi-unit character move to its end; offsets past the end
+// of the line keep their distance from it.
+func convertColumn(text string, offset int, from, to string) int {
+	byteOffset, units := 0, 0
+	for byteOffset < len(text) && units < offset {
+		r, size := utf8.DecodeRuneInString(text[byteOffset:])
+		units += runeWidth(r, size, from)
+		byteOffset += size
+	}
+
+	converted := 0
+	for position := 0; position < byteOffset; {
+		r, size := utf8.DecodeRuneInString(text[position:])
+		converted += runeWidth(r, size, to)
+		position += size
+	}
+	if offset > units {
+		converted += offset - units
+	}
+	return converted
+}
+
+// runeWidth returns how many units of the given kind a character occupies.
+func runeWidth(r rune, size int, unit string) int {
+	switch unit {
+	case columnUnitByte:
+		return size
+	case columnUnitRune:
+		return 1
+	default:
+		if r >= 0x10000 {
+			return 2
+		}
+		return 1
+	}
+}
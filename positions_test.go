@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConvertColumn(t *testing.T) {
+	// "é" is 2 bytes and 1 UTF-16 unit, "😀" is 4 bytes and 2 UTF-16 units
+	text := `s := "é😀"; x := 1`
+
+	tests := []struct {
+		name     string
+		offset   int
+		from, to string
+		expected int
+	}{
+		{name: "ascii prefix unchanged", offset: 5, from: columnUnitByte, to: columnUnitUTF16, expected: 5},
+		{name: "bytes to utf16 after emoji", offset: 13, from: columnUnitByte, to: columnUnitUTF16, expected: 10},
+		{name: "runes to bytes after emoji", offset: 9, from: columnUnitRune, to: columnUnitByte, expected: 13},
+		{name: "utf16 to runes after emoji", offset: 10, from: columnUnitUTF16, to: columnUnitRune, expected: 9},
+		{name: "inside a character moves to its end", offset: 7, from: columnUnitByte, to: columnUnitRune, expected: 7},
+		{name: "past the end of the line", offset: 25, from: columnUnitByte, to: columnUnitUTF16, expected: 22},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := convertColumn(text, tt.offset, tt.from, tt.to); got != tt.expected {
+				t.Errorf("convertColumn(%d, %s -> %s) = %d, want %d", tt.offset, tt.from, tt.to, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestColumnConverter(t *testing.T) {
+	workspacePath := t.TempDir()
+	content := "package main\n\nvar greeting = \"héllo\"; var answer = 42\n"
+	if err := os.WriteFile(filepath.Join(workspacePath, "main.go"), []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	if _, err := newColumnConverter("codepoint", positionEncodingUTF16, workspacePath); err == nil {
+		t.Error("Expected error for unknown column unit")
+	}
+
+	// A rune column is converted to UTF-16 for a server without UTF-8 support
+	columns, err := newColumnConverter(columnUnitRune, positionEncodingUTF16, workspacePath)
+	if err != nil {
+		t.Fatalf("newColumnConverter failed: %v", err)
+	}
+	if got := columns.toLSP("main.go", 2, 28); got != 28 {
+		t.Errorf("Expected BMP runes to match UTF-16 units, got %d", got)
+	}
+
+	// A byte column is passed through unchanged when gopls negotiated UTF-8
+	columns, err = newColumnConverter("", positionEncodingUTF8, workspacePath)
+	if err != nil {
+		t.Fatalf("newColumnConverter failed: %v", err)
+	}
+	if got := columns.toLSP("main.go", 2, 29); got != 29 {
+		t.Errorf("Expected identity conversion, got %d", got)
+	}
+
+	// UTF-8 results are reported in runes when requested
+	columns, err = newColumnConverter(columnUnitRune, positionEncodingUTF8, workspacePath)
+	if err != nil {
+		t.Fatalf("newColumnConverter failed: %v", err)
+	}
+	result := columns.rangeResult("file://"+filepath.Join(workspacePath, "main.go"),
+		Range{Start: Position{Line: 2, Character: 29}, End: Position{Line: 2, Character: 35}})
+	if result.Line != 3 || result.Character != 28 || result.EndCharacter != 34 {
+		t.Errorf("Unexpected converted range: %+v", result)
+	}
+
+	// Navigation results carry paths relative to the workspace
+	result = columns.rangeResult("main.go",
+		Range{Start: Position{Line: 2, Character: 29}, End: Position{Line: 2, Character: 35}})
+	if result.URI != "main.go" || result.Character != 28 || result.EndCharacter != 34 {
+		t.Errorf("Unexpected converted range for a relative path: %+v", result)
+	}
+
+	// Files that cannot be read leave offsets untouched
+	if got := columns.fromLSP("file:///does/not/exist.go", 0, 12); got != 12 {
+		t.Errorf("Expected unreadable file to keep the offset, got %d", got)
+	}
+}
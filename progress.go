@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// initialLoadGracePeriod is how long after initialization the workspace is
+// considered loaded if gopls reports no work-done progress at all.
+const initialLoadGracePeriod = time.Second
+
+// Workspace states reported by workspace_status.
+const (
+	workspaceStateStopped    = "stopped"
+	workspaceStateStarting   = "starting"
+	workspaceStateRestarting = "restarting"
+	workspaceStateLoading    = "loading"
+	workspaceStateBusy       = "busy"
+	workspaceStateReady      = "ready"
+)
+
+// progressTask is a work-done progress operation reported by gopls.
+type progressTask struct {
+	title      string
+	message    string
+	percentage *int
+	started    time.Time
+}
+
+// resetProgress discards progress of the previous gopls session and starts
+// waiting for the initial workspace load again. Waiters on the previous
+// session are woken up so they can wait on the new one.
+func (c *goplsClient) resetProgress() {
+	c.progressMux.Lock()
+	defer c.progressMux.Unlock()
+
+	c.progressTasks = make(map[string]*progressTask)
+	if !c.loaded {
+		close(c.loadedCh)
+	}
+	c.loaded = false
+	c.loadedCh = make(chan struct{})
+}
+
+// expectInitialLoad marks the workspace loaded after grace unless gopls has
+// started reporting progress by then.
+func (c *goplsClient) expectInitialLoad(grace time.Duration) {
+	c.progressMux.Lock()
+	loadedCh := c.loadedCh
+	c.progressMux.Unlock()
+
+	time.AfterFunc(grace, func() {
+		c.progressMux.Lock()
+		defer c.progressMux.Unlock()
+
+		if c.loadedCh == loadedCh && len(c.progressTasks) == 0 {
+			c.markLoadedLocked()
+		}
+	})
+}
+
+// markLoadedLocked records that the initial workspace load finished. Callers hold progressMux.
+func (c *goplsClient) markLoadedLocked() {
+	if c.loaded {
+		return
+	}
+	c.loaded = true
+	close(c.loadedCh)
+	c.logger.Info("workspace loaded", "workspacePath", c.workspacePath)
+}
+
+// handleWorkDoneProgressCreate answers window/workDoneProgress/create and
+// tracks the token until gopls ends the operation.
+func (c *goplsClient) handleWorkDoneProgressCreate(rawParams json.RawMessage) (any, error) {
+	var params WorkDoneProgressCreateParams
+	if err := decodeParams(rawParams, &params); err != nil {
+		return nil, err
+	}
+
+	c.progressMux.Lock()
+	c.progressTasks[string(params.Token)] = &progressTask{started: time.Now()}
+	c.progressMux.Unlock()
+
+	return nullResult, nil
+}
+
+// handleProgress handles $/progress notifications for work-done progress.
+func (c *goplsClient) handleProgress(rawParams json.RawMessage) {
+	var params ProgressParams
+	if err := json.Unmarshal(rawParams, &params); err != nil {
+		c.logger.Debug("invalid progress params", "error", err)
+		return
+	}
+	var value WorkDoneProgressValue
+	if err := json.Unmarshal(params.Value, &value); err != nil {
+		c.logger.Debug("ignoring non work-done progress", "token", string(params.Token))
+		return
+	}
+
+	token := string(params.Token)
+
+	c.progressMux.Lock()
+	defer c.progressMux.Unlock()
+
+	switch value.Kind {
+	case "begin":
+		c.logger.Debug("progress started", "token", token, "title", value.Title, "message", value.Message)
+		c.progressTasks[token] = &progressTask{
+			title:      value.Title,
+			message:    value.Message,
+			percentage: value.Percentage,
+			started:    time.Now(),
+		}
+	case "report":
+		task, exists := c.progressTasks[token]
+		if !exists {
+			return
+		}
+		if value.Message != "" {
+			task.message = value.Message
+		}
+		if value.Percentage != nil {
+			task.percentage = value.Percentage
+		}
+	case "end":
+		task, exists := c.progressTasks[token]
+		if !exists {
+			return
+		}
+		c.logger.Debug("progress finished", "token", token, "title", task.title,
+			"message", value.Message, "duration", time.Since(task.started))
+		delete(c.progressTasks, token)
+		if len(c.progressTasks) == 0 {
+			c.markLoadedLocked()
+		}
+	}
+}
+
+// waitForInitialLoad blocks until gopls has finished loading the workspace.
+func (c *goplsClient) waitForInitialLoad(ctx context.Context) error {
+	for {
+		if err := c.waitUntilRunning(ctx); err != nil {
+			return err
+		}
+
+		c.progressMux.Lock()
+		loaded, loadedCh := c.loaded, c.loadedCh
+		c.progressMux.Unlock()
+		if loaded {
+			return nil
+		}
+
+		select {
+		case <-loadedCh:
+			// Loaded, or gopls restarted and the new session must be waited on
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for workspace load cancelled: %w", ctx.Err())
+		}
+	}
+}
+
+// workspaceBusy reports whether gopls has work-done progress in flight.
+func (c *goplsClient) workspaceBusy() bool {
+	c.progressMux.Lock()
+	defer c.progressMux.Unlock()
+
+	return len(c.progressTasks) > 0
+}
+
+// workspaceStatus reports the readiness of the workspace and what gopls is currently doing.
+func (c *goplsClient) workspaceStatus() WorkspaceStatus {
+	c.mu.RLock()
+	running, restarting, initialized := c.running, c.restarting, c.initialized
+	c.mu.RUnlock()
+
+	c.progressMux.Lock()
+	defer c.progressMux.Unlock()
+
+	status := WorkspaceStatus{
+		Workspace: c.workspacePath,
+		Tasks:     make([]ProgressTaskInfo, 0, len(c.progressTasks)),
+		Requests:  c.scheduler.stats(),
+		Cache:     c.cache.stats(),
+		Process:   c.watchdog.snapshot(),
+	}
+	for _, task := range c.progressTasks {
+		status.Tasks = append(status.Tasks, ProgressTaskInfo{
+			Title:      task.title,
+			Message:    task.message,
+			Percentage: task.percentage,
+			ElapsedMs:  time.Since(task.started).Milliseconds(),
+		})
+	}
+	sort.Slice(status.Tasks, func(i, j int) bool {
+		return status.Tasks[i].ElapsedMs > status.Tasks[j].ElapsedMs
+	})
+
+	switch {
+	case restarting:
+		status.State = workspaceStateRestarting
+	case !running:
+		status.State = workspaceStateStopped
+	case !initialized:
+		status.State = workspaceStateStarting
+	case !c.loaded:
+		status.State = workspaceStateLoading
+	case len(c.progressTasks) > 0:
+		status.State = workspaceStateBusy
+	default:
+		status.State = workspaceStateReady
+	}
+	status.Ready = status.State == workspaceStateReady || status.State == workspaceStateBusy
+	return status
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// sendProgress delivers a $/progress notification to the client.
+func sendProgress(client *goplsClient, token, value string) {
+	client.handleLSPMessage(&incomingMessage{
+		JSONRPC: jsonrpcVersion,
+		Method:  "$/progress",
+		Params:  json.RawMessage(`{"token":` + token + `,"value":` + value + `}`),
+	})
+}
+
+func TestProgressTracking(t *testing.T) {
+	client := newClient("/test/workspace", workspaceConfig{}, newTestLogger())
+	client.running = true
+	client.initialized = true
+	client.resetProgress()
+
+	if state := client.workspaceStatus().State; state != workspaceStateLoading {
+		t.Errorf("Expected loading state before progress ends, got %s", state)
+	}
+
+	if _, err := client.handleWorkDoneProgressCreate(json.RawMessage(`{"token":"load"}`)); err != nil {
+		t.Fatalf("failed to create progress: %v", err)
+	}
+	sendProgress(client, `"load"`, `{"kind":"begin","title":"Setting up workspace","message":"Loading packages..."}`)
+	sendProgress(client, `"load"`, `{"kind":"report","percentage":40}`)
+
+	status := client.workspaceStatus()
+	if len(status.Tasks) != 1 || status.Tasks[0].Title != "Setting up workspace" {
+		t.Fatalf("Expected one loading task, got %+v", status.Tasks)
+	}
+	if status.Tasks[0].Message != "Loading packages..." || *status.Tasks[0].Percentage != 40 {
+		t.Errorf("Expected report to update the task, got %+v", status.Tasks[0])
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- client.waitForInitialLoad(context.Background())
+	}()
+	select {
+	case err := <-errCh:
+		t.Fatalf("Expected waitForInitialLoad to block while loading, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	sendProgress(client, `"load"`, `{"kind":"end","message":"Finished loading packages."}`)
+	if err := <-errCh; err != nil {
+		t.Errorf("Expected load to finish, got %v", err)
+	}
+
+	// Later progress marks the workspace busy but keeps it ready
+	sendProgress(client, `7`, `{"kind":"begin","title":"Diagnosing"}`)
+	status = client.workspaceStatus()
+	if status.State != workspaceStateBusy || !status.Ready || !client.workspaceBusy() {
+		t.Errorf("Expected busy but ready workspace, got %+v", status)
+	}
+	sendProgress(client, `7`, `{"kind":"end"}`)
+	if state := client.workspaceStatus().State; state != workspaceStateReady {
+		t.Errorf("Expected ready state, got %s", state)
+	}
+}
+
+func TestInitialLoadWithoutProgress(t *testing.T) {
+	client := newClient("/test/workspace", workspaceConfig{}, newTestLogger())
+	client.running = true
+	client.initialized = true
+	client.resetProgress()
+	client.expectInitialLoad(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := client.waitForInitialLoad(ctx); err != nil {
+		t.Fatalf("Expected workspace to be loaded after the grace period, got %v", err)
+	}
+
+	// A new gopls session waits for its own load
+	client.resetProgress()
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := client.waitForInitialLoad(ctx); err == nil {
+		t.Error("Expected wait to time out after the session was reset")
+	}
+}
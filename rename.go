@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// renameSymbol validates the position with textDocument/prepareRename and
+// computes the file edits of a textDocument/rename request without writing them.
+func (c *goplsClient) renameSymbol(
+	ctx context.Context, relativePath string, line, character int, newName string,
+) ([]fileEdit, error) {
+	c.logger.Debug("renameSymbol called",
+		"relativePath", relativePath, "line", line, "character", character, "newName", newName)
+
+	// Renames are workspace-wide and would miss packages that are still loading
+	if err := c.waitForInitialLoad(ctx); err != nil {
+		return nil, err
+	}
+
+	// Ensure file is open in gopls
+	if err := c.ensureFileOpen(relativePath); err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	params := c.positionParams(relativePath, line, character)
+	if c.supportsPrepareRename() {
+		// Send textDocument/prepareRename request to reject positions without a symbol
+		var result json.RawMessage
+		if err := c.call(ctx, "textDocument/prepareRename", params, &result); err != nil {
+			return nil, fmt.Errorf("failed to prepare rename: %w", err)
+		}
+		_, renameable, err := decodePrepareRename(result)
+		if err != nil {
+			return nil, err
+		}
+		if !renameable {
+			return nil, fmt.Errorf("no symbol to rename at %s:%d:%d",
+				relativePath, convertLineFromLSP(line), character)
+		}
+	}
+
+	// Send textDocument/rename request and wait for response
+	var edit WorkspaceEdit
+	renameParams := RenameParams{TextDocumentPositionParams: params, NewName: newName}
+	if err := c.call(ctx, "textDocument/rename", renameParams, &edit); err != nil {
+		return nil, fmt.Errorf("failed to rename symbol: %w", err)
+	}
+
+	return c.planWorkspaceEdit(&edit, nil)
+}
+
+// supportsPrepareRename reports whether gopls advertised textDocument/prepareRename.
+func (c *goplsClient) supportsPrepareRename() bool {
+	capabilities, _ := c.capabilities()
+	var options struct {
+		PrepareProvider bool `json:"prepareProvider"`
+	}
+	if len(capabilities.RenameProvider.Options) == 0 ||
+		json.Unmarshal(capabilities.RenameProvider.Options, &options) != nil {
+		return false
+	}
+	return options.PrepareProvider
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MegaGrindStone/gopls-mcp/internal/fakegopls"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// renameScript scripts a gopls that renames helper to assist in main.go and
+// finds no symbol on the first line.
+func renameScript() *fakegopls.Script {
+	script := fakeGoplsScript()
+	script.Responses = append(script.Responses,
+		fakegopls.Response{
+			Method: "textDocument/prepareRename",
+			Params: json.RawMessage(`{"position":{"line":0}}`),
+			Result: json.RawMessage(`null`),
+		},
+		fakegopls.Response{
+			Method: "textDocument/prepareRename",
+			Result: json.RawMessage(`{"range":{"start":{"line":3,"character":1},"end":{"line":3,"character":7}},` +
+				`"placeholder":"helper"}`),
+		},
+		fakegopls.Response{
+			Method: "textDocument/rename",
+			Params: json.RawMessage(`{"newName":"assist"}`),
+			Result: json.RawMessage(`{"changes":{"${rootUri}/main.go":[` +
+				`{"range":{"start":{"line":3,"character":1},"end":{"line":3,"character":7}},"newText":"assist"},` +
+				`{"range":{"start":{"line":6,"character":5},"end":{"line":6,"character":11}},"newText":"assist"}]}}`),
+		},
+	)
+	return script
+}
+
+func TestRenameSymbol(t *testing.T) {
+	workspacePath := newFakeGoplsWorkspace(t)
+	config := fakeGoplsConfig(t, writeFakeGoplsScript(t, renameScript()))
+	client := startFakeGoplsClient(t, workspacePath, config)
+	session := connectMCP(t, map[string]*goplsClient{workspacePath: client})
+
+	mainPath := filepath.Join(workspacePath, "main.go")
+	original, err := os.ReadFile(mainPath)
+	if err != nil {
+		t.Fatalf("failed to read main.go: %v", err)
+	}
+
+	arguments := map[string]any{
+		"workspace": workspacePath, "path": "main.go", "line": 4, "character": 1, "newName": "assist",
+	}
+	preview := callTool[RenameSymbolResult](t, session, toolRenameSymbol, arguments)
+	if preview.Applied || len(preview.Files) != 1 || preview.Files[0].Path != "main.go" ||
+		len(preview.Files[0].Edits) != 2 || preview.Files[0].Edits[1].Range.Line != 7 {
+		t.Errorf("Unexpected rename preview: %+v", preview)
+	}
+	expectedDiff := "--- a/main.go\n+++ b/main.go\n@@ -1,7 +1,7 @@\n package main\n \n func main() {\n" +
+		"-\thelper()\n+\tassist()\n }\n \n-func helper() {}\n+func assist() {}\n"
+	if preview.Diff != expectedDiff {
+		t.Errorf("Unexpected rename diff:\n%s", preview.Diff)
+	}
+	if content, _ := os.ReadFile(mainPath); string(content) != string(original) {
+		t.Error("Expected a preview to leave main.go unchanged")
+	}
+
+	arguments["apply"] = true
+	applied := callTool[RenameSymbolResult](t, session, toolRenameSymbol, arguments)
+	if !applied.Applied || applied.Diff != expectedDiff {
+		t.Errorf("Unexpected applied rename: %+v", applied)
+	}
+	content, err := os.ReadFile(mainPath)
+	if err != nil || !strings.Contains(string(content), "func assist() {}") {
+		t.Errorf("Expected main.go to be renamed, got %q, %v", content, err)
+	}
+	if version, isOpen := client.documentVersion("main.go"); !isOpen || version != 2 {
+		t.Errorf("Expected the open document to be synchronized, got version %d", version)
+	}
+
+	// A position without a symbol is rejected by prepareRename
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	result, err := session.CallTool(ctx, &mcp.CallToolParams{
+		Name: toolRenameSymbol,
+		Arguments: map[string]any{
+			"workspace": workspacePath, "path": "main.go", "line": 1, "character": 0, "newName": "other",
+		},
+	})
+	if err == nil && !result.IsError {
+		t.Error("Expected rename without a symbol to fail")
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+const (
+	// defaultMaxInFlight is how many requests a gopls instance works on at once.
+	defaultMaxInFlight = 4
+	// defaultMaxQueued is how many requests may wait for a slot before new ones are rejected.
+	defaultMaxQueued = 32
+)
+
+// Request priority classes, from most to least urgent.
+const (
+	priorityInteractive = iota
+	priorityBackground
+	priorityClasses
+)
+
+// errRequestQueueFull is returned when a request cannot even be queued.
+var errRequestQueueFull = fmt.Errorf("gopls request queue is full")
+
+// backgroundMethods are LSP methods that scan the whole workspace. They are
+// scheduled after interactive lookups such as hover or definition.
+var backgroundMethods = map[string]bool{
+	"workspace/symbol":            true,
+	"workspace/executeCommand":    true,
+	"textDocument/references":     true,
+	"textDocument/implementation": true,
+	"textDocument/rename":         true,
+	"callHierarchy/incomingCalls": true,
+	"callHierarchy/outgoingCalls": true,
+}
+
+// unscheduledMethods bypass the scheduler because the gopls lifecycle depends on them.
+var unscheduledMethods = map[string]bool{
+	"initialize": true,
+	"shutdown":   true,
+}
+
+// requestPriority returns the priority class of an LSP method.
+func requestPriority(method string) int {
+	if backgroundMethods[method] {
+		return priorityBackground
+	}
+	return priorityInteractive
+}
+
+// scheduledRequest is a request waiting for a slot.
+type scheduledRequest struct {
+	priority int
+	ready    chan struct{}
+	granted  bool
+}
+
+// requestScheduler bounds the requests in flight to a gopls instance and
+// orders waiting requests by priority, first come first served within a class.
+type requestScheduler struct {
+	mu          sync.Mutex
+	maxInFlight int
+	maxQueued   int
+	inFlight    [priorityClasses]int
+	queues      [priorityClasses][]*scheduledRequest
+	peakQueued  int
+	started     int64
+	rejected    int64
+}
+
+// newRequestScheduler creates a scheduler. Non-positive limits select the defaults.
+func newRequestScheduler(maxInFlight, maxQueued int) *requestScheduler {
+	if maxInFlight <= 0 {
+		maxInFlight = defaultMaxInFlight
+	}
+	if maxQueued <= 0 {
+		maxQueued = defaultMaxQueued
+	}
+	return &requestScheduler{maxInFlight: maxInFlight, maxQueued: maxQueued}
+}
+
+// acquire waits until a request of the given priority may be sent to gopls.
+// It fails immediately when the queue is full and when ctx is done while waiting.
+// Every successful acquire must be followed by release.
+func (s *requestScheduler) acquire(ctx context.Context, priority int) error {
+	s.mu.Lock()
+
+	// Requests never overtake waiting requests of the same or a higher priority
+	if s.canStartLocked(priority) && !s.hasWaitingLocked(priority) {
+		s.inFlight[priority]++
+		s.started++
+		s.mu.Unlock()
+		return nil
+	}
+
+	if queued := s.queuedLocked(); queued >= s.maxQueued {
+		s.rejected++
+		inFlight := s.inFlightLocked()
+		s.mu.Unlock()
+		return fmt.Errorf("%w: %d requests waiting and %d in flight, retry later",
+			errRequestQueueFull, queued, inFlight)
+	}
+
+	request := &scheduledRequest{priority: priority, ready: make(chan struct{})}
+	s.queues[priority] = append(s.queues[priority], request)
+	if queued := s.queuedLocked(); queued > s.peakQueued {
+		s.peakQueued = queued
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-request.ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		granted := request.granted
+		if !granted {
+			s.removeLocked(request)
+		}
+		s.mu.Unlock()
+
+		// The slot was handed over while ctx was being cancelled; pass it on
+		if granted {
+			s.release(priority)
+		}
+		return fmt.Errorf("waiting for a gopls request slot cancelled: %w", ctx.Err())
+	}
+}
+
+// release frees the slot of a finished request and hands it to the most
+// urgent waiting request.
+func (s *requestScheduler) release(priority int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.inFlight[priority]--
+	for class := range s.queues {
+		for len(s.queues[class]) > 0 && s.canStartLocked(class) {
+			next := s.queues[class][0]
+			s.queues[class] = s.queues[class][1:]
+			s.inFlight[class]++
+			s.started++
+			next.granted = true
+			close(next.ready)
+		}
+	}
+}
+
+// canStartLocked reports whether a request of the given priority may start now.
+// Background requests leave one slot free so a burst of workspace-wide scans
+// cannot delay quick lookups. Callers hold mu.
+func (s *requestScheduler) canStartLocked(priority int) bool {
+	if s.inFlightLocked() >= s.maxInFlight {
+		return false
+	}
+	if priority == priorityBackground && s.maxInFlight > 1 {
+		return s.inFlight[priorityBackground] < s.maxInFlight-1
+	}
+	return true
+}
+
+// hasWaitingLocked reports whether requests of the given or a higher priority
+// are waiting. Callers hold mu.
+func (s *requestScheduler) hasWaitingLocked(priority int) bool {
+	for class := 0; class <= priority; class++ {
+		if len(s.queues[class]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// removeLocked drops an abandoned request from its queue. Callers hold mu.
+func (s *requestScheduler) removeLocked(request *scheduledRequest) {
+	queue := s.queues[request.priority]
+	for i, waiting := range queue {
+		if waiting == request {
+			s.queues[request.priority] = append(queue[:i], queue[i+1:]...)
+			return
+		}
+	}
+}
+
+// inFlightLocked returns the number of requests in flight. Callers hold mu.
+func (s *requestScheduler) inFlightLocked() int {
+	total := 0
+	for _, count := range s.inFlight {
+		total += count
+	}
+	return total
+}
+
+// queuedLocked returns the number of waiting requests. Callers hold mu.
+func (s *requestScheduler) queuedLocked() int {
+	total := 0
+	for _, queue := range s.queues {
+		total += len(queue)
+	}
+	return total
+}
+
+// stats returns the current limits, queue depths and counters.
+func (s *requestScheduler) stats() RequestQueueStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return RequestQueueStats{
+		MaxInFlight:       s.maxInFlight,
+		MaxQueued:         s.maxQueued,
+		InFlight:          s.inFlightLocked(),
+		QueuedInteractive: len(s.queues[priorityInteractive]),
+		QueuedBackground:  len(s.queues[priorityBackground]),
+		PeakQueued:        s.peakQueued,
+		Started:           s.started,
+		Rejected:          s.rejected,
+	}
+}
+
+// scheduleRequest waits for a slot to send an LSP request and returns the
+// function that frees it. Lifecycle requests are not scheduled.
+func (c *goplsClient) scheduleRequest(ctx context.Context, method string) (func(), error) {
+	if unscheduledMethods[method] {
+		return func() {}, nil
+	}
+
+	priority := requestPriority(method)
+	if err := c.scheduler.acquire(ctx, priority); err != nil {
+		if errors.Is(err, errRequestQueueFull) {
+			c.logger.Warn("rejected gopls request", "method", method, "error", err)
+		}
+		return nil, fmt.Errorf("failed to schedule %s: %w", method, err)
+	}
+	return func() {
+		c.scheduler.release(priority)
+		c.markUsed()
+	}, nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// acquireAsync acquires a slot in the background and reports the result on the returned channel.
+func acquireAsync(ctx context.Context, scheduler *requestScheduler, priority int) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		done <- scheduler.acquire(ctx, priority)
+	}()
+	return done
+}
+
+// waitQueued waits until the scheduler has the given number of waiting requests.
+func waitQueued(t *testing.T, scheduler *requestScheduler, queued int) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		stats := scheduler.stats()
+		if stats.QueuedInteractive+stats.QueuedBackground == queued {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("Expected %d queued requests, got %+v", queued, scheduler.stats())
+}
+
+func TestRequestSchedulerPriority(t *testing.T) {
+	ctx := context.Background()
+	scheduler := newRequestScheduler(2, 10)
+
+	// Background requests leave a slot for interactive ones
+	if err := scheduler.acquire(ctx, priorityBackground); err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+	background := acquireAsync(ctx, scheduler, priorityBackground)
+	waitQueued(t, scheduler, 1)
+
+	if err := scheduler.acquire(ctx, priorityInteractive); err != nil {
+		t.Fatalf("Expected interactive request to use the reserved slot: %v", err)
+	}
+	interactive := acquireAsync(ctx, scheduler, priorityInteractive)
+	waitQueued(t, scheduler, 2)
+
+	// The freed slot goes to the waiting interactive request first
+	scheduler.release(priorityInteractive)
+	select {
+	case err := <-interactive:
+		if err != nil {
+			t.Fatalf("interactive acquire failed: %v", err)
+		}
+	case <-background:
+		t.Fatal("Expected interactive request to be scheduled before the background one")
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for interactive request")
+	}
+
+	// The waiting background request takes over the background slot
+	scheduler.release(priorityBackground)
+	if err := <-background; err != nil {
+		t.Fatalf("background acquire failed: %v", err)
+	}
+	scheduler.release(priorityInteractive)
+
+	stats := scheduler.stats()
+	if stats.InFlight != 1 || stats.Started != 4 || stats.PeakQueued != 2 {
+		t.Errorf("Unexpected scheduler stats: %+v", stats)
+	}
+}
+
+func TestRequestSchedulerQueueFull(t *testing.T) {
+	ctx := context.Background()
+	scheduler := newRequestScheduler(1, 1)
+
+	if err := scheduler.acquire(ctx, priorityInteractive); err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+	waiting := acquireAsync(ctx, scheduler, priorityInteractive)
+	waitQueued(t, scheduler, 1)
+
+	err := scheduler.acquire(ctx, priorityInteractive)
+	if !errors.Is(err, errRequestQueueFull) {
+		t.Fatalf("Expected queue full error, got %v", err)
+	}
+	if stats := scheduler.stats(); stats.Rejected != 1 {
+		t.Errorf("Expected one rejected request, got %+v", stats)
+	}
+
+	scheduler.release(priorityInteractive)
+	if err := <-waiting; err != nil {
+		t.Fatalf("queued acquire failed: %v", err)
+	}
+}
+
+func TestRequestSchedulerCancel(t *testing.T) {
+	scheduler := newRequestScheduler(1, 10)
+
+	if err := scheduler.acquire(context.Background(), priorityInteractive); err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := scheduler.acquire(ctx, priorityInteractive); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline error, got %v", err)
+	}
+
+	// The abandoned request no longer occupies the queue
+	stats := scheduler.stats()
+	if stats.QueuedInteractive != 0 || stats.InFlight != 1 {
+		t.Errorf("Unexpected scheduler stats after cancel: %+v", stats)
+	}
+
+	scheduler.release(priorityInteractive)
+	if err := scheduler.acquire(context.Background(), priorityInteractive); err != nil {
+		t.Fatalf("Expected slot to be free again: %v", err)
+	}
+}
+
+func TestRequestPriority(t *testing.T) {
+	if requestPriority("workspace/symbol") != priorityBackground {
+		t.Error("Expected workspace/symbol to be a background request")
+	}
+	if requestPriority("textDocument/hover") != priorityInteractive {
+		t.Error("Expected textDocument/hover to be an interactive request")
+	}
+}
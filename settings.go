@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// goplsSettingsSection is the configuration section gopls requests its settings under.
+const goplsSettingsSection = "gopls"
+
+// cloneSettings returns a shallow copy of settings.
+func cloneSettings(settings map[string]any) map[string]any {
+	cloned := make(map[string]any, len(settings))
+	for key, value := range settings {
+		cloned[key] = value
+	}
+	return cloned
+}
+
+// mergeSettings returns base overridden by the top-level keys of override. A
+// null value removes the key. When replace is set, base is ignored.
+func mergeSettings(base, override map[string]any, replace bool) map[string]any {
+	merged := make(map[string]any, len(base)+len(override))
+	if !replace {
+		for key, value := range base {
+			merged[key] = value
+		}
+	}
+	for key, value := range override {
+		if value == nil {
+			delete(merged, key)
+			continue
+		}
+		merged[key] = value
+	}
+	return merged
+}
+
+// currentSettings returns a copy of the gopls settings for the workspace.
+func (c *goplsClient) currentSettings() map[string]any {
+	c.settingsMux.RLock()
+	defer c.settingsMux.RUnlock()
+
+	return cloneSettings(c.settings)
+}
+
+// initializationOptions returns the settings sent with the initialize request,
+// or nil when none are configured.
+func (c *goplsClient) initializationOptions() map[string]any {
+	settings := c.currentSettings()
+	if len(settings) == 0 {
+		return nil
+	}
+	return settings
+}
+
+// updateSettings changes the gopls settings and notifies gopls with
+// workspace/didChangeConfiguration, after which gopls pulls the new values
+// through workspace/configuration. The settings survive gopls restarts.
+func (c *goplsClient) updateSettings(
+	ctx context.Context, settings map[string]any, replace bool,
+) (map[string]any, error) {
+	c.logger.Debug("updateSettings called", "keys", len(settings), "replace", replace)
+
+	if err := c.waitUntilRunning(ctx); err != nil {
+		return nil, err
+	}
+
+	c.settingsMux.Lock()
+	c.settings = mergeSettings(c.settings, settings, replace)
+	updated := cloneSettings(c.settings)
+	c.settingsMux.Unlock()
+
+	// Settings such as buildFlags change what gopls reports
+	c.cache.invalidate()
+
+	params := DidChangeConfigurationParams{
+		Settings: map[string]any{goplsSettingsSection: updated},
+	}
+	if err := c.notify("workspace/didChangeConfiguration", params); err != nil {
+		return nil, fmt.Errorf("failed to send didChangeConfiguration notification: %w", err)
+	}
+
+	return updated, nil
+}
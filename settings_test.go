@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestMergeSettings(t *testing.T) {
+	base := map[string]any{"staticcheck": true, "buildFlags": []any{"-tags=integration"}}
+
+	merged := mergeSettings(base, map[string]any{"staticcheck": false, "buildFlags": nil, "gofumpt": true}, false)
+	if merged["staticcheck"] != false || merged["gofumpt"] != true {
+		t.Errorf("Expected override values, got %v", merged)
+	}
+	if _, exists := merged["buildFlags"]; exists {
+		t.Error("Expected null value to remove the setting")
+	}
+	if base["staticcheck"] != true {
+		t.Error("Expected base settings to be left untouched")
+	}
+
+	replaced := mergeSettings(base, map[string]any{"gofumpt": true}, true)
+	if len(replaced) != 1 || replaced["gofumpt"] != true {
+		t.Errorf("Expected replace to drop existing settings, got %v", replaced)
+	}
+}
+
+func TestUpdateSettings(t *testing.T) {
+	config := workspaceConfig{Settings: map[string]any{"staticcheck": true}}
+	client := newClient("/test/workspace", config, newTestLogger())
+	client.running = true
+	messages := captureNotifications(t, client)
+
+	if options := client.initializationOptions(); options["staticcheck"] != true {
+		t.Errorf("Expected configured settings in initializationOptions, got %v", options)
+	}
+
+	settings, err := client.updateSettings(context.Background(), map[string]any{"gofumpt": true}, false)
+	if err != nil {
+		t.Fatalf("updateSettings failed: %v", err)
+	}
+	if settings["staticcheck"] != true || settings["gofumpt"] != true {
+		t.Errorf("Expected merged settings, got %v", settings)
+	}
+
+	message := expectNotification(t, messages, "workspace/didChangeConfiguration")
+	var params struct {
+		Settings map[string]map[string]any `json:"settings"`
+	}
+	if err := json.Unmarshal(message.Params, &params); err != nil {
+		t.Fatalf("failed to decode params: %v", err)
+	}
+	if params.Settings[goplsSettingsSection]["gofumpt"] != true {
+		t.Errorf("Expected notification to carry the new settings, got %s", message.Params)
+	}
+
+	// gopls pulls the updated settings through workspace/configuration
+	result, err := client.handleWorkspaceConfiguration(
+		json.RawMessage(`{"items":[{"section":"gopls"},{"section":"other"}]}`))
+	if err != nil {
+		t.Fatalf("handleWorkspaceConfiguration failed: %v", err)
+	}
+	items := result.([]any)
+	if gopls, ok := items[0].(map[string]any); !ok || gopls["gofumpt"] != true {
+		t.Errorf("Expected gopls section to return current settings, got %v", items[0])
+	}
+	if items[1] != nil {
+		t.Errorf("Expected other sections to be null, got %v", items[1])
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+const (
+	// shutdownDrainTimeout bounds how long stop waits for in-flight requests.
+	shutdownDrainTimeout = 5 * time.Second
+	// shutdownRequestTimeout bounds how long gopls may take to answer shutdown.
+	shutdownRequestTimeout = 5 * time.Second
+	// shutdownExitTimeout is how long gopls gets to exit after each escalation step.
+	shutdownExitTimeout = 2 * time.Second
+)
+
+// errShuttingDown rejects requests made or still pending when gopls is stopped.
+var errShuttingDown = fmt.Errorf("gopls is shutting down")
+
+// shutdown stops gopls gracefully: it drains in-flight requests, performs the
+// LSP shutdown/exit handshake and escalates to SIGTERM and then SIGKILL if the
+// process does not exit in time. Callers must hold c.mu, which is released
+// while in-flight requests drain; new requests are rejected meanwhile and gopls
+// is no longer reported as running. A caller arriving during a shutdown in
+// progress waits for it to complete instead.
+func (c *goplsClient) shutdown() error {
+	if done := c.shuttingDown; done != nil {
+		c.mu.Unlock()
+		<-done
+		c.mu.Lock()
+		return nil
+	}
+
+	done := make(chan struct{})
+	c.shuttingDown = done
+	defer func() {
+		c.shuttingDown = nil
+		close(done)
+	}()
+
+	cmd, exited := c.cmd, c.exited
+
+	// Clearing cmd first tells the supervisor this exit was requested
+	c.cmd = nil
+	c.running = false
+	c.setDraining(true)
+
+	c.mu.Unlock()
+	drained := c.drainRequests(shutdownDrainTimeout)
+	c.mu.Lock()
+	if !drained {
+		c.logger.Warn("rejecting in-flight requests after drain timeout")
+		c.failPendingRequests(errShuttingDown)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownRequestTimeout)
+	err := c.sendShutdown(ctx)
+	cancel()
+	if err != nil {
+		c.logger.Warn("gopls shutdown handshake failed", "error", err)
+	}
+
+	err = c.waitForProcessExit(cmd, exited)
+	c.closePipes()
+	c.failPendingRequests(errShuttingDown)
+	c.running = false
+	return err
+}
+
+// sendShutdown sends the shutdown request and, once gopls acknowledges it,
+// the exit notification.
+func (c *goplsClient) sendShutdown(ctx context.Context) error {
+	if err := c.call(ctx, "shutdown", nil, nil); err != nil {
+		return fmt.Errorf("failed to send shutdown request: %w", err)
+	}
+	if err := c.notify("exit", nil); err != nil {
+		return fmt.Errorf("failed to send exit notification: %w", err)
+	}
+	return nil
+}
+
+// setDraining starts or stops rejecting new requests.
+func (c *goplsClient) setDraining(draining bool) {
+	c.responsesMux.Lock()
+	defer c.responsesMux.Unlock()
+
+	c.draining = draining
+}
+
+// drainRequests waits until no requests are pending or the timeout elapses.
+// It reports whether all requests completed.
+func (c *goplsClient) drainRequests(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		c.responsesMux.Lock()
+		pending := len(c.responses)
+		c.responsesMux.Unlock()
+
+		if pending == 0 {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+
+		c.logger.Debug("waiting for in-flight requests", "pending", pending)
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// waitForProcessExit waits for gopls to exit on its own, then sends SIGTERM
+// and finally SIGKILL if it is still running.
+func (c *goplsClient) waitForProcessExit(cmd *exec.Cmd, exited chan struct{}) error {
+	if cmd == nil || cmd.Process == nil {
+		return nil
+	}
+
+	if waitForClose(exited, shutdownExitTimeout) {
+		return nil
+	}
+
+	c.logger.Warn("gopls did not exit after shutdown, sending SIGTERM", "pid", cmd.Process.Pid)
+	if err := cmd.Process.Signal(syscall.SIGTERM); err == nil && waitForClose(exited, shutdownExitTimeout) {
+		return nil
+	}
+
+	c.logger.Warn("gopls did not exit after SIGTERM, killing it", "pid", cmd.Process.Pid)
+	err := cmd.Process.Kill()
+	<-exited
+	if err != nil {
+		return fmt.Errorf("failed to kill gopls: %w", err)
+	}
+	return nil
+}
+
+// waitForClose reports whether ch is closed before the timeout elapses.
+func waitForClose(ch chan struct{}, timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-ch:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
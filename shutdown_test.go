@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+// holdDrain registers a request that gopls never answers, so a shutdown keeps
+// draining until the returned function answers it.
+func holdDrain(client *goplsClient) func() {
+	client.responsesMux.Lock()
+	client.responses[-1] = make(chan *ResponseMessage, 1)
+	client.responsesMux.Unlock()
+
+	return func() {
+		client.routeResponse(-1, &ResponseMessage{JSONRPC: jsonrpcVersion})
+	}
+}
+
+// waitUntilDraining waits until a shutdown drains in-flight requests.
+func waitUntilDraining(t *testing.T, client *goplsClient) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		client.responsesMux.Lock()
+		draining := client.draining
+		client.responsesMux.Unlock()
+		if draining {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected gopls to be shut down")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSendShutdownHandshake(t *testing.T) {
+	client := newClient("/test/workspace", workspaceConfig{}, newTestLogger())
+
+	reader, writer := io.Pipe()
+	client.stdin = writer
+	bufReader := bufio.NewReader(reader)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- client.sendShutdown(context.Background())
+	}()
+
+	request, err := client.readLSPMessage(bufReader)
+	if err != nil {
+		t.Fatalf("failed to read shutdown request: %v", err)
+	}
+	if request.Method != "shutdown" || !request.isRequest() {
+		t.Fatalf("Expected shutdown request, got %+v", request)
+	}
+
+	// Exit must not be sent before gopls acknowledges shutdown
+	client.handleLSPMessage(&incomingMessage{
+		JSONRPC: jsonrpcVersion,
+		ID:      request.ID,
+		Result:  json.RawMessage("null"),
+	})
+
+	notification, err := client.readLSPMessage(bufReader)
+	if err != nil {
+		t.Fatalf("failed to read exit notification: %v", err)
+	}
+	if notification.Method != "exit" || !notification.isNotification() {
+		t.Errorf("Expected exit notification, got %+v", notification)
+	}
+
+	if err := <-errCh; err != nil {
+		t.Errorf("Expected successful handshake, got %v", err)
+	}
+}
+
+func TestShutdownRejectsRequestsWhileDraining(t *testing.T) {
+	client := newClient("/test/workspace", workspaceConfig{}, newTestLogger())
+
+	reader, writer := io.Pipe()
+	client.stdin = writer
+	client.running = true
+	bufReader := bufio.NewReader(reader)
+
+	// A request is still in flight when gopls is stopped
+	release := holdDrain(client)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- client.stop()
+	}()
+	waitUntilDraining(t, client)
+
+	// The client lock is released during the drain and new requests are rejected
+	running := make(chan bool, 1)
+	go func() {
+		running <- client.isRunning()
+	}()
+	select {
+	case <-running:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the client lock to be released while requests drain")
+	}
+	if err := client.call(context.Background(), "textDocument/hover", nil, nil); !errors.Is(err, errShuttingDown) {
+		t.Errorf("Expected a request during the drain to be rejected, got %v", err)
+	}
+
+	// Once the request completes, the shutdown handshake proceeds
+	release()
+	request, err := client.readLSPMessage(bufReader)
+	if err != nil {
+		t.Fatalf("failed to read shutdown request: %v", err)
+	}
+	if request.Method != "shutdown" {
+		t.Fatalf("Expected shutdown request, got %+v", request)
+	}
+	client.handleLSPMessage(&incomingMessage{
+		JSONRPC: jsonrpcVersion,
+		ID:      request.ID,
+		Result:  json.RawMessage("null"),
+	})
+	if _, err := client.readLSPMessage(bufReader); err != nil {
+		t.Fatalf("failed to read exit notification: %v", err)
+	}
+
+	if err := <-errCh; err != nil {
+		t.Errorf("Expected stop to succeed, got %v", err)
+	}
+	if client.isRunning() {
+		t.Error("Expected gopls not to be running after stop")
+	}
+}
+
+func TestStopWaitsForRecycleShutdown(t *testing.T) {
+	workspacePath := newFakeGoplsWorkspace(t)
+	client := startFakeGoplsClient(t, workspacePath, fakeGoplsConfig(t, writeFakeGoplsScript(t, fakeGoplsScript())))
+
+	client.mu.RLock()
+	cmd, exited := client.cmd, client.exited
+	client.mu.RUnlock()
+
+	release := holdDrain(client)
+	recycled := make(chan struct{})
+	go func() {
+		client.recycle(cmd, processSample{})
+		close(recycled)
+	}()
+	waitUntilDraining(t, client)
+
+	// A stop arriving during the recycle waits for its shutdown
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- client.stop()
+	}()
+	select {
+	case err := <-stopped:
+		t.Fatalf("Expected stop to wait for the shutdown in progress, got %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	release()
+	if err := <-stopped; err != nil {
+		t.Errorf("Expected stop to succeed, got %v", err)
+	}
+	select {
+	case <-exited:
+	default:
+		t.Error("Expected gopls to have exited once stop returns")
+	}
+
+	<-recycled
+	if client.isRunning() {
+		t.Error("Expected a stopped client not to relaunch gopls")
+	}
+}
+
+func TestDrainRequests(t *testing.T) {
+	client := newClient("/test/workspace", workspaceConfig{}, newTestLogger())
+
+	if !client.drainRequests(time.Millisecond) {
+		t.Error("Expected drain to succeed with no pending requests")
+	}
+
+	responseCh := make(chan *ResponseMessage, 1)
+	client.responses[1] = responseCh
+	if client.drainRequests(10 * time.Millisecond) {
+		t.Error("Expected drain to time out with a pending request")
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		client.routeResponse(1, &ResponseMessage{JSONRPC: jsonrpcVersion})
+	}()
+	if !client.drainRequests(time.Second) {
+		t.Error("Expected drain to succeed once the request completes")
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+const (
+	// restartInitialBackoff is the delay before the first restart attempt.
+	restartInitialBackoff = 500 * time.Millisecond
+	// restartMaxBackoff caps the delay between restart attempts.
+	restartMaxBackoff = 30 * time.Second
+	// restartMaxAttempts is how many consecutive restarts are tried before giving up.
+	restartMaxAttempts = 10
+	// restartStableAfter is how long gopls must stay up before the backoff resets.
+	restartStableAfter = time.Minute
+	// restartWaitTimeout bounds how long a request waits for a restart in progress.
+	restartWaitTimeout = 10 * time.Second
+)
+
+// restartBackoff returns the delay before the given zero-based restart attempt.
+func restartBackoff(attempt int) time.Duration {
+	backoff := restartInitialBackoff
+	for range attempt {
+		backoff *= 2
+		if backoff >= restartMaxBackoff {
+			return restartMaxBackoff
+		}
+	}
+	return backoff
+}
+
+// superviseProcess waits for the gopls process to exit and restarts it when the
+// exit was not requested through stop.
+func (c *goplsClient) superviseProcess(cmd *exec.Cmd, exited chan struct{}) {
+	err := cmd.Wait()
+	close(exited)
+
+	c.mu.Lock()
+	if c.cmd != cmd || c.stopping {
+		c.mu.Unlock()
+		return
+	}
+
+	c.logger.Error("gopls process exited unexpectedly", "pid", cmd.Process.Pid, "error", err)
+	c.closePipes()
+	c.running = false
+	c.cmd = nil
+	c.restarting = true
+	c.ready = make(chan struct{})
+	if time.Since(c.startedAt) >= restartStableAfter {
+		c.restartAttempts = 0
+	}
+	c.mu.Unlock()
+
+	c.failPendingRequests(fmt.Errorf("gopls exited: %w", err))
+	c.resetSessionState()
+	c.restart()
+}
+
+// restart relaunches gopls with exponential backoff until it initializes, the
+// client is stopped, or the attempt budget is exhausted.
+func (c *goplsClient) restart() {
+	for {
+		c.mu.Lock()
+		if c.stopping || c.processCtx.Err() != nil {
+			c.finishRestart()
+			c.mu.Unlock()
+			return
+		}
+		if c.restartAttempts >= restartMaxAttempts {
+			c.logger.Error("giving up on restarting gopls", "attempts", c.restartAttempts)
+			c.setLifecycleState(lifecycleStopped)
+			c.finishRestart()
+			c.mu.Unlock()
+			return
+		}
+		backoff := restartBackoff(c.restartAttempts)
+		c.restartAttempts++
+		attempt := c.restartAttempts
+		c.mu.Unlock()
+
+		c.logger.Info("restarting gopls", "attempt", attempt, "backoff", backoff)
+		select {
+		case <-time.After(backoff):
+		case <-c.processCtx.Done():
+		}
+
+		c.mu.Lock()
+		if c.stopping || c.processCtx.Err() != nil {
+			c.finishRestart()
+			c.mu.Unlock()
+			return
+		}
+		err := c.launch(c.processCtx)
+		c.mu.Unlock()
+		if err != nil {
+			c.logger.Warn("failed to restart gopls", "attempt", attempt, "error", err)
+			continue
+		}
+
+		c.reopenDocuments()
+
+		c.mu.Lock()
+		c.restarts++
+		c.finishRestart()
+		c.mu.Unlock()
+		c.logger.Info("gopls restarted successfully", "attempt", attempt)
+		return
+	}
+}
+
+// finishRestart ends a restart cycle and wakes requests waiting on it.
+// Callers must hold c.mu.
+func (c *goplsClient) finishRestart() {
+	if !c.restarting {
+		return
+	}
+	c.restarting = false
+	close(c.ready)
+}
+
+// failPendingRequests fails every in-flight request with the given error.
+func (c *goplsClient) failPendingRequests(err error) {
+	c.responsesMux.Lock()
+	defer c.responsesMux.Unlock()
+
+	for id, ch := range c.responses {
+		ch <- &ResponseMessage{
+			JSONRPC: jsonrpcVersion,
+			Error:   &ResponseError{Code: jsonrpcInternalError, Message: err.Error()},
+		}
+		delete(c.responses, id)
+	}
+}
+
+// resetSessionState discards state that belonged to the exited gopls session.
+func (c *goplsClient) resetSessionState() {
+	c.registrationsMux.Lock()
+	c.registrations = make(map[string]Registration)
+	c.updateFileWatchersLocked()
+	c.registrationsMux.Unlock()
+
+	c.resetDiagnostics()
+	c.cache.invalidate()
+}
+
+// reopenDocuments re-sends didOpen for every file that was open before a restart.
+func (c *goplsClient) reopenDocuments() {
+	c.openFilesMux.Lock()
+	paths := make([]string, 0, len(c.openFiles))
+	for path := range c.openFiles {
+		paths = append(paths, path)
+	}
+	c.openFiles = make(map[string]*openDocument)
+	c.openFilesMux.Unlock()
+
+	for _, path := range paths {
+		if err := c.ensureFileOpen(path); err != nil {
+			c.logger.Warn("failed to reopen file after restart", "relativePath", path, "error", err)
+		}
+	}
+}
+
+// waitUntilRunning returns once gopls is running, waiting briefly while a
+// restart is in progress and starting gopls again on demand when it was, or is
+// being, stopped while idle.
+func (c *goplsClient) waitUntilRunning(ctx context.Context) error {
+	c.mu.RLock()
+	running, restarting, ready := c.running, c.restarting, c.ready
+	c.mu.RUnlock()
+
+	if running {
+		return nil
+	}
+	if !restarting {
+		if err := c.activate(ctx); err != nil {
+			return err
+		}
+		if c.isRunning() {
+			return nil
+		}
+		return fmt.Errorf("gopls is not running")
+	}
+
+	c.logger.Debug("waiting for gopls restart")
+	timer := time.NewTimer(restartWaitTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-ready:
+		if c.isRunning() {
+			return nil
+		}
+		return fmt.Errorf("gopls failed to restart")
+	case <-timer.C:
+		return fmt.Errorf("timeout waiting for gopls to restart")
+	case <-ctx.Done():
+		return fmt.Errorf("cancelled waiting for gopls to restart: %w", ctx.Err())
+	}
+}
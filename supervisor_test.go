@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRestartBackoff(t *testing.T) {
+	tests := []struct {
+		attempt  int
+		expected time.Duration
+	}{
+		{attempt: 0, expected: restartInitialBackoff},
+		{attempt: 1, expected: 2 * restartInitialBackoff},
+		{attempt: 3, expected: 8 * restartInitialBackoff},
+		{attempt: 20, expected: restartMaxBackoff},
+	}
+
+	for _, tt := range tests {
+		if got := restartBackoff(tt.attempt); got != tt.expected {
+			t.Errorf("restartBackoff(%d) = %s, want %s", tt.attempt, got, tt.expected)
+		}
+	}
+}
+
+func TestWaitUntilRunning(t *testing.T) {
+	client := newClient("/test/workspace", workspaceConfig{}, newTestLogger())
+
+	// A client that was never started fails immediately
+	if err := client.waitUntilRunning(context.Background()); err == nil {
+		t.Error("Expected error for client that is not running")
+	}
+
+	// A client being restarted blocks until the restart finishes
+	client.mu.Lock()
+	client.restarting = true
+	client.ready = make(chan struct{})
+	client.mu.Unlock()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- client.waitUntilRunning(context.Background())
+	}()
+
+	select {
+	case err := <-errCh:
+		t.Fatalf("Expected waitUntilRunning to block during restart, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	client.mu.Lock()
+	client.running = true
+	client.finishRestart()
+	client.mu.Unlock()
+
+	if err := <-errCh; err != nil {
+		t.Errorf("Expected nil error after restart, got %v", err)
+	}
+
+	// Waiting honours the caller's context
+	client.mu.Lock()
+	client.running = false
+	client.restarting = true
+	client.ready = make(chan struct{})
+	client.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := client.waitUntilRunning(ctx); err == nil {
+		t.Error("Expected error for cancelled context")
+	}
+}
+
+func TestFailPendingRequests(t *testing.T) {
+	client := newClient("/test/workspace", workspaceConfig{}, newTestLogger())
+
+	responseCh := make(chan *ResponseMessage, 1)
+	client.responses[7] = responseCh
+
+	client.failPendingRequests(context.Canceled)
+
+	response := <-responseCh
+	if response.Error == nil || response.Error.Code != jsonrpcInternalError {
+		t.Errorf("Expected internal error response, got %+v", response)
+	}
+	if len(client.responses) != 0 {
+		t.Errorf("Expected pending responses to be cleared, got %d", len(client.responses))
+	}
+}
+
+func TestStartFailsWhenGoplsExitsImmediately(t *testing.T) {
+	// The fake gopls exits at once when its script cannot be loaded
+	config := fakeGoplsConfig(t, filepath.Join(t.TempDir(), "missing.json"))
+	client := newClient(t.TempDir(), config, newTestLogger())
+	t.Cleanup(func() { _ = client.stop() })
+
+	started := time.Now()
+	err := client.start(context.Background())
+	if !errors.Is(err, errGoplsExited) {
+		t.Errorf("Expected start to fail because gopls exited, got %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("Expected start to fail as soon as gopls exits, took %s", elapsed)
+	}
+	if client.isRunning() {
+		t.Error("Expected the client not to be running")
+	}
+}
@@ -1,15 +1,16 @@
 package main
 
 import (
+	"context"
 	"fmt"
 )
 
 // getDocumentSymbols sends a textDocument/documentSymbol request to gopls.
-func (c *goplsClient) getDocumentSymbols(relativePath string) ([]DocumentSymbol, error) {
+func (c *goplsClient) getDocumentSymbols(ctx context.Context, relativePath string) ([]DocumentSymbol, error) {
 	c.logger.Debug("getDocumentSymbols called", "relativePath", relativePath)
 
-	if !c.isRunning() {
-		return nil, fmt.Errorf("gopls is not running")
+	if err := c.waitUntilRunning(ctx); err != nil {
+		return nil, err
 	}
 
 	// Ensure file is open in gopls
@@ -17,131 +18,43 @@ func (c *goplsClient) getDocumentSymbols(relativePath string) ([]DocumentSymbol,
 		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 
-	// Convert relative path to URI for LSP request
-	fileURI := c.relativePathToURI(relativePath)
-
-	// Create textDocument/documentSymbol request
-	request := map[string]any{
-		"jsonrpc": "2.0",
-		"id":      c.nextRequestID(),
-		"method":  "textDocument/documentSymbol",
-		"params": map[string]any{
-			"textDocument": map[string]any{
-				"uri": fileURI,
-			},
-		},
-	}
-
-	// Send request and wait for response
-	response, err := c.sendRequestAndWait(request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get document symbols: %w", err)
-	}
-
-	// Parse response to get symbols
-	symbols, err := c.parseDocumentSymbolsFromResponse(response)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse document symbols: %w", err)
-	}
-
-	return symbols, nil
-}
-
-// parseDocumentSymbolsFromResponse extracts document symbols from LSP response.
-func (c *goplsClient) parseDocumentSymbolsFromResponse(response map[string]any) ([]DocumentSymbol, error) {
-	result, resultOk := response["result"]
-	if !resultOk {
-		return nil, fmt.Errorf("invalid response format")
-	}
-
-	if result == nil {
-		return []DocumentSymbol{}, nil
-	}
-
-	symbolsData, symbolsOk := result.([]any)
-	if !symbolsOk {
-		return nil, fmt.Errorf("invalid response format")
-	}
-
-	var symbols []DocumentSymbol
-	for _, symbolData := range symbolsData {
-		if symbolMap, ok := symbolData.(map[string]any); ok {
-			symbol := c.parseDocumentSymbol(symbolMap)
-			symbols = append(symbols, symbol)
+	method := "textDocument/documentSymbol"
+	return cachedQuery(c, method, relativePath, 0, 0, "", func() ([]DocumentSymbol, error) {
+		params := DocumentSymbolParams{
+			TextDocument: TextDocumentIdentifier{URI: c.relativePathToURI(relativePath)},
 		}
-	}
 
-	return symbols, nil
-}
-
-// parseDocumentSymbol parses a document symbol from a map.
-func (c *goplsClient) parseDocumentSymbol(symbolMap map[string]any) DocumentSymbol {
-	var symbol DocumentSymbol
-
-	if name, ok := symbolMap["name"].(string); ok {
-		symbol.Name = name
-	}
-
-	if detail, ok := symbolMap["detail"].(string); ok {
-		symbol.Detail = detail
-	}
-
-	if kind, ok := symbolMap["kind"].(float64); ok {
-		symbol.Kind = int(kind)
-	}
-
-	if deprecated, ok := symbolMap["deprecated"].(bool); ok {
-		symbol.Deprecated = deprecated
-	}
-
-	if rangeMap, ok := symbolMap["range"].(map[string]any); ok {
-		symbol.Range = c.parseRange(rangeMap)
-	}
-
-	if selectionRangeMap, ok := symbolMap["selectionRange"].(map[string]any); ok {
-		symbol.SelectionRange = c.parseRange(selectionRangeMap)
-	}
+		// Send textDocument/documentSymbol request and wait for response
+		var symbols []DocumentSymbol
+		if err := c.call(ctx, method, params, &symbols); err != nil {
+			return nil, fmt.Errorf("failed to get document symbols: %w", err)
+		}
 
-	if childrenData, ok := symbolMap["children"].([]any); ok {
-		for _, childData := range childrenData {
-			if childMap, childOk := childData.(map[string]any); childOk {
-				child := c.parseDocumentSymbol(childMap)
-				symbol.Children = append(symbol.Children, child)
-			}
+		if symbols == nil {
+			return []DocumentSymbol{}, nil
 		}
-	}
 
-	return symbol
+		return symbols, nil
+	})
 }
 
 // getWorkspaceSymbols sends a workspace/symbol request to gopls.
-func (c *goplsClient) getWorkspaceSymbols(query string) ([]SymbolInformation, error) {
+func (c *goplsClient) getWorkspaceSymbols(ctx context.Context, query string) ([]SymbolInformation, error) {
 	c.logger.Debug("getWorkspaceSymbols called", "query", query)
 
-	if !c.isRunning() {
-		return nil, fmt.Errorf("gopls is not running")
-	}
-
-	// Create workspace/symbol request
-	request := map[string]any{
-		"jsonrpc": "2.0",
-		"id":      c.nextRequestID(),
-		"method":  "workspace/symbol",
-		"params": map[string]any{
-			"query": query,
-		},
+	// Workspace-wide results are partial until gopls has loaded every package
+	if err := c.waitForInitialLoad(ctx); err != nil {
+		return nil, err
 	}
 
-	// Send request and wait for response
-	response, err := c.sendRequestAndWait(request)
-	if err != nil {
+	// Send workspace/symbol request and wait for response
+	var symbols []SymbolInformation
+	if err := c.call(ctx, "workspace/symbol", WorkspaceSymbolParams{Query: query}, &symbols); err != nil {
 		return nil, fmt.Errorf("failed to get workspace symbols: %w", err)
 	}
 
-	// Parse response to get symbols
-	symbols, err := c.parseWorkspaceSymbolsFromResponse(response)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse workspace symbols: %w", err)
+	if symbols == nil {
+		return []SymbolInformation{}, nil
 	}
 
 	// Convert URIs back to relative paths
@@ -151,57 +64,3 @@ func (c *goplsClient) getWorkspaceSymbols(query string) ([]SymbolInformation, er
 
 	return symbols, nil
 }
-
-// parseWorkspaceSymbolsFromResponse extracts workspace symbols from LSP response.
-func (c *goplsClient) parseWorkspaceSymbolsFromResponse(response map[string]any) ([]SymbolInformation, error) {
-	result, resultOk := response["result"]
-	if !resultOk {
-		return nil, fmt.Errorf("invalid response format")
-	}
-
-	if result == nil {
-		return []SymbolInformation{}, nil
-	}
-
-	symbolsData, symbolsOk := result.([]any)
-	if !symbolsOk {
-		return nil, fmt.Errorf("invalid response format")
-	}
-
-	var symbols []SymbolInformation
-	for _, symbolData := range symbolsData {
-		if symbolMap, ok := symbolData.(map[string]any); ok {
-			symbol := c.parseSymbolInformation(symbolMap)
-			symbols = append(symbols, symbol)
-		}
-	}
-
-	return symbols, nil
-}
-
-// parseSymbolInformation parses a symbol information from a map.
-func (c *goplsClient) parseSymbolInformation(symbolMap map[string]any) SymbolInformation {
-	var symbol SymbolInformation
-
-	if name, ok := symbolMap["name"].(string); ok {
-		symbol.Name = name
-	}
-
-	if kind, ok := symbolMap["kind"].(float64); ok {
-		symbol.Kind = int(kind)
-	}
-
-	if deprecated, ok := symbolMap["deprecated"].(bool); ok {
-		symbol.Deprecated = deprecated
-	}
-
-	if location, ok := symbolMap["location"].(map[string]any); ok {
-		symbol.Location = c.parseLocationFromMap(location)
-	}
-
-	if containerName, ok := symbolMap["containerName"].(string); ok {
-		symbol.ContainerName = containerName
-	}
-
-	return symbol
-}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// toolTimeouts holds the deadline applied to each MCP tool call.
+type toolTimeouts struct {
+	defaultTimeout time.Duration
+	perTool        map[string]time.Duration
+}
+
+// newToolTimeouts creates tool timeouts with the given default and no per-tool overrides.
+func newToolTimeouts(defaultTimeout time.Duration) toolTimeouts {
+	return toolTimeouts{
+		defaultTimeout: defaultTimeout,
+		perTool:        make(map[string]time.Duration),
+	}
+}
+
+// parseToolTimeouts parses a comma-separated list of tool=duration overrides,
+// e.g. "find_references=60s,get_workspace_symbols=2m".
+func parseToolTimeouts(defaultTimeout time.Duration, overrides string) (toolTimeouts, error) {
+	timeouts := newToolTimeouts(defaultTimeout)
+	if defaultTimeout <= 0 {
+		return timeouts, fmt.Errorf("default tool timeout must be positive: %s", defaultTimeout)
+	}
+
+	for _, entry := range strings.Split(overrides, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		tool, value, found := strings.Cut(entry, "=")
+		if !found {
+			return timeouts, fmt.Errorf("invalid tool timeout %q: expected tool=duration", entry)
+		}
+
+		timeout, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return timeouts, fmt.Errorf("invalid duration for tool %s: %w", tool, err)
+		}
+		if timeout <= 0 {
+			return timeouts, fmt.Errorf("timeout for tool %s must be positive: %s", tool, timeout)
+		}
+
+		timeouts.perTool[strings.TrimSpace(tool)] = timeout
+	}
+
+	return timeouts, nil
+}
+
+// forTool returns the timeout that applies to the named tool.
+func (t toolTimeouts) forTool(tool string) time.Duration {
+	if timeout, ok := t.perTool[tool]; ok {
+		return timeout
+	}
+	if t.defaultTimeout > 0 {
+		return t.defaultTimeout
+	}
+	return defaultRequestTimeout
+}
+
+// withToolTimeout derives a context bounded by the named tool's deadline.
+func (m mcpTools) withToolTimeout(ctx context.Context, tool string) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, m.timeouts.forTool(tool))
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseToolTimeouts(t *testing.T) {
+	timeouts, err := parseToolTimeouts(30*time.Second, "find_references=60s, get_workspace_symbols=2m")
+	if err != nil {
+		t.Fatalf("parseToolTimeouts failed: %v", err)
+	}
+
+	if got := timeouts.forTool(toolFindReferences); got != 60*time.Second {
+		t.Errorf("Expected 60s for find_references, got %s", got)
+	}
+	if got := timeouts.forTool(toolGetWorkspaceSymbols); got != 2*time.Minute {
+		t.Errorf("Expected 2m for get_workspace_symbols, got %s", got)
+	}
+	if got := timeouts.forTool(toolGetHoverInfo); got != 30*time.Second {
+		t.Errorf("Expected default 30s for get_hover_info, got %s", got)
+	}
+
+	invalid := []string{"find_references", "find_references=soon", "find_references=-1s"}
+	for _, overrides := range invalid {
+		if _, err := parseToolTimeouts(30*time.Second, overrides); err == nil {
+			t.Errorf("Expected error for overrides %q", overrides)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultTraceMaxSizeMB is the size at which a trace file is rotated.
+	defaultTraceMaxSizeMB = 10
+	// traceBackups is how many rotated trace files are kept next to the current one.
+	traceBackups = 3
+	// maxPendingTraces bounds the requests waiting for a response to pair with.
+	maxPendingTraces = 1000
+)
+
+// Directions of traced JSON-RPC frames.
+const (
+	traceDirectionSend    = "send"
+	traceDirectionReceive = "receive"
+)
+
+// traceEntry is one line of an LSP trace file.
+type traceEntry struct {
+	Time      string          `json:"time"`
+	Direction string          `json:"direction"`
+	Kind      string          `json:"kind"`
+	ID        json.RawMessage `json:"id,omitempty"`
+	Method    string          `json:"method,omitempty"`
+	LatencyMs *float64        `json:"latencyMs,omitempty"`
+	Message   json.RawMessage `json:"message"`
+}
+
+// pendingTrace is a traced request waiting for its response.
+type pendingTrace struct {
+	method string
+	sent   time.Time
+}
+
+// lspTracer writes every JSON-RPC frame exchanged with gopls to a JSONL file.
+// Responses are paired with their requests to record the method and latency.
+// The file is rotated when it reaches maxSize, keeping traceBackups old files.
+type lspTracer struct {
+	mu      sync.Mutex
+	path    string
+	maxSize int64
+	logger  *slog.Logger
+	file    *os.File
+	size    int64
+	pending map[string]pendingTrace
+	failed  bool
+}
+
+// newLSPTracer creates a tracer writing to a file in dir named after the workspace.
+// A non-positive maxSizeMB selects the default size.
+func newLSPTracer(dir, workspacePath string, maxSizeMB int, logger *slog.Logger) *lspTracer {
+	if maxSizeMB <= 0 {
+		maxSizeMB = defaultTraceMaxSizeMB
+	}
+	return &lspTracer{
+		path:    filepath.Join(dir, traceFileName(workspacePath)),
+		maxSize: int64(maxSizeMB) << 20,
+		logger:  logger,
+		pending: make(map[string]pendingTrace),
+	}
+}
+
+// traceFileName returns a trace file name that is readable and unique per workspace.
+func traceFileName(workspacePath string) string {
+	sum := sha256.Sum256([]byte(workspacePath))
+	return fmt.Sprintf("%s-%s.jsonl", filepath.Base(workspacePath), hex.EncodeToString(sum[:4]))
+}
+
+// record appends a frame to the trace. Tracing errors are logged once and
+// never fail the LSP exchange. A nil tracer records nothing.
+func (t *lspTracer) record(direction string, frame []byte) {
+	if t == nil {
+		return
+	}
+
+	now := time.Now()
+	var header incomingMessage
+	_ = json.Unmarshal(frame, &header)
+
+	entry := traceEntry{
+		Time:      now.UTC().Format(time.RFC3339Nano),
+		Direction: direction,
+		Kind:      traceKind(&header),
+		ID:        header.ID,
+		Method:    header.Method,
+		Message:   frame,
+	}
+	if !json.Valid(frame) {
+		entry.Message, _ = json.Marshal(string(frame))
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.pairLocked(&entry, now)
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		t.logger.Debug("failed to encode trace entry", "error", err)
+		return
+	}
+	t.writeLocked(append(line, '\n'))
+}
+
+// traceKind classifies a frame as a request, response or notification.
+func traceKind(header *incomingMessage) string {
+	switch {
+	case header.isRequest():
+		return "request"
+	case header.isNotification():
+		return "notification"
+	default:
+		return "response"
+	}
+}
+
+// pairLocked remembers requests and completes responses with the method and
+// latency of their request. Callers hold mu.
+func (t *lspTracer) pairLocked(entry *traceEntry, now time.Time) {
+	switch entry.Kind {
+	case "request":
+		if len(t.pending) >= maxPendingTraces {
+			t.pending = make(map[string]pendingTrace)
+		}
+		t.pending[entry.Direction+":"+string(entry.ID)] = pendingTrace{method: entry.Method, sent: now}
+	case "response":
+		// A response travels in the opposite direction of its request
+		requestDirection := traceDirectionSend
+		if entry.Direction == traceDirectionSend {
+			requestDirection = traceDirectionReceive
+		}
+		key := requestDirection + ":" + string(entry.ID)
+		request, exists := t.pending[key]
+		if !exists {
+			return
+		}
+		delete(t.pending, key)
+		latency := float64(now.Sub(request.sent).Microseconds()) / 1000
+		entry.Method = request.method
+		entry.LatencyMs = &latency
+	}
+}
+
+// writeLocked appends a line to the trace file, rotating it first when it
+// would grow past maxSize. Callers hold mu.
+func (t *lspTracer) writeLocked(line []byte) {
+	if t.file != nil && t.size+int64(len(line)) > t.maxSize {
+		t.rotateLocked()
+	}
+	if t.file == nil && !t.openLocked() {
+		return
+	}
+
+	n, err := t.file.Write(line)
+	t.size += int64(n)
+	if err != nil {
+		t.failLocked("failed to write LSP trace", err)
+	}
+}
+
+// openLocked opens the trace file for appending. Callers hold mu.
+func (t *lspTracer) openLocked() bool {
+	if t.failed {
+		return false
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0755); err != nil {
+		t.failLocked("failed to create LSP trace directory", err)
+		return false
+	}
+	file, err := os.OpenFile(t.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.failLocked("failed to open LSP trace", err)
+		return false
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		t.failLocked("failed to stat LSP trace", err)
+		return false
+	}
+
+	t.file = file
+	t.size = info.Size()
+	t.logger.Info("tracing LSP traffic", "path", t.path)
+	return true
+}
+
+// rotateLocked closes the current file and shifts it to the backups,
+// dropping the oldest one. Callers hold mu.
+func (t *lspTracer) rotateLocked() {
+	t.closeLocked()
+	for i := traceBackups; i > 0; i-- {
+		source := t.path
+		if i > 1 {
+			source = fmt.Sprintf("%s.%d", t.path, i-1)
+		}
+		if err := os.Rename(source, fmt.Sprintf("%s.%d", t.path, i)); err != nil && !os.IsNotExist(err) {
+			t.logger.Debug("failed to rotate LSP trace", "path", source, "error", err)
+		}
+	}
+}
+
+// failLocked disables tracing after an error so a broken trace never floods the log. Callers hold mu.
+func (t *lspTracer) failLocked(message string, err error) {
+	t.logger.Warn(message, "path", t.path, "error", err)
+	t.closeLocked()
+	t.failed = true
+}
+
+// closeLocked closes the trace file. Callers hold mu.
+func (t *lspTracer) closeLocked() {
+	if t.file == nil {
+		return
+	}
+	if err := t.file.Close(); err != nil {
+		t.logger.Debug("failed to close LSP trace", "path", t.path, "error", err)
+	}
+	t.file = nil
+	t.size = 0
+}
+
+// close closes the trace file; it is reopened by the next recorded frame.
+func (t *lspTracer) close() {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.closeLocked()
+	t.pending = make(map[string]pendingTrace)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// discardWriteCloser is a gopls stdin that drops everything written to it.
+type discardWriteCloser struct{ io.Writer }
+
+// Close implements io.Closer.
+func (discardWriteCloser) Close() error { return nil }
+
+// readTraceEntries reads every entry of a trace file.
+func readTraceEntries(t *testing.T, path string) []traceEntry {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open trace: %v", err)
+	}
+	defer file.Close()
+
+	var entries []traceEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry traceEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("invalid trace line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestLSPTracerPairsResponses(t *testing.T) {
+	tracer := newLSPTracer(t.TempDir(), "/test/workspace", 0, newTestLogger())
+	defer tracer.close()
+
+	tracer.record(traceDirectionSend, []byte(`{"jsonrpc":"2.0","id":1,"method":"textDocument/hover","params":{}}`))
+	tracer.record(traceDirectionReceive, []byte(`{"jsonrpc":"2.0","id":"cfg","method":"workspace/configuration"}`))
+	tracer.record(traceDirectionSend, []byte(`{"jsonrpc":"2.0","id":"cfg","result":[null]}`))
+	tracer.record(traceDirectionReceive, []byte(`{"jsonrpc":"2.0","method":"window/logMessage","params":{}}`))
+	tracer.record(traceDirectionReceive, []byte(`{"jsonrpc":"2.0","id":1,"result":null}`))
+
+	entries := readTraceEntries(t, tracer.path)
+	if len(entries) != 5 {
+		t.Fatalf("Expected 5 trace entries, got %d", len(entries))
+	}
+
+	kinds := make([]string, len(entries))
+	for i, entry := range entries {
+		kinds[i] = entry.Direction + " " + entry.Kind
+	}
+	expected := "send request,receive request,send response,receive notification,receive response"
+	if strings.Join(kinds, ",") != expected {
+		t.Errorf("Unexpected frame classification: %v", kinds)
+	}
+
+	for _, i := range []int{2, 4} {
+		if entries[i].LatencyMs == nil {
+			t.Errorf("Expected latency for response %d", i)
+		}
+	}
+	if entries[2].Method != "workspace/configuration" || entries[4].Method != "textDocument/hover" {
+		t.Errorf("Expected responses to carry the method of their request, got %q and %q",
+			entries[2].Method, entries[4].Method)
+	}
+	if entries[3].LatencyMs != nil {
+		t.Error("Expected no latency for notifications")
+	}
+}
+
+func TestLSPTracerRotation(t *testing.T) {
+	tracer := newLSPTracer(t.TempDir(), "/test/workspace", 0, newTestLogger())
+	tracer.maxSize = 512
+	defer tracer.close()
+
+	frame := []byte(`{"jsonrpc":"2.0","method":"$/progress","params":{"token":"load","value":{"kind":"report"}}}`)
+	for range 50 {
+		tracer.record(traceDirectionReceive, frame)
+	}
+
+	for i := 1; i <= traceBackups; i++ {
+		info, err := os.Stat(tracer.path + "." + strconv.Itoa(i))
+		if err != nil {
+			t.Fatalf("Expected rotated trace %d: %v", i, err)
+		}
+		if info.Size() > tracer.maxSize {
+			t.Errorf("Rotated trace %d exceeds the size cap: %d bytes", i, info.Size())
+		}
+	}
+	if _, err := os.Stat(tracer.path + "." + strconv.Itoa(traceBackups+1)); !os.IsNotExist(err) {
+		t.Errorf("Expected at most %d rotated traces, got error %v", traceBackups, err)
+	}
+	if entries := readTraceEntries(t, tracer.path); len(entries) == 0 {
+		t.Error("Expected the current trace to hold the latest frames")
+	}
+}
+
+func TestClientTracesFrames(t *testing.T) {
+	traceDir := t.TempDir()
+	client := newClient(t.TempDir(), workspaceConfig{TraceDir: traceDir}, newTestLogger())
+	client.stdin = discardWriteCloser{io.Discard}
+
+	if err := client.notify("initialized", struct{}{}); err != nil {
+		t.Fatalf("notify failed: %v", err)
+	}
+	frame := `{"jsonrpc":"2.0","method":"window/showMessage","params":{"type":3,"message":"hi"}}`
+	reader := bufio.NewReader(strings.NewReader("Content-Length: " + strconv.Itoa(len(frame)) + "\r\n\r\n" + frame))
+	if _, err := client.readLSPMessage(reader); err != nil {
+		t.Fatalf("readLSPMessage failed: %v", err)
+	}
+	client.tracer.close()
+
+	entries := readTraceEntries(t, filepath.Join(traceDir, traceFileName(client.workspacePath)))
+	if len(entries) != 2 || entries[0].Method != "initialized" || entries[1].Method != "window/showMessage" {
+		t.Fatalf("Expected sent and received frames in the trace, got %+v", entries)
+	}
+}
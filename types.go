@@ -1,5 +1,7 @@
 package main
 
+import "encoding/json"
+
 const (
 	fileScheme = "file"
 )
@@ -41,6 +43,19 @@ type ReferenceParams struct {
 	Context ReferenceContext `json:"context"`
 }
 
+// RenameParams represents parameters for rename requests.
+type RenameParams struct {
+	TextDocumentPositionParams
+	NewName string `json:"newName"`
+}
+
+// PrepareRenameResult represents the symbol a textDocument/prepareRename
+// request found at a position.
+type PrepareRenameResult struct {
+	Range       *Range `json:"range,omitempty"`
+	Placeholder string `json:"placeholder,omitempty"`
+}
+
 // ReferenceContext represents context for find references requests.
 type ReferenceContext struct {
 	IncludeDeclaration bool `json:"includeDeclaration"`
@@ -74,6 +89,8 @@ type Diagnostic struct {
 	Source      string                         `json:"source,omitempty"`
 	Message     string                         `json:"message"`
 	RelatedInfo []DiagnosticRelatedInformation `json:"relatedInformation,omitempty"`
+	// Data is preserved so that code action requests can send it back to gopls.
+	Data json.RawMessage `json:"data,omitempty"`
 }
 
 // DiagnosticRelatedInformation represents related information for a diagnostic.
@@ -154,7 +171,554 @@ type TextEdit struct {
 	NewText string `json:"newText"`
 }
 
-// WorkspaceEdit represents a workspace edit.
+// WorkspaceEdit represents a workspace edit. Changes are applied before the
+// ordered DocumentChanges.
 type WorkspaceEdit struct {
-	Changes map[string][]TextEdit `json:"changes,omitempty"`
+	Changes         map[string][]TextEdit `json:"changes,omitempty"`
+	DocumentChanges []DocumentChange      `json:"documentChanges,omitempty"`
+}
+
+// DocumentChange represents an entry of WorkspaceEdit.documentChanges: a text
+// document edit or a create, rename or delete file operation. Exactly one field is set.
+type DocumentChange struct {
+	TextDocumentEdit *TextDocumentEdit
+	CreateFile       *CreateFile
+	RenameFile       *RenameFile
+	DeleteFile       *DeleteFile
+}
+
+// TextDocumentEdit represents edits to a specific version of a text document.
+type TextDocumentEdit struct {
+	TextDocument OptionalVersionedTextDocumentIdentifier `json:"textDocument"`
+	Edits        []TextEdit                              `json:"edits"`
+}
+
+// OptionalVersionedTextDocumentIdentifier identifies a document and the version
+// the edits were computed for. A nil version means the version is not checked.
+type OptionalVersionedTextDocumentIdentifier struct {
+	URI     string `json:"uri"`
+	Version *int   `json:"version"`
+}
+
+// CreateFile represents a file creation operation.
+type CreateFile struct {
+	Kind    string             `json:"kind"`
+	URI     string             `json:"uri"`
+	Options *CreateFileOptions `json:"options,omitempty"`
+}
+
+// CreateFileOptions represents options for creating a file.
+type CreateFileOptions struct {
+	Overwrite      bool `json:"overwrite,omitempty"`
+	IgnoreIfExists bool `json:"ignoreIfExists,omitempty"`
+}
+
+// RenameFile represents a file rename operation.
+type RenameFile struct {
+	Kind    string             `json:"kind"`
+	OldURI  string             `json:"oldUri"`
+	NewURI  string             `json:"newUri"`
+	Options *RenameFileOptions `json:"options,omitempty"`
+}
+
+// RenameFileOptions represents options for renaming a file.
+type RenameFileOptions struct {
+	Overwrite      bool `json:"overwrite,omitempty"`
+	IgnoreIfExists bool `json:"ignoreIfExists,omitempty"`
+}
+
+// DeleteFile represents a file deletion operation.
+type DeleteFile struct {
+	Kind    string             `json:"kind"`
+	URI     string             `json:"uri"`
+	Options *DeleteFileOptions `json:"options,omitempty"`
+}
+
+// DeleteFileOptions represents options for deleting a file.
+type DeleteFileOptions struct {
+	Recursive         bool `json:"recursive,omitempty"`
+	IgnoreIfNotExists bool `json:"ignoreIfNotExists,omitempty"`
+}
+
+// MarkupContent represents human readable content with a given markup kind.
+type MarkupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+// WorkspaceFolder represents a workspace folder known to the server.
+type WorkspaceFolder struct {
+	URI  string `json:"uri"`
+	Name string `json:"name"`
+}
+
+// InitializeParams represents parameters for the initialize request.
+type InitializeParams struct {
+	ProcessID        int                `json:"processId"`
+	RootURI          string             `json:"rootUri"`
+	WorkspaceFolders []WorkspaceFolder  `json:"workspaceFolders"`
+	Capabilities     ClientCapabilities `json:"capabilities"`
+	// InitializationOptions carries the gopls settings for the workspace.
+	InitializationOptions map[string]any `json:"initializationOptions,omitempty"`
+}
+
+// InitializeResult represents the result of the initialize request.
+type InitializeResult struct {
+	Capabilities ServerCapabilities `json:"capabilities"`
+	ServerInfo   *ServerInfo        `json:"serverInfo,omitempty"`
+}
+
+// ServerInfo represents the name and version reported by gopls.
+type ServerInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// ServerCapabilities represents the capabilities negotiated with gopls.
+type ServerCapabilities struct {
+	PositionEncoding                string                 `json:"positionEncoding,omitempty"`
+	TextDocumentSync                json.RawMessage        `json:"textDocumentSync,omitempty"`
+	HoverProvider                   ProviderCapability     `json:"hoverProvider"`
+	CompletionProvider              ProviderCapability     `json:"completionProvider"`
+	SignatureHelpProvider           ProviderCapability     `json:"signatureHelpProvider"`
+	DefinitionProvider              ProviderCapability     `json:"definitionProvider"`
+	TypeDefinitionProvider          ProviderCapability     `json:"typeDefinitionProvider"`
+	ImplementationProvider          ProviderCapability     `json:"implementationProvider"`
+	ReferencesProvider              ProviderCapability     `json:"referencesProvider"`
+	DocumentSymbolProvider          ProviderCapability     `json:"documentSymbolProvider"`
+	WorkspaceSymbolProvider         ProviderCapability     `json:"workspaceSymbolProvider"`
+	CodeActionProvider              ProviderCapability     `json:"codeActionProvider"`
+	DocumentFormattingProvider      ProviderCapability     `json:"documentFormattingProvider"`
+	DocumentRangeFormattingProvider ProviderCapability     `json:"documentRangeFormattingProvider"`
+	RenameProvider                  ProviderCapability     `json:"renameProvider"`
+	CallHierarchyProvider           ProviderCapability     `json:"callHierarchyProvider"`
+	InlayHintProvider               ProviderCapability     `json:"inlayHintProvider"`
+	DiagnosticProvider              ProviderCapability     `json:"diagnosticProvider"`
+	ExecuteCommandProvider          *ExecuteCommandOptions `json:"executeCommandProvider,omitempty"`
+}
+
+// ProviderCapability represents a server capability advertised either as a
+// boolean or as an options object. An options object means the capability is enabled.
+type ProviderCapability struct {
+	Enabled bool
+	Options json.RawMessage
+}
+
+// ExecuteCommandOptions represents the commands gopls can execute.
+type ExecuteCommandOptions struct {
+	Commands []string `json:"commands"`
+}
+
+// InitializedParams represents parameters for the initialized notification.
+type InitializedParams struct{}
+
+// ClientCapabilities represents the capabilities advertised to gopls.
+type ClientCapabilities struct {
+	General      *GeneralClientCapabilities     `json:"general,omitempty"`
+	TextDocument TextDocumentClientCapabilities `json:"textDocument"`
+	Workspace    WorkspaceClientCapabilities    `json:"workspace"`
+	Window       WindowClientCapabilities       `json:"window"`
+}
+
+// GeneralClientCapabilities represents general client capabilities.
+type GeneralClientCapabilities struct {
+	// PositionEncodings lists the supported position encodings in order of preference.
+	PositionEncodings []string `json:"positionEncodings,omitempty"`
+}
+
+// TextDocumentClientCapabilities represents text document specific client capabilities.
+type TextDocumentClientCapabilities struct {
+	Synchronization *TextDocumentSyncClientCapabilities `json:"synchronization,omitempty"`
+	Hover           *HoverClientCapabilities            `json:"hover,omitempty"`
+	Definition      *DefinitionClientCapabilities       `json:"definition,omitempty"`
+	References      *ReferenceClientCapabilities        `json:"references,omitempty"`
+	// PublishDiagnostics asks gopls to tag diagnostics with the document version.
+	PublishDiagnostics *PublishDiagnosticsClientCapabilities `json:"publishDiagnostics,omitempty"`
+	Diagnostic         *DiagnosticClientCapabilities         `json:"diagnostic,omitempty"`
+	Rename             *RenameClientCapabilities             `json:"rename,omitempty"`
+	CodeAction         *CodeActionClientCapabilities         `json:"codeAction,omitempty"`
+}
+
+// PublishDiagnosticsClientCapabilities represents client capabilities for pushed diagnostics.
+type PublishDiagnosticsClientCapabilities struct {
+	VersionSupport bool `json:"versionSupport,omitempty"`
+	DataSupport    bool `json:"dataSupport,omitempty"`
+}
+
+// DiagnosticClientCapabilities represents client capabilities for pulled diagnostics.
+type DiagnosticClientCapabilities struct {
+	DynamicRegistration bool `json:"dynamicRegistration,omitempty"`
+}
+
+// TextDocumentSyncClientCapabilities represents client capabilities for document synchronization.
+type TextDocumentSyncClientCapabilities struct {
+	DidSave bool `json:"didSave,omitempty"`
+}
+
+// HoverClientCapabilities represents client capabilities for hover requests.
+type HoverClientCapabilities struct {
+	ContentFormat []string `json:"contentFormat,omitempty"`
+}
+
+// DefinitionClientCapabilities represents client capabilities for definition requests.
+type DefinitionClientCapabilities struct {
+	LinkSupport bool `json:"linkSupport,omitempty"`
+}
+
+// ReferenceClientCapabilities represents client capabilities for references requests.
+type ReferenceClientCapabilities struct{}
+
+// RenameClientCapabilities represents client capabilities for rename requests.
+type RenameClientCapabilities struct {
+	PrepareSupport bool `json:"prepareSupport,omitempty"`
+}
+
+// WorkspaceClientCapabilities represents workspace specific client capabilities.
+type WorkspaceClientCapabilities struct {
+	WorkspaceFolders      bool                                     `json:"workspaceFolders,omitempty"`
+	Configuration         bool                                     `json:"configuration,omitempty"`
+	DidChangeWatchedFiles *DidChangeWatchedFilesClientCapabilities `json:"didChangeWatchedFiles,omitempty"`
+	WorkspaceEdit         *WorkspaceEditClientCapabilities         `json:"workspaceEdit,omitempty"`
+}
+
+// WorkspaceEditClientCapabilities represents client capabilities for workspace edits.
+type WorkspaceEditClientCapabilities struct {
+	DocumentChanges    bool     `json:"documentChanges,omitempty"`
+	ResourceOperations []string `json:"resourceOperations,omitempty"`
+	FailureHandling    string   `json:"failureHandling,omitempty"`
+}
+
+// DidChangeWatchedFilesClientCapabilities represents client capabilities for file watching.
+type DidChangeWatchedFilesClientCapabilities struct {
+	DynamicRegistration    bool `json:"dynamicRegistration,omitempty"`
+	RelativePatternSupport bool `json:"relativePatternSupport,omitempty"`
+}
+
+// WindowClientCapabilities represents window specific client capabilities.
+type WindowClientCapabilities struct {
+	WorkDoneProgress bool `json:"workDoneProgress,omitempty"`
+}
+
+// TextDocumentItem represents a text document transferred to gopls.
+type TextDocumentItem struct {
+	URI        string `json:"uri"`
+	LanguageID string `json:"languageId"`
+	Version    int    `json:"version"`
+	Text       string `json:"text"`
+}
+
+// DidOpenTextDocumentParams represents parameters for the textDocument/didOpen notification.
+type DidOpenTextDocumentParams struct {
+	TextDocument TextDocumentItem `json:"textDocument"`
+}
+
+// VersionedTextDocumentIdentifier identifies a specific version of a text document.
+type VersionedTextDocumentIdentifier struct {
+	URI     string `json:"uri"`
+	Version int    `json:"version"`
+}
+
+// TextDocumentContentChangeEvent represents a change to a text document. A change
+// without a range replaces the whole document.
+type TextDocumentContentChangeEvent struct {
+	Text string `json:"text"`
+}
+
+// DidChangeTextDocumentParams represents parameters for the textDocument/didChange notification.
+type DidChangeTextDocumentParams struct {
+	TextDocument   VersionedTextDocumentIdentifier  `json:"textDocument"`
+	ContentChanges []TextDocumentContentChangeEvent `json:"contentChanges"`
+}
+
+// DidSaveTextDocumentParams represents parameters for the textDocument/didSave notification.
+type DidSaveTextDocumentParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// DidCloseTextDocumentParams represents parameters for the textDocument/didClose notification.
+type DidCloseTextDocumentParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// PublishDiagnosticsParams represents parameters for the textDocument/publishDiagnostics notification.
+type PublishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Version     *int         `json:"version,omitempty"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// DocumentDiagnosticParams represents parameters for the textDocument/diagnostic request.
+type DocumentDiagnosticParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// DocumentDiagnosticReport represents a full document diagnostic report.
+type DocumentDiagnosticReport struct {
+	Kind     string       `json:"kind"`
+	ResultID string       `json:"resultId,omitempty"`
+	Items    []Diagnostic `json:"items"`
+}
+
+// DocumentSymbolParams represents parameters for document symbol requests.
+type DocumentSymbolParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// WorkspaceSymbolParams represents parameters for workspace symbol requests.
+type WorkspaceSymbolParams struct {
+	Query string `json:"query"`
+}
+
+// FormattingOptions represents options for formatting requests.
+type FormattingOptions struct {
+	TabSize      int  `json:"tabSize"`
+	InsertSpaces bool `json:"insertSpaces"`
+}
+
+// DocumentFormattingParams represents parameters for document formatting requests.
+type DocumentFormattingParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Options      FormattingOptions      `json:"options"`
+}
+
+// DocumentRangeFormattingParams represents parameters for range formatting requests.
+type DocumentRangeFormattingParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+	Options      FormattingOptions      `json:"options"`
+}
+
+// CodeActionContext represents context for code action requests.
+type CodeActionContext struct {
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	Only        []string     `json:"only,omitempty"`
+}
+
+// CodeActionParams represents parameters for code action requests.
+type CodeActionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+	Context      CodeActionContext      `json:"context"`
+}
+
+// CodeActionClientCapabilities represents client capabilities for code actions.
+type CodeActionClientCapabilities struct {
+	CodeActionLiteralSupport *CodeActionLiteralSupport `json:"codeActionLiteralSupport,omitempty"`
+	IsPreferredSupport       bool                      `json:"isPreferredSupport,omitempty"`
+	DataSupport              bool                      `json:"dataSupport,omitempty"`
+	ResolveSupport           *CodeActionResolveSupport `json:"resolveSupport,omitempty"`
+}
+
+// CodeActionLiteralSupport lists the code action kinds the client understands.
+type CodeActionLiteralSupport struct {
+	CodeActionKind CodeActionKindValueSet `json:"codeActionKind"`
+}
+
+// CodeActionKindValueSet represents a set of code action kinds.
+type CodeActionKindValueSet struct {
+	ValueSet []string `json:"valueSet"`
+}
+
+// CodeActionResolveSupport lists the code action properties gopls may leave to codeAction/resolve.
+type CodeActionResolveSupport struct {
+	Properties []string `json:"properties"`
+}
+
+// Command represents a reference to a command on the server.
+type Command struct {
+	Title     string            `json:"title"`
+	Command   string            `json:"command"`
+	Arguments []json.RawMessage `json:"arguments,omitempty"`
+}
+
+// CodeAction represents a code action offered by the server.
+type CodeAction struct {
+	Title       string          `json:"title"`
+	Kind        string          `json:"kind,omitempty"`
+	Diagnostics []Diagnostic    `json:"diagnostics,omitempty"`
+	IsPreferred bool            `json:"isPreferred,omitempty"`
+	Edit        *WorkspaceEdit  `json:"edit,omitempty"`
+	Command     *Command        `json:"command,omitempty"`
+	Data        json.RawMessage `json:"data,omitempty"`
+}
+
+// ExecuteCommandParams represents parameters for workspace/executeCommand requests.
+type ExecuteCommandParams struct {
+	Command   string            `json:"command"`
+	Arguments []json.RawMessage `json:"arguments,omitempty"`
+}
+
+// CallHierarchyItem represents a function or method in a call hierarchy.
+type CallHierarchyItem struct {
+	Name           string          `json:"name"`
+	Kind           int             `json:"kind"`
+	Tags           []int           `json:"tags,omitempty"`
+	Detail         string          `json:"detail,omitempty"`
+	URI            string          `json:"uri"`
+	Range          Range           `json:"range"`
+	SelectionRange Range           `json:"selectionRange"`
+	Data           json.RawMessage `json:"data,omitempty"`
+}
+
+// CallHierarchyCallsParams represents parameters for incoming and outgoing calls requests.
+type CallHierarchyCallsParams struct {
+	Item CallHierarchyItem `json:"item"`
+}
+
+// CallHierarchyIncomingCall represents a function calling a call hierarchy item.
+// FromRanges are the call sites in the caller.
+type CallHierarchyIncomingCall struct {
+	From       CallHierarchyItem `json:"from"`
+	FromRanges []Range           `json:"fromRanges"`
+}
+
+// CallHierarchyOutgoingCall represents a function called by a call hierarchy item.
+// FromRanges are the call sites in the calling item.
+type CallHierarchyOutgoingCall struct {
+	To         CallHierarchyItem `json:"to"`
+	FromRanges []Range           `json:"fromRanges"`
+}
+
+// InlayHintParams represents parameters for inlay hint requests.
+type InlayHintParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+}
+
+// Registration represents a capability registration requested by gopls.
+type Registration struct {
+	ID              string          `json:"id"`
+	Method          string          `json:"method"`
+	RegisterOptions json.RawMessage `json:"registerOptions,omitempty"`
+}
+
+// RegistrationParams represents parameters for the client/registerCapability request.
+type RegistrationParams struct {
+	Registrations []Registration `json:"registrations"`
+}
+
+// Unregistration represents a capability unregistration requested by gopls.
+type Unregistration struct {
+	ID     string `json:"id"`
+	Method string `json:"method"`
+}
+
+// UnregistrationParams represents parameters for the client/unregisterCapability request.
+type UnregistrationParams struct {
+	// The LSP specification spells this field "unregisterations".
+	Unregistrations []Unregistration `json:"unregisterations"`
+}
+
+// ConfigurationItem represents a single configuration section requested by gopls.
+type ConfigurationItem struct {
+	ScopeURI string `json:"scopeUri,omitempty"`
+	Section  string `json:"section,omitempty"`
+}
+
+// ConfigurationParams represents parameters for the workspace/configuration request.
+type ConfigurationParams struct {
+	Items []ConfigurationItem `json:"items"`
+}
+
+// DidChangeConfigurationParams represents parameters for the workspace/didChangeConfiguration notification.
+type DidChangeConfigurationParams struct {
+	Settings any `json:"settings"`
+}
+
+// WorkDoneProgressCreateParams represents parameters for the window/workDoneProgress/create request.
+type WorkDoneProgressCreateParams struct {
+	// Token is either an integer or a string.
+	Token json.RawMessage `json:"token"`
+}
+
+// ProgressParams represents parameters for the $/progress notification.
+type ProgressParams struct {
+	Token json.RawMessage `json:"token"`
+	Value json.RawMessage `json:"value"`
+}
+
+// WorkDoneProgressValue represents the begin, report and end payloads of work-done progress.
+type WorkDoneProgressValue struct {
+	Kind        string `json:"kind"`
+	Title       string `json:"title,omitempty"`
+	Message     string `json:"message,omitempty"`
+	Percentage  *int   `json:"percentage,omitempty"`
+	Cancellable bool   `json:"cancellable,omitempty"`
+}
+
+// ApplyWorkspaceEditParams represents parameters for the workspace/applyEdit request.
+type ApplyWorkspaceEditParams struct {
+	Label string        `json:"label,omitempty"`
+	Edit  WorkspaceEdit `json:"edit"`
+}
+
+// ApplyWorkspaceEditResult represents the result of the workspace/applyEdit request.
+type ApplyWorkspaceEditResult struct {
+	Applied       bool   `json:"applied"`
+	FailureReason string `json:"failureReason,omitempty"`
+}
+
+// MessageActionItem represents an action offered by a window/showMessageRequest.
+type MessageActionItem struct {
+	Title string `json:"title"`
+}
+
+// ShowMessageParams represents parameters for the window/showMessage notification.
+type ShowMessageParams struct {
+	Type    int    `json:"type"`
+	Message string `json:"message"`
+}
+
+// LogMessageParams represents parameters for the window/logMessage notification.
+type LogMessageParams struct {
+	Type    int    `json:"type"`
+	Message string `json:"message"`
+}
+
+// ShowMessageRequestParams represents parameters for the window/showMessageRequest request.
+type ShowMessageRequestParams struct {
+	Type    int                 `json:"type"`
+	Message string              `json:"message"`
+	Actions []MessageActionItem `json:"actions,omitempty"`
+}
+
+// ShowDocumentResult represents the result of the window/showDocument request.
+type ShowDocumentResult struct {
+	Success bool `json:"success"`
+}
+
+// CancelParams represents parameters for the $/cancelRequest notification.
+type CancelParams struct {
+	ID int `json:"id"`
+}
+
+// DidChangeWatchedFilesRegistrationOptions represents the watchers gopls registers
+// for workspace/didChangeWatchedFiles.
+type DidChangeWatchedFilesRegistrationOptions struct {
+	Watchers []FileSystemWatcher `json:"watchers"`
+}
+
+// FileSystemWatcher represents a glob pattern gopls wants to be notified about.
+// GlobPattern is either a pattern string or a RelativePattern.
+type FileSystemWatcher struct {
+	GlobPattern json.RawMessage `json:"globPattern"`
+	Kind        *int            `json:"kind,omitempty"`
+}
+
+// RelativePattern represents a glob pattern relative to a base URI. BaseURI is
+// either a URI string or a WorkspaceFolder.
+type RelativePattern struct {
+	BaseURI json.RawMessage `json:"baseUri"`
+	Pattern string          `json:"pattern"`
+}
+
+// FileEvent represents a change to a watched file.
+type FileEvent struct {
+	URI  string `json:"uri"`
+	Type int    `json:"type"`
+}
+
+// DidChangeWatchedFilesParams represents parameters for the workspace/didChangeWatchedFiles notification.
+type DidChangeWatchedFilesParams struct {
+	Changes []FileEvent `json:"changes"`
 }
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// processSampleInterval is how often the gopls process is sampled.
+	processSampleInterval = 5 * time.Second
+	// recycleMinUptime gives a freshly started gopls time to load the workspace
+	// before it can be recycled, so a ceiling below the loaded size cannot make
+	// gopls restart in a tight loop.
+	recycleMinUptime = time.Minute
+	// clockTicksPerSecond is the unit of CPU times in /proc (USER_HZ).
+	clockTicksPerSecond = 100
+)
+
+// processSample is a reading of the resource usage of a process.
+type processSample struct {
+	rssBytes int64
+	cpuTime  time.Duration
+	at       time.Time
+}
+
+// readProcessSample reads the resident memory and CPU time of a process from
+// /proc. It fails on systems without procfs.
+func readProcessSample(pid int) (processSample, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return processSample{}, fmt.Errorf("failed to read process stats: %w", err)
+	}
+	sample, err := parseProcStat(data, int64(os.Getpagesize()))
+	if err != nil {
+		return processSample{}, err
+	}
+	sample.at = time.Now()
+	return sample, nil
+}
+
+// parseProcStat extracts the CPU time and resident set size from the contents
+// of /proc/<pid>/stat.
+func parseProcStat(data []byte, pageSize int64) (processSample, error) {
+	// The command name may contain spaces and parentheses, so fields are
+	// counted from the last closing parenthesis, starting with field 3 (state)
+	end := bytes.LastIndexByte(data, ')')
+	if end < 0 {
+		return processSample{}, fmt.Errorf("invalid process stats: %q", data)
+	}
+	fields := bytes.Fields(data[end+1:])
+	if len(fields) < 22 {
+		return processSample{}, fmt.Errorf("invalid process stats: only %d fields", len(fields)+2)
+	}
+
+	var values [3]int64
+	for i, index := range []int{11, 12, 21} { // utime, stime, rss
+		value, err := strconv.ParseInt(string(fields[index]), 10, 64)
+		if err != nil {
+			return processSample{}, fmt.Errorf("invalid process stats field %d: %w", index+3, err)
+		}
+		values[i] = value
+	}
+
+	ticks := values[0] + values[1]
+	return processSample{
+		rssBytes: values[2] * pageSize,
+		cpuTime:  time.Duration(ticks) * time.Second / clockTicksPerSecond,
+	}, nil
+}
+
+// processWatchdog keeps the latest resource usage of the gopls process and
+// decides when it has to be recycled for using too much memory.
+type processWatchdog struct {
+	mu        sync.Mutex
+	interval  time.Duration
+	minUptime time.Duration
+	maxMemory int64
+	pid       int
+	last      processSample
+	stats     ProcessStats
+}
+
+// newProcessWatchdog creates a watchdog with a memory ceiling in MB; zero
+// samples the process without ever recycling it.
+func newProcessWatchdog(maxMemoryMB int) *processWatchdog {
+	return &processWatchdog{
+		interval:  processSampleInterval,
+		minUptime: recycleMinUptime,
+		maxMemory: int64(maxMemoryMB) << 20,
+		stats:     ProcessStats{MaxMemoryMB: maxMemoryMB},
+	}
+}
+
+// record stores a sample of the process and computes its CPU usage since the
+// previous sample of the same process.
+func (w *processWatchdog) record(pid int, sample processSample) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.stats.PID = pid
+	w.stats.RSSMB = bytesToMB(sample.rssBytes)
+	w.stats.SampledAt = sample.at.UTC().Format(time.RFC3339Nano)
+	w.stats.CPUPercent = 0
+	if elapsed := sample.at.Sub(w.last.at); w.pid == pid && elapsed > 0 {
+		w.stats.CPUPercent = float64(sample.cpuTime-w.last.cpuTime) / float64(elapsed) * 100
+	}
+	w.pid = pid
+	w.last = sample
+}
+
+// exceeded reports whether a sample of a process that has been up for uptime
+// is above the memory ceiling and the process may be recycled.
+func (w *processWatchdog) exceeded(sample processSample, uptime time.Duration) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.maxMemory > 0 && sample.rssBytes > w.maxMemory && uptime >= w.minUptime
+}
+
+// recordRecycle counts a recycle caused by the given sample.
+func (w *processWatchdog) recordRecycle(sample processSample) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.stats.Recycles++
+	w.stats.LastRecycle = &RecycleEvent{
+		Time:        sample.at.UTC().Format(time.RFC3339Nano),
+		RSSMB:       bytesToMB(sample.rssBytes),
+		MaxMemoryMB: w.stats.MaxMemoryMB,
+	}
+}
+
+// forget clears the readings of an exited process.
+func (w *processWatchdog) forget(pid int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.pid != pid {
+		return
+	}
+	w.pid = 0
+	w.last = processSample{}
+	w.stats.PID = 0
+	w.stats.RSSMB = 0
+	w.stats.CPUPercent = 0
+	w.stats.SampledAt = ""
+}
+
+// snapshot returns the latest process statistics.
+func (w *processWatchdog) snapshot() ProcessStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	stats := w.stats
+	if stats.LastRecycle != nil {
+		lastRecycle := *stats.LastRecycle
+		stats.LastRecycle = &lastRecycle
+	}
+	return stats
+}
+
+// bytesToMB converts a byte count to megabytes rounded to two decimals.
+func bytesToMB(size int64) float64 {
+	return float64(size*100>>20) / 100
+}
+
+// watchProcess samples a gopls process until it exits and recycles it when
+// it crosses the memory ceiling.
+func (c *goplsClient) watchProcess(cmd *exec.Cmd, exited chan struct{}, startedAt time.Time) {
+	pid := cmd.Process.Pid
+	defer c.watchdog.forget(pid)
+
+	ticker := time.NewTicker(c.watchdog.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-exited:
+			return
+		case <-ticker.C:
+		}
+
+		sample, err := readProcessSample(pid)
+		if err != nil {
+			// Without procfs there is nothing to watch
+			c.logger.Debug("stopped sampling gopls", "pid", pid, "error", err)
+			return
+		}
+		c.watchdog.record(pid, sample)
+
+		if c.watchdog.exceeded(sample, time.Since(startedAt)) {
+			c.recycle(cmd, sample)
+			return
+		}
+	}
+}
+
+// recycle replaces a gopls process that uses too much memory: it shuts the
+// process down gracefully, launches a new one and re-opens the tracked
+// documents. Requests made meanwhile wait as they do for a crash restart.
+func (c *goplsClient) recycle(cmd *exec.Cmd, sample processSample) {
+	c.mu.Lock()
+	if c.cmd != cmd || c.stopping || c.restarting {
+		c.mu.Unlock()
+		return
+	}
+
+	c.logger.Warn("recycling gopls above memory ceiling", "pid", cmd.Process.Pid,
+		"rssMB", bytesToMB(sample.rssBytes), "maxMemoryMB", c.config.MaxMemoryMB)
+	c.restarting = true
+	c.ready = make(chan struct{})
+	if err := c.shutdown(); err != nil {
+		c.logger.Warn("failed to shut down gopls for recycling", "error", err)
+	}
+	c.mu.Unlock()
+
+	c.watchdog.recordRecycle(sample)
+	c.resetSessionState()
+
+	c.mu.Lock()
+	if c.stopping || c.processCtx.Err() != nil {
+		c.finishRestart()
+		c.mu.Unlock()
+		return
+	}
+	err := c.launch(c.processCtx)
+	c.mu.Unlock()
+	if err != nil {
+		c.logger.Warn("failed to relaunch recycled gopls", "error", err)
+		c.mu.Lock()
+		c.restartAttempts = 0
+		c.mu.Unlock()
+		c.restart()
+		return
+	}
+
+	c.reopenDocuments()
+
+	c.mu.Lock()
+	c.finishRestart()
+	c.mu.Unlock()
+	c.logger.Info("gopls recycled", "pid", c.pid())
+}
+
+// pid returns the process ID of the running gopls, or zero.
+func (c *goplsClient) pid() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.cmd == nil || c.cmd.Process == nil {
+		return 0
+	}
+	return c.cmd.Process.Pid
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestParseProcStat(t *testing.T) {
+	stat := "4242 (gopls (worker)) S 1 4242 4242 0 -1 4194560 9000 0 0 0 " +
+		"250 50 0 0 20 0 12 0 100 2000000000 51200 18446744073709551615 1 1 0 0 0 0 0 0 0 0 0 0 17 3 0 0 0 0 0\n"
+
+	sample, err := parseProcStat([]byte(stat), 4096)
+	if err != nil {
+		t.Fatalf("parseProcStat failed: %v", err)
+	}
+	if sample.cpuTime != 3*time.Second {
+		t.Errorf("Expected 3s of CPU time, got %v", sample.cpuTime)
+	}
+	if sample.rssBytes != 51200*4096 {
+		t.Errorf("Expected 200 MB resident, got %d bytes", sample.rssBytes)
+	}
+
+	if _, err := parseProcStat([]byte("4242 (gopls) S 1"), 4096); err == nil {
+		t.Error("Expected error for truncated stats")
+	}
+}
+
+func TestProcessWatchdog(t *testing.T) {
+	watchdog := newProcessWatchdog(100)
+	start := time.Now()
+
+	watchdog.record(7, processSample{rssBytes: 50 << 20, cpuTime: time.Second, at: start})
+	watchdog.record(7, processSample{rssBytes: 150 << 20, cpuTime: 1500 * time.Millisecond, at: start.Add(time.Second)})
+	stats := watchdog.snapshot()
+	if stats.PID != 7 || stats.RSSMB != 150 || stats.CPUPercent != 50 {
+		t.Errorf("Unexpected process stats: %+v", stats)
+	}
+
+	sample := processSample{rssBytes: 150 << 20, at: start}
+	if watchdog.exceeded(sample, time.Second) {
+		t.Error("Expected a freshly started gopls not to be recycled")
+	}
+	if !watchdog.exceeded(sample, recycleMinUptime) {
+		t.Error("Expected gopls above the ceiling to be recycled")
+	}
+	if newProcessWatchdog(0).exceeded(sample, recycleMinUptime) {
+		t.Error("Expected no recycling without a ceiling")
+	}
+
+	watchdog.recordRecycle(sample)
+	watchdog.forget(7)
+	stats = watchdog.snapshot()
+	if stats.PID != 0 || stats.RSSMB != 0 || stats.Recycles != 1 || stats.LastRecycle == nil ||
+		stats.LastRecycle.RSSMB != 150 || stats.LastRecycle.MaxMemoryMB != 100 {
+		t.Errorf("Unexpected stats after recycle: %+v", stats)
+	}
+}
+
+func TestToolCallWaitsForRecycle(t *testing.T) {
+	workspacePath := newFakeGoplsWorkspace(t)
+	client := startFakeGoplsClient(t, workspacePath, fakeGoplsConfig(t, writeFakeGoplsScript(t, fakeGoplsScript())))
+	session := connectMCP(t, map[string]*goplsClient{workspacePath: client})
+
+	client.mu.RLock()
+	cmd := client.cmd
+	client.mu.RUnlock()
+
+	release := holdDrain(client)
+	recycled := make(chan struct{})
+	go func() {
+		client.recycle(cmd, processSample{})
+		close(recycled)
+	}()
+	waitUntilDraining(t, client)
+
+	type toolResult struct {
+		result *mcp.CallToolResult
+		err    error
+	}
+	hoverCh := make(chan toolResult, 1)
+	go func() {
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      toolGetHoverInfo,
+			Arguments: map[string]any{"workspace": workspacePath, "path": "main.go", "line": 4, "character": 1},
+		})
+		hoverCh <- toolResult{result, err}
+	}()
+	select {
+	case hover := <-hoverCh:
+		t.Fatalf("Expected the tool call to wait for the recycle, got %+v", hover)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	release()
+	<-recycled
+	hover := <-hoverCh
+	if hover.err != nil || hover.result.IsError {
+		t.Fatalf("Expected the tool call to succeed once gopls is recycled, got %+v", hover)
+	}
+	if client.pid() == cmd.Process.Pid {
+		t.Error("Expected the tool call to be answered by the new gopls")
+	}
+}
+
+func TestMemoryWatchdogRecyclesGopls(t *testing.T) {
+	if _, err := os.Stat("/proc/self/stat"); err != nil {
+		t.Skip("procfs not available")
+	}
+
+	workspacePath := newFakeGoplsWorkspace(t)
+	config := fakeGoplsConfig(t, writeFakeGoplsScript(t, fakeGoplsScript()))
+	config.MaxMemoryMB = 1
+
+	client := newClient(workspacePath, config, newTestLogger())
+	client.watchdog.interval = 20 * time.Millisecond
+	client.watchdog.minUptime = 0
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := client.start(ctx); err != nil {
+		t.Fatalf("failed to start fake gopls: %v", err)
+	}
+	defer func() { _ = client.stop() }()
+	if err := client.ensureFileOpen("main.go"); err != nil {
+		t.Fatalf("ensureFileOpen failed: %v", err)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for client.workspaceStatus().Process.Recycles == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected gopls above the memory ceiling to be recycled")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Stop recycling and wait for the replacement gopls
+	client.watchdog.mu.Lock()
+	client.watchdog.maxMemory = 0
+	client.watchdog.mu.Unlock()
+	if err := client.waitUntilRunning(ctx); err != nil {
+		t.Fatalf("Expected gopls to run again after recycling: %v", err)
+	}
+
+	if _, isOpen := client.documentVersion("main.go"); !isOpen {
+		t.Error("Expected tracked documents to be re-opened after recycling")
+	}
+	hover, err := client.getHover(ctx, "main.go", 3, 1)
+	if err != nil || len(hover.Contents) != 1 {
+		t.Errorf("Expected the recycled gopls to answer, got %+v, %v", hover, err)
+	}
+	if lastRecycle := client.workspaceStatus().Process.LastRecycle; lastRecycle == nil || lastRecycle.MaxMemoryMB != 1 {
+		t.Errorf("Expected the recycle to be reported, got %+v", lastRecycle)
+	}
+}
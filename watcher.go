@@ -0,0 +1,409 @@
+package main
+
+import (
+	"encoding/json"
+	"io/fs"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// File change types reported in workspace/didChangeWatchedFiles.
+const (
+	fileCreated = 1
+	fileChanged = 2
+	fileDeleted = 3
+)
+
+// Watch kinds a FileSystemWatcher can subscribe to.
+const (
+	watchKindCreate = 1
+	watchKindChange = 2
+	watchKindDelete = 4
+	watchKindAll    = watchKindCreate | watchKindChange | watchKindDelete
+)
+
+const (
+	// watchPollInterval is how often the polling watcher rescans the workspace.
+	watchPollInterval = 2 * time.Second
+	// watchBatchDelay is how long changes are collected before they are forwarded.
+	watchBatchDelay = 100 * time.Millisecond
+)
+
+// fileChange is a change to a file identified by its absolute path.
+type fileChange struct {
+	path       string
+	changeType int
+}
+
+// changeSource reports batches of file changes under a workspace.
+type changeSource interface {
+	changes() <-chan []fileChange
+	close() error
+}
+
+// fileWatcher is a compiled FileSystemWatcher registered by gopls.
+type fileWatcher struct {
+	base    string
+	pattern *regexp.Regexp
+	kind    int
+}
+
+// matches reports whether the watcher subscribes to the given change.
+func (w fileWatcher) matches(change fileChange) bool {
+	if w.kind&watchKindForChange(change.changeType) == 0 {
+		return false
+	}
+
+	path := filepath.ToSlash(change.path)
+	if w.base != "" {
+		relative, err := filepath.Rel(w.base, change.path)
+		if err != nil || strings.HasPrefix(relative, "..") {
+			return false
+		}
+		path = filepath.ToSlash(relative)
+	}
+	return w.pattern.MatchString(path)
+}
+
+// watchKindForChange returns the watch kind bit that covers a change type.
+func watchKindForChange(changeType int) int {
+	switch changeType {
+	case fileCreated:
+		return watchKindCreate
+	case fileDeleted:
+		return watchKindDelete
+	default:
+		return watchKindChange
+	}
+}
+
+// compileFileWatchers compiles the watchers of every didChangeWatchedFiles registration.
+func (c *goplsClient) compileFileWatchers(registrations map[string]Registration) []fileWatcher {
+	var watchers []fileWatcher
+	for _, registration := range registrations {
+		if registration.Method != "workspace/didChangeWatchedFiles" {
+			continue
+		}
+
+		var options DidChangeWatchedFilesRegistrationOptions
+		if err := json.Unmarshal(registration.RegisterOptions, &options); err != nil {
+			c.logger.Warn("invalid file watcher registration", "id", registration.ID, "error", err)
+			continue
+		}
+
+		for _, watcher := range options.Watchers {
+			compiled, err := c.compileFileWatcher(watcher)
+			if err != nil {
+				c.logger.Warn("ignoring file watcher", "id", registration.ID, "error", err)
+				continue
+			}
+			watchers = append(watchers, compiled)
+		}
+	}
+	return watchers
+}
+
+// compileFileWatcher compiles a single FileSystemWatcher.
+func (c *goplsClient) compileFileWatcher(watcher FileSystemWatcher) (fileWatcher, error) {
+	compiled := fileWatcher{kind: watchKindAll}
+	if watcher.Kind != nil {
+		compiled.kind = *watcher.Kind
+	}
+
+	pattern := ""
+	if err := json.Unmarshal(watcher.GlobPattern, &pattern); err != nil {
+		var relative RelativePattern
+		if err := json.Unmarshal(watcher.GlobPattern, &relative); err != nil {
+			return compiled, err
+		}
+		pattern = relative.Pattern
+		compiled.base = baseURIPath(relative.BaseURI)
+	}
+
+	re, err := compileGlob(pattern)
+	if err != nil {
+		return compiled, err
+	}
+	compiled.pattern = re
+	return compiled, nil
+}
+
+// baseURIPath returns the file system path of a RelativePattern base, which is
+// either a URI string or a WorkspaceFolder.
+func baseURIPath(raw json.RawMessage) string {
+	var uri string
+	if err := json.Unmarshal(raw, &uri); err != nil {
+		var folder WorkspaceFolder
+		if err := json.Unmarshal(raw, &folder); err != nil {
+			return ""
+		}
+		uri = folder.URI
+	}
+
+	parsedURI, err := url.Parse(uri)
+	if err != nil || parsedURI.Scheme != fileScheme {
+		return ""
+	}
+	return filepath.FromSlash(parsedURI.Path)
+}
+
+// updateFileWatchersLocked recompiles the registered file watchers. Callers must hold c.registrationsMux.
+func (c *goplsClient) updateFileWatchersLocked() {
+	watchers := c.compileFileWatchers(c.registrations)
+
+	c.fileWatchersMux.Lock()
+	c.fileWatchers = watchers
+	c.fileWatchersMux.Unlock()
+}
+
+// startWatcher starts watching the workspace for file changes, using the native
+// watcher when available and polling otherwise.
+func (c *goplsClient) startWatcher() {
+	source, err := newNativeChangeSource(c.workspacePath, c.logger)
+	if err != nil {
+		c.logger.Info("native file watching unavailable, polling instead", "error", err)
+		source = newPollingChangeSource(c.workspacePath, watchPollInterval)
+	}
+
+	c.watcherMux.Lock()
+	c.watcher = source
+	c.watcherMux.Unlock()
+
+	go func() {
+		for changes := range source.changes() {
+			c.forwardFileChanges(changes)
+		}
+	}()
+}
+
+// stopWatcher stops the workspace watcher.
+func (c *goplsClient) stopWatcher() {
+	c.watcherMux.Lock()
+	source := c.watcher
+	c.watcher = nil
+	c.watcherMux.Unlock()
+
+	if source != nil {
+		if err := source.close(); err != nil {
+			c.logger.Debug("failed to close file watcher", "error", err)
+		}
+	}
+}
+
+// forwardFileChanges sends the changes gopls subscribed to as workspace/didChangeWatchedFiles
+// and re-synchronizes open documents that changed on disk.
+func (c *goplsClient) forwardFileChanges(changes []fileChange) {
+	c.fileWatchersMux.RLock()
+	watchers := c.fileWatchers
+	c.fileWatchersMux.RUnlock()
+
+	var events []FileEvent
+	for _, change := range changes {
+		for _, watcher := range watchers {
+			if watcher.matches(change) {
+				events = append(events, FileEvent{
+					URI:  "file://" + filepath.ToSlash(change.path),
+					Type: change.changeType,
+				})
+				break
+			}
+		}
+		c.resyncOpenDocument(change)
+	}
+
+	if len(events) == 0 || !c.isRunning() {
+		return
+	}
+
+	params := DidChangeWatchedFilesParams{Changes: events}
+	if err := c.notify("workspace/didChangeWatchedFiles", params); err != nil {
+		c.logger.Debug("failed to forward file changes", "error", err)
+		return
+	}
+	c.cache.invalidate()
+	c.logger.Debug("forwarded file changes to gopls", "count", len(events))
+}
+
+// resyncOpenDocument pushes the new contents of an open document that changed on disk.
+func (c *goplsClient) resyncOpenDocument(change fileChange) {
+	relativePath, err := filepath.Rel(c.workspacePath, change.path)
+	if err != nil {
+		return
+	}
+
+	c.openFilesMux.Lock()
+	_, isOpen := c.openFiles[relativePath]
+	c.openFilesMux.Unlock()
+
+	if isOpen && c.isRunning() {
+		// Deleted documents are closed by ensureFileOpen, so its error is expected
+		_ = c.ensureFileOpen(relativePath)
+	}
+}
+
+// skipWatchDir reports whether a directory is excluded from watching, following
+// the go command's convention of ignoring directories starting with "." or "_".
+func skipWatchDir(name string) bool {
+	return strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "node_modules"
+}
+
+// changeBatcher coalesces changes for the same path until they are flushed.
+type changeBatcher struct {
+	order   []string
+	pending map[string]int
+}
+
+// add records a change, merging it with an earlier change to the same path.
+func (b *changeBatcher) add(change fileChange) {
+	if b.pending == nil {
+		b.pending = make(map[string]int)
+	}
+
+	previous, exists := b.pending[change.path]
+	switch {
+	case !exists:
+		b.order = append(b.order, change.path)
+		b.pending[change.path] = change.changeType
+	case previous == fileCreated && change.changeType == fileChanged:
+		// A file created and then written is still a creation
+	case previous == fileCreated && change.changeType == fileDeleted:
+		// A file created and deleted within one batch never existed for gopls
+		delete(b.pending, change.path)
+	case previous == fileDeleted && change.changeType == fileCreated:
+		// A file replaced within one batch, as atomic-rename saves do, was changed
+		b.pending[change.path] = fileChanged
+	default:
+		b.pending[change.path] = change.changeType
+	}
+}
+
+// flush returns the pending changes in arrival order and resets the batcher.
+func (b *changeBatcher) flush() []fileChange {
+	changes := make([]fileChange, 0, len(b.pending))
+	for _, path := range b.order {
+		if changeType, ok := b.pending[path]; ok {
+			changes = append(changes, fileChange{path: path, changeType: changeType})
+			delete(b.pending, path)
+		}
+	}
+	b.order = b.order[:0]
+	return changes
+}
+
+// fileSnapshot records what the polling watcher last saw of a file.
+type fileSnapshot struct {
+	modTime time.Time
+	size    int64
+}
+
+// pollingChangeSource detects changes by periodically rescanning the workspace.
+type pollingChangeSource struct {
+	root     string
+	interval time.Duration
+	baseline map[string]fileSnapshot
+	events   chan []fileChange
+	done     chan struct{}
+	once     sync.Once
+}
+
+// newPollingChangeSource starts a polling watcher rooted at root.
+func newPollingChangeSource(root string, interval time.Duration) *pollingChangeSource {
+	source := &pollingChangeSource{
+		root:     root,
+		interval: interval,
+		events:   make(chan []fileChange, 16),
+		done:     make(chan struct{}),
+	}
+	source.baseline = source.scan()
+	go source.run()
+	return source
+}
+
+// changes returns the channel of change batches.
+func (s *pollingChangeSource) changes() <-chan []fileChange {
+	return s.events
+}
+
+// close stops the polling watcher.
+func (s *pollingChangeSource) close() error {
+	s.once.Do(func() { close(s.done) })
+	return nil
+}
+
+// run rescans the workspace on every tick and reports the differences.
+func (s *pollingChangeSource) run() {
+	defer close(s.events)
+
+	previous := s.baseline
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+
+		current := s.scan()
+		changes := diffSnapshots(previous, current)
+		previous = current
+		if len(changes) == 0 {
+			continue
+		}
+
+		select {
+		case s.events <- changes:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// scan records the modification time and size of every file under the root.
+func (s *pollingChangeSource) scan() map[string]fileSnapshot {
+	snapshot := make(map[string]fileSnapshot)
+	_ = filepath.WalkDir(s.root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.IsDir() {
+			if path != s.root && skipWatchDir(entry.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		snapshot[path] = fileSnapshot{modTime: info.ModTime(), size: info.Size()}
+		return nil
+	})
+	return snapshot
+}
+
+// diffSnapshots returns the changes between two workspace scans.
+func diffSnapshots(previous, current map[string]fileSnapshot) []fileChange {
+	var changes []fileChange
+	for path, snapshot := range current {
+		old, existed := previous[path]
+		switch {
+		case !existed:
+			changes = append(changes, fileChange{path: path, changeType: fileCreated})
+		case old != snapshot:
+			changes = append(changes, fileChange{path: path, changeType: fileChanged})
+		}
+	}
+	for path := range previous {
+		if _, exists := current[path]; !exists {
+			changes = append(changes, fileChange{path: path, changeType: fileDeleted})
+		}
+	}
+	return changes
+}
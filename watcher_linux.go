@@ -0,0 +1,243 @@
+//go:build linux
+
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// inotifyDirMask is the set of inotify events watched on every directory.
+	inotifyDirMask = syscall.IN_CREATE | syscall.IN_CLOSE_WRITE | syscall.IN_MODIFY |
+		syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_ONLYDIR
+	// inotifyPollTimeout bounds how long the reader blocks before checking for close.
+	inotifyPollTimeout = 500 * time.Millisecond
+)
+
+// inotifyChangeSource watches a workspace tree with inotify.
+type inotifyChangeSource struct {
+	fd     int
+	epfd   int
+	logger *slog.Logger
+	dirs   map[int32]string
+	events chan []fileChange
+	done   chan struct{}
+	once   sync.Once
+}
+
+// newNativeChangeSource starts an inotify watcher for every directory under root.
+func newNativeChangeSource(root string, logger *slog.Logger) (changeSource, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize inotify: %w", err)
+	}
+
+	epfd, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC)
+	if err != nil {
+		_ = syscall.Close(fd)
+		return nil, fmt.Errorf("failed to create epoll instance: %w", err)
+	}
+
+	event := syscall.EpollEvent{Events: syscall.EPOLLIN, Fd: int32(fd)}
+	if err := syscall.EpollCtl(epfd, syscall.EPOLL_CTL_ADD, fd, &event); err != nil {
+		_ = syscall.Close(epfd)
+		_ = syscall.Close(fd)
+		return nil, fmt.Errorf("failed to register inotify with epoll: %w", err)
+	}
+
+	source := &inotifyChangeSource{
+		fd:     fd,
+		epfd:   epfd,
+		logger: logger,
+		dirs:   make(map[int32]string),
+		events: make(chan []fileChange, 16),
+		done:   make(chan struct{}),
+	}
+	if err := source.watchTree(root, true, nil); err != nil {
+		source.closeDescriptors()
+		return nil, err
+	}
+
+	go source.run()
+	return source, nil
+}
+
+// changes returns the channel of change batches.
+func (s *inotifyChangeSource) changes() <-chan []fileChange {
+	return s.events
+}
+
+// close stops the watcher.
+func (s *inotifyChangeSource) close() error {
+	s.once.Do(func() { close(s.done) })
+	return nil
+}
+
+// closeDescriptors releases the inotify and epoll file descriptors.
+func (s *inotifyChangeSource) closeDescriptors() {
+	_ = syscall.Close(s.epfd)
+	_ = syscall.Close(s.fd)
+}
+
+// watchTree adds a watch for dir and every directory below it. When batcher is
+// non-nil, files found in the tree are reported as created, because they may
+// have appeared before the watch was in place.
+func (s *inotifyChangeSource) watchTree(dir string, isRoot bool, batcher *changeBatcher) error {
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			return nil
+		}
+
+		if !entry.IsDir() {
+			if batcher != nil {
+				batcher.add(fileChange{path: path, changeType: fileCreated})
+			}
+			return nil
+		}
+
+		if (path != dir || !isRoot) && skipWatchDir(entry.Name()) {
+			return filepath.SkipDir
+		}
+
+		wd, err := syscall.InotifyAddWatch(s.fd, path, inotifyDirMask)
+		if err != nil {
+			if errors.Is(err, syscall.ENOSPC) {
+				return fmt.Errorf("inotify watch limit reached: %w", err)
+			}
+			// Directories can disappear or be unreadable; skip them
+			return filepath.SkipDir
+		}
+		s.dirs[int32(wd)] = path
+		return nil
+	})
+}
+
+// run reads inotify events and forwards them in batches until the watcher is closed.
+func (s *inotifyChangeSource) run() {
+	defer close(s.events)
+	defer s.closeDescriptors()
+
+	buffer := make([]byte, 64*1024)
+	epollEvents := make([]syscall.EpollEvent, 1)
+	batcher := &changeBatcher{}
+	var batchStarted time.Time
+
+	for {
+		select {
+		case <-s.done:
+			return
+		default:
+		}
+
+		timeout := inotifyPollTimeout
+		if !batchStarted.IsZero() {
+			timeout = watchBatchDelay
+		}
+
+		ready, err := syscall.EpollWait(s.epfd, epollEvents, int(timeout.Milliseconds()))
+		if err != nil && !errors.Is(err, syscall.EINTR) {
+			s.logger.Error("inotify wait failed", "error", err)
+			return
+		}
+		if ready > 0 {
+			if err := s.readEvents(buffer, batcher); err != nil {
+				s.logger.Error("failed to read inotify events", "error", err)
+				return
+			}
+			if batchStarted.IsZero() && len(batcher.pending) > 0 {
+				batchStarted = time.Now()
+			}
+		}
+
+		if batchStarted.IsZero() || time.Since(batchStarted) < watchBatchDelay {
+			continue
+		}
+		batchStarted = time.Time{}
+
+		changes := batcher.flush()
+		if len(changes) == 0 {
+			continue
+		}
+		select {
+		case s.events <- changes:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// readEvents drains the inotify descriptor into the batcher.
+func (s *inotifyChangeSource) readEvents(buffer []byte, batcher *changeBatcher) error {
+	for {
+		n, err := syscall.Read(s.fd, buffer)
+		if err != nil {
+			if errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EINTR) {
+				return nil
+			}
+			return err
+		}
+		if n <= 0 {
+			return nil
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			wd := int32(binary.NativeEndian.Uint32(buffer[offset:]))
+			mask := binary.NativeEndian.Uint32(buffer[offset+4:])
+			nameLength := int(binary.NativeEndian.Uint32(buffer[offset+12:]))
+			nameStart := offset + syscall.SizeofInotifyEvent
+			name := strings.TrimRight(string(buffer[nameStart:nameStart+nameLength]), "\x00")
+			offset = nameStart + nameLength
+
+			s.handleEvent(wd, mask, name, batcher)
+		}
+	}
+}
+
+// handleEvent translates a single inotify event into file changes.
+func (s *inotifyChangeSource) handleEvent(wd int32, mask uint32, name string, batcher *changeBatcher) {
+	if mask&syscall.IN_Q_OVERFLOW != 0 {
+		s.logger.Warn("inotify event queue overflowed; some file changes were missed")
+		return
+	}
+
+	dir, ok := s.dirs[wd]
+	if !ok {
+		return
+	}
+	if mask&syscall.IN_IGNORED != 0 {
+		delete(s.dirs, wd)
+		return
+	}
+	if name == "" {
+		return
+	}
+
+	path := filepath.Join(dir, name)
+	isDir := mask&syscall.IN_ISDIR != 0
+
+	switch {
+	case mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0:
+		batcher.add(fileChange{path: path, changeType: fileCreated})
+		if isDir && !skipWatchDir(name) {
+			if err := s.watchTree(path, false, batcher); err != nil {
+				s.logger.Warn("failed to watch new directory", "path", path, "error", err)
+			}
+		}
+	case mask&(syscall.IN_DELETE|syscall.IN_MOVED_FROM) != 0:
+		batcher.add(fileChange{path: path, changeType: fileDeleted})
+	case !isDir && mask&(syscall.IN_CLOSE_WRITE|syscall.IN_MODIFY) != 0:
+		batcher.add(fileChange{path: path, changeType: fileChanged})
+	}
+}
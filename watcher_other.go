@@ -0,0 +1,14 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"log/slog"
+)
+
+// newNativeChangeSource reports that native file watching is unavailable, so the
+// polling watcher is used instead.
+func newNativeChangeSource(_ string, _ *slog.Logger) (changeSource, error) {
+	return nil, errors.New("native file watching is only supported on linux")
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// registerFileWatchers registers didChangeWatchedFiles watchers with the client.
+func registerFileWatchers(t *testing.T, client *goplsClient, options string) {
+	t.Helper()
+
+	raw := `{"registrations":[{"id":"watch","method":"workspace/didChangeWatchedFiles",` +
+		`"registerOptions":` + options + `}]}`
+	if _, err := client.handleRegisterCapability(json.RawMessage(raw)); err != nil {
+		t.Fatalf("failed to register watchers: %v", err)
+	}
+}
+
+func TestFileWatcherMatching(t *testing.T) {
+	client := newClient("/ws", workspaceConfig{}, newTestLogger())
+	registerFileWatchers(t, client, `{"watchers":[`+
+		`{"globPattern":{"baseUri":"file:///ws","pattern":"**/*.{go,mod}"}},`+
+		`{"globPattern":"/ws/**/*.work","kind":1}]}`)
+
+	if len(client.fileWatchers) != 2 {
+		t.Fatalf("Expected 2 compiled watchers, got %d", len(client.fileWatchers))
+	}
+
+	relative, absolute := client.fileWatchers[0], client.fileWatchers[1]
+	if !relative.matches(fileChange{path: "/ws/pkg/a.go", changeType: fileChanged}) {
+		t.Error("Expected relative pattern to match a Go file under its base")
+	}
+	if relative.matches(fileChange{path: "/elsewhere/a.go", changeType: fileChanged}) {
+		t.Error("Expected relative pattern not to match outside its base")
+	}
+	if !absolute.matches(fileChange{path: "/ws/go.work", changeType: fileCreated}) {
+		t.Error("Expected absolute pattern to match go.work creation")
+	}
+	if absolute.matches(fileChange{path: "/ws/go.work", changeType: fileChanged}) {
+		t.Error("Expected create-only watcher to ignore changes")
+	}
+
+	// Unregistering removes the watchers
+	raw := `{"unregisterations":[{"id":"watch","method":"workspace/didChangeWatchedFiles"}]}`
+	if _, err := client.handleUnregisterCapability(json.RawMessage(raw)); err != nil {
+		t.Fatalf("failed to unregister watchers: %v", err)
+	}
+	if len(client.fileWatchers) != 0 {
+		t.Errorf("Expected no watchers after unregistration, got %d", len(client.fileWatchers))
+	}
+}
+
+func TestForwardFileChanges(t *testing.T) {
+	client := newClient("/ws", workspaceConfig{}, newTestLogger())
+	client.running = true
+	messages := captureNotifications(t, client)
+	registerFileWatchers(t, client, `{"watchers":[{"globPattern":"**/*.go"}]}`)
+
+	client.forwardFileChanges([]fileChange{
+		{path: "/ws/main.go", changeType: fileChanged},
+		{path: "/ws/README.md", changeType: fileChanged},
+	})
+
+	message := expectNotification(t, messages, "workspace/didChangeWatchedFiles")
+	var params DidChangeWatchedFilesParams
+	if err := json.Unmarshal(message.Params, &params); err != nil {
+		t.Fatalf("failed to decode params: %v", err)
+	}
+	if len(params.Changes) != 1 || params.Changes[0].URI != "file:///ws/main.go" {
+		t.Errorf("Expected only main.go to be forwarded, got %+v", params.Changes)
+	}
+}
+
+func TestChangeBatcher(t *testing.T) {
+	batcher := &changeBatcher{}
+	batcher.add(fileChange{path: "a.go", changeType: fileCreated})
+	batcher.add(fileChange{path: "a.go", changeType: fileChanged})
+	batcher.add(fileChange{path: "b.go", changeType: fileChanged})
+	batcher.add(fileChange{path: "tmp.go", changeType: fileCreated})
+	batcher.add(fileChange{path: "tmp.go", changeType: fileDeleted})
+	batcher.add(fileChange{path: "b.go", changeType: fileDeleted})
+	batcher.add(fileChange{path: "c.go", changeType: fileDeleted})
+	batcher.add(fileChange{path: "c.go", changeType: fileCreated})
+
+	changes := batcher.flush()
+	expected := []fileChange{
+		{path: "a.go", changeType: fileCreated},
+		{path: "b.go", changeType: fileDeleted},
+		{path: "c.go", changeType: fileChanged},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected[i], changes[i])
+		}
+	}
+	if len(batcher.flush()) != 0 {
+		t.Error("Expected batcher to be empty after flush")
+	}
+}
+
+// collectChanges waits for a change to path with the given type.
+func collectChanges(t *testing.T, source changeSource, path string, changeType int) {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case changes := <-source.changes():
+			for _, change := range changes {
+				if change.path == path && change.changeType == changeType {
+					return
+				}
+			}
+		case <-timeout:
+			t.Fatalf("Timed out waiting for change %d to %s", changeType, path)
+		}
+	}
+}
+
+// exerciseChangeSource checks that a change source reports creations, changes and deletions.
+func exerciseChangeSource(t *testing.T, root string, source changeSource) {
+	t.Helper()
+	defer func() { _ = source.close() }()
+
+	path := filepath.Join(root, "pkg", "new.go")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("package pkg\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	collectChanges(t, source, path, fileCreated)
+
+	if err := os.WriteFile(path, []byte("package pkg\n\nvar x = 1\n"), 0644); err != nil {
+		t.Fatalf("failed to update file: %v", err)
+	}
+	collectChanges(t, source, path, fileChanged)
+
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+	collectChanges(t, source, path, fileDeleted)
+}
+
+func TestPollingChangeSource(t *testing.T) {
+	root := t.TempDir()
+	exerciseChangeSource(t, root, newPollingChangeSource(root, 20*time.Millisecond))
+}
+
+func TestNativeChangeSource(t *testing.T) {
+	root := t.TempDir()
+	source, err := newNativeChangeSource(root, newTestLogger())
+	if err != nil {
+		t.Skipf("native file watching unavailable: %v", err)
+	}
+	exerciseChangeSource(t, root, source)
+}